@@ -0,0 +1,262 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// AddAnnotationError is an error from an invalid call to AddAnnotation on a ResponseWriter
+// or FinalizeResponseWriter.
+type AddAnnotationError struct {
+	// RuleID is the ID of the Rule that the Annotation was added for.
+	RuleID string
+	// CallSite is the location of the invalid AddAnnotation call, in the form "file.go:line".
+	//
+	// Empty if the call site is unknown, for example for invalid Annotations that were
+	// returned by a plugin to a Client.
+	CallSite string
+	// Err is the reason that the call was invalid.
+	Err error
+}
+
+// Error implements error.
+func (a *AddAnnotationError) Error() string {
+	if a == nil {
+		return ""
+	}
+	var sb strings.Builder
+	_, _ = sb.WriteString(`invalid Annotation for rule "`)
+	_, _ = sb.WriteString(a.RuleID)
+	_, _ = sb.WriteString(`"`)
+	if a.CallSite != "" {
+		_, _ = sb.WriteString(" added at ")
+		_, _ = sb.WriteString(a.CallSite)
+	}
+	if a.Err != nil {
+		_, _ = sb.WriteString(": ")
+		_, _ = sb.WriteString(a.Err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns Err.
+func (a *AddAnnotationError) Unwrap() error {
+	if a == nil {
+		return nil
+	}
+	return a.Err
+}
+
+// AggregateError is the error returned for a Check call if one or more calls to AddAnnotation
+// were invalid.
+//
+// Use errors.As to access the individual AddAnnotationErrors within a plugin, for example in
+// tests that call a Spec with NewClientForSpec. Errors are returned to Clients of plugin
+// binaries as messages only.
+type AggregateError struct {
+	// Errors are the errors of the invalid AddAnnotation calls.
+	//
+	// Errors are sorted by RuleID. Errors for the same Rule are in the order of the calls.
+	// Always non-empty.
+	Errors []*AddAnnotationError
+}
+
+// Error implements error.
+//
+// The messages of the Errors are separated by newlines. If there is more than one error,
+// the messages are preceded by a summary line that lists the offending Rule IDs.
+func (a *AggregateError) Error() string {
+	if a == nil {
+		return ""
+	}
+	messages := make([]string, 0, len(a.Errors)+1)
+	if len(a.Errors) > 1 {
+		ruleIDs := a.RuleIDs()
+		quotedRuleIDs := make([]string, len(ruleIDs))
+		for i, ruleID := range ruleIDs {
+			quotedRuleIDs[i] = strconv.Quote(ruleID)
+		}
+		messages = append(
+			messages,
+			strconv.Itoa(len(a.Errors))+" invalid Annotations for rules "+strings.Join(quotedRuleIDs, ", ")+":",
+		)
+	}
+	for _, addAnnotationError := range a.Errors {
+		messages = append(messages, addAnnotationError.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// RuleIDs returns the sorted, deduplicated IDs of the Rules that had invalid Annotations.
+func (a *AggregateError) RuleIDs() []string {
+	if a == nil {
+		return nil
+	}
+	var ruleIDs []string
+	for _, addAnnotationError := range a.Errors {
+		// Errors are sorted by RuleID.
+		if len(ruleIDs) == 0 || ruleIDs[len(ruleIDs)-1] != addAnnotationError.RuleID {
+			ruleIDs = append(ruleIDs, addAnnotationError.RuleID)
+		}
+	}
+	return ruleIDs
+}
+
+// Unwrap returns Errors, so that errors.Is and errors.As inspect each error.
+func (a *AggregateError) Unwrap() []error {
+	if a == nil {
+		return nil
+	}
+	errs := make([]error, len(a.Errors))
+	for i, addAnnotationError := range a.Errors {
+		errs[i] = addAnnotationError
+	}
+	return errs
+}
+
+// MixedLocationError is an error for an Annotation that was added for a file of the against
+// Files with the options for the Location, or for a file of the Files with the options for the
+// against Location.
+//
+// For example, breaking change Rules often have the against descriptor at hand, which
+// must be passed to WithAgainstDescriptor rather than WithDescriptor. A descriptor is
+// detected as an against descriptor if its file is one of the AgainstFiles, even if
+// the Files contain a file with the same name.
+//
+// MixedLocationErrors are wrapped in AddAnnotationErrors.
+type MixedLocationError struct {
+	// FileName is the name of the file that the Annotation was added for.
+	FileName string
+	// IsAgainstFile is true if the file is one of the AgainstFiles and was given to an option
+	// for the Location, and false if the file is one of the Files and was given to an option
+	// for the against Location.
+	IsAgainstFile bool
+	// Option is the option that the file was given to, such as "WithDescriptor".
+	Option string
+	// SuggestedOption is the option that should be used instead, such as "WithAgainstDescriptor".
+	SuggestedOption string
+}
+
+// Error implements error.
+func (m *MixedLocationError) Error() string {
+	if m == nil {
+		return ""
+	}
+	var sb strings.Builder
+	_, _ = sb.WriteString(m.Option)
+	_, _ = sb.WriteString(` was given `)
+	if m.IsAgainstFile {
+		_, _ = sb.WriteString(`against file "`)
+	} else {
+		_, _ = sb.WriteString(`file "`)
+	}
+	_, _ = sb.WriteString(m.FileName)
+	_, _ = sb.WriteString(`", use `)
+	_, _ = sb.WriteString(m.SuggestedOption)
+	_, _ = sb.WriteString(` instead`)
+	return sb.String()
+}
+
+// UnknownFileError is an error for an Annotation that was added for a file that is not in the
+// Files or AgainstFiles of the Request, when the UnknownFilePolicy is UnknownFilePolicyError.
+//
+// The file of every Location is validated when the Annotation is added within the plugin, so
+// that plugin authors get an error that references the Rule and the call site, rather than an
+// error from the Client when the Response is read.
+//
+// UnknownFileErrors are wrapped in AddAnnotationErrors.
+type UnknownFileError struct {
+	// FileName is the name of the file that the Annotation was added for.
+	FileName string
+	// IsAgainstFile is true if the file was given to an option for the against Location, and
+	// is therefore not in the AgainstFiles.
+	IsAgainstFile bool
+}
+
+// Error implements error.
+func (u *UnknownFileError) Error() string {
+	if u == nil {
+		return ""
+	}
+	var sb strings.Builder
+	_, _ = sb.WriteString(`cannot add annotation for unknown `)
+	if u.IsAgainstFile {
+		_, _ = sb.WriteString(`against `)
+	}
+	_, _ = sb.WriteString(`file: "`)
+	_, _ = sb.WriteString(u.FileName)
+	_, _ = sb.WriteString(`"`)
+	return sb.String()
+}
+
+// *** PRIVATE ***
+
+// newAggregateError returns a new AggregateError for the given errors, or nil if there
+// are no errors.
+//
+// The errors are sorted by Rule ID, with the relative order of errors for the same Rule
+// retained, so that the error is deterministic regardless of the order that the
+// RuleHandlers ran in.
+func newAggregateError(addAnnotationErrors []*AddAnnotationError) *AggregateError {
+	if len(addAnnotationErrors) == 0 {
+		return nil
+	}
+	addAnnotationErrors = slices.Clone(addAnnotationErrors)
+	slices.SortStableFunc(
+		addAnnotationErrors,
+		func(one *AddAnnotationError, two *AddAnnotationError) int {
+			return strings.Compare(one.RuleID, two.RuleID)
+		},
+	)
+	return &AggregateError{
+		Errors: addAnnotationErrors,
+	}
+}
+
+// newMixedLocationError returns a new MixedLocationError for a file of the other Files that
+// was given to the options for the Location if isAgainst is false, or the against Location if
+// isAgainst is true.
+func newMixedLocationError(fileName string, isAgainst bool, isDescriptor bool) *MixedLocationError {
+	option, suggestedOption := "WithFileName", "WithAgainstFileName"
+	if isDescriptor {
+		option, suggestedOption = "WithDescriptor", "WithAgainstDescriptor"
+	}
+	if isAgainst {
+		option, suggestedOption = suggestedOption, option
+	}
+	return &MixedLocationError{
+		FileName:        fileName,
+		IsAgainstFile:   !isAgainst,
+		Option:          option,
+		SuggestedOption: suggestedOption,
+	}
+}
+
+// getCallSite returns the call site of the caller of the function that calls getCallSite,
+// in the form "file.go:line".
+//
+// Returns the empty string if the call site cannot be determined.
+func getCallSite() string {
+	// 0 is getCallSite, 1 is the function that calls getCallSite, and 2 is its caller.
+	_, file, line, ok := runtime.Caller(2)
+	if !ok {
+		return ""
+	}
+	return file + ":" + strconv.Itoa(line)
+}
@@ -15,6 +15,7 @@
 package check
 
 import (
+	"slices"
 	"sort"
 
 	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
@@ -28,12 +29,24 @@ import (
 //
 // Annotations are created on the server-side via ResponseWriters, and returned
 // from Clients on Responses.
+//
+// The plugin protocol only carries the RuleID, Message, Location, and AgainstLocation of an
+// Annotation. The other values of Annotations returned from Clients are derived on the
+// client-side, as noted on each method.
 type Annotation interface {
 	// RuleID is the ID of the Rule that failed.
 	//
 	// This will always be present, have at least four characters, and only use
 	// characters from A-Z and underscores.
 	RuleID() string
+	// RuleCategories are the Categories of the Rule that failed.
+	//
+	// On the server-side (i.e. within the plugin), this is always populated from the RuleSpec.
+	// On the client-side, this is only populated if CheckCallWithRuleCategories is used, in which
+	// case the Categories are joined from ListRules.
+	//
+	// May be empty if the Rule has no Categories.
+	RuleCategories() []Category
 	// Message is a user-readable message describing the failure.
 	//
 	// If present, this will be a complete sentence starting with a capital letter
@@ -45,6 +58,26 @@ type Annotation interface {
 	//
 	// Will only potentially be produced for breaking change rules.
 	AgainstLocation() Location
+	// LocationUnavailableReason is the reason that the Annotation has no Location, or has a
+	// Location without a position.
+	//
+	// This is LocationUnavailableReasonNone if the Location has a position, or is a whole-file
+	// Location as requested.
+	//
+	// On the client-side, this is LocationUnavailableReasonUnknown unless the Location has a
+	// position.
+	LocationUnavailableReason() LocationUnavailableReason
+	// IsImport returns true if the Location is within a File that is an import.
+	//
+	// RuleHandlers may add Annotations for descriptors within imports, for example with
+	// WithDescriptor on the message type of a field. These Annotations are allowed, and are
+	// returned to the host, but hosts typically should not report them, as users generally
+	// cannot change imports. See CheckCallWithoutImportAnnotations to filter them on the
+	// client-side.
+	//
+	// Files that are not in the Request, and are added as per UnknownFilePolicyFileNameOnly,
+	// are imports. Returns false if there is no Location.
+	IsImport() bool
 
 	toProto() *checkv1beta1.Annotation
 
@@ -55,23 +88,30 @@ type Annotation interface {
 
 type annotation struct {
 	ruleID          string
+	ruleCategories  []Category
 	message         string
 	location        Location
 	againstLocation Location
+	// locationUnavailableReason is only set on the Location, not the AgainstLocation.
+	locationUnavailableReason LocationUnavailableReason
 }
 
 func newAnnotation(
 	ruleID string,
+	ruleCategories []Category,
 	message string,
 	location Location,
 	againstLocation Location,
+	locationUnavailableReason LocationUnavailableReason,
 ) (*annotation, error) {
 	// TODO: validation
 	return &annotation{
-		ruleID:          ruleID,
-		message:         message,
-		location:        location,
-		againstLocation: againstLocation,
+		ruleID:                    ruleID,
+		ruleCategories:            ruleCategories,
+		message:                   message,
+		location:                  location,
+		againstLocation:           againstLocation,
+		locationUnavailableReason: locationUnavailableReason,
 	}, nil
 }
 
@@ -79,6 +119,10 @@ func (a *annotation) RuleID() string {
 	return a.ruleID
 }
 
+func (a *annotation) RuleCategories() []Category {
+	return slices.Clone(a.ruleCategories)
+}
+
 func (a *annotation) Message() string {
 	return a.message
 }
@@ -91,6 +135,14 @@ func (a *annotation) AgainstLocation() Location {
 	return a.againstLocation
 }
 
+func (a *annotation) LocationUnavailableReason() LocationUnavailableReason {
+	return a.locationUnavailableReason
+}
+
+func (a *annotation) IsImport() bool {
+	return a.location != nil && a.location.File().IsImport()
+}
+
 func (a *annotation) toProto() *checkv1beta1.Annotation {
 	if a == nil {
 		return nil
@@ -0,0 +1,83 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"io"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// AnnotationSink observes the Annotations added by RuleHandlers and Finalize within a plugin.
+//
+// This allows plugin authors to export Annotations to a destination of their choosing as they
+// are added, for example to write them to a file, or to send them to a telemetry endpoint for
+// fleet-wide analytics on how often each Rule is hit. Pass an AnnotationSink to
+// MainWithAnnotationSink or ServerWithAnnotationSink.
+//
+// AnnotationSinks cannot change the behavior of Check calls. Annotations are observed as they
+// are added, so Annotations that are later suppressed, or that belong to a Check call that
+// later fails, are observed as well. Invalid Annotations, which result in an AddAnnotationError,
+// are not observed.
+//
+// ObserveAnnotation may be called concurrently, as Rules may be run in parallel, and must not
+// block for long, as it is called synchronously from AddAnnotation.
+type AnnotationSink interface {
+	// ObserveAnnotation is called for each Annotation as it is added.
+	ObserveAnnotation(annotation Annotation)
+}
+
+// AnnotationSinkFunc is a function that implements AnnotationSink.
+type AnnotationSinkFunc func(Annotation)
+
+// ObserveAnnotation implements AnnotationSink.
+func (a AnnotationSinkFunc) ObserveAnnotation(annotation Annotation) {
+	a(annotation)
+}
+
+// NewNDJSONAnnotationSink returns a new AnnotationSink that writes each Annotation to the
+// io.Writer as a JSON-serialized buf.plugin.check.v1beta1.Annotation, followed by a newline.
+//
+// Writes are serialized, so the io.Writer does not need to be safe for concurrent use.
+// Errors writing to the io.Writer are ignored, as AnnotationSinks cannot change the behavior
+// of Check calls.
+func NewNDJSONAnnotationSink(writer io.Writer) AnnotationSink {
+	return newNDJSONAnnotationSink(writer)
+}
+
+// *** PRIVATE ***
+
+type ndjsonAnnotationSink struct {
+	writer io.Writer
+	lock   sync.Mutex
+}
+
+func newNDJSONAnnotationSink(writer io.Writer) *ndjsonAnnotationSink {
+	return &ndjsonAnnotationSink{
+		writer: writer,
+	}
+}
+
+func (n *ndjsonAnnotationSink) ObserveAnnotation(annotation Annotation) {
+	data, err := protojson.Marshal(annotation.toProto())
+	if err != nil {
+		return
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	_, _ = n.writer.Write(append(data, '\n'))
+}
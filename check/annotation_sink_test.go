@@ -0,0 +1,92 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestAnnotationSink(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var lock sync.Mutex
+	var observedRuleIDs []string
+	annotationSink := AnnotationSinkFunc(
+		func(annotation Annotation) {
+			lock.Lock()
+			defer lock.Unlock()
+			observedRuleIDs = append(observedRuleIDs, annotation.RuleID())
+		},
+	)
+	ndjsonBuffer := &bytes.Buffer{}
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule1.",
+					Type:      RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+							responseWriter.AddAnnotation(WithMessage("first"))
+							responseWriter.AddAnnotation(WithMessage("second"))
+							// Invalid Annotations are not observed.
+							responseWriter.AddAnnotation(WithFileName("unknown.proto"))
+							return nil
+						},
+					),
+				},
+				{
+					ID:        "RULE2",
+					IsDefault: true,
+					Purpose:   "Test rule2.",
+					Type:      RuleTypeLint,
+					Handler:   nopRuleHandler,
+				},
+			},
+		},
+		ClientWithSpecServerOptions(
+			ServerWithAnnotationSink(annotationSink),
+			ServerWithAnnotationSink(NewNDJSONAnnotationSink(ndjsonBuffer)),
+		),
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	// AnnotationSinks do not change the behavior of Check calls.
+	require.Error(t, err)
+	require.Equal(t, []string{"RULE1", "RULE1"}, observedRuleIDs)
+	lines := strings.Split(strings.TrimSuffix(ndjsonBuffer.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	var messages []string
+	for _, line := range lines {
+		protoAnnotation := &checkv1beta1.Annotation{}
+		require.NoError(t, protojson.Unmarshal([]byte(line), protoAnnotation))
+		require.Equal(t, "RULE1", protoAnnotation.GetRuleId())
+		messages = append(messages, protoAnnotation.GetMessage())
+	}
+	require.ElementsMatch(t, []string{"first", "second"}, messages)
+}
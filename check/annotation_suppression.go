@@ -0,0 +1,70 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// *** PRIVATE ***
+
+// annotationSuppression suppresses the Annotations of a Rule within a File or descriptor.
+//
+// Added with ResponseWriter.SuppressAnnotations.
+type annotationSuppression struct {
+	ruleID   string
+	fileName string
+	// wholeFile is true if all Annotations for the File are suppressed.
+	wholeFile bool
+	// sourcePath is the SourcePath of the descriptor, if wholeFile is false.
+	//
+	// If empty, the descriptor could not be resolved to a SourcePath, and only whole-file
+	// Annotations are suppressed.
+	sourcePath protoreflect.SourcePath
+}
+
+func newAnnotationSuppression(ruleID string, descriptor protoreflect.Descriptor, location Location) *annotationSuppression {
+	_, wholeFile := descriptor.(protoreflect.FileDescriptor)
+	return &annotationSuppression{
+		ruleID:     ruleID,
+		fileName:   location.File().FileDescriptor().Path(),
+		wholeFile:  wholeFile,
+		sourcePath: location.SourcePath(),
+	}
+}
+
+// matches returns true if the Annotation is suppressed.
+//
+// An Annotation is suppressed if it is for the Rule, and its Location is within the File
+// or descriptor.
+func (a *annotationSuppression) matches(annotation Annotation) bool {
+	if annotation.RuleID() != a.ruleID {
+		return false
+	}
+	location := annotation.Location()
+	if location == nil || location.File().FileDescriptor().Path() != a.fileName {
+		return false
+	}
+	if a.wholeFile {
+		return true
+	}
+	sourcePath := location.UnclonedSourcePath()
+	if len(a.sourcePath) == 0 {
+		return len(sourcePath) == 0
+	}
+	return len(sourcePath) >= len(a.sourcePath) && slices.Equal(sourcePath[:len(a.sourcePath)], a.sourcePath)
+}
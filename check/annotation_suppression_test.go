@@ -0,0 +1,141 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestSuppressAnnotations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewDescriptorAnnotatingRuleSpec("RULE1"),
+				{
+					ID:        "RULE2",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, responseWriter ResponseWriter, request Request) error {
+							for _, file := range request.Files() {
+								fileDescriptor := file.FileDescriptor()
+								switch fileDescriptor.Path() {
+								case "a.proto":
+									responseWriter.SuppressAnnotations("RULE1", fileDescriptor.Messages().ByName("Foo"))
+								case "b.proto":
+									responseWriter.SuppressAnnotations("RULE1", fileDescriptor)
+								}
+							}
+							return nil
+						},
+					),
+				},
+				{
+					ID:        "RULE3",
+					IsDefault: false,
+					Purpose:   "Test rule.",
+					Type:      RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, responseWriter ResponseWriter, request Request) error {
+							responseWriter.SuppressAnnotations("RULE9", request.Files()[0].FileDescriptor())
+							return nil
+						},
+					),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	files, err := FilesForProtoFiles(
+		[]*checkv1beta1.File{
+			{
+				FileDescriptorProto: testNewSuppressionFileDescriptorProto("a.proto", "Foo", "Bar"),
+			},
+			{
+				FileDescriptorProto: testNewSuppressionFileDescriptorProto("b.proto", "Baz"),
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	request, err := NewRequest(files)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	// The Annotations for Foo and its field are suppressed, as are all Annotations for b.proto.
+	require.Equal(
+		t,
+		[]string{"a.Bar", "a.Bar.value"},
+		xslices.Map(response.Annotations(), Annotation.Message),
+	)
+
+	request, err = NewRequest(files, WithRuleIDs("RULE3"))
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.ErrorContains(t, err, `unknown rule ID "RULE9"`)
+}
+
+// testNewSuppressionFileDescriptorProto returns a new FileDescriptorProto with a message with
+// a single field for each message name, and SourceCodeInfo for all messages and fields.
+func testNewSuppressionFileDescriptorProto(fileName string, messageNames ...string) *descriptorpb.FileDescriptorProto {
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:           proto.String(fileName),
+		Package:        proto.String(fileName[:1]),
+		Syntax:         proto.String("proto3"),
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+	}
+	for i, messageName := range messageNames {
+		fileDescriptorProto.MessageType = append(
+			fileDescriptorProto.MessageType,
+			&descriptorpb.DescriptorProto{
+				Name: proto.String(messageName),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("value"),
+						Number:   proto.Int32(1),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						JsonName: proto.String("value"),
+					},
+				},
+			},
+		)
+		line := int32(i * 3)
+		fileDescriptorProto.SourceCodeInfo.Location = append(
+			fileDescriptorProto.SourceCodeInfo.Location,
+			&descriptorpb.SourceCodeInfo_Location{
+				Path: protoreflect.SourcePath{4, int32(i)},
+				Span: []int32{line, 0, line + 2, 1},
+			},
+			&descriptorpb.SourceCodeInfo_Location{
+				Path: protoreflect.SourcePath{4, int32(i), 2, 0},
+				Span: []int32{line + 1, 2, 20},
+			},
+		)
+	}
+	return fileDescriptorProto
+}
@@ -17,13 +17,14 @@ package check
 import (
 	"context"
 	"fmt"
+	"math/rand/v2"
 	"slices"
+	"sync"
 
 	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
 	"github.com/bufbuild/bufplugin-go/internal/pkg/thread"
 	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
 	"github.com/bufbuild/pluginrpc-go"
-	"github.com/bufbuild/protovalidate-go"
 )
 
 const defaultPageSize = 250
@@ -31,24 +32,58 @@ const defaultPageSize = 250
 // *** PRIVATE ***
 
 type checkServiceHandler struct {
-	spec                 *Spec
-	parallelism          int
+	spec        *Spec
+	parallelism int
+	// maxPageSize is 0 if the requested page size is used.
+	maxPageSize          int
 	rules                []Rule
 	ruleIDToRule         map[string]Rule
 	ruleIDToRuleHandler  map[string]RuleHandler
+	ruleIDToRuleSpec     map[string]*RuleSpec
 	ruleIDToIndex        map[string]int
 	categories           []Category
 	categoryIDToCategory map[string]Category
 	categoryIDToIndex    map[string]int
+	// coverageRecorder is nil if coverage is not recorded.
+	coverageRecorder *coverageRecorder
+	// annotationSinks observe the Annotations added by RuleHandlers and Finalize.
+	annotationSinks []AnnotationSink
+	// requestSnapshotter is nil if request snapshots are not written.
+	requestSnapshotter *requestSnapshotter
+	// requestCopies is true if each RuleHandler and Finalize is given its own copy of the Request.
+	requestCopies bool
+	// requestMutationDetection is true if the copies of the Request are validated to not
+	// be modified. If true, requestCopies is also true.
+	requestMutationDetection bool
+	// shuffleSeed is nil if the Rules and Files are not shuffled.
+	//
+	// Otherwise, shuffleSeed is the seed given to ServerWithShuffle.
+	shuffleSeed *int64
+	// lazyInit is nil if the Spec was fully validated on construction.
+	//
+	// Otherwise, lazyInit validates the Spec on the first Check call.
+	lazyInit func() error
 }
 
-func newCheckServiceHandler(spec *Spec, parallelism int) (*checkServiceHandler, error) {
-	validator, err := protovalidate.New()
-	if err != nil {
-		return nil, err
-	}
-	if err := validateSpec(validator, spec); err != nil {
-		return nil, err
+// newCheckServiceHandler returns a new checkServiceHandler for the Spec.
+//
+// If lazyInit is true, only the validation required to serve ListRules and ListCategories is
+// performed, and the Spec is fully validated on the first Check call.
+func newCheckServiceHandler(spec *Spec, parallelism int, lazyInit bool) (*checkServiceHandler, error) {
+	var lazyInitFunc func() error
+	if lazyInit {
+		if err := validateSpecIDs(spec); err != nil {
+			return nil, err
+		}
+		lazyInitFunc = sync.OnceValue(
+			func() error {
+				return validateSpecWithValidator(spec)
+			},
+		)
+	} else {
+		if err := validateSpecWithValidator(spec); err != nil {
+			return nil, err
+		}
 	}
 	categorySpecs := slices.Clone(spec.Categories)
 	sortCategorySpecs(categorySpecs)
@@ -74,6 +109,7 @@ func newCheckServiceHandler(spec *Spec, parallelism int) (*checkServiceHandler,
 	rules := make([]Rule, len(ruleSpecs))
 	ruleIDToRuleHandler := make(map[string]RuleHandler, len(ruleSpecs))
 	ruleIDToRule := make(map[string]Rule, len(ruleSpecs))
+	ruleIDToRuleSpec := make(map[string]*RuleSpec, len(ruleSpecs))
 	ruleIDToIndex := make(map[string]int, len(ruleSpecs))
 	for i, ruleSpec := range ruleSpecs {
 		rule, err := ruleSpecToRule(ruleSpec, categoryIDToCategory)
@@ -87,6 +123,7 @@ func newCheckServiceHandler(spec *Spec, parallelism int) (*checkServiceHandler,
 		}
 		rules[i] = rule
 		ruleIDToRuleHandler[id] = ruleSpec.Handler
+		ruleIDToRuleSpec[id] = ruleSpec
 		ruleIDToRule[id] = rule
 		ruleIDToIndex[id] = i
 	}
@@ -95,11 +132,13 @@ func newCheckServiceHandler(spec *Spec, parallelism int) (*checkServiceHandler,
 		parallelism:          parallelism,
 		rules:                rules,
 		ruleIDToRuleHandler:  ruleIDToRuleHandler,
+		ruleIDToRuleSpec:     ruleIDToRuleSpec,
 		ruleIDToRule:         ruleIDToRule,
 		ruleIDToIndex:        ruleIDToIndex,
 		categories:           categories,
 		categoryIDToCategory: categoryIDToCategory,
 		categoryIDToIndex:    categoryIDToIndex,
+		lazyInit:             lazyInitFunc,
 	}, nil
 }
 
@@ -107,16 +146,69 @@ func (c *checkServiceHandler) Check(
 	ctx context.Context,
 	checkRequest *checkv1beta1.CheckRequest,
 ) (*checkv1beta1.CheckResponse, error) {
-	request, err := RequestForProtoRequest(checkRequest)
+	if c.requestSnapshotter == nil {
+		return c.check(ctx, checkRequest)
+	}
+	checkResponse, err := c.check(ctx, checkRequest)
+	if err != nil {
+		return nil, c.requestSnapshotter.wrapError(err, checkRequest)
+	}
+	return checkResponse, nil
+}
+
+func (c *checkServiceHandler) check(
+	ctx context.Context,
+	checkRequest *checkv1beta1.CheckRequest,
+) (*checkv1beta1.CheckResponse, error) {
+	// Panics are only recovered when request snapshots are enabled, so that the
+	// CheckRequest can be written before the plugin exits.
+	shouldRecover := c.requestSnapshotter != nil
+	if err := ctx.Err(); err != nil {
+		return nil, newContextDoneError(err)
+	}
+	if c.lazyInit != nil {
+		if err := c.lazyInit(); err != nil {
+			return nil, err
+		}
+	}
+	request, err := RequestForProtoRequest(c.maybePruneCheckRequest(checkRequest))
 	if err != nil {
 		return nil, err
 	}
-	if c.spec.Before != nil {
-		ctx, request, err = c.spec.Before(ctx, request)
+	if len(c.spec.DefaultOptions) > 0 {
+		request, err = newRequest(
+			request.UnclonedFiles(),
+			WithAgainstFiles(request.UnclonedAgainstFiles()),
+			WithOptions(mergeOptions(c.spec.DefaultOptions, request.Options())),
+			WithRuleIDs(request.RuleIDs()...),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var shuffleRand *rand.Rand
+	if c.shuffleSeed != nil {
+		shuffleRand = newShuffleRand(*c.shuffleSeed)
+		request, err = shuffleRequest(shuffleRand, request)
 		if err != nil {
 			return nil, err
 		}
 	}
+	// The RequestStore is scoped to this Check call, and is set before Before is called
+	// so that Before can populate it.
+	ctx = withRequestStore(ctx)
+	if c.spec.Before != nil {
+		if err := callRecover(
+			shouldRecover,
+			"Before",
+			func() error {
+				ctx, request, err = c.spec.Before(ctx, request)
+				return err
+			},
+		); err != nil {
+			return nil, err
+		}
+	}
 	rules := xslices.Filter(c.rules, func(rule Rule) bool { return rule.IsDefault() })
 	if ruleIDs := request.RuleIDs(); len(ruleIDs) > 0 {
 		rules = make([]Rule, 0)
@@ -128,33 +220,95 @@ func (c *checkServiceHandler) Check(
 			rules = append(rules, rule)
 		}
 	}
-	multiResponseWriter, err := newMultiResponseWriter(request)
+	multiResponseWriter, err := newMultiResponseWriter(request, c.ruleIDToRule, c.spec.UnknownFilePolicy)
+	if err != nil {
+		return nil, err
+	}
+	multiResponseWriter.annotationSinks = c.annotationSinks
+	multiResponseWriter.exceptions, err = getExceptions(request.Options(), c.rules, c.ruleIDToRule)
 	if err != nil {
 		return nil, err
 	}
+	if shuffleRand != nil {
+		rules = shuffleSlice(shuffleRand, rules)
+	}
+	// Rules are started in order, so each Rule must come after its dependencies.
+	rules = sortRulesByDependencies(rules, c.ruleIDToRuleSpec)
+	ruleIDToDoneC := make(map[string]chan struct{}, len(rules))
+	for _, rule := range rules {
+		ruleIDToDoneC[rule.ID()] = make(chan struct{})
+	}
 	if err := thread.Parallelize(
 		ctx,
 		xslices.Map(
 			rules,
 			func(rule Rule) func(context.Context) error {
 				return func(ctx context.Context) error {
+					defer close(ruleIDToDoneC[rule.ID()])
 					ruleHandler, ok := c.ruleIDToRuleHandler[rule.ID()]
 					if !ok {
 						// This should never happen.
 						return fmt.Errorf("no RuleHandler for id %q", rule.ID())
 					}
-					return ruleHandler.Handle(
-						ctx,
-						multiResponseWriter.newResponseWriter(rule.ID()),
-						request,
+					ctx, err := c.waitForRuleDependencies(ctx, rule.ID(), ruleIDToDoneC, multiResponseWriter)
+					if err != nil {
+						return err
+					}
+					name := fmt.Sprintf("RuleHandler for %q", rule.ID())
+					return callRecover(
+						shouldRecover,
+						name,
+						func() error {
+							return c.callWithRequest(
+								name,
+								request,
+								func(request Request) error {
+									return ruleHandler.Handle(
+										withCoverageVisitor(ctx, c.coverageRecorder, rule.ID()),
+										multiResponseWriter.newResponseWriter(rule.ID()),
+										request,
+									)
+								},
+							)
+						},
 					)
 				}
 			},
 		),
 		thread.WithParallelism(c.parallelism),
 	); err != nil {
+		// If the context is done, we do not know which RuleHandlers completed, so the
+		// cancellation takes precedence over any errors from RuleHandlers.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, newContextDoneError(ctxErr)
+		}
 		return nil, err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, newContextDoneError(err)
+	}
+	if c.spec.Finalize != nil {
+		if err := callRecover(
+			shouldRecover,
+			"Finalize",
+			func() error {
+				return c.callWithRequest(
+					"Finalize",
+					request,
+					func(request Request) error {
+						return c.spec.Finalize(
+							ctx,
+							multiResponseWriter.newFinalizeResponseWriter(xslices.Map(rules, Rule.ID)),
+							request,
+							multiResponseWriter.sortedAnnotations(),
+						)
+					},
+				)
+			},
+		); err != nil {
+			return nil, err
+		}
+	}
 	response, err := multiResponseWriter.toResponse()
 	if err != nil {
 		return nil, err
@@ -162,6 +316,95 @@ func (c *checkServiceHandler) Check(
 	return response.toProto(), nil
 }
 
+// waitForRuleDependencies waits for the dependencies of the Rule that are run as part of
+// the Check call to complete, and returns a context with the RuleDependencies of the Rule.
+func (c *checkServiceHandler) waitForRuleDependencies(
+	ctx context.Context,
+	ruleID string,
+	ruleIDToDoneC map[string]chan struct{},
+	multiResponseWriter *multiResponseWriter,
+) (context.Context, error) {
+	ruleSpec, ok := c.ruleIDToRuleSpec[ruleID]
+	if !ok || len(ruleSpec.DependencyIDs) == 0 {
+		return ctx, nil
+	}
+	var dependencyIDs []string
+	for _, dependencyID := range ruleSpec.DependencyIDs {
+		doneC, ok := ruleIDToDoneC[dependencyID]
+		if !ok {
+			continue
+		}
+		select {
+		case <-doneC:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		dependencyIDs = append(dependencyIDs, dependencyID)
+	}
+	return withRuleDependencies(ctx, multiResponseWriter, dependencyIDs), nil
+}
+
+// callWithRequest calls f with the Request, or with a copy of the Request if request copies
+// are enabled.
+//
+// If request mutation detection is enabled, the copy is validated to not have been modified
+// by f. The name is used in the returned error.
+func (c *checkServiceHandler) callWithRequest(name string, request Request, f func(Request) error) error {
+	if !c.requestCopies {
+		return f(request)
+	}
+	copiedRequest, err := copyRequest(request)
+	if err != nil {
+		return err
+	}
+	if err := f(copiedRequest); err != nil {
+		return err
+	}
+	if c.requestMutationDetection {
+		return validateRequestNotMutated(name, request, copiedRequest)
+	}
+	return nil
+}
+
+// validateSpecWithValidator validates the Spec with the Validator for the process.
+func validateSpecWithValidator(spec *Spec) error {
+	validator, err := getValidator()
+	if err != nil {
+		return err
+	}
+	return validateSpec(validator, spec)
+}
+
+// maybePruneCheckRequest prunes the descriptors from the CheckRequest that are not
+// inspected by the Rules that will be run, if all of these Rules declare their
+// DescriptorKinds.
+//
+// Returns the CheckRequest itself if nothing can be pruned.
+func (c *checkServiceHandler) maybePruneCheckRequest(checkRequest *checkv1beta1.CheckRequest) *checkv1beta1.CheckRequest {
+	var ruleSpecs []*RuleSpec
+	if ruleIDs := checkRequest.GetRuleIds(); len(ruleIDs) > 0 {
+		for _, ruleID := range ruleIDs {
+			ruleSpec, ok := c.ruleIDToRuleSpec[ruleID]
+			if !ok {
+				// Unknown Rule IDs are reported when the Rules are selected.
+				return checkRequest
+			}
+			ruleSpecs = append(ruleSpecs, ruleSpec)
+		}
+	} else {
+		for _, rule := range c.rules {
+			if rule.IsDefault() {
+				ruleSpecs = append(ruleSpecs, c.ruleIDToRuleSpec[rule.ID()])
+			}
+		}
+	}
+	descriptorKinds, ok := descriptorKindsForRuleSpecs(ruleSpecs)
+	if !ok {
+		return checkRequest
+	}
+	return pruneCheckRequest(checkRequest, descriptorKinds)
+}
+
 func (c *checkServiceHandler) ListRules(_ context.Context, listRulesRequest *checkv1beta1.ListRulesRequest) (*checkv1beta1.ListRulesResponse, error) {
 	rules, nextPageToken, err := c.getRulesAndNextPageToken(
 		int(listRulesRequest.GetPageSize()),
@@ -190,6 +433,17 @@ func (c *checkServiceHandler) ListCategories(_ context.Context, listCategoriesRe
 	}, nil
 }
 
+// effectivePageSize returns the page size to use for the requested page size.
+func (c *checkServiceHandler) effectivePageSize(pageSize int) int {
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	if c.maxPageSize > 0 && pageSize > c.maxPageSize {
+		pageSize = c.maxPageSize
+	}
+	return pageSize
+}
+
 func (c *checkServiceHandler) getRulesAndNextPageToken(pageSize int, pageToken string) ([]Rule, string, error) {
 	index := 0
 	if pageToken != "" {
@@ -199,9 +453,7 @@ func (c *checkServiceHandler) getRulesAndNextPageToken(pageSize int, pageToken s
 			return nil, "", pluginrpc.NewErrorf(pluginrpc.CodeInvalidArgument, "unknown page token: %q", pageToken)
 		}
 	}
-	if pageSize == 0 {
-		pageSize = defaultPageSize
-	}
+	pageSize = c.effectivePageSize(pageSize)
 	resultRules := make([]Rule, 0, len(c.rules)-index)
 	for range pageSize {
 		if index >= len(c.rules) {
@@ -226,9 +478,7 @@ func (c *checkServiceHandler) getCategoriesAndNextPageToken(pageSize int, pageTo
 			return nil, "", pluginrpc.NewErrorf(pluginrpc.CodeInvalidArgument, "unknown page token: %q", pageToken)
 		}
 	}
-	if pageSize == 0 {
-		pageSize = defaultPageSize
-	}
+	pageSize = c.effectivePageSize(pageSize)
 	resultCategories := make([]Category, 0, len(c.categories)-index)
 	for range pageSize {
 		if index >= len(c.categories) {
@@ -0,0 +1,73 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/bufbuild/pluginrpc-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckServiceHandlerCancellation(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var executed atomic.Int64
+	ruleSpecs := make([]*RuleSpec, 10)
+	for i := range ruleSpecs {
+		ruleSpecs[i] = &RuleSpec{
+			ID:        fmt.Sprintf("RULE%d", i),
+			IsDefault: true,
+			Purpose:   "Test rule.",
+			Type:      RuleTypeLint,
+			Handler: RuleHandlerFunc(
+				func(context.Context, ResponseWriter, Request) error {
+					executed.Add(1)
+					// Cancel the Check call from within the first RuleHandler.
+					cancel()
+					return nil
+				},
+			),
+		}
+	}
+	checkServiceHandler, err := newCheckServiceHandler(&Spec{Rules: ruleSpecs}, 1, false)
+	require.NoError(t, err)
+	_, err = checkServiceHandler.Check(ctx, &checkv1beta1.CheckRequest{})
+	pluginrpcError := &pluginrpc.Error{}
+	require.True(t, errors.As(err, &pluginrpcError))
+	require.Equal(t, pluginrpc.CodeCanceled, pluginrpcError.Code())
+	// With a parallelism of 1, no further RuleHandlers are started after cancellation.
+	require.Equal(t, int64(1), executed.Load())
+
+	// Already-cancelled contexts do not run any RuleHandlers.
+	_, err = checkServiceHandler.Check(ctx, &checkv1beta1.CheckRequest{})
+	require.True(t, errors.As(err, &pluginrpcError))
+	require.Equal(t, pluginrpc.CodeCanceled, pluginrpcError.Code())
+	require.Equal(t, int64(1), executed.Load())
+
+	deadlineCtx, deadlineCancel := context.WithDeadline(context.Background(), time.Now())
+	defer deadlineCancel()
+	_, err = checkServiceHandler.Check(deadlineCtx, &checkv1beta1.CheckRequest{})
+	require.True(t, errors.As(err, &pluginrpcError))
+	require.Equal(t, pluginrpc.CodeDeadlineExceeded, pluginrpcError.Code())
+}
@@ -0,0 +1,473 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkcompile compiles .proto files into check.Files.
+//
+// This is used by checktest to build Requests from .proto files on disk, and can be used
+// by other tools that want to run plugins against .proto files without buf.
+package checkcompile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/linker"
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/protoutil"
+	"github.com/bufbuild/protocompile/reporter"
+	"github.com/bufbuild/protocompile/wellknownimports"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Compile compiles the .proto files at the given paths into check.Files.
+//
+// The dirPaths are the directories that contain the .proto files, and correspond to the -I flag
+// of protoc. Imports within the .proto files are resolved relative to these directories, and
+// the well-known types are always available. The filePaths are the files to compile, relative
+// to one of the dirPaths, and correspond to the arguments passed to protoc. Any imports of the
+// filePaths are compiled as well, and marked as imports.
+//
+// By default, the well-known imports are resolved from the source files that are shipped with
+// protoc, after the dirPaths and any dependencies. See CompileWithWellKnownImports and
+// CompileWithoutWellKnownImports to change this.
+//
+// Source code info is included, matching what buf produces. Files that do not specify a syntax
+// are marked as such, and unused imports are recorded, so that Rules that check for these
+// conditions behave as they do within buf.
+//
+// If the files fail to compile, a *CompileError is returned that describes every error.
+func Compile(ctx context.Context, dirPaths []string, filePaths []string, options ...CompileOption) ([]check.File, error) {
+	return compile(
+		ctx,
+		[]compileUnit{
+			{
+				dirPaths:  fromSlashPaths(dirPaths),
+				filePaths: fromSlashPaths(filePaths),
+			},
+		},
+		options...,
+	)
+}
+
+// Module is a root of .proto files with its own import resolution, for use with CompileModules.
+//
+// This simulates a module within a buf workspace.
+type Module struct {
+	// Name is the name of the Module, used to reference the Module from the DepNames
+	// of other Modules.
+	//
+	// Required, and must be unique.
+	Name string
+	// DirPath is the root directory of the Module.
+	//
+	// Required.
+	DirPath string
+	// FilePaths are the files to compile within the Module, relative to DirPath.
+	//
+	// These files are not marked as imports. If empty, the Module is only used to resolve
+	// imports from other Modules.
+	FilePaths []string
+	// DepNames are the Names of the Modules that the files within this Module can import from.
+	//
+	// Dependencies are transitive. Files within this Module cannot import files from Modules
+	// that are not dependencies.
+	DepNames []string
+}
+
+// CompileModules compiles the .proto files within the given Modules into check.Files.
+//
+// Imports within each Module are resolved relative to the DirPath of the Module and the DirPaths
+// of its dependencies, and the well-known types are always available. Any imports of the
+// FilePaths of the Modules are compiled as well, and marked as imports, including files
+// within other Modules. This allows reproducing cross-Module scenarios as they occur within
+// a buf workspace. As with buf, file paths should be unique across Modules.
+//
+// At least one Module must have FilePaths.
+//
+// See Compile for what is included in the resulting check.Files.
+func CompileModules(ctx context.Context, modules []Module, options ...CompileOption) ([]check.File, error) {
+	nameToModule, err := validateModules(modules)
+	if err != nil {
+		return nil, err
+	}
+	var compileUnits []compileUnit
+	for _, module := range modules {
+		if len(module.FilePaths) == 0 {
+			continue
+		}
+		var dirPaths []string
+		for _, depModule := range moduleAndTransitiveDeps(nameToModule, module) {
+			dirPaths = append(dirPaths, depModule.DirPath)
+		}
+		compileUnits = append(
+			compileUnits,
+			compileUnit{
+				dirPaths:  fromSlashPaths(dirPaths),
+				filePaths: fromSlashPaths(module.FilePaths),
+			},
+		)
+	}
+	return compile(ctx, compileUnits, options...)
+}
+
+// CompileOption is an option for Compile and CompileModules.
+type CompileOption func(*compileOptions)
+
+// CompileWithDependencies returns a new CompileOption that makes the given precompiled
+// FileDescriptorProtos available as imports.
+//
+// This allows large dependencies, such as vendored third-party APIs, to be provided as a
+// FileDescriptorSet instead of as .proto files. The FileDescriptorProtos must include all of
+// their own dependencies, other than the well-known types. Files found within the dirPaths
+// take precedence over the given FileDescriptorProtos. Any FileDescriptorProtos that are
+// imported are marked as imports, and keep whatever source code info they were built with.
+func CompileWithDependencies(fileDescriptorProtos ...*descriptorpb.FileDescriptorProto) CompileOption {
+	return func(compileOptions *compileOptions) {
+		compileOptions.dependencies = append(compileOptions.dependencies, fileDescriptorProtos...)
+	}
+}
+
+// CompileWithoutSourceCodeInfo returns a new CompileOption that results in the check.Files
+// not having any SourceCodeInfo, including the check.Files for dependencies given with
+// CompileWithDependencies.
+//
+// This simulates hosts that strip source code info, for example for performance, so that
+// Rules can be tested to degrade gracefully. See check.Location for how Locations behave
+// in this case.
+func CompileWithoutSourceCodeInfo() CompileOption {
+	return func(compileOptions *compileOptions) {
+		compileOptions.withoutSourceCodeInfo = true
+	}
+}
+
+// CompileWithWellKnownImports returns a new CompileOption that makes the given precompiled
+// FileDescriptorProtos available as the well-known imports, instead of the source files that
+// are shipped with protoc.
+//
+// This allows Rules to be tested against modified or older definitions of the well-known types,
+// for example a google/protobuf/descriptor.proto that predates a given option. Only the given
+// FileDescriptorProtos are available, so any other well-known imports that are needed must be
+// given as well. As with CompileWithDependencies, files found within the dirPaths and any
+// dependencies take precedence, and the FileDescriptorProtos keep whatever source code info
+// they were built with.
+//
+// It is not valid to use both CompileWithWellKnownImports and CompileWithoutWellKnownImports.
+func CompileWithWellKnownImports(fileDescriptorProtos ...*descriptorpb.FileDescriptorProto) CompileOption {
+	return func(compileOptions *compileOptions) {
+		compileOptions.wellKnownImports = append(compileOptions.wellKnownImports, fileDescriptorProtos...)
+	}
+}
+
+// CompileWithoutWellKnownImports returns a new CompileOption that does not make the well-known
+// imports available.
+//
+// Any imports of the well-known types must then be resolved from the dirPaths or from
+// dependencies given with CompileWithDependencies. This allows tests to provide their own
+// .proto sources for the well-known types.
+//
+// It is not valid to use both CompileWithWellKnownImports and CompileWithoutWellKnownImports.
+func CompileWithoutWellKnownImports() CompileOption {
+	return func(compileOptions *compileOptions) {
+		compileOptions.withoutWellKnownImports = true
+	}
+}
+
+// *** PRIVATE ***
+
+type compileOptions struct {
+	dependencies            []*descriptorpb.FileDescriptorProto
+	withoutSourceCodeInfo   bool
+	wellKnownImports        []*descriptorpb.FileDescriptorProto
+	withoutWellKnownImports bool
+}
+
+func newCompileOptions() *compileOptions {
+	return &compileOptions{}
+}
+
+// newFileDescriptorProtoResolver returns a new protocompile.Resolver that resolves the given
+// precompiled FileDescriptorProtos by name.
+//
+// The kind describes the FileDescriptorProtos within errors, such as "dependency".
+func newFileDescriptorProtoResolver(kind string, fileDescriptorProtos []*descriptorpb.FileDescriptorProto) (protocompile.Resolver, error) {
+	nameToFileDescriptorProto := make(map[string]*descriptorpb.FileDescriptorProto, len(fileDescriptorProtos))
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		name := fileDescriptorProto.GetName()
+		if name == "" {
+			return nil, fmt.Errorf("%s FileDescriptorProto has no name", kind)
+		}
+		if _, ok := nameToFileDescriptorProto[name]; ok {
+			return nil, fmt.Errorf("duplicate %s FileDescriptorProto %q", kind, name)
+		}
+		nameToFileDescriptorProto[name] = fileDescriptorProto
+	}
+	return protocompile.ResolverFunc(
+		func(path string) (protocompile.SearchResult, error) {
+			fileDescriptorProto, ok := nameToFileDescriptorProto[path]
+			if !ok {
+				return protocompile.SearchResult{}, protoregistry.NotFound
+			}
+			return protocompile.SearchResult{
+				Proto: fileDescriptorProto,
+			}, nil
+		},
+	), nil
+}
+
+// compileUnit is a set of files to compile with the same import resolution.
+type compileUnit struct {
+	dirPaths  []string
+	filePaths []string
+}
+
+// compile compiles each compileUnit with its own Compiler, and merges the results.
+//
+// Files that are compiled by multiple compileUnits, such as a dependency imported from two
+// Modules, are only included once.
+func compile(ctx context.Context, compileUnits []compileUnit, options ...CompileOption) ([]check.File, error) {
+	compileOptions := newCompileOptions()
+	for _, option := range options {
+		option(compileOptions)
+	}
+	if len(compileOptions.wellKnownImports) > 0 && compileOptions.withoutWellKnownImports {
+		return nil, errors.New("cannot use both CompileWithWellKnownImports and CompileWithoutWellKnownImports")
+	}
+	dependencyResolver, err := newFileDescriptorProtoResolver("dependency", compileOptions.dependencies)
+	if err != nil {
+		return nil, err
+	}
+	withWellKnownImports := wellknownimports.WithStandardImports
+	switch {
+	case compileOptions.withoutWellKnownImports:
+		withWellKnownImports = func(resolver protocompile.Resolver) protocompile.Resolver {
+			return resolver
+		}
+	case len(compileOptions.wellKnownImports) > 0:
+		wellKnownImportsResolver, err := newFileDescriptorProtoResolver("well-known import", compileOptions.wellKnownImports)
+		if err != nil {
+			return nil, err
+		}
+		withWellKnownImports = func(resolver protocompile.Resolver) protocompile.Resolver {
+			return protocompile.CompositeResolver{resolver, wellKnownImportsResolver}
+		}
+	}
+	// This is what buf uses.
+	sourceInfoMode := protocompile.SourceInfoExtraOptionLocations
+	if compileOptions.withoutSourceCodeInfo {
+		sourceInfoMode = protocompile.SourceInfoNone
+	}
+	toSlashFilePathMap := make(map[string]struct{})
+	var warningErrorsWithPos []reporter.ErrorWithPos
+	var files []linker.File
+	for _, compileUnit := range compileUnits {
+		for _, filePath := range compileUnit.filePaths {
+			toSlashFilePathMap[filepath.ToSlash(filePath)] = struct{}{}
+		}
+		var errorsWithPos []reporter.ErrorWithPos
+		compiler := protocompile.Compiler{
+			Resolver: withWellKnownImports(
+				protocompile.CompositeResolver{
+					&protocompile.SourceResolver{
+						ImportPaths: compileUnit.dirPaths,
+					},
+					dependencyResolver,
+				},
+			),
+			Reporter: reporter.NewReporter(
+				func(errorWithPos reporter.ErrorWithPos) error {
+					// Keep going so that all errors are reported in the CompileError.
+					errorsWithPos = append(errorsWithPos, errorWithPos)
+					return nil
+				},
+				func(errorWithPos reporter.ErrorWithPos) {
+					warningErrorsWithPos = append(warningErrorsWithPos, errorWithPos)
+				},
+			),
+			SourceInfoMode: sourceInfoMode,
+		}
+		unitFiles, err := compiler.Compile(ctx, compileUnit.filePaths...)
+		if err != nil {
+			if compileErr := newCompileError(compileUnit.dirPaths, errorsWithPos); compileErr != nil {
+				return nil, compileErr
+			}
+			return nil, err
+		}
+		files = append(files, unitFiles...)
+	}
+	syntaxUnspecifiedFilePaths := make(map[string]struct{})
+	filePathToUnusedDependencyFilePaths := make(map[string]map[string]struct{})
+	for _, warningErrorWithPos := range warningErrorsWithPos {
+		maybeAddSyntaxUnspecified(syntaxUnspecifiedFilePaths, warningErrorWithPos)
+		maybeAddUnusedDependency(filePathToUnusedDependencyFilePaths, warningErrorWithPos)
+	}
+	fileDescriptorSet := fileDescriptorSetForFileDescriptors(files)
+
+	protoFiles := make([]*checkv1beta1.File, len(fileDescriptorSet.GetFile()))
+	for i, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+		if compileOptions.withoutSourceCodeInfo && fileDescriptorProto.GetSourceCodeInfo() != nil {
+			// Precompiled dependencies may have SourceCodeInfo. Clone so that the
+			// FileDescriptorProto given by the caller is not modified.
+			fileDescriptorProto = proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+			fileDescriptorProto.SourceCodeInfo = nil
+		}
+		_, isNotImport := toSlashFilePathMap[fileDescriptorProto.GetName()]
+		_, isSyntaxUnspecified := syntaxUnspecifiedFilePaths[fileDescriptorProto.GetName()]
+		unusedDependencyIndexes := unusedDependencyIndexesForFilePathToUnusedDependencyFilePaths(
+			fileDescriptorProto,
+			filePathToUnusedDependencyFilePaths[fileDescriptorProto.GetName()],
+		)
+		protoFiles[i] = &checkv1beta1.File{
+			FileDescriptorProto: fileDescriptorProto,
+			IsImport:            !isNotImport,
+			IsSyntaxUnspecified: isSyntaxUnspecified,
+			UnusedDependency:    unusedDependencyIndexes,
+		}
+	}
+	return check.FilesForProtoFiles(protoFiles)
+}
+
+// validateModules validates the Modules, and returns a map from Name to Module.
+func validateModules(modules []Module) (map[string]Module, error) {
+	nameToModule := make(map[string]Module, len(modules))
+	var hasFilePaths bool
+	for _, module := range modules {
+		if module.Name == "" {
+			return nil, errors.New("no Name specified on Module")
+		}
+		if module.DirPath == "" {
+			return nil, fmt.Errorf("no DirPath specified on Module %q", module.Name)
+		}
+		if _, ok := nameToModule[module.Name]; ok {
+			return nil, fmt.Errorf("duplicate Module name %q", module.Name)
+		}
+		nameToModule[module.Name] = module
+		if len(module.FilePaths) > 0 {
+			hasFilePaths = true
+		}
+	}
+	if !hasFilePaths {
+		return nil, errors.New("no FilePaths specified on any Module")
+	}
+	for _, module := range modules {
+		for _, depName := range module.DepNames {
+			if _, ok := nameToModule[depName]; !ok {
+				return nil, fmt.Errorf("module %q has unknown dependency %q", module.Name, depName)
+			}
+		}
+	}
+	return nameToModule, nil
+}
+
+// moduleAndTransitiveDeps returns the Module followed by its transitive dependencies,
+// in breadth-first order.
+//
+// Cycles between Modules are allowed.
+func moduleAndTransitiveDeps(nameToModule map[string]Module, module Module) []Module {
+	seen := map[string]struct{}{
+		module.Name: {},
+	}
+	result := []Module{module}
+	for i := 0; i < len(result); i++ {
+		for _, depName := range result[i].DepNames {
+			if _, ok := seen[depName]; ok {
+				continue
+			}
+			seen[depName] = struct{}{}
+			result = append(result, nameToModule[depName])
+		}
+	}
+	return result
+}
+
+func unusedDependencyIndexesForFilePathToUnusedDependencyFilePaths(
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+	unusedDependencyFilePaths map[string]struct{},
+) []int32 {
+	unusedDependencyIndexes := make([]int32, 0, len(unusedDependencyFilePaths))
+	if len(unusedDependencyFilePaths) == 0 {
+		return unusedDependencyIndexes
+	}
+	dependencyFilePaths := fileDescriptorProto.GetDependency()
+	for i := range len(dependencyFilePaths) {
+		if _, ok := unusedDependencyFilePaths[dependencyFilePaths[i]]; ok {
+			unusedDependencyIndexes = append(unusedDependencyIndexes, int32(i))
+		}
+	}
+	return unusedDependencyIndexes
+}
+
+func maybeAddSyntaxUnspecified(
+	syntaxUnspecifiedFilePaths map[string]struct{},
+	errorWithPos reporter.ErrorWithPos,
+) {
+	if !errors.Is(errorWithPos, parser.ErrNoSyntax) {
+		return
+	}
+	syntaxUnspecifiedFilePaths[errorWithPos.GetPosition().Filename] = struct{}{}
+}
+
+func maybeAddUnusedDependency(
+	filePathToUnusedDependencyFilePaths map[string]map[string]struct{},
+	errorWithPos reporter.ErrorWithPos,
+) {
+	var errorUnusedImport linker.ErrorUnusedImport
+	if !errors.As(errorWithPos, &errorUnusedImport) {
+		return
+	}
+	pos := errorWithPos.GetPosition()
+	unusedDependencyFilePaths, ok := filePathToUnusedDependencyFilePaths[pos.Filename]
+	if !ok {
+		unusedDependencyFilePaths = make(map[string]struct{})
+		filePathToUnusedDependencyFilePaths[pos.Filename] = unusedDependencyFilePaths
+	}
+	unusedDependencyFilePaths[errorUnusedImport.UnusedImport()] = struct{}{}
+}
+
+func fileDescriptorSetForFileDescriptors[D protoreflect.FileDescriptor](files []D) *descriptorpb.FileDescriptorSet {
+	soFar := make(map[string]struct{}, len(files))
+	slice := make([]*descriptorpb.FileDescriptorProto, 0, len(files))
+	for _, file := range files {
+		toFileDescriptorProtoSlice(file, &slice, soFar)
+	}
+	return &descriptorpb.FileDescriptorSet{File: slice}
+}
+
+func toFileDescriptorProtoSlice(file protoreflect.FileDescriptor, results *[]*descriptorpb.FileDescriptorProto, soFar map[string]struct{}) {
+	if _, exists := soFar[file.Path()]; exists {
+		return
+	}
+	soFar[file.Path()] = struct{}{}
+	// Add dependencies first so the resulting slice is in topological order
+	imports := file.Imports()
+	for i, length := 0, imports.Len(); i < length; i++ {
+		toFileDescriptorProtoSlice(imports.Get(i).FileDescriptor, results, soFar)
+	}
+	*results = append(*results, protoutil.ProtoFromFileDescriptor(file))
+}
+
+func fromSlashPaths(paths []string) []string {
+	fromSlashPaths := make([]string, len(paths))
+	for i, path := range paths {
+		fromSlashPaths[i] = filepath.Clean(filepath.FromSlash(path))
+	}
+	return fromSlashPaths
+}
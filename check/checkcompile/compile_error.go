@@ -0,0 +1,183 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkcompile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/protocompile/reporter"
+)
+
+// CompileError is returned from Compile and CompileModules when the .proto files fail to compile.
+//
+// The Error message includes every Diagnostic along with the line of source it refers to, so that
+// failures within test .proto files can be fixed without re-running protoc.
+type CompileError struct {
+	// Diagnostics are the errors reported by the compiler, in the order they were reported.
+	//
+	// Always non-empty.
+	Diagnostics []Diagnostic
+
+	errs []error
+}
+
+// Error implements error.
+func (c *CompileError) Error() string {
+	var sb strings.Builder
+	switch len(c.Diagnostics) {
+	case 1:
+		_, _ = sb.WriteString("failed to compile .proto files:")
+	default:
+		_, _ = sb.WriteString(strconv.Itoa(len(c.Diagnostics)))
+		_, _ = sb.WriteString(" errors compiling .proto files:")
+	}
+	for _, diagnostic := range c.Diagnostics {
+		_, _ = sb.WriteString("\n\n")
+		_, _ = sb.WriteString(diagnostic.String())
+	}
+	return sb.String()
+}
+
+// Unwrap returns the underlying errors reported by the compiler.
+//
+// Each of these is a reporter.ErrorWithPos.
+func (c *CompileError) Unwrap() []error {
+	return c.errs
+}
+
+// Diagnostic is a single error reported by the compiler.
+type Diagnostic struct {
+	// FileName is the path of the file, relative to the directory it was found in.
+	FileName string
+	// Line is the 1-indexed line of the error.
+	//
+	// Zero if the position within the file is unknown.
+	Line int
+	// Column is the 1-indexed column of the error.
+	//
+	// Zero if the position within the file is unknown.
+	Column int
+	// Message is the error message, without position information.
+	Message string
+	// SourceLine is the line of source that the error refers to, without a trailing newline.
+	//
+	// Empty if the position within the file is unknown, or the file could not be read.
+	SourceLine string
+
+	// sourceLinePrefix is the content of SourceLine before the error, with every
+	// character other than a tab replaced with a space, so that a caret can be
+	// aligned underneath the error regardless of tab width.
+	sourceLinePrefix string
+}
+
+// String renders the Diagnostic as "file:line:column: message", followed by the line of
+// source with a caret pointing at the error, if available.
+func (d Diagnostic) String() string {
+	var sb strings.Builder
+	_, _ = sb.WriteString(d.FileName)
+	if d.Line > 0 {
+		_, _ = sb.WriteString(fmt.Sprintf(":%d:%d", d.Line, d.Column))
+	}
+	_, _ = sb.WriteString(": ")
+	_, _ = sb.WriteString(d.Message)
+	if d.SourceLine == "" {
+		return sb.String()
+	}
+	lineNumber := strconv.Itoa(d.Line)
+	_, _ = sb.WriteString(fmt.Sprintf("\n %s | %s", lineNumber, d.SourceLine))
+	_, _ = sb.WriteString(fmt.Sprintf("\n %s | %s^", strings.Repeat(" ", len(lineNumber)), d.sourceLinePrefix))
+	return sb.String()
+}
+
+// *** PRIVATE ***
+
+// newCompileError returns a new CompileError for the given errors reported by the compiler.
+//
+// The source for each Diagnostic is read from the first of dirPaths that contains the file. If
+// there are no errors, this returns nil.
+func newCompileError(dirPaths []string, errorsWithPos []reporter.ErrorWithPos) error {
+	if len(errorsWithPos) == 0 {
+		return nil
+	}
+	fileNameToData := make(map[string][]byte)
+	diagnostics := make([]Diagnostic, len(errorsWithPos))
+	errs := make([]error, len(errorsWithPos))
+	for i, errorWithPos := range errorsWithPos {
+		pos := errorWithPos.GetPosition()
+		data, ok := fileNameToData[pos.Filename]
+		if !ok {
+			data = readFileFromDirPaths(dirPaths, pos.Filename)
+			fileNameToData[pos.Filename] = data
+		}
+		diagnostic := Diagnostic{
+			FileName: pos.Filename,
+			Line:     pos.Line,
+			Column:   pos.Col,
+			Message:  errorWithPos.Unwrap().Error(),
+		}
+		if pos.Line > 0 && pos.Offset <= len(data) {
+			diagnostic.SourceLine, diagnostic.sourceLinePrefix = sourceLineAtOffset(data, pos.Offset)
+		}
+		diagnostics[i] = diagnostic
+		errs[i] = errorWithPos
+	}
+	return &CompileError{
+		Diagnostics: diagnostics,
+		errs:        errs,
+	}
+}
+
+// readFileFromDirPaths reads the file from the first of dirPaths that contains it.
+//
+// Returns nil if the file cannot be read, as the source is only used for rendering.
+func readFileFromDirPaths(dirPaths []string, fileName string) []byte {
+	for _, dirPath := range dirPaths {
+		data, err := os.ReadFile(filepath.Join(dirPath, filepath.FromSlash(fileName)))
+		if err == nil {
+			return data
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// sourceLineAtOffset returns the line of data that contains offset, and the content of that
+// line before offset with every character other than a tab replaced with a space.
+func sourceLineAtOffset(data []byte, offset int) (string, string) {
+	start := bytes.LastIndexByte(data[:offset], '\n') + 1
+	end := len(data)
+	if index := bytes.IndexByte(data[offset:], '\n'); index >= 0 {
+		end = offset + index
+	}
+	line := strings.TrimSuffix(string(data[start:end]), "\r")
+	prefix := strings.Map(
+		func(r rune) rune {
+			if r == '\t' {
+				return r
+			}
+			return ' '
+		},
+		string(data[start:offset]),
+	)
+	return line, prefix
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkconfig loads plugin-specific configuration files for use within RuleHandlers.
+//
+// Options are flat key/values. Plugins that need more complex configuration can instead have
+// users pass the path to a YAML or JSON configuration file as an option, and decode it into a
+// typed Go struct with Load:
+//
+//	type config struct {
+//		ServiceSuffixes []string `yaml:"service_suffixes"`
+//	}
+//
+//	func handleServiceSuffix(ctx context.Context, responseWriter check.ResponseWriter, request check.Request) error {
+//		config, err := checkconfig.Load[config](ctx, request, checkconfig.DefaultOptionKey)
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Configuration files are decoded strictly: unknown keys result in an error. If the decoded
+// type implements Validator, Validate is called after decoding.
+package checkconfig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultOptionKey is the conventional option key for the path to a plugin's configuration file.
+const DefaultOptionKey = "config_file"
+
+// Validator is implemented by configuration types that validate themselves after decoding.
+type Validator interface {
+	// Validate returns error if the decoded configuration is invalid.
+	Validate() error
+}
+
+// Load decodes the configuration file at the path set by the given option key into a new T.
+//
+// T is typically a struct with yaml tags. YAML is a superset of JSON, so JSON files are also
+// accepted. Relative paths are resolved relative to the working directory of the plugin.
+//
+// Returns nil and no error if the option is not set. Returns error if the option value is not a
+// string, if the file cannot be read, or if the file cannot be decoded into T.
+//
+// Within a Check call, each file is read and decoded once per T, and shared between all
+// RuleHandlers via the check.RequestStore. The returned value should not be modified.
+func Load[T any](ctx context.Context, request check.Request, optionKey string) (*T, error) {
+	filePath, err := check.GetStringValue(request.Options(), optionKey)
+	if err != nil {
+		return nil, err
+	}
+	if filePath == "" {
+		return nil, nil
+	}
+	return check.LoadOrComputeForRequest(
+		ctx,
+		request,
+		loadKey[T]{
+			optionKey: optionKey,
+		},
+		func() (*T, error) {
+			return DecodeFile[T](filePath)
+		},
+	)
+}
+
+// DecodeFile decodes the YAML or JSON file at the given path into a new T.
+func DecodeFile[T any](filePath string) (*T, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	value, err := Decode[T](data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return value, nil
+}
+
+// Decode decodes the YAML or JSON data into a new T.
+//
+// Unknown keys result in an error. If *T or T implements Validator, Validate is called.
+func Decode[T any](data []byte) (*T, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	value := new(T)
+	if err := decoder.Decode(value); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("config is empty")
+		}
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	validator, ok := any(value).(Validator)
+	if !ok {
+		validator, ok = any(*value).(Validator)
+	}
+	if ok {
+		if err := validator.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+	}
+	return value, nil
+}
+
+// *** PRIVATE ***
+
+// loadKey is the check.RequestStore key for Load.
+//
+// The type parameter ensures that the same file decoded into different types is stored
+// separately.
+type loadKey[T any] struct {
+	optionKey string
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkconfig
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	ServiceSuffixes []string `yaml:"service_suffixes"`
+}
+
+func (t *testConfig) Validate() error {
+	if len(t.ServiceSuffixes) == 0 {
+		return errors.New("service_suffixes is required")
+	}
+	return nil
+}
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	config, err := Decode[testConfig]([]byte(`{"service_suffixes": ["Service"]}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"Service"}, config.ServiceSuffixes)
+	_, err = Decode[testConfig]([]byte(`service_suffixes: []`))
+	require.ErrorContains(t, err, "service_suffixes is required")
+	_, err = Decode[testConfig]([]byte(`unknown: true`))
+	require.Error(t, err)
+	_, err = Decode[testConfig](nil)
+	require.Error(t, err)
+	_, err = DecodeFile[testConfig](filepath.Join("testdata", "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				{
+					ID:        "SERVICE_SUFFIXES",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      check.RuleTypeLint,
+					Handler: check.RuleHandlerFunc(
+						func(ctx context.Context, responseWriter check.ResponseWriter, request check.Request) error {
+							config, err := Load[testConfig](ctx, request, DefaultOptionKey)
+							if err != nil {
+								return err
+							}
+							if config == nil {
+								responseWriter.AddAnnotation(check.WithMessage("no config"))
+								return nil
+							}
+							responseWriter.AddAnnotation(check.WithMessage(strings.Join(config.ServiceSuffixes, ",")))
+							return nil
+						},
+					),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		optionValue     any
+		expectedMessage string
+		expectError     bool
+	}{
+		{
+			optionValue:     filepath.Join("testdata", "config.yaml"),
+			expectedMessage: "Service,API",
+		},
+		{
+			expectedMessage: "no config",
+		},
+		{
+			optionValue: filepath.Join("testdata", "missing.yaml"),
+			expectError: true,
+		},
+		{
+			optionValue: int64(1),
+			expectError: true,
+		},
+	} {
+		var keyToValue map[string]any
+		if testCase.optionValue != nil {
+			keyToValue = map[string]any{DefaultOptionKey: testCase.optionValue}
+		}
+		options, err := check.NewOptions(keyToValue)
+		require.NoError(t, err)
+		request, err := check.NewRequest(nil, check.WithOptions(options))
+		require.NoError(t, err)
+		response, err := client.Check(ctx, request)
+		if testCase.expectError {
+			require.Error(t, err)
+			continue
+		}
+		require.NoError(t, err)
+		annotations := response.Annotations()
+		require.Len(t, annotations, 1)
+		require.Equal(t, testCase.expectedMessage, annotations[0].Message())
+	}
+}
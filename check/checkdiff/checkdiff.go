@@ -0,0 +1,333 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkdiff filters Annotations by changes to the underlying files.
+//
+// This enables "only new violations" workflows, for example in code review bots that should
+// only comment on lines that were changed in a pull request, or in CI jobs that should only
+// fail on lint violations that were not present on the AgainstFiles.
+package checkdiff
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/bufplugin-go/check"
+)
+
+// ChangedLines is a set of changed lines within files.
+//
+// Paths are slash-separated, and lines are 1-indexed.
+type ChangedLines interface {
+	// ContainsLine returns true if the line of the file at the path was changed.
+	ContainsLine(path string, line int) bool
+	// ContainsFile returns true if any line of the file at the path was changed.
+	ContainsFile(path string) bool
+	// Paths returns the sorted paths of all files with changed lines.
+	Paths() []string
+
+	isChangedLines()
+}
+
+// NewChangedLines returns a new ChangedLines for the given map from path to changed lines.
+//
+// Lines are 1-indexed. Paths are cleaned.
+func NewChangedLines(pathToLines map[string][]int) ChangedLines {
+	changedLines := newChangedLines()
+	for path, lines := range pathToLines {
+		for _, line := range lines {
+			changedLines.add(path, line)
+		}
+	}
+	return changedLines
+}
+
+// ParseUnifiedDiff parses a unified diff, such as the output of git diff or diff -u, and
+// returns the lines that were added or modified in the new version of each file.
+//
+// Removed lines are not included, as they do not exist in the new version. Deleted files are
+// not included.
+//
+// By default, the first component of each path is stripped, as with patch -p1, so that the
+// a/ and b/ prefixes of git diff are removed.
+func ParseUnifiedDiff(data []byte, options ...ParseUnifiedDiffOption) (ChangedLines, error) {
+	parseUnifiedDiffOptions := newParseUnifiedDiffOptions()
+	for _, option := range options {
+		option(parseUnifiedDiffOptions)
+	}
+	return parseUnifiedDiff(data, parseUnifiedDiffOptions)
+}
+
+// ParseUnifiedDiffOption is an option for ParseUnifiedDiff.
+type ParseUnifiedDiffOption func(*parseUnifiedDiffOptions)
+
+// ParseUnifiedDiffWithStrip returns a new ParseUnifiedDiffOption that strips the given number
+// of leading components from each path, as with patch -p.
+//
+// The default is 1. A value < 0 has no effect.
+func ParseUnifiedDiffWithStrip(strip int) ParseUnifiedDiffOption {
+	return func(parseUnifiedDiffOptions *parseUnifiedDiffOptions) {
+		if strip >= 0 {
+			parseUnifiedDiffOptions.strip = strip
+		}
+	}
+}
+
+// ParseUnifiedDiffWithDirPath returns a new ParseUnifiedDiffOption that only includes files
+// within the given directory, and makes their paths relative to the directory.
+//
+// Paths within a diff are typically relative to the root of a repository, while the paths of
+// Files are relative to the directory that contains the .proto files. Set this to the path of
+// this directory relative to the root of the repository, after stripping, so that the paths
+// match.
+func ParseUnifiedDiffWithDirPath(dirPath string) ParseUnifiedDiffOption {
+	return func(parseUnifiedDiffOptions *parseUnifiedDiffOptions) {
+		parseUnifiedDiffOptions.dirPath = dirPath
+	}
+}
+
+// FilterAnnotations returns the Annotations that intersect with the ChangedLines.
+//
+// An Annotation with a Location with a position is included if any line within its span was
+// changed. An Annotation with a Location without a position is included if any line of its
+// File was changed. Annotations without a Location cannot be attributed to any change, and
+// are always included.
+//
+// The Annotations are typically the result of Response.Annotations, and their order is
+// preserved.
+func FilterAnnotations(annotations []check.Annotation, changedLines ChangedLines) []check.Annotation {
+	filtered := make([]check.Annotation, 0, len(annotations))
+	for _, annotation := range annotations {
+		if annotationIntersects(annotation, changedLines) {
+			filtered = append(filtered, annotation)
+		}
+	}
+	return filtered
+}
+
+// *** PRIVATE ***
+
+type changedLines struct {
+	pathToLines map[string]map[int]struct{}
+}
+
+func newChangedLines() *changedLines {
+	return &changedLines{
+		pathToLines: make(map[string]map[int]struct{}),
+	}
+}
+
+func (c *changedLines) ContainsLine(filePath string, line int) bool {
+	_, ok := c.pathToLines[path.Clean(filePath)][line]
+	return ok
+}
+
+func (c *changedLines) ContainsFile(filePath string) bool {
+	return len(c.pathToLines[path.Clean(filePath)]) > 0
+}
+
+func (c *changedLines) Paths() []string {
+	paths := make([]string, 0, len(c.pathToLines))
+	for filePath := range c.pathToLines {
+		paths = append(paths, filePath)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func (c *changedLines) add(filePath string, line int) {
+	filePath = path.Clean(filePath)
+	lines, ok := c.pathToLines[filePath]
+	if !ok {
+		lines = make(map[int]struct{})
+		c.pathToLines[filePath] = lines
+	}
+	lines[line] = struct{}{}
+}
+
+func (*changedLines) isChangedLines() {}
+
+type parseUnifiedDiffOptions struct {
+	strip   int
+	dirPath string
+}
+
+func newParseUnifiedDiffOptions() *parseUnifiedDiffOptions {
+	return &parseUnifiedDiffOptions{
+		strip: 1,
+	}
+}
+
+func parseUnifiedDiff(data []byte, parseUnifiedDiffOptions *parseUnifiedDiffOptions) (*changedLines, error) {
+	changedLines := newChangedLines()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	var (
+		// filePath is empty if the current file is not included.
+		filePath string
+		// newLine is the next line number in the new version of the file.
+		newLine int
+		// remainingOld and remainingNew are the number of lines remaining in the current hunk.
+		remainingOld int
+		remainingNew int
+		lineNumber   int
+	)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if remainingOld > 0 || remainingNew > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				if filePath != "" {
+					changedLines.add(filePath, newLine)
+				}
+				newLine++
+				remainingNew--
+			case strings.HasPrefix(line, "-"):
+				remainingOld--
+			case strings.HasPrefix(line, " "), line == "":
+				newLine++
+				remainingOld--
+				remainingNew--
+			case strings.HasPrefix(line, `\`):
+				// "\ No newline at end of file"
+			default:
+				return nil, fmt.Errorf("line %d: unexpected line within hunk: %q", lineNumber, line)
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			newFilePath, err := parseDiffFilePath(strings.TrimPrefix(line, "+++ "), parseUnifiedDiffOptions)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			filePath = newFilePath
+		case strings.HasPrefix(line, "@@ "):
+			start, oldCount, newCount, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			newLine = start
+			remainingOld = oldCount
+			remainingNew = newCount
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" after the last line of a hunk.
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return changedLines, nil
+}
+
+// parseDiffFilePath parses the path of a +++ line, returning empty if the file should not
+// be included.
+func parseDiffFilePath(value string, parseUnifiedDiffOptions *parseUnifiedDiffOptions) (string, error) {
+	if strings.HasPrefix(value, `"`) {
+		end := strings.LastIndex(value, `"`)
+		if end <= 0 {
+			return "", fmt.Errorf("invalid quoted path: %s", value)
+		}
+		unquoted, err := strconv.Unquote(value[:end+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted path: %s", value)
+		}
+		value = unquoted
+	} else if index := strings.IndexByte(value, '\t'); index >= 0 {
+		// diff -u appends a tab and a timestamp.
+		value = value[:index]
+	}
+	if value == "/dev/null" {
+		return "", nil
+	}
+	components := strings.Split(value, "/")
+	if len(components) <= parseUnifiedDiffOptions.strip {
+		return "", fmt.Errorf("cannot strip %d components from path %q", parseUnifiedDiffOptions.strip, value)
+	}
+	filePath := path.Clean(strings.Join(components[parseUnifiedDiffOptions.strip:], "/"))
+	if parseUnifiedDiffOptions.dirPath == "" {
+		return filePath, nil
+	}
+	dirPath := path.Clean(parseUnifiedDiffOptions.dirPath)
+	if dirPath == "." {
+		return filePath, nil
+	}
+	relFilePath, ok := strings.CutPrefix(filePath, dirPath+"/")
+	if !ok {
+		return "", nil
+	}
+	return relFilePath, nil
+}
+
+// parseHunkHeader parses a hunk header of the form "@@ -l,s +l,s @@", returning the start
+// line in the new file, and the number of lines in the old and new file.
+func parseHunkHeader(line string) (int, int, int, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[3] != "@@" || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header: %q", line)
+	}
+	_, oldCount, err := parseHunkRange(fields[1][1:])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header: %q: %w", line, err)
+	}
+	newStart, newCount, err := parseHunkRange(fields[2][1:])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header: %q: %w", line, err)
+	}
+	return newStart, oldCount, newCount, nil
+}
+
+// parseHunkRange parses a range of the form "l,s" or "l", in which case s is 1.
+func parseHunkRange(value string) (int, int, error) {
+	startString, countString, hasCount := strings.Cut(value, ",")
+	start, err := strconv.Atoi(startString)
+	if err != nil {
+		return 0, 0, err
+	}
+	count := 1
+	if hasCount {
+		count, err = strconv.Atoi(countString)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if start < 0 || count < 0 {
+		return 0, 0, errors.New("negative range")
+	}
+	return start, count, nil
+}
+
+func annotationIntersects(annotation check.Annotation, changedLines ChangedLines) bool {
+	location := annotation.Location()
+	if location == nil {
+		return true
+	}
+	filePath := location.File().FileDescriptor().Path()
+	if !location.HasPosition() {
+		return changedLines.ContainsFile(filePath)
+	}
+	for line := location.StartLine() + 1; line <= location.EndLine()+1; line++ {
+		if changedLines.ContainsLine(filePath, line) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkdiff
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checkcompile"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+)
+
+const testDiff = `diff --git a/proto/simple.proto b/proto/simple.proto
+index 1111111..2222222 100644
+--- a/proto/simple.proto
++++ b/proto/simple.proto
+@@ -4,4 +4,5 @@ package simple;
+ 
+ message Foo {
+   string one = 1;
++  string two = 2;
+ }
+diff --git a/proto/deleted.proto b/proto/deleted.proto
+deleted file mode 100644
+--- a/proto/deleted.proto
++++ /dev/null
+@@ -1,2 +0,0 @@
+-syntax = "proto3";
+-package deleted;
+diff --git a/other/new.proto b/other/new.proto
+new file mode 100644
+--- /dev/null
++++ b/other/new.proto
+@@ -0,0 +1,2 @@
++syntax = "proto3";
++++ a line that looks like a header
+\ No newline at end of file
+`
+
+func TestParseUnifiedDiff(t *testing.T) {
+	t.Parallel()
+
+	changedLines, err := ParseUnifiedDiff([]byte(testDiff))
+	require.NoError(t, err)
+	require.Equal(t, []string{"other/new.proto", "proto/simple.proto"}, changedLines.Paths())
+	require.True(t, changedLines.ContainsLine("proto/simple.proto", 7))
+	require.False(t, changedLines.ContainsLine("proto/simple.proto", 6))
+	require.True(t, changedLines.ContainsLine("other/new.proto", 1))
+	require.True(t, changedLines.ContainsLine("other/new.proto", 2))
+	require.False(t, changedLines.ContainsFile("proto/deleted.proto"))
+
+	changedLines, err = ParseUnifiedDiff([]byte(testDiff), ParseUnifiedDiffWithDirPath("proto"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"simple.proto"}, changedLines.Paths())
+
+	changedLines, err = ParseUnifiedDiff([]byte(testDiff), ParseUnifiedDiffWithStrip(0))
+	require.NoError(t, err)
+	require.Equal(t, []string{"b/other/new.proto", "b/proto/simple.proto"}, changedLines.Paths())
+
+	changedLines, err = ParseUnifiedDiff(
+		[]byte("--- \"a/foo bar.proto\"\n+++ \"b/foo bar.proto\"\n@@ -1 +1 @@\n-foo\n+bar\n"),
+	)
+	require.NoError(t, err)
+	require.True(t, changedLines.ContainsLine("foo bar.proto", 1))
+
+	_, err = ParseUnifiedDiff([]byte("+++ b/foo.proto\n@@ -1 +x @@\n"))
+	require.ErrorContains(t, err, "line 2: invalid hunk header")
+	_, err = ParseUnifiedDiff([]byte("+++ b/foo.proto\n@@ -1 +1 @@\nfoo\n"))
+	require.ErrorContains(t, err, "line 3: unexpected line within hunk")
+}
+
+func TestFilterAnnotations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	files, err := checkcompile.Compile(ctx, []string{"testdata"}, []string{"simple.proto"})
+	require.NoError(t, err)
+	request, err := check.NewRequest(files)
+	require.NoError(t, err)
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule1.",
+					Type:      check.RuleTypeLint,
+					Handler: check.RuleHandlerFunc(
+						func(_ context.Context, responseWriter check.ResponseWriter, request check.Request) error {
+							fileDescriptor := request.Files()[0].FileDescriptor()
+							messageDescriptor := fileDescriptor.Messages().Get(0)
+							responseWriter.AddAnnotation(check.WithMessage("None."))
+							responseWriter.AddAnnotation(check.WithMessage("File."), check.WithFileName(fileDescriptor.Path()))
+							responseWriter.AddAnnotation(check.WithMessage("Message."), check.WithDescriptor(messageDescriptor))
+							responseWriter.AddAnnotation(check.WithMessage("One."), check.WithDescriptor(messageDescriptor.Fields().Get(0)))
+							responseWriter.AddAnnotation(check.WithMessage("Two."), check.WithDescriptor(messageDescriptor.Fields().Get(1)))
+							return nil
+						},
+					),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+
+	changedLines, err := ParseUnifiedDiff([]byte(testDiff), ParseUnifiedDiffWithDirPath("proto"))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{"None.", "File.", "Message.", "Two."},
+		xslices.Map(FilterAnnotations(response.Annotations(), changedLines), check.Annotation.Message),
+	)
+	require.Equal(
+		t,
+		[]string{"None."},
+		xslices.Map(FilterAnnotations(response.Annotations(), NewChangedLines(nil)), check.Annotation.Message),
+	)
+	require.Equal(
+		t,
+		[]string{"None.", "File.", "Message.", "One."},
+		xslices.Map(
+			FilterAnnotations(response.Annotations(), NewChangedLines(map[string][]int{"./simple.proto": {6}})),
+			check.Annotation.Message,
+		),
+	)
+}
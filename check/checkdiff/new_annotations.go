@@ -0,0 +1,159 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkdiff
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+)
+
+// NewAnnotations runs the same lint Rules against both the Files and the AgainstFiles of the
+// Request, and returns only the Annotations on the Files that were not present on the
+// AgainstFiles.
+//
+// This provides a "no new lint violations" mode, allowing teams to adopt lint Rules on
+// existing schemas without first fixing every existing violation. Annotations are matched by
+// AnnotationFingerprint. If the same fingerprint occurs more often on the Files than on the
+// AgainstFiles, the surplus Annotations, in order, are returned.
+//
+// The Rules run are the RuleIDs of the Request, or all default lint Rules of the Client if the
+// Request has no RuleIDs. All Rules must be lint Rules, as breaking change Rules require
+// AgainstFiles themselves. The Options of the Request are used for both runs.
+//
+// The Request must have AgainstFiles. The returned Annotations are sorted.
+func NewAnnotations(
+	ctx context.Context,
+	client check.Client,
+	request check.Request,
+	options ...check.CheckCallOption,
+) ([]check.Annotation, error) {
+	if len(request.UnclonedAgainstFiles()) == 0 {
+		return nil, errors.New("checkdiff.NewAnnotations requires a Request with AgainstFiles")
+	}
+	ruleIDs, err := lintRuleIDs(ctx, client, request.RuleIDs())
+	if err != nil {
+		return nil, err
+	}
+	if len(ruleIDs) == 0 {
+		return nil, nil
+	}
+	annotations, err := checkFiles(ctx, client, request.Files(), request.Options(), ruleIDs, options...)
+	if err != nil {
+		return nil, err
+	}
+	againstAnnotations, err := checkFiles(ctx, client, request.AgainstFiles(), request.Options(), ruleIDs, options...)
+	if err != nil {
+		return nil, err
+	}
+	fingerprintToAgainstCount := make(map[string]int, len(againstAnnotations))
+	for _, againstAnnotation := range againstAnnotations {
+		fingerprintToAgainstCount[AnnotationFingerprint(againstAnnotation)]++
+	}
+	newAnnotations := make([]check.Annotation, 0, len(annotations))
+	for _, annotation := range annotations {
+		fingerprint := AnnotationFingerprint(annotation)
+		if fingerprintToAgainstCount[fingerprint] > 0 {
+			fingerprintToAgainstCount[fingerprint]--
+			continue
+		}
+		newAnnotations = append(newAnnotations, annotation)
+	}
+	return newAnnotations, nil
+}
+
+// AnnotationFingerprint returns a fingerprint for the Annotation that is stable across
+// changes that only move the Annotation within its File.
+//
+// The fingerprint is derived from the Rule ID, the path of the File of the Location, if any,
+// and the message. Positions are not included, so that adding a line above an existing
+// violation does not make it a new violation. Messages that include positions will not
+// match across such changes.
+func AnnotationFingerprint(annotation check.Annotation) string {
+	var filePath string
+	if location := annotation.Location(); location != nil {
+		filePath = location.File().FileDescriptor().Path()
+	}
+	hash := sha256.New()
+	for _, value := range []string{annotation.RuleID(), filePath, annotation.Message()} {
+		_, _ = hash.Write([]byte(value))
+		_, _ = hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// *** PRIVATE ***
+
+// lintRuleIDs returns the given Rule IDs, or the IDs of the default lint Rules if none are
+// given, and validates that all Rules are lint Rules.
+func lintRuleIDs(ctx context.Context, client check.Client, ruleIDs []string) ([]string, error) {
+	rules, err := client.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(ruleIDs) == 0 {
+		return xslices.Map(
+			xslices.Filter(
+				rules,
+				func(rule check.Rule) bool {
+					return rule.IsDefault() && rule.Type() == check.RuleTypeLint
+				},
+			),
+			check.Rule.ID,
+		), nil
+	}
+	ruleIDToRule := make(map[string]check.Rule, len(rules))
+	for _, rule := range rules {
+		ruleIDToRule[rule.ID()] = rule
+	}
+	for _, ruleID := range ruleIDs {
+		rule, ok := ruleIDToRule[ruleID]
+		if !ok {
+			return nil, fmt.Errorf("unknown Rule ID: %q", ruleID)
+		}
+		if rule.Type() != check.RuleTypeLint {
+			return nil, fmt.Errorf("rule %q is not a lint rule", ruleID)
+		}
+	}
+	return ruleIDs, nil
+}
+
+func checkFiles(
+	ctx context.Context,
+	client check.Client,
+	files []check.File,
+	options check.Options,
+	ruleIDs []string,
+	checkCallOptions ...check.CheckCallOption,
+) ([]check.Annotation, error) {
+	request, err := check.NewRequest(
+		files,
+		check.WithOptions(options),
+		check.WithRuleIDs(ruleIDs...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Check(ctx, request, checkCallOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return response.Annotations(), nil
+}
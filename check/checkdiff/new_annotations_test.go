@@ -0,0 +1,90 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkdiff
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checkcompile"
+	"github.com/bufbuild/bufplugin-go/check/checkutil"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestNewAnnotations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	files, err := checkcompile.Compile(ctx, []string{"testdata/new_annotations/current"}, []string{"simple.proto"})
+	require.NoError(t, err)
+	againstFiles, err := checkcompile.Compile(ctx, []string{"testdata/new_annotations/against"}, []string{"simple.proto"})
+	require.NoError(t, err)
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				{
+					ID:        "FIELD_LOWER",
+					IsDefault: true,
+					Purpose:   "Checks that field names are lowercase.",
+					Type:      check.RuleTypeLint,
+					Handler: checkutil.NewFieldRuleHandler(
+						func(_ context.Context, responseWriter check.ResponseWriter, _ check.Request, fieldDescriptor protoreflect.FieldDescriptor) error {
+							if name := string(fieldDescriptor.Name()); name != strings.ToLower(name) {
+								responseWriter.AddAnnotation(
+									check.WithMessagef("Field %q is not lowercase.", name),
+									check.WithDescriptor(fieldDescriptor),
+								)
+							}
+							return nil
+						},
+					),
+				},
+				{
+					ID:        "BREAKING",
+					IsDefault: true,
+					Purpose:   "Test breaking.",
+					Type:      check.RuleTypeBreaking,
+					Handler: check.RuleHandlerFunc(
+						func(context.Context, check.ResponseWriter, check.Request) error {
+							return nil
+						},
+					),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	request, err := check.NewRequest(files, check.WithAgainstFiles(againstFiles))
+	require.NoError(t, err)
+	annotations, err := NewAnnotations(ctx, client, request)
+	require.NoError(t, err)
+	require.Equal(t, []string{`Field "BadTwo" is not lowercase.`}, xslices.Map(annotations, check.Annotation.Message))
+	require.Equal(t, 8, annotations[0].Location().StartLine()+1)
+
+	request, err = check.NewRequest(files, check.WithAgainstFiles(againstFiles), check.WithRuleIDs("BREAKING"))
+	require.NoError(t, err)
+	_, err = NewAnnotations(ctx, client, request)
+	require.ErrorContains(t, err, `rule "BREAKING" is not a lint rule`)
+
+	request, err = check.NewRequest(files)
+	require.NoError(t, err)
+	_, err = NewAnnotations(ctx, client, request)
+	require.ErrorContains(t, err, "requires a Request with AgainstFiles")
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkformat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/bufbuild/bufplugin-go/check"
+)
+
+const (
+	// GitLabSeverityInfo is the GitLab Code Quality info severity.
+	GitLabSeverityInfo = "info"
+	// GitLabSeverityMinor is the GitLab Code Quality minor severity.
+	GitLabSeverityMinor = "minor"
+	// GitLabSeverityMajor is the GitLab Code Quality major severity.
+	GitLabSeverityMajor = "major"
+	// GitLabSeverityCritical is the GitLab Code Quality critical severity.
+	GitLabSeverityCritical = "critical"
+	// GitLabSeverityBlocker is the GitLab Code Quality blocker severity.
+	GitLabSeverityBlocker = "blocker"
+)
+
+// MarshalGitLabCodeQualityReport returns the Annotations as a GitLab Code Quality report.
+//
+// The report is a JSON array of Code Climate issues, and can be uploaded as a codequality
+// artifact in GitLab CI to display the Annotations in merge request widgets. Each Annotation
+// becomes an issue with the Rule ID as its check name, and the message as its description.
+//
+// Lines are 1-indexed. Annotations with a Location without a position are reported on line 1
+// of the file. Annotations without a Location are reported with an empty path, as GitLab
+// requires a location on every issue.
+//
+// Fingerprints are derived from the Rule ID, file path, and message, so that issues are
+// tracked across commits that only move the affected lines. Annotations that would otherwise
+// have the same fingerprint are disambiguated by their order.
+//
+// See https://docs.gitlab.com/ee/ci/testing/code_quality.html.
+func MarshalGitLabCodeQualityReport(annotations []check.Annotation, options ...GitLabOption) ([]byte, error) {
+	gitLabOptions := newGitLabOptions()
+	for _, option := range options {
+		option(gitLabOptions)
+	}
+	issues := make([]*externalGitLabIssue, len(annotations))
+	fingerprintToCount := make(map[string]int)
+	for i, annotation := range annotations {
+		severity := gitLabOptions.severity
+		if gitLabOptions.getSeverity != nil {
+			severity = gitLabOptions.getSeverity(annotation)
+		}
+		if err := validateGitLabSeverity(severity); err != nil {
+			return nil, err
+		}
+		issue := &externalGitLabIssue{
+			Type:        "issue",
+			CheckName:   annotation.RuleID(),
+			Description: annotation.Message(),
+			Severity:    severity,
+			Location: &externalGitLabLocation{
+				Lines: &externalGitLabLines{
+					Begin: 1,
+				},
+			},
+		}
+		if issue.Description == "" {
+			issue.Description = annotation.RuleID()
+		}
+		if location := annotation.Location(); location != nil {
+			issue.Location.Path = location.File().FileDescriptor().Path()
+			if location.HasPosition() {
+				issue.Location.Lines.Begin = location.StartLine() + 1
+				issue.Location.Lines.End = location.EndLine() + 1
+			}
+		}
+		fingerprint := gitLabFingerprint(annotation.RuleID(), issue.Location.Path, annotation.Message())
+		count := fingerprintToCount[fingerprint]
+		fingerprintToCount[fingerprint] = count + 1
+		if count > 0 {
+			fingerprint = gitLabFingerprint(fingerprint, strconv.Itoa(count))
+		}
+		issue.Fingerprint = fingerprint
+		issues[i] = issue
+	}
+	data, err := json.MarshalIndent(issues, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// GitLabOption is an option for GitLab Code Quality conversion.
+type GitLabOption func(*gitLabOptions)
+
+// GitLabWithSeverity returns a new GitLabOption that sets the severity of all issues.
+//
+// The severity must be one of GitLabSeverityInfo, GitLabSeverityMinor, GitLabSeverityMajor,
+// GitLabSeverityCritical, or GitLabSeverityBlocker. The default is GitLabSeverityMajor.
+func GitLabWithSeverity(severity string) GitLabOption {
+	return func(gitLabOptions *gitLabOptions) {
+		gitLabOptions.severity = severity
+	}
+}
+
+// GitLabWithSeverityFunc returns a new GitLabOption that sets the severity of each issue
+// to the result of calling getSeverity with its Annotation.
+//
+// This allows severities to differ by Rule. This takes precedence over GitLabWithSeverity.
+func GitLabWithSeverityFunc(getSeverity func(check.Annotation) string) GitLabOption {
+	return func(gitLabOptions *gitLabOptions) {
+		gitLabOptions.getSeverity = getSeverity
+	}
+}
+
+// *** PRIVATE ***
+
+type externalGitLabIssue struct {
+	Type        string                  `json:"type"`
+	CheckName   string                  `json:"check_name"`
+	Description string                  `json:"description"`
+	Fingerprint string                  `json:"fingerprint"`
+	Severity    string                  `json:"severity"`
+	Location    *externalGitLabLocation `json:"location"`
+}
+
+type externalGitLabLocation struct {
+	Path  string               `json:"path"`
+	Lines *externalGitLabLines `json:"lines"`
+}
+
+type externalGitLabLines struct {
+	Begin int `json:"begin"`
+	End   int `json:"end,omitempty"`
+}
+
+type gitLabOptions struct {
+	severity    string
+	getSeverity func(check.Annotation) string
+}
+
+func newGitLabOptions() *gitLabOptions {
+	return &gitLabOptions{
+		severity: GitLabSeverityMajor,
+	}
+}
+
+func validateGitLabSeverity(severity string) error {
+	switch severity {
+	case GitLabSeverityInfo, GitLabSeverityMinor, GitLabSeverityMajor, GitLabSeverityCritical, GitLabSeverityBlocker:
+		return nil
+	default:
+		return fmt.Errorf("invalid GitLab severity: %q", severity)
+	}
+}
+
+// gitLabFingerprint returns the hex-encoded SHA256 digest of the NUL-separated values.
+func gitLabFingerprint(values ...string) string {
+	hash := sha256.New()
+	for _, value := range values {
+		_, _ = hash.Write([]byte(value))
+		_, _ = hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkformat
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalGitLabCodeQualityReport(t *testing.T) {
+	t.Parallel()
+
+	annotations := testAnnotations(t)
+	data, err := MarshalGitLabCodeQualityReport(annotations)
+	require.NoError(t, err)
+	var issues []*externalGitLabIssue
+	require.NoError(t, json.Unmarshal(data, &issues))
+	require.Len(t, issues, 4)
+	require.Equal(
+		t,
+		&externalGitLabLocation{Path: "", Lines: &externalGitLabLines{Begin: 1}},
+		issues[0].Location,
+	)
+	require.Equal(
+		t,
+		&externalGitLabLocation{Path: "simple.proto", Lines: &externalGitLabLines{Begin: 1}},
+		issues[1].Location,
+	)
+	require.Equal(
+		t,
+		&externalGitLabLocation{Path: "simple.proto", Lines: &externalGitLabLines{Begin: 6, End: 6}},
+		issues[3].Location,
+	)
+	require.Equal(t, "RULE1", issues[3].CheckName)
+	require.Equal(t, "Field.", issues[3].Description)
+	require.Equal(t, GitLabSeverityMajor, issues[3].Severity)
+	fingerprints := make(map[string]struct{})
+	for _, issue := range issues {
+		fingerprints[issue.Fingerprint] = struct{}{}
+	}
+	require.Len(t, fingerprints, 4)
+
+	// Duplicate Annotations have distinct fingerprints.
+	data, err = MarshalGitLabCodeQualityReport([]check.Annotation{annotations[3], annotations[3]})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &issues))
+	require.NotEqual(t, issues[0].Fingerprint, issues[1].Fingerprint)
+
+	data, err = MarshalGitLabCodeQualityReport(
+		annotations,
+		GitLabWithSeverityFunc(
+			func(annotation check.Annotation) string {
+				if annotation.Location() == nil {
+					return GitLabSeverityInfo
+				}
+				return GitLabSeverityBlocker
+			},
+		),
+	)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &issues))
+	require.Equal(t, GitLabSeverityInfo, issues[0].Severity)
+	require.Equal(t, GitLabSeverityBlocker, issues[1].Severity)
+
+	_, err = MarshalGitLabCodeQualityReport(annotations, GitLabWithSeverity("high"))
+	require.ErrorContains(t, err, `invalid GitLab severity: "high"`)
+}
@@ -0,0 +1,249 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/bufbuild/bufplugin-go/check"
+)
+
+const (
+	// ReviewdogSeverityError is the reviewdog ERROR severity.
+	ReviewdogSeverityError = "ERROR"
+	// ReviewdogSeverityWarning is the reviewdog WARNING severity.
+	ReviewdogSeverityWarning = "WARNING"
+	// ReviewdogSeverityInfo is the reviewdog INFO severity.
+	ReviewdogSeverityInfo = "INFO"
+)
+
+// MarshalReviewdogDiagnosticResult returns the Annotations as a reviewdog DiagnosticResult in
+// the rdjson format.
+//
+// The result can be passed to reviewdog with -f=rdjson. Each Annotation becomes a Diagnostic
+// with the Rule ID as its code. Lines and columns are 1-indexed, and end positions are
+// exclusive, as specified by the Reviewdog Diagnostic Format. The against Location of an
+// Annotation, if any, becomes a related location.
+//
+// Only the JSON encoding of the Reviewdog Diagnostic Format is produced. The protobuf encoding
+// would require a dependency on the generated reviewdog types.
+//
+// See https://github.com/reviewdog/reviewdog/tree/master/proto/rdf.
+func MarshalReviewdogDiagnosticResult(annotations []check.Annotation, options ...ReviewdogOption) ([]byte, error) {
+	reviewdogOptions, err := newReviewdogOptionsForOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+	diagnosticResult := &externalReviewdogDiagnosticResult{
+		Source:      reviewdogOptions.source(),
+		Severity:    reviewdogOptions.severity,
+		Diagnostics: make([]*externalReviewdogDiagnostic, len(annotations)),
+	}
+	for i, annotation := range annotations {
+		diagnosticResult.Diagnostics[i] = reviewdogDiagnosticForAnnotation(annotation, nil)
+	}
+	data, err := json.MarshalIndent(diagnosticResult, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// WriteReviewdogDiagnostics writes the Annotations as reviewdog Diagnostics in the rdjsonl
+// format, with one Diagnostic per line.
+//
+// The result can be passed to reviewdog with -f=rdjsonl. As rdjsonl has no top-level result,
+// the source and severity are set on each Diagnostic.
+//
+// See MarshalReviewdogDiagnosticResult for details on how Annotations are converted.
+func WriteReviewdogDiagnostics(writer io.Writer, annotations []check.Annotation, options ...ReviewdogOption) error {
+	reviewdogOptions, err := newReviewdogOptionsForOptions(options...)
+	if err != nil {
+		return err
+	}
+	for _, annotation := range annotations {
+		data, err := json.Marshal(reviewdogDiagnosticForAnnotation(annotation, reviewdogOptions))
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReviewdogOption is an option for reviewdog conversion.
+type ReviewdogOption func(*reviewdogOptions)
+
+// ReviewdogWithSourceName returns a new ReviewdogOption that sets the name of the source of
+// the Diagnostics, typically the name of the plugin.
+//
+// The default is to not set a source.
+func ReviewdogWithSourceName(sourceName string) ReviewdogOption {
+	return func(reviewdogOptions *reviewdogOptions) {
+		reviewdogOptions.sourceName = sourceName
+	}
+}
+
+// ReviewdogWithSourceURL returns a new ReviewdogOption that sets the URL of the source of
+// the Diagnostics.
+//
+// This only has an effect if ReviewdogWithSourceName is also set.
+func ReviewdogWithSourceURL(sourceURL string) ReviewdogOption {
+	return func(reviewdogOptions *reviewdogOptions) {
+		reviewdogOptions.sourceURL = sourceURL
+	}
+}
+
+// ReviewdogWithSeverity returns a new ReviewdogOption that sets the severity of the
+// Diagnostics.
+//
+// The severity must be one of ReviewdogSeverityError, ReviewdogSeverityWarning, or
+// ReviewdogSeverityInfo. The default is to not set a severity, in which case reviewdog
+// uses its own default.
+func ReviewdogWithSeverity(severity string) ReviewdogOption {
+	return func(reviewdogOptions *reviewdogOptions) {
+		reviewdogOptions.severity = severity
+	}
+}
+
+// *** PRIVATE ***
+
+type externalReviewdogDiagnosticResult struct {
+	Source      *externalReviewdogSource       `json:"source,omitempty"`
+	Severity    string                         `json:"severity,omitempty"`
+	Diagnostics []*externalReviewdogDiagnostic `json:"diagnostics"`
+}
+
+type externalReviewdogDiagnostic struct {
+	Message          string                              `json:"message"`
+	Location         *externalReviewdogLocation          `json:"location,omitempty"`
+	Severity         string                              `json:"severity,omitempty"`
+	Source           *externalReviewdogSource            `json:"source,omitempty"`
+	Code             *externalReviewdogCode              `json:"code,omitempty"`
+	RelatedLocations []*externalReviewdogRelatedLocation `json:"related_locations,omitempty"`
+}
+
+type externalReviewdogLocation struct {
+	Path  string                  `json:"path"`
+	Range *externalReviewdogRange `json:"range,omitempty"`
+}
+
+type externalReviewdogRange struct {
+	Start *externalReviewdogPosition `json:"start,omitempty"`
+	End   *externalReviewdogPosition `json:"end,omitempty"`
+}
+
+type externalReviewdogPosition struct {
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+type externalReviewdogSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type externalReviewdogCode struct {
+	Value string `json:"value"`
+}
+
+type externalReviewdogRelatedLocation struct {
+	Message  string                     `json:"message,omitempty"`
+	Location *externalReviewdogLocation `json:"location"`
+}
+
+type reviewdogOptions struct {
+	sourceName string
+	sourceURL  string
+	severity   string
+}
+
+func newReviewdogOptionsForOptions(options ...ReviewdogOption) (*reviewdogOptions, error) {
+	reviewdogOptions := &reviewdogOptions{}
+	for _, option := range options {
+		option(reviewdogOptions)
+	}
+	switch reviewdogOptions.severity {
+	case "", ReviewdogSeverityError, ReviewdogSeverityWarning, ReviewdogSeverityInfo:
+	default:
+		return nil, fmt.Errorf("invalid reviewdog severity: %q", reviewdogOptions.severity)
+	}
+	return reviewdogOptions, nil
+}
+
+func (r *reviewdogOptions) source() *externalReviewdogSource {
+	if r.sourceName == "" {
+		return nil
+	}
+	return &externalReviewdogSource{
+		Name: r.sourceName,
+		URL:  r.sourceURL,
+	}
+}
+
+// reviewdogDiagnosticForAnnotation converts the Annotation to a Diagnostic.
+//
+// If reviewdogOptions is non-nil, the source and severity are set on the Diagnostic.
+func reviewdogDiagnosticForAnnotation(annotation check.Annotation, reviewdogOptions *reviewdogOptions) *externalReviewdogDiagnostic {
+	diagnostic := &externalReviewdogDiagnostic{
+		Message:  annotation.Message(),
+		Location: reviewdogLocationForLocation(annotation.Location()),
+		Code: &externalReviewdogCode{
+			Value: annotation.RuleID(),
+		},
+	}
+	if diagnostic.Message == "" {
+		diagnostic.Message = annotation.RuleID()
+	}
+	if againstLocation := reviewdogLocationForLocation(annotation.AgainstLocation()); againstLocation != nil {
+		diagnostic.RelatedLocations = []*externalReviewdogRelatedLocation{
+			{
+				Message:  "against",
+				Location: againstLocation,
+			},
+		}
+	}
+	if reviewdogOptions != nil {
+		diagnostic.Source = reviewdogOptions.source()
+		diagnostic.Severity = reviewdogOptions.severity
+	}
+	return diagnostic
+}
+
+func reviewdogLocationForLocation(location check.Location) *externalReviewdogLocation {
+	if location == nil {
+		return nil
+	}
+	reviewdogLocation := &externalReviewdogLocation{
+		Path: location.File().FileDescriptor().Path(),
+	}
+	if location.HasPosition() {
+		reviewdogLocation.Range = &externalReviewdogRange{
+			Start: &externalReviewdogPosition{
+				Line:   location.StartLine() + 1,
+				Column: location.StartColumn() + 1,
+			},
+			End: &externalReviewdogPosition{
+				Line:   location.EndLine() + 1,
+				Column: location.EndColumn() + 1,
+			},
+		}
+	}
+	return reviewdogLocation
+}
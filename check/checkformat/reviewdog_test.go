@@ -0,0 +1,78 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkformat
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalReviewdogDiagnosticResult(t *testing.T) {
+	t.Parallel()
+
+	annotations := testAnnotations(t)
+	data, err := MarshalReviewdogDiagnosticResult(
+		annotations[2:],
+		ReviewdogWithSourceName("buf-plugin-test"),
+		ReviewdogWithSeverity(ReviewdogSeverityWarning),
+	)
+	require.NoError(t, err)
+	require.JSONEq(
+		t,
+		`{
+  "source": {"name": "buf-plugin-test"},
+  "severity": "WARNING",
+  "diagnostics": [
+    {
+      "message": "Message.",
+      "location": {
+        "path": "simple.proto",
+        "range": {"start": {"line": 5, "column": 1}, "end": {"line": 7, "column": 2}}
+      },
+      "code": {"value": "RULE1"}
+    },
+    {
+      "message": "Field.",
+      "location": {
+        "path": "simple.proto",
+        "range": {"start": {"line": 6, "column": 3}, "end": {"line": 6, "column": 18}}
+      },
+      "code": {"value": "RULE1"}
+    }
+  ]
+}`,
+		string(data),
+	)
+
+	_, err = MarshalReviewdogDiagnosticResult(annotations, ReviewdogWithSeverity("FATAL"))
+	require.ErrorContains(t, err, `invalid reviewdog severity: "FATAL"`)
+}
+
+func TestWriteReviewdogDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	annotations := testAnnotations(t)
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, WriteReviewdogDiagnostics(buffer, annotations[:2], ReviewdogWithSourceName("buf-plugin-test")))
+	require.Equal(
+		t,
+		`{"message":"None.","source":{"name":"buf-plugin-test"},"code":{"value":"RULE1"}}
+{"message":"File.","location":{"path":"simple.proto"},"source":{"name":"buf-plugin-test"},"code":{"value":"RULE1"}}
+`,
+		buffer.String(),
+	)
+}
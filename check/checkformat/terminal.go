@@ -0,0 +1,254 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkformat formats Annotations for terminals and for other tools such as reviewdog
+// and GitLab.
+package checkformat
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/bufplugin-go/check"
+)
+
+// TerminalFormatter formats Annotations for terminals.
+//
+// Each Annotation is formatted as:
+//
+//	file:line:column: [RULE_ID] message
+//
+// Lines and columns are 1-indexed. If the Annotation has a Location without a position, the
+// line and column are omitted. If the Annotation has no Location, the file is omitted as well.
+//
+// If TerminalFormatterWithSource is set, an excerpt of the source is printed after each
+// Annotation with a position, with the span of the Annotation underlined:
+//
+//	foo.proto:7:3: [FIELD_LOWER_SNAKE_CASE] Field name "barBaz" should be lower_snake_case.
+//	   7 |   string barBaz = 2;
+//	     |   ^^^^^^^^^^^^^^^^^^
+//
+// A TerminalFormatter is safe to use concurrently.
+type TerminalFormatter interface {
+	// FormatAnnotation formats the Annotation, including any source excerpt.
+	//
+	// The result always ends in a newline.
+	FormatAnnotation(annotation check.Annotation) string
+	// WriteAnnotations writes the formatted Annotations to the writer, in order.
+	WriteAnnotations(writer io.Writer, annotations []check.Annotation) error
+
+	isTerminalFormatter()
+}
+
+// NewTerminalFormatter returns a new TerminalFormatter.
+func NewTerminalFormatter(options ...TerminalFormatterOption) TerminalFormatter {
+	return newTerminalFormatter(options...)
+}
+
+// TerminalFormatterOption is an option for a new TerminalFormatter.
+type TerminalFormatterOption func(*terminalFormatterOptions)
+
+// TerminalFormatterWithColor returns a new TerminalFormatterOption that colorizes the output
+// with ANSI escape sequences.
+//
+// The default is to not colorize the output. Callers are responsible for determining if
+// the output is a terminal that supports color.
+func TerminalFormatterWithColor() TerminalFormatterOption {
+	return func(terminalFormatterOptions *terminalFormatterOptions) {
+		terminalFormatterOptions.color = true
+	}
+}
+
+// TerminalFormatterWithSource returns a new TerminalFormatterOption that prints an excerpt of
+// the source for each Annotation with a position.
+//
+// The readSource function is called with the path of a File, as given by the path of its
+// FileDescriptor, and should return the contents of the File. Each path is read at most once.
+// If readSource returns an error, no excerpts are printed for that File.
+func TerminalFormatterWithSource(readSource func(path string) ([]byte, error)) TerminalFormatterOption {
+	return func(terminalFormatterOptions *terminalFormatterOptions) {
+		terminalFormatterOptions.readSource = readSource
+	}
+}
+
+// *** PRIVATE ***
+
+const (
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+	ansiReset  = "\x1b[0m"
+
+	// tabWidth is the width of a tab character when computing columns.
+	//
+	// This matches the protobuf specification for SourceCodeInfo, in which a tab advances
+	// the column to the next multiple of 8.
+	tabWidth = 8
+)
+
+type terminalFormatter struct {
+	color      bool
+	readSource func(string) ([]byte, error)
+
+	// pathToLines contains the source lines for each path, or nil if the source
+	// could not be read.
+	pathToLines map[string][]string
+	lock        sync.Mutex
+}
+
+func newTerminalFormatter(options ...TerminalFormatterOption) *terminalFormatter {
+	terminalFormatterOptions := newTerminalFormatterOptions()
+	for _, option := range options {
+		option(terminalFormatterOptions)
+	}
+	return &terminalFormatter{
+		color:       terminalFormatterOptions.color,
+		readSource:  terminalFormatterOptions.readSource,
+		pathToLines: make(map[string][]string),
+	}
+}
+
+func (t *terminalFormatter) FormatAnnotation(annotation check.Annotation) string {
+	var builder strings.Builder
+	location := annotation.Location()
+	if location != nil {
+		prefix := location.File().FileDescriptor().Path()
+		if location.HasPosition() {
+			prefix += ":" + strconv.Itoa(location.StartLine()+1) + ":" + strconv.Itoa(location.StartColumn()+1)
+		}
+		builder.WriteString(t.colorize(ansiBold, prefix+":"))
+		builder.WriteString(" ")
+	}
+	builder.WriteString(t.colorize(ansiYellow, "["+annotation.RuleID()+"]"))
+	if message := annotation.Message(); message != "" {
+		builder.WriteString(" ")
+		builder.WriteString(message)
+	}
+	builder.WriteString("\n")
+	if location != nil && location.HasPosition() {
+		t.writeExcerpt(&builder, location)
+	}
+	return builder.String()
+}
+
+func (t *terminalFormatter) WriteAnnotations(writer io.Writer, annotations []check.Annotation) error {
+	for _, annotation := range annotations {
+		if _, err := io.WriteString(writer, t.FormatAnnotation(annotation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *terminalFormatter) writeExcerpt(builder *strings.Builder, location check.Location) {
+	lines := t.getLines(location.File().FileDescriptor().Path())
+	startLine := location.StartLine()
+	if startLine < 0 || startLine >= len(lines) {
+		return
+	}
+	line := lines[startLine]
+	lineNumber := strconv.Itoa(startLine + 1)
+	gutterWidth := max(len(lineNumber), 4)
+	builder.WriteString(t.colorize(ansiBlue, strings.Repeat(" ", gutterWidth-len(lineNumber))+lineNumber+" |"))
+	if line != "" {
+		builder.WriteString(" ")
+		builder.WriteString(line)
+	}
+	builder.WriteString("\n")
+	endColumn := -1
+	if location.EndLine() == startLine {
+		endColumn = location.EndColumn()
+	}
+	marker := underline(line, location.StartColumn(), endColumn)
+	if marker == "" {
+		return
+	}
+	builder.WriteString(t.colorize(ansiBlue, strings.Repeat(" ", gutterWidth)+" |"))
+	builder.WriteString(" ")
+	builder.WriteString(t.colorize(ansiRed, marker))
+	builder.WriteString("\n")
+}
+
+// underline returns the marker line that underlines the given columns of the line.
+//
+// The marker line reproduces any tabs before the underline, so that it aligns with the line.
+// If endColumn is -1, the line is underlined to its end. Returns empty if there is nothing
+// to underline.
+func underline(line string, startColumn int, endColumn int) string {
+	var prefix strings.Builder
+	var numCarets int
+	column := 0
+	for _, r := range line {
+		if endColumn != -1 && column >= endColumn {
+			break
+		}
+		nextColumn := column + 1
+		if r == '\t' {
+			nextColumn = (column/tabWidth + 1) * tabWidth
+		}
+		switch {
+		case column < startColumn && r == '\t':
+			prefix.WriteRune('\t')
+		case column < startColumn:
+			prefix.WriteRune(' ')
+		default:
+			numCarets += nextColumn - column
+		}
+		column = nextColumn
+	}
+	if numCarets == 0 {
+		return ""
+	}
+	return prefix.String() + strings.Repeat("^", numCarets)
+}
+
+func (t *terminalFormatter) getLines(path string) []string {
+	if t.readSource == nil {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	lines, ok := t.pathToLines[path]
+	if !ok {
+		if data, err := t.readSource(path); err == nil {
+			lines = strings.Split(string(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))), "\n")
+		}
+		t.pathToLines[path] = lines
+	}
+	return lines
+}
+
+func (t *terminalFormatter) colorize(ansiCode string, s string) string {
+	if !t.color {
+		return s
+	}
+	return ansiCode + s + ansiReset
+}
+
+func (*terminalFormatter) isTerminalFormatter() {}
+
+// hasPosition returns true if the Location refers to a position within its File.
+type terminalFormatterOptions struct {
+	color      bool
+	readSource func(string) ([]byte, error)
+}
+
+func newTerminalFormatterOptions() *terminalFormatterOptions {
+	return &terminalFormatterOptions{}
+}
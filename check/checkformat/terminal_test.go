@@ -0,0 +1,127 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkformat
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checkcompile"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerminalFormatter(t *testing.T) {
+	t.Parallel()
+
+	annotations := testAnnotations(t)
+	readSource := func(path string) ([]byte, error) {
+		return os.ReadFile(filepath.Join("testdata", path))
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, NewTerminalFormatter().WriteAnnotations(buffer, annotations))
+	require.Equal(
+		t,
+		`[RULE1] None.
+simple.proto: [RULE1] File.
+simple.proto:5:1: [RULE1] Message.
+simple.proto:6:3: [RULE1] Field.
+`,
+		buffer.String(),
+	)
+
+	buffer.Reset()
+	require.NoError(t, NewTerminalFormatter(TerminalFormatterWithSource(readSource)).WriteAnnotations(buffer, annotations))
+	require.Equal(
+		t,
+		`[RULE1] None.
+simple.proto: [RULE1] File.
+simple.proto:5:1: [RULE1] Message.
+   5 | message Foo {
+     | ^^^^^^^^^^^^^
+simple.proto:6:3: [RULE1] Field.
+   6 |   string bar = 1;
+     |   ^^^^^^^^^^^^^^^
+`,
+		buffer.String(),
+	)
+
+	require.Equal(
+		t,
+		"\x1b[1msimple.proto:6:3:\x1b[0m \x1b[33m[RULE1]\x1b[0m Field.\n",
+		NewTerminalFormatter(TerminalFormatterWithColor()).FormatAnnotation(annotations[3]),
+	)
+
+	// Missing sources result in no excerpts.
+	require.Equal(
+		t,
+		"simple.proto:6:3: [RULE1] Field.\n",
+		NewTerminalFormatter(
+			TerminalFormatterWithSource(
+				func(string) ([]byte, error) {
+					return nil, os.ErrNotExist
+				},
+			),
+		).FormatAnnotation(annotations[3]),
+	)
+}
+
+func TestUnderline(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "  ^^^", underline("  foo bar", 2, 5))
+	require.Equal(t, "  ^^^^^^^", underline("  foo bar", 2, -1))
+	require.Equal(t, "\t^^^", underline("\tfoo", 8, 11))
+	require.Equal(t, "", underline("foo", 5, 6))
+}
+
+func testAnnotations(t *testing.T) []check.Annotation {
+	ctx := context.Background()
+	files, err := checkcompile.Compile(ctx, []string{"testdata"}, []string{"simple.proto"})
+	require.NoError(t, err)
+	request, err := check.NewRequest(files)
+	require.NoError(t, err)
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule1.",
+					Type:      check.RuleTypeLint,
+					Handler: check.RuleHandlerFunc(
+						func(_ context.Context, responseWriter check.ResponseWriter, request check.Request) error {
+							fileDescriptor := request.Files()[0].FileDescriptor()
+							messageDescriptor := fileDescriptor.Messages().Get(0)
+							responseWriter.AddAnnotation(check.WithMessage("Message."), check.WithDescriptor(messageDescriptor))
+							responseWriter.AddAnnotation(check.WithMessage("Field."), check.WithDescriptor(messageDescriptor.Fields().Get(0)))
+							responseWriter.AddAnnotation(check.WithMessage("File."), check.WithFileName(fileDescriptor.Path()))
+							responseWriter.AddAnnotation(check.WithMessage("None."))
+							return nil
+						},
+					),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	return response.Annotations()
+}
@@ -0,0 +1,366 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkgen generates Go code from the Rules of a plugin.
+//
+// GenerateRuleIDs emits a typed enum with a constant for each Rule ID, so that plugin code,
+// tests, and documentation reference Rule IDs that are checked at compile time, for example
+// when passed to check.WithRuleIDs.
+//
+// See cmd/bufplugin-gen-rule-ids for a generator that invokes plugin binaries, which can be
+// used with go:generate:
+//
+//	//go:generate go run github.com/bufbuild/bufplugin-go/cmd/bufplugin-gen-rule-ids --plugin buf-plugin-foo --package foo --output rule_ids.gen.go
+//
+// To generate from a Spec in-process, call Main with MainWithSpec from your own main function:
+//
+//	func main() {
+//		checkgen.Main(checkgen.MainWithSpec(spec))
+//	}
+package checkgen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/template"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/pluginrpc-go"
+)
+
+// DefaultTypeName is the default name of the generated Rule ID type.
+const DefaultTypeName = "RuleID"
+
+// GenerateRuleIDs generates a Go file in the given package that declares a type for the Rule IDs
+// of the Client, and a constant of that type for each Rule ID.
+//
+// For a Rule with ID "TIMESTAMP_SUFFIX", and the default type name, this generates:
+//
+//	type RuleID string
+//
+//	const (
+//		RuleIDTimestampSuffix RuleID = "TIMESTAMP_SUFFIX"
+//	)
+//
+// Along with AllRuleIDs, which returns all RuleIDs in sorted order, and RuleIDStrings, which
+// converts RuleIDs to strings for use with check.WithRuleIDs. Constants are documented with the
+// Purpose of the Rule, and deprecated Rules are marked as deprecated.
+//
+// The returned source is formatted.
+func GenerateRuleIDs(
+	ctx context.Context,
+	client check.Client,
+	packageName string,
+	options ...GenerateRuleIDsOption,
+) ([]byte, error) {
+	generateRuleIDsOptions := newGenerateRuleIDsOptions()
+	for _, option := range options {
+		option(generateRuleIDsOptions)
+	}
+	if !token.IsIdentifier(packageName) {
+		return nil, fmt.Errorf("invalid package name: %q", packageName)
+	}
+	typeName := generateRuleIDsOptions.typeName
+	if !token.IsIdentifier(typeName) || !token.IsExported(typeName) {
+		return nil, fmt.Errorf("invalid type name: %q, must be an exported Go identifier", typeName)
+	}
+	rules, err := client.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data := &ruleIDsTemplateData{
+		Generator:   generateRuleIDsOptions.generator,
+		PackageName: packageName,
+		TypeName:    typeName,
+	}
+	for _, rule := range rules {
+		ruleIDTemplateData := &ruleIDTemplateData{
+			ConstantName: typeName + constantNameSuffixForRuleID(rule.ID()),
+			ID:           rule.ID(),
+			PurposeLines: strings.Split(rule.Purpose(), "\n"),
+		}
+		if rule.Deprecated() {
+			ruleIDTemplateData.Deprecated = "This Rule is deprecated."
+			if replacementIDs := rule.ReplacementIDs(); len(replacementIDs) > 0 {
+				ruleIDTemplateData.Deprecated = "This Rule is deprecated, and replaced by " + strings.Join(replacementIDs, ", ") + "."
+			}
+		}
+		data.RuleIDs = append(data.RuleIDs, ruleIDTemplateData)
+	}
+	buffer := bytes.NewBuffer(nil)
+	if err := ruleIDsTemplate.Execute(buffer, data); err != nil {
+		return nil, err
+	}
+	return format.Source(buffer.Bytes())
+}
+
+// GenerateRuleIDsOption is an option for GenerateRuleIDs.
+type GenerateRuleIDsOption func(*generateRuleIDsOptions)
+
+// GenerateRuleIDsWithTypeName returns a new GenerateRuleIDsOption that sets the name of the
+// generated Rule ID type.
+//
+// The name is also used as the prefix of the constants, and within the names of the generated
+// functions. For example, with "LintRuleID", the constants are named LintRuleIDFoo, and the
+// functions are named AllLintRuleIDs and LintRuleIDStrings.
+//
+// The default is DefaultTypeName.
+func GenerateRuleIDsWithTypeName(typeName string) GenerateRuleIDsOption {
+	return func(generateRuleIDsOptions *generateRuleIDsOptions) {
+		generateRuleIDsOptions.typeName = typeName
+	}
+}
+
+// GenerateRuleIDsWithGenerator returns a new GenerateRuleIDsOption that sets the name of the
+// generator in the "Code generated by" header.
+//
+// The default is "bufplugin-gen-rule-ids".
+func GenerateRuleIDsWithGenerator(generator string) GenerateRuleIDsOption {
+	return func(generateRuleIDsOptions *generateRuleIDsOptions) {
+		generateRuleIDsOptions.generator = generator
+	}
+}
+
+// Main is the main entrypoint for the generator.
+//
+// The generator is invoked as:
+//
+//	bufplugin-gen-rule-ids [flags]
+//
+// Flags:
+//
+//	--plugin PATH       The plugin binary to list the Rules of. Required unless MainWithSpec is used.
+//	--plugin-arg ARG    An argument to pass to the plugin binary. Repeatable.
+//	--package NAME      The name of the Go package of the generated file. Required.
+//	--type-name NAME    The name of the generated Rule ID type. Defaults to DefaultTypeName.
+//	--output PATH       The file to write. Defaults to stdout.
+func Main(options ...MainOption) {
+	mainOptions := newMainOptions()
+	for _, option := range options {
+		option(mainOptions)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if err := run(ctx, os.Args[1:], os.Stdout, mainOptions.spec); err != nil {
+		if errString := err.Error(); errString != "" {
+			_, _ = os.Stderr.Write([]byte(errString + "\n"))
+		}
+		cancel()
+		os.Exit(1)
+	}
+}
+
+// MainOption is an option for Main.
+type MainOption func(*mainOptions)
+
+// MainWithSpec returns a new MainOption that lists the Rules of the given Spec in-process
+// instead of invoking a plugin binary.
+//
+// If this option is set, the --plugin and --plugin-arg flags may not be used.
+func MainWithSpec(spec *check.Spec) MainOption {
+	return func(mainOptions *mainOptions) {
+		mainOptions.spec = spec
+	}
+}
+
+// *** PRIVATE ***
+
+var ruleIDsTemplate = template.Must(template.New("ruleIDs").Parse(`// Code generated by {{.Generator}}. DO NOT EDIT.
+
+package {{.PackageName}}
+
+// {{.TypeName}} is the ID of a Rule.
+type {{.TypeName}} string
+
+const (
+{{- range .RuleIDs}}
+	// {{.ConstantName}} is the ID of the {{.ID}} Rule.
+	//
+{{- range .PurposeLines}}
+	// {{.}}
+{{- end}}
+{{- if .Deprecated}}
+	//
+	// Deprecated: {{.Deprecated}}
+{{- end}}
+	{{.ConstantName}} {{$.TypeName}} = "{{.ID}}"
+{{- end}}
+)
+
+// All{{.TypeName}}s returns all {{.TypeName}}s, sorted by ID.
+func All{{.TypeName}}s() []{{.TypeName}} {
+	return []{{.TypeName}}{
+{{- range .RuleIDs}}
+		{{.ConstantName}},
+{{- end}}
+	}
+}
+
+// {{.TypeName}}Strings returns the {{.TypeName}}s as strings, for use with check.WithRuleIDs.
+func {{.TypeName}}Strings(ruleIDs ...{{.TypeName}}) []string {
+	ruleIDStrings := make([]string, len(ruleIDs))
+	for i, ruleID := range ruleIDs {
+		ruleIDStrings[i] = string(ruleID)
+	}
+	return ruleIDStrings
+}
+
+// String implements fmt.Stringer.
+func (r {{.TypeName}}) String() string {
+	return string(r)
+}
+`))
+
+type ruleIDsTemplateData struct {
+	Generator   string
+	PackageName string
+	TypeName    string
+	RuleIDs     []*ruleIDTemplateData
+}
+
+type ruleIDTemplateData struct {
+	ConstantName string
+	ID           string
+	PurposeLines []string
+	Deprecated   string
+}
+
+type generateRuleIDsOptions struct {
+	typeName  string
+	generator string
+}
+
+func newGenerateRuleIDsOptions() *generateRuleIDsOptions {
+	return &generateRuleIDsOptions{
+		typeName:  DefaultTypeName,
+		generator: "bufplugin-gen-rule-ids",
+	}
+}
+
+type mainOptions struct {
+	spec *check.Spec
+}
+
+func newMainOptions() *mainOptions {
+	return &mainOptions{}
+}
+
+type flags struct {
+	pluginPath  string
+	pluginArgs  stringSliceFlag
+	packageName string
+	typeName    string
+	outputPath  string
+}
+
+func parseFlags(args []string) (*flags, error) {
+	flags := &flags{}
+	flagSet := flag.NewFlagSet("bufplugin-gen-rule-ids", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	flagSet.StringVar(&flags.pluginPath, "plugin", "", "")
+	flagSet.Var(&flags.pluginArgs, "plugin-arg", "")
+	flagSet.StringVar(&flags.packageName, "package", "", "")
+	flagSet.StringVar(&flags.typeName, "type-name", DefaultTypeName, "")
+	flagSet.StringVar(&flags.outputPath, "output", "", "")
+	if err := flagSet.Parse(args); err != nil {
+		return nil, err
+	}
+	if flagSet.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flagSet.Args(), " "))
+	}
+	if flags.packageName == "" {
+		return nil, errors.New("--package is required")
+	}
+	return flags, nil
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer, spec *check.Spec) error {
+	flags, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
+	client, err := newClient(flags, spec)
+	if err != nil {
+		return err
+	}
+	data, err := GenerateRuleIDs(
+		ctx,
+		client,
+		flags.packageName,
+		GenerateRuleIDsWithTypeName(flags.typeName),
+	)
+	if err != nil {
+		return err
+	}
+	if flags.outputPath == "" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(flags.outputPath, data, 0o600)
+}
+
+func newClient(flags *flags, spec *check.Spec) (check.Client, error) {
+	if spec != nil {
+		if flags.pluginPath != "" || len(flags.pluginArgs) > 0 {
+			return nil, errors.New("--plugin and --plugin-arg cannot be used when generating from a Spec in-process")
+		}
+		return check.NewClientForSpec(spec)
+	}
+	if flags.pluginPath == "" {
+		return nil, errors.New("--plugin is required")
+	}
+	return check.NewClientForRunner(
+		pluginrpc.NewExecRunner(
+			flags.pluginPath,
+			pluginrpc.ExecRunnerWithArgs(flags.pluginArgs...),
+		),
+	), nil
+}
+
+// constantNameSuffixForRuleID returns the CamelCase form of the Rule ID.
+//
+// Rule IDs only consist of capital letters and underscores, for example "TIMESTAMP_SUFFIX"
+// results in "TimestampSuffix".
+func constantNameSuffixForRuleID(ruleID string) string {
+	var builder strings.Builder
+	for _, part := range strings.Split(ruleID, "_") {
+		if part == "" {
+			continue
+		}
+		builder.WriteString(part[:1])
+		builder.WriteString(strings.ToLower(part[1:]))
+	}
+	return builder.String()
+}
+
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
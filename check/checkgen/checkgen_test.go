@@ -0,0 +1,91 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkgen
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRuleIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := check.NewClientForSpec(testSpec)
+	require.NoError(t, err)
+	data, err := GenerateRuleIDs(ctx, client, "foo")
+	require.NoError(t, err)
+	expectedData, err := os.ReadFile("testdata/rule_ids.golden")
+	require.NoError(t, err)
+	require.Equal(t, string(expectedData), string(data))
+
+	data, err = GenerateRuleIDs(ctx, client, "foo", GenerateRuleIDsWithTypeName("LintRuleID"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "\tLintRuleIDFieldLowerSnakeCase LintRuleID = \"FIELD_LOWER_SNAKE_CASE\"\n")
+	require.Contains(t, string(data), "func AllLintRuleIDs() []LintRuleID {")
+
+	_, err = GenerateRuleIDs(ctx, client, "foo", GenerateRuleIDsWithTypeName("ruleID"))
+	require.Error(t, err)
+	_, err = GenerateRuleIDs(ctx, client, "foo-bar")
+	require.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	stdout := bytes.NewBuffer(nil)
+	require.NoError(t, run(ctx, []string{"--package", "foo"}, stdout, testSpec))
+	expectedData, err := os.ReadFile("testdata/rule_ids.golden")
+	require.NoError(t, err)
+	require.Equal(t, string(expectedData), stdout.String())
+
+	require.EqualError(t, run(ctx, nil, stdout, testSpec), "--package is required")
+	require.EqualError(t, run(ctx, []string{"--package", "foo"}, stdout, nil), "--plugin is required")
+	require.Error(t, run(ctx, []string{"--package", "foo", "--plugin", "buf-plugin-foo"}, stdout, testSpec))
+}
+
+var testSpec = &check.Spec{
+	Rules: []*check.RuleSpec{
+		{
+			ID:        "FIELD_LOWER_SNAKE_CASE",
+			IsDefault: true,
+			Purpose:   "Checks that all field names are lower_snake_case.",
+			Type:      check.RuleTypeLint,
+			Handler:   testNopRuleHandler,
+		},
+		{
+			ID:             "FIELD_SNAKE_CASE",
+			Purpose:        "Checks that all field names are snake_case.",
+			Type:           check.RuleTypeLint,
+			Deprecated:     true,
+			ReplacementIDs: []string{"FIELD_LOWER_SNAKE_CASE"},
+			Handler:        testNopRuleHandler,
+		},
+		{
+			ID:      "SERVICE_SUFFIX",
+			Purpose: "Checks that all service names end in Service.",
+			Type:    check.RuleTypeLint,
+			Handler: testNopRuleHandler,
+		},
+	},
+}
+
+var testNopRuleHandler = check.RuleHandlerFunc(func(context.Context, check.ResponseWriter, check.Request) error { return nil })
@@ -0,0 +1,188 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checknaming implements naming convention conversions and validators
+// for use within lint plugins.
+//
+// The conversions split a name into words on delimiters ('.', '-', '_', and whitespace)
+// and on case changes, such that "HTTPServer", "http_server", and "httpServer" all
+// result in the words "http" and "server".
+package checknaming
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ToLowerSnakeCase converts the string to lower_snake_case.
+func ToLowerSnakeCase(s string) string {
+	return strings.ToLower(toSnakeCase(s))
+}
+
+// ToUpperSnakeCase converts the string to UPPER_SNAKE_CASE.
+func ToUpperSnakeCase(s string) string {
+	return strings.ToUpper(toSnakeCase(s))
+}
+
+// ToPascalCase converts the string to PascalCase.
+func ToPascalCase(s string) string {
+	var sb strings.Builder
+	for _, word := range splitWords(s) {
+		_, _ = sb.WriteString(capitalize(word))
+	}
+	return sb.String()
+}
+
+// ToCamelCase converts the string to camelCase.
+func ToCamelCase(s string) string {
+	var sb strings.Builder
+	for i, word := range splitWords(s) {
+		if i == 0 {
+			_, _ = sb.WriteString(strings.ToLower(word))
+			continue
+		}
+		_, _ = sb.WriteString(capitalize(word))
+	}
+	return sb.String()
+}
+
+// ToJSONName converts the field name to the JSON name that protoc would compute
+// for it if json_name is not set.
+//
+// This differs from ToCamelCase in that only underscores are treated as delimiters,
+// and the case of all other characters is preserved.
+func ToJSONName(fieldName string) string {
+	var sb strings.Builder
+	var capitalizeNext bool
+	for _, c := range fieldName {
+		if c == '_' {
+			capitalizeNext = true
+			continue
+		}
+		if capitalizeNext && 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		capitalizeNext = false
+		_, _ = sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// IsLowerSnakeCase returns true if the string is lower_snake_case.
+func IsLowerSnakeCase(s string) bool {
+	return s != "" && s == ToLowerSnakeCase(s)
+}
+
+// IsUpperSnakeCase returns true if the string is UPPER_SNAKE_CASE.
+func IsUpperSnakeCase(s string) bool {
+	return s != "" && s == ToUpperSnakeCase(s)
+}
+
+// IsPascalCase returns true if the string is PascalCase.
+//
+// The string must start with an uppercase letter, and only consist of letters and digits.
+// Consecutive uppercase letters, such as in "HTTPServer", are allowed.
+func IsPascalCase(s string) bool {
+	return isAlphanumericStartingWith(s, unicode.IsUpper)
+}
+
+// IsCamelCase returns true if the string is camelCase.
+//
+// The string must start with a lowercase letter, and only consist of letters and digits.
+func IsCamelCase(s string) bool {
+	return isAlphanumericStartingWith(s, unicode.IsLower)
+}
+
+// IsLowerSnakeCasePackageName returns true if the string is a dotted package name where
+// every component is lower_snake_case, for example "foo.bar_baz.v1".
+func IsLowerSnakeCasePackageName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, component := range strings.Split(s, ".") {
+		if !IsLowerSnakeCase(component) {
+			return false
+		}
+	}
+	return true
+}
+
+// *** PRIVATE ***
+
+func splitWords(s string) []string {
+	var words []string
+	for _, word := range strings.Split(toSnakeCase(s), "_") {
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+func capitalize(word string) string {
+	if word == "" {
+		return ""
+	}
+	runes := []rune(strings.ToLower(word))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func isAlphanumericStartingWith(s string, first func(rune) bool) bool {
+	for i, c := range s {
+		if i == 0 {
+			if !first(c) {
+				return false
+			}
+			continue
+		}
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func toSnakeCase(s string) string {
+	output := ""
+	s = strings.TrimFunc(s, isDelimiter)
+	for i, c := range s {
+		if isDelimiter(c) {
+			c = '_'
+		}
+		switch {
+		case i == 0:
+			output += string(c)
+		case isSnakeCaseNewWord(c, false) &&
+			output[len(output)-1] != '_' &&
+			((i < len(s)-1 && !isSnakeCaseNewWord(rune(s[i+1]), true) && !isDelimiter(rune(s[i+1]))) ||
+				(unicode.IsLower(rune(s[i-1])))):
+			output += "_" + string(c)
+		case !(isDelimiter(c) && output[len(output)-1] == '_'):
+			output += string(c)
+		}
+	}
+	return output
+}
+
+func isSnakeCaseNewWord(r rune, newWordOnDigits bool) bool {
+	if newWordOnDigits {
+		return unicode.IsUpper(r) || unicode.IsDigit(r)
+	}
+	return unicode.IsUpper(r)
+}
+
+func isDelimiter(r rune) bool {
+	return r == '.' || r == '-' || r == '_' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checknaming
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestConversions(t *testing.T) {
+	t.Parallel()
+
+	testConversions(t, "foo_bar", "foo_bar", "FOO_BAR", "FooBar", "fooBar")
+	testConversions(t, "FooBar", "foo_bar", "FOO_BAR", "FooBar", "fooBar")
+	testConversions(t, "fooBar", "foo_bar", "FOO_BAR", "FooBar", "fooBar")
+	testConversions(t, "FOO_BAR", "foo_bar", "FOO_BAR", "FooBar", "fooBar")
+	testConversions(t, "HTTPServer", "http_server", "HTTP_SERVER", "HttpServer", "httpServer")
+	testConversions(t, "foo.bar-baz", "foo_bar_baz", "FOO_BAR_BAZ", "FooBarBaz", "fooBarBaz")
+	testConversions(t, "__foo__bar__", "foo_bar", "FOO_BAR", "FooBar", "fooBar")
+	testConversions(t, "foo1", "foo1", "FOO1", "Foo1", "foo1")
+	testConversions(t, "", "", "", "", "")
+}
+
+func TestValidators(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsLowerSnakeCase("foo_bar"))
+	assert.True(t, IsLowerSnakeCase("foo"))
+	assert.True(t, IsLowerSnakeCase("foo_bar1"))
+	assert.False(t, IsLowerSnakeCase("fooBar"))
+	assert.False(t, IsLowerSnakeCase("foo__bar"))
+	assert.False(t, IsLowerSnakeCase("_foo"))
+	assert.False(t, IsLowerSnakeCase(""))
+
+	assert.True(t, IsUpperSnakeCase("FOO_BAR"))
+	assert.False(t, IsUpperSnakeCase("FOO_bar"))
+	assert.False(t, IsUpperSnakeCase(""))
+
+	assert.True(t, IsPascalCase("FooBar"))
+	assert.True(t, IsPascalCase("HTTPServer"))
+	assert.False(t, IsPascalCase("fooBar"))
+	assert.False(t, IsPascalCase("Foo_Bar"))
+	assert.False(t, IsPascalCase(""))
+
+	assert.True(t, IsCamelCase("fooBar"))
+	assert.False(t, IsCamelCase("FooBar"))
+	assert.False(t, IsCamelCase("foo_bar"))
+
+	assert.True(t, IsLowerSnakeCasePackageName("foo.bar_baz.v1"))
+	assert.True(t, IsLowerSnakeCasePackageName("foo"))
+	assert.False(t, IsLowerSnakeCasePackageName("foo.Bar"))
+	assert.False(t, IsLowerSnakeCasePackageName("foo..bar"))
+	assert.False(t, IsLowerSnakeCasePackageName(""))
+}
+
+func TestToJSONNameMatchesProtoc(t *testing.T) {
+	t.Parallel()
+
+	fieldNames := []string{
+		"foo",
+		"foo_bar",
+		"foo_bar_baz",
+		"foo__bar",
+		"_foo",
+		"foo_",
+		"foo_1",
+		"foo1_bar",
+		"fooBar",
+		"FooBar",
+		"foo_Bar",
+		"FOO_BAR",
+	}
+	fieldDescriptorProtos := make([]*descriptorpb.FieldDescriptorProto, len(fieldNames))
+	for i, fieldName := range fieldNames {
+		fieldDescriptorProtos[i] = &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(fieldName),
+			Number: proto.Int32(int32(i + 1)),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		}
+	}
+	fileDescriptor, err := protodesc.NewFile(
+		&descriptorpb.FileDescriptorProto{
+			Name:   proto.String("foo.proto"),
+			Syntax: proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name:  proto.String("Foo"),
+					Field: fieldDescriptorProtos,
+				},
+			},
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	fields := fileDescriptor.Messages().Get(0).Fields()
+	for i := range fields.Len() {
+		fieldDescriptor := fields.Get(i)
+		assert.Equal(t, fieldDescriptor.JSONName(), ToJSONName(string(fieldDescriptor.Name())), string(fieldDescriptor.Name()))
+	}
+}
+
+func testConversions(
+	t *testing.T,
+	input string,
+	expectedLowerSnakeCase string,
+	expectedUpperSnakeCase string,
+	expectedPascalCase string,
+	expectedCamelCase string,
+) {
+	assert.Equal(t, expectedLowerSnakeCase, ToLowerSnakeCase(input), input)
+	assert.Equal(t, expectedUpperSnakeCase, ToUpperSnakeCase(input), input)
+	assert.Equal(t, expectedPascalCase, ToPascalCase(input), input)
+	assert.Equal(t, expectedCamelCase, ToCamelCase(input), input)
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checknaming
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// ConventionLowerSnakeCase is lower_snake_case.
+	ConventionLowerSnakeCase Convention = 1
+	// ConventionUpperSnakeCase is UPPER_SNAKE_CASE.
+	ConventionUpperSnakeCase Convention = 2
+	// ConventionPascalCase is PascalCase.
+	ConventionPascalCase Convention = 3
+	// ConventionCamelCase is camelCase.
+	ConventionCamelCase Convention = 4
+	// ConventionLowerSnakeCasePackageName is a dotted package name with lower_snake_case components.
+	ConventionLowerSnakeCasePackageName Convention = 5
+)
+
+var (
+	conventionToString = map[Convention]string{
+		ConventionLowerSnakeCase:            "lower_snake_case",
+		ConventionUpperSnakeCase:            "UPPER_SNAKE_CASE",
+		ConventionPascalCase:                "PascalCase",
+		ConventionCamelCase:                 "camelCase",
+		ConventionLowerSnakeCasePackageName: "lower_snake_case.package_name",
+	}
+	stringToConvention = map[string]Convention{
+		"lower_snake_case":              ConventionLowerSnakeCase,
+		"UPPER_SNAKE_CASE":              ConventionUpperSnakeCase,
+		"PascalCase":                    ConventionPascalCase,
+		"camelCase":                     ConventionCamelCase,
+		"lower_snake_case.package_name": ConventionLowerSnakeCasePackageName,
+	}
+	conventionToIsValid = map[Convention]func(string) bool{
+		ConventionLowerSnakeCase:            IsLowerSnakeCase,
+		ConventionUpperSnakeCase:            IsUpperSnakeCase,
+		ConventionPascalCase:                IsPascalCase,
+		ConventionCamelCase:                 IsCamelCase,
+		ConventionLowerSnakeCasePackageName: IsLowerSnakeCasePackageName,
+	}
+)
+
+// Convention is a naming convention.
+type Convention int
+
+// ParseConvention parses the Convention from its string representation.
+//
+// The string representation is the same as returned from String, for example "lower_snake_case".
+func ParseConvention(s string) (Convention, error) {
+	convention, ok := stringToConvention[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown naming convention: %q", s)
+	}
+	return convention, nil
+}
+
+// IsValid returns true if the name conforms to the Convention.
+//
+// Always returns false for unknown Conventions.
+func (c Convention) IsValid(name string) bool {
+	isValid, ok := conventionToIsValid[c]
+	if !ok {
+		return false
+	}
+	return isValid(name)
+}
+
+// String implements fmt.Stringer.
+func (c Convention) String() string {
+	if s, ok := conventionToString[c]; ok {
+		return s
+	}
+	return strconv.Itoa(int(c))
+}
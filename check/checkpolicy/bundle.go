@@ -0,0 +1,212 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpolicy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checkconfig"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/thread"
+)
+
+// BundleVersion is the version of the Bundle format.
+const BundleVersion = "v1"
+
+// Bundle is a check policy artifact, for distribution to hosts.
+//
+// A Bundle selects plugins, and for each plugin the Rules to run, the Options to run them
+// with, and the Policy to apply. This allows centralized governance teams to ship a single
+// YAML or JSON file that hosts evaluate with EvaluateBundle:
+//
+//	version: v1
+//	plugins:
+//	  - name: buf-plugin-field-lower-snake-case
+//	    rule_ids:
+//	      - FIELD_LOWER_SNAKE_CASE
+//	  - name: buf-plugin-timestamp-suffix
+//	    options:
+//	      timestamp_suffix: _time
+//	    policy:
+//	      rules:
+//	        TIMESTAMP_SUFFIX:
+//	          severity: warning
+//
+// Plugins are referenced by name, and hosts determine how names map to check.Clients.
+type Bundle struct {
+	// Version is the version of the Bundle format.
+	//
+	// Must be BundleVersion.
+	Version string `json:"version" yaml:"version"`
+	// Plugins are the plugins to run.
+	Plugins []*BundlePlugin `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+}
+
+// BundlePlugin is a plugin within a Bundle.
+type BundlePlugin struct {
+	// Name is the name of the plugin.
+	//
+	// Required, and unique within the Bundle.
+	Name string `json:"name" yaml:"name"`
+	// RuleIDs are the Rules to run, as with check.WithRuleIDs.
+	//
+	// If empty, the default Rules of the plugin are run.
+	RuleIDs []string `json:"rule_ids,omitempty" yaml:"rule_ids,omitempty"`
+	// Options are the Options to run the plugin with.
+	Options map[string]any `json:"options,omitempty" yaml:"options,omitempty"`
+	// Policy is the Policy to apply to the plugin, if any.
+	Policy *Policy `json:"policy,omitempty" yaml:"policy,omitempty"`
+}
+
+// ParseBundle parses the YAML or JSON data into a new Bundle.
+//
+// Unknown keys result in an error. The Bundle is validated.
+func ParseBundle(data []byte) (*Bundle, error) {
+	return checkconfig.Decode[Bundle](data)
+}
+
+// Validate returns error if the Bundle is invalid.
+func (b *Bundle) Validate() error {
+	if b.Version != BundleVersion {
+		return fmt.Errorf("unsupported bundle version %q, expected %q", b.Version, BundleVersion)
+	}
+	names := make(map[string]struct{}, len(b.Plugins))
+	for i, plugin := range b.Plugins {
+		if plugin == nil || plugin.Name == "" {
+			return fmt.Errorf("plugin %d: name is required", i)
+		}
+		if _, ok := names[plugin.Name]; ok {
+			return fmt.Errorf("duplicate plugin name: %q", plugin.Name)
+		}
+		names[plugin.Name] = struct{}{}
+		if _, err := plugin.newRequest(nil, nil); err != nil {
+			return fmt.Errorf("plugin %q: %w", plugin.Name, err)
+		}
+		if err := plugin.Policy.Validate(); err != nil {
+			return fmt.Errorf("plugin %q: %w", plugin.Name, err)
+		}
+	}
+	return nil
+}
+
+// BundleAnnotation is an Annotation that resulted from evaluating a Bundle.
+type BundleAnnotation struct {
+	// PluginName is the name of the BundlePlugin that produced the Annotation.
+	PluginName string
+	// Annotation is the Annotation.
+	Annotation check.Annotation
+	// Severity is the Severity of the Annotation, as determined by the Policy of the
+	// BundlePlugin.
+	Severity Severity
+}
+
+// EvaluateBundle evaluates the Files against the Bundle.
+//
+// Each plugin of the Bundle is run with the check.Client for its name from pluginNameToClient,
+// with the Policy of the plugin applied (see WrapClient). Plugins are run concurrently.
+// Clients for names that are not within the Bundle are not run.
+//
+// The returned BundleAnnotations are in the order of the plugins within the Bundle, and
+// then in the order of the Annotations of each plugin. Returns error if the Bundle is
+// invalid, or if there is no check.Client for a plugin of the Bundle.
+func EvaluateBundle(
+	ctx context.Context,
+	bundle *Bundle,
+	pluginNameToClient map[string]check.Client,
+	files []check.File,
+	options ...EvaluateBundleOption,
+) ([]BundleAnnotation, error) {
+	evaluateBundleOptions := newEvaluateBundleOptions()
+	for _, option := range options {
+		option(evaluateBundleOptions)
+	}
+	if err := bundle.Validate(); err != nil {
+		return nil, err
+	}
+	clients := make([]check.Client, len(bundle.Plugins))
+	for i, plugin := range bundle.Plugins {
+		client, ok := pluginNameToClient[plugin.Name]
+		if !ok {
+			return nil, fmt.Errorf("no Client for plugin %q", plugin.Name)
+		}
+		// The Policy is validated above.
+		clients[i], _ = WrapClient(client, plugin.Policy)
+	}
+	pluginBundleAnnotations := make([][]BundleAnnotation, len(bundle.Plugins))
+	jobs := make([]func(context.Context) error, len(bundle.Plugins))
+	for i, plugin := range bundle.Plugins {
+		jobs[i] = func(ctx context.Context) error {
+			request, err := plugin.newRequest(files, evaluateBundleOptions.againstFiles)
+			if err != nil {
+				return err
+			}
+			response, err := clients[i].Check(ctx, request)
+			if err != nil {
+				return fmt.Errorf("plugin %q: %w", plugin.Name, err)
+			}
+			for _, annotation := range response.Annotations() {
+				pluginBundleAnnotations[i] = append(
+					pluginBundleAnnotations[i],
+					BundleAnnotation{
+						PluginName: plugin.Name,
+						Annotation: annotation,
+						Severity:   plugin.Policy.Severity(annotation),
+					},
+				)
+			}
+			return nil
+		}
+	}
+	if err := thread.Parallelize(ctx, jobs); err != nil {
+		return nil, err
+	}
+	return slices.Concat(pluginBundleAnnotations...), nil
+}
+
+// EvaluateBundleOption is an option for EvaluateBundle.
+type EvaluateBundleOption func(*evaluateBundleOptions)
+
+// EvaluateBundleWithAgainstFiles returns a new EvaluateBundleOption that sets the
+// AgainstFiles to evaluate breaking change Rules against.
+func EvaluateBundleWithAgainstFiles(againstFiles []check.File) EvaluateBundleOption {
+	return func(evaluateBundleOptions *evaluateBundleOptions) {
+		evaluateBundleOptions.againstFiles = againstFiles
+	}
+}
+
+// *** PRIVATE ***
+
+func (p *BundlePlugin) newRequest(files []check.File, againstFiles []check.File) (check.Request, error) {
+	options, err := check.NewOptions(p.Options)
+	if err != nil {
+		return nil, err
+	}
+	return check.NewRequest(
+		files,
+		check.WithAgainstFiles(againstFiles),
+		check.WithOptions(options),
+		check.WithRuleIDs(p.RuleIDs...),
+	)
+}
+
+type evaluateBundleOptions struct {
+	againstFiles []check.File
+}
+
+func newEvaluateBundleOptions() *evaluateBundleOptions {
+	return &evaluateBundleOptions{}
+}
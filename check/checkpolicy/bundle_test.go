@@ -0,0 +1,133 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpolicy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateBundle(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bundle, err := ParseBundle(
+		[]byte(`
+version: v1
+plugins:
+  - name: plugin-b
+    rule_ids:
+      - RULE1
+    options:
+      suffix: bundle
+  - name: plugin-a
+    policy:
+      rules:
+        RULE2:
+          severity: warning
+          options:
+            suffix: policy
+`),
+	)
+	require.NoError(t, err)
+	newClient := func() check.Client {
+		client, err := check.NewClientForSpec(
+			&check.Spec{
+				Rules: []*check.RuleSpec{
+					testNewRuleSpec("RULE1", true),
+					testNewRuleSpec("RULE2", true),
+				},
+			},
+		)
+		require.NoError(t, err)
+		return client
+	}
+	pluginNameToClient := map[string]check.Client{
+		"plugin-a": newClient(),
+		"plugin-b": newClient(),
+		// Not within the Bundle, and therefore not run.
+		"plugin-c": newClient(),
+	}
+	bundleAnnotations, err := EvaluateBundle(ctx, bundle, pluginNameToClient, nil)
+	require.NoError(t, err)
+	type result struct {
+		pluginName string
+		ruleID     string
+		message    string
+		severity   Severity
+	}
+	results := make([]result, len(bundleAnnotations))
+	for i, bundleAnnotation := range bundleAnnotations {
+		results[i] = result{
+			pluginName: bundleAnnotation.PluginName,
+			ruleID:     bundleAnnotation.Annotation.RuleID(),
+			message:    bundleAnnotation.Annotation.Message(),
+			severity:   bundleAnnotation.Severity,
+		}
+	}
+	require.Equal(
+		t,
+		[]result{
+			{pluginName: "plugin-b", ruleID: "RULE1", message: "bundle", severity: SeverityError},
+			{pluginName: "plugin-a", ruleID: "RULE1", message: "none", severity: SeverityError},
+			{pluginName: "plugin-a", ruleID: "RULE2", message: "policy", severity: SeverityWarning},
+		},
+		results,
+	)
+
+	delete(pluginNameToClient, "plugin-a")
+	_, err = EvaluateBundle(ctx, bundle, pluginNameToClient, nil)
+	require.EqualError(t, err, `no Client for plugin "plugin-a"`)
+}
+
+func TestParseBundleInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range []struct {
+		data          string
+		expectedError string
+	}{
+		{
+			data:          "version: v2",
+			expectedError: `unsupported bundle version "v2", expected "v1"`,
+		},
+		{
+			data:          "version: v1\nplugins: [{name: a}, {name: a}]",
+			expectedError: `duplicate plugin name: "a"`,
+		},
+		{
+			data:          "version: v1\nplugins: [{rule_ids: [RULE1]}]",
+			expectedError: `plugin 0: name is required`,
+		},
+		{
+			data:          "version: v1\nplugins: [{name: a, options: {suffix: 0}}]",
+			expectedError: `plugin "a": option "suffix": invalid option value: int must be non-zero`,
+		},
+		{
+			data:          "version: v1\nplugins: [{name: a, policy: {rules: {RULE1: {severity: 5}}}}]",
+			expectedError: `unknown severity: "5"`,
+		},
+		{
+			data:          "version: v1\nplugins: [{name: a, rules: [RULE1]}]",
+			expectedError: `field rules not found`,
+		},
+	} {
+		_, err := ParseBundle([]byte(testCase.data))
+		require.ErrorContains(t, err, testCase.expectedError, testCase.data)
+	}
+}
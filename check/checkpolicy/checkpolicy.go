@@ -0,0 +1,334 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkpolicy applies host-defined overrides to the Rules of plugins.
+//
+// A Policy declares, per Rule and per Category, whether Rules are enabled, the Severity of
+// their Annotations, and their option values. Hosts typically decode a Policy from their own
+// configuration file:
+//
+//	rules:
+//	  FIELD_LOWER_SNAKE_CASE:
+//	    severity: warning
+//	  SERVICE_SUFFIX:
+//	    options:
+//	      service_suffix: Service
+//	categories:
+//	  COMMENTS:
+//	    enabled: false
+//
+// WrapClient applies a Policy to the Check calls of a check.Client, so that the same Policy
+// is applied uniformly to all plugins of a host. A Bundle packages the plugins, Rules, Options,
+// and Policies of an organization into a single artifact, see EvaluateBundle.
+package checkpolicy
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+)
+
+// Policy is a set of overrides for the Rules of plugins.
+//
+// Rule and Category IDs that a plugin does not have are ignored, so that a single Policy can
+// be applied to all plugins of a host.
+type Policy struct {
+	// Rules are the Overrides for individual Rules, by Rule ID.
+	//
+	// The fields set by a Rule Override take precedence over those set by Category Overrides.
+	Rules map[string]Override `json:"rules,omitempty" yaml:"rules,omitempty"`
+	// Categories are the Overrides for all Rules within a Category, by Category ID.
+	//
+	// If a Rule is within multiple Categories with Overrides, each field is taken from the
+	// Category with the lowest ID that sets it.
+	Categories map[string]Override `json:"categories,omitempty" yaml:"categories,omitempty"`
+}
+
+// Override overrides the behavior of a Rule, or of all Rules within a Category.
+//
+// All fields are optional. Fields that are not set are not overridden.
+type Override struct {
+	// Enabled enables or disables the Rule.
+	//
+	// Disabled Rules are never run. Enabled Rules are run in addition to the default Rules
+	// if the Request does not select Rules with RuleIDs. If the Request selects Rules, only
+	// disabling Rules has an effect.
+	Enabled *bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// Severity is the Severity of the Annotations of the Rule.
+	Severity Severity `json:"severity,omitempty" yaml:"severity,omitempty"`
+	// Options are option values for the Rule.
+	//
+	// These take precedence over the Options of the Request with the same keys, for this Rule
+	// only. Rules with different Options are run with separate Check calls to the plugin.
+	Options map[string]any `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// Validate returns error if the Policy is invalid.
+//
+// Severities must be known, and Options must be valid check.Options.
+func (p *Policy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, ruleID := range slices.Sorted(maps.Keys(p.Rules)) {
+		if err := validateOverride(p.Rules[ruleID]); err != nil {
+			return fmt.Errorf("rule %q: %w", ruleID, err)
+		}
+	}
+	for _, categoryID := range slices.Sorted(maps.Keys(p.Categories)) {
+		if err := validateOverride(p.Categories[categoryID]); err != nil {
+			return fmt.Errorf("category %q: %w", categoryID, err)
+		}
+	}
+	return nil
+}
+
+// Severity returns the Severity of the Annotation.
+//
+// The Severity is determined by the Rule ID and RuleCategories of the Annotation. Clients
+// returned by WrapClient populate the RuleCategories if the Policy has Category Overrides.
+// Returns SeverityError if the Severity is not overridden.
+func (p *Policy) Severity(annotation check.Annotation) Severity {
+	override := p.ruleOverride(annotation.RuleID(), xslices.Map(annotation.RuleCategories(), check.Category.ID))
+	if override.Severity == 0 {
+		return SeverityError
+	}
+	return override.Severity
+}
+
+// WrapClient returns a new check.Client that applies the Policy to the Check calls of the
+// given check.Client.
+//
+// Each Check call runs the Rules selected by the Request, or the default Rules if the Request
+// does not select Rules, with the Rules enabled and disabled by the Policy added and removed.
+// Rules with Options Overrides are run with their Options. The Rules and Categories listed by
+// the returned Client are not modified. Use Policy.Severity to get the Severity of the
+// returned Annotations.
+//
+// Returns error if the Policy is invalid.
+func WrapClient(client check.Client, policy *Policy) (check.Client, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policyClient{
+		Client: client,
+		policy: policy,
+	}, nil
+}
+
+// *** PRIVATE ***
+
+type policyClient struct {
+	check.Client
+
+	policy *Policy
+}
+
+func (c *policyClient) Check(ctx context.Context, request check.Request, options ...check.CheckCallOption) (check.Response, error) {
+	if c.policy != nil && len(c.policy.Categories) > 0 {
+		options = append(slices.Clone(options), check.CheckCallWithRuleCategories())
+	}
+	rules, err := c.Client.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ruleIDToRule := make(map[string]check.Rule, len(rules))
+	for _, rule := range rules {
+		ruleIDToRule[rule.ID()] = rule
+	}
+	ruleIDs, err := c.getRuleIDs(request, rules, ruleIDToRule)
+	if err != nil {
+		return nil, err
+	}
+	var annotations []check.Annotation
+	for _, ruleIDGroup := range c.getRuleIDGroups(ruleIDs, ruleIDToRule) {
+		groupRequest, err := newGroupRequest(request, ruleIDGroup)
+		if err != nil {
+			return nil, err
+		}
+		response, err := c.Client.Check(ctx, groupRequest, options...)
+		if err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, response.Annotations()...)
+	}
+	return check.NewResponse(annotations)
+}
+
+// getRuleIDs returns the sorted IDs of the Rules to run for the Request.
+func (c *policyClient) getRuleIDs(
+	request check.Request,
+	rules []check.Rule,
+	ruleIDToRule map[string]check.Rule,
+) ([]string, error) {
+	var ruleIDs []string
+	if requestRuleIDs := request.RuleIDs(); len(requestRuleIDs) > 0 {
+		resolvedRuleIDs, err := check.ResolveRuleIDs(requestRuleIDs, rules)
+		if err != nil {
+			return nil, err
+		}
+		ruleIDs = resolvedRuleIDs
+	} else {
+		for _, rule := range rules {
+			if enabled := c.policy.ruleOverride(rule.ID(), categoryIDs(rule)).Enabled; enabled != nil {
+				if *enabled {
+					ruleIDs = append(ruleIDs, rule.ID())
+				}
+				continue
+			}
+			if rule.IsDefault() {
+				ruleIDs = append(ruleIDs, rule.ID())
+			}
+		}
+	}
+	return xslices.Filter(
+		ruleIDs,
+		func(ruleID string) bool {
+			// Rule IDs that the plugin does not have are kept, so that the plugin returns
+			// the same error as it would without the Policy.
+			enabled := c.policy.ruleOverride(ruleID, categoryIDs(ruleIDToRule[ruleID])).Enabled
+			return enabled == nil || *enabled
+		},
+	), nil
+}
+
+// getRuleIDGroups groups the Rule IDs by their Options Overrides.
+//
+// The groups are sorted by their first Rule ID.
+func (c *policyClient) getRuleIDGroups(ruleIDs []string, ruleIDToRule map[string]check.Rule) []*ruleIDGroup {
+	var ruleIDGroups []*ruleIDGroup
+	optionsKeyToRuleIDGroup := make(map[string]*ruleIDGroup)
+	for _, ruleID := range ruleIDs {
+		options := c.policy.ruleOverride(ruleID, categoryIDs(ruleIDToRule[ruleID])).Options
+		optionsKey := getOptionsKey(options)
+		group, ok := optionsKeyToRuleIDGroup[optionsKey]
+		if !ok {
+			group = &ruleIDGroup{
+				options: options,
+			}
+			optionsKeyToRuleIDGroup[optionsKey] = group
+			ruleIDGroups = append(ruleIDGroups, group)
+		}
+		group.ruleIDs = append(group.ruleIDs, ruleID)
+	}
+	return ruleIDGroups
+}
+
+// ruleOverride returns the effective Override for the Rule with the given ID and Category IDs.
+func (p *Policy) ruleOverride(ruleID string, categoryIDs []string) Override {
+	var override Override
+	if p == nil {
+		return override
+	}
+	// Categories with lower IDs take precedence, so they are merged last.
+	categoryIDs = slices.Clone(categoryIDs)
+	slices.Sort(categoryIDs)
+	for _, categoryID := range slices.Backward(categoryIDs) {
+		if categoryOverride, ok := p.Categories[categoryID]; ok {
+			override = mergeOverride(override, categoryOverride)
+		}
+	}
+	if ruleOverride, ok := p.Rules[ruleID]; ok {
+		override = mergeOverride(override, ruleOverride)
+	}
+	return override
+}
+
+// ruleIDGroup is a group of Rules that are run with the same Options.
+type ruleIDGroup struct {
+	ruleIDs []string
+	// options are the Options Overrides of the Rules, if any.
+	options map[string]any
+}
+
+func newGroupRequest(request check.Request, ruleIDGroup *ruleIDGroup) (check.Request, error) {
+	options := request.Options()
+	if len(ruleIDGroup.options) > 0 {
+		keyToValue := make(map[string]any)
+		options.Range(
+			func(key string, value any) {
+				keyToValue[key] = value
+			},
+		)
+		maps.Copy(keyToValue, ruleIDGroup.options)
+		var err error
+		options, err = check.NewOptions(keyToValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return check.NewRequest(
+		request.UnclonedFiles(),
+		check.WithAgainstFiles(request.UnclonedAgainstFiles()),
+		check.WithOptions(options),
+		check.WithRuleIDs(ruleIDGroup.ruleIDs...),
+	)
+}
+
+func mergeOverride(base Override, override Override) Override {
+	if override.Enabled != nil {
+		base.Enabled = override.Enabled
+	}
+	if override.Severity != 0 {
+		base.Severity = override.Severity
+	}
+	if len(override.Options) > 0 {
+		options := maps.Clone(base.Options)
+		if options == nil {
+			options = make(map[string]any, len(override.Options))
+		}
+		maps.Copy(options, override.Options)
+		base.Options = options
+	}
+	return base
+}
+
+func validateOverride(override Override) error {
+	if override.Severity != 0 {
+		if err := validateSeverity(override.Severity); err != nil {
+			return err
+		}
+	}
+	if len(override.Options) > 0 {
+		if _, err := check.NewOptions(override.Options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getOptionsKey returns a key that is equal for equal Options Overrides.
+//
+// Option values are limited to scalars and slices of scalars, which have a deterministic
+// Go-syntax representation.
+func getOptionsKey(options map[string]any) string {
+	var builder strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(options)) {
+		_, _ = fmt.Fprintf(&builder, "%q=%#v;", key, options[key])
+	}
+	return builder.String()
+}
+
+// categoryIDs returns the Category IDs of the Rule, or nil if the Rule is nil.
+func categoryIDs(rule check.Rule) []string {
+	if rule == nil {
+		return nil
+	}
+	return xslices.Map(rule.Categories(), check.Category.ID)
+}
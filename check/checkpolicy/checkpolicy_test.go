@@ -0,0 +1,246 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpolicy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestWrapClient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				testNewRuleSpec("RULE1", true, "CATEGORY1"),
+				testNewRuleSpec("RULE2", true, "CATEGORY1", "CATEGORY2"),
+				testNewRuleSpec("RULE3", false),
+			},
+			Categories: []*check.CategorySpec{
+				{ID: "CATEGORY1", Purpose: "Test category."},
+				{ID: "CATEGORY2", Purpose: "Test category."},
+			},
+		},
+	)
+	require.NoError(t, err)
+	options, err := check.NewOptions(map[string]any{"suffix": "request"})
+	require.NoError(t, err)
+	request, err := check.NewRequest(nil, check.WithOptions(options))
+	require.NoError(t, err)
+
+	testCheck := func(policy *Policy, request check.Request) map[string]string {
+		policyClient, err := WrapClient(client, policy)
+		require.NoError(t, err)
+		response, err := policyClient.Check(ctx, request)
+		require.NoError(t, err)
+		ruleIDToMessage := make(map[string]string)
+		for _, annotation := range response.Annotations() {
+			ruleIDToMessage[annotation.RuleID()] = annotation.Message()
+		}
+		return ruleIDToMessage
+	}
+
+	require.Equal(
+		t,
+		map[string]string{"RULE1": "request", "RULE2": "request"},
+		testCheck(nil, request),
+	)
+	// The Rule Override takes precedence over the Category Override.
+	require.Equal(
+		t,
+		map[string]string{"RULE2": "request", "RULE3": "request"},
+		testCheck(
+			&Policy{
+				Rules: map[string]Override{
+					"RULE2": {Enabled: testBoolPtr(true)},
+					"RULE3": {Enabled: testBoolPtr(true)},
+				},
+				Categories: map[string]Override{
+					"CATEGORY1": {Enabled: testBoolPtr(false)},
+				},
+			},
+			request,
+		),
+	)
+	// Rules are run with their own Options.
+	require.Equal(
+		t,
+		map[string]string{"RULE1": "rule", "RULE2": "category"},
+		testCheck(
+			&Policy{
+				Rules: map[string]Override{
+					"RULE1": {Options: map[string]any{"suffix": "rule"}},
+				},
+				Categories: map[string]Override{
+					"CATEGORY1": {Options: map[string]any{"suffix": "category"}},
+				},
+			},
+			request,
+		),
+	)
+	// If the Request selects Rules, enabling other Rules has no effect.
+	selectRequest, err := check.NewRequest(nil, check.WithRuleIDs("category:CATEGORY2", "RULE3"))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		map[string]string{"RULE2": "none"},
+		testCheck(
+			&Policy{
+				Rules: map[string]Override{
+					"RULE1": {Enabled: testBoolPtr(true)},
+					"RULE3": {Enabled: testBoolPtr(false)},
+				},
+			},
+			selectRequest,
+		),
+	)
+	// If all selected Rules are disabled, no Rules are run.
+	require.Empty(
+		t,
+		testCheck(
+			&Policy{
+				Rules: map[string]Override{
+					"RULE3": {Enabled: testBoolPtr(false)},
+				},
+				Categories: map[string]Override{
+					"CATEGORY1": {Enabled: testBoolPtr(false)},
+				},
+			},
+			selectRequest,
+		),
+	)
+}
+
+func TestPolicySeverity(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	policy := &Policy{
+		Rules: map[string]Override{
+			"RULE1": {Severity: SeverityInfo},
+		},
+		Categories: map[string]Override{
+			"CATEGORY1": {Severity: SeverityWarning},
+			"CATEGORY2": {Severity: SeverityError},
+		},
+	}
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				testNewRuleSpec("RULE1", true, "CATEGORY1"),
+				testNewRuleSpec("RULE2", true, "CATEGORY1", "CATEGORY2"),
+				testNewRuleSpec("RULE3", true),
+			},
+			Categories: []*check.CategorySpec{
+				{ID: "CATEGORY1", Purpose: "Test category."},
+				{ID: "CATEGORY2", Purpose: "Test category."},
+			},
+		},
+	)
+	require.NoError(t, err)
+	client, err = WrapClient(client, policy)
+	require.NoError(t, err)
+	request, err := check.NewRequest(nil)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	ruleIDToSeverity := make(map[string]Severity)
+	for _, annotation := range response.Annotations() {
+		ruleIDToSeverity[annotation.RuleID()] = policy.Severity(annotation)
+	}
+	require.Equal(
+		t,
+		map[string]Severity{
+			"RULE1": SeverityInfo,
+			// The Category with the lowest ID takes precedence.
+			"RULE2": SeverityWarning,
+			"RULE3": SeverityError,
+		},
+		ruleIDToSeverity,
+	)
+}
+
+func TestPolicyValidate(t *testing.T) {
+	t.Parallel()
+
+	var policy Policy
+	require.NoError(
+		t,
+		yaml.Unmarshal(
+			[]byte(`
+rules:
+  RULE1:
+    severity: warning
+    options:
+      suffix: Service
+      max_length: 10
+categories:
+  CATEGORY1:
+    enabled: false
+`),
+			&policy,
+		),
+	)
+	require.NoError(t, policy.Validate())
+	require.Equal(t, SeverityWarning, policy.Rules["RULE1"].Severity)
+	require.Equal(t, map[string]any{"suffix": "Service", "max_length": 10}, policy.Rules["RULE1"].Options)
+	require.False(t, *policy.Categories["CATEGORY1"].Enabled)
+
+	require.ErrorContains(
+		t,
+		yaml.Unmarshal([]byte("rules: {RULE1: {severity: fatal}}"), &Policy{}),
+		`unknown severity: "fatal"`,
+	)
+	require.EqualError(
+		t,
+		(&Policy{Rules: map[string]Override{"RULE1": {Severity: Severity(4)}}}).Validate(),
+		`rule "RULE1": unknown severity: 4`,
+	)
+	_, err := WrapClient(nil, &Policy{Categories: map[string]Override{"CATEGORY1": {Options: map[string]any{"A": "b"}}}})
+	require.ErrorContains(t, err, `category "CATEGORY1": invalid option key "A"`)
+}
+
+func testNewRuleSpec(id string, isDefault bool, categoryIDs ...string) *check.RuleSpec {
+	return &check.RuleSpec{
+		ID:          id,
+		CategoryIDs: categoryIDs,
+		IsDefault:   isDefault,
+		Purpose:     "Test rule.",
+		Type:        check.RuleTypeLint,
+		Handler: check.RuleHandlerFunc(
+			func(_ context.Context, responseWriter check.ResponseWriter, request check.Request) error {
+				suffix, err := check.GetStringValue(request.Options(), "suffix")
+				if err != nil {
+					return err
+				}
+				if suffix == "" {
+					suffix = "none"
+				}
+				responseWriter.AddAnnotation(check.WithMessage(suffix))
+				return nil
+			},
+		),
+	}
+}
+
+func testBoolPtr(value bool) *bool {
+	return &value
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpolicy
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// SeverityError is the Severity of Annotations that should fail the check.
+	//
+	// This is the Severity of Annotations whose Rules do not have a Severity override.
+	SeverityError Severity = 1
+	// SeverityWarning is the Severity of Annotations that should be reported, but should
+	// not fail the check.
+	SeverityWarning Severity = 2
+	// SeverityInfo is the Severity of informational Annotations.
+	SeverityInfo Severity = 3
+)
+
+var (
+	severityToString = map[Severity]string{
+		SeverityError:   "error",
+		SeverityWarning: "warning",
+		SeverityInfo:    "info",
+	}
+	stringToSeverity = map[string]Severity{
+		"error":   SeverityError,
+		"warning": SeverityWarning,
+		"info":    SeverityInfo,
+	}
+)
+
+// Severity is the severity of the Annotations of a Rule.
+//
+// Severity is not part of the plugin protocol. Plugins produce Annotations without a
+// Severity, and hosts assign Severities with a Policy. The zero value denotes that the
+// Severity is not overridden.
+//
+// Severity implements encoding.TextMarshaler and encoding.TextUnmarshaler, using the values
+// "error", "warning", and "info", so that Policies can be loaded from YAML or JSON.
+type Severity int
+
+// ParseSeverity parses the Severity from its string value.
+func ParseSeverity(value string) (Severity, error) {
+	if severity, ok := stringToSeverity[value]; ok {
+		return severity, nil
+	}
+	return 0, fmt.Errorf("unknown severity: %q", value)
+}
+
+// String implements fmt.Stringer.
+func (s Severity) String() string {
+	if value, ok := severityToString[s]; ok {
+		return value
+	}
+	return strconv.Itoa(int(s))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	if err := validateSeverity(s); err != nil {
+		return nil, err
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(data []byte) error {
+	severity, err := ParseSeverity(string(data))
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+// *** PRIVATE ***
+
+func validateSeverity(severity Severity) error {
+	if _, ok := severityToString[severity]; !ok {
+		return fmt.Errorf("unknown severity: %v", severity)
+	}
+	return nil
+}
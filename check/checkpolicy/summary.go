@@ -0,0 +1,197 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpolicy
+
+import (
+	"fmt"
+
+	"github.com/bufbuild/bufplugin-go/check"
+)
+
+const (
+	// ExitCodeSuccess is the exit code for a Summary that did not fail.
+	ExitCodeSuccess = 0
+	// ExitCodeAnnotations is the exit code for a Summary that failed.
+	//
+	// This matches checkrun.ExitCodeAnnotations, and the exit code of buf lint and buf breaking.
+	// Hosts should exit with 1 if the check itself failed, for example if a plugin returned an
+	// error, so that failed checks can be told apart from failing schemas.
+	ExitCodeAnnotations = 100
+)
+
+// Summary summarizes the Annotations of a check by Severity.
+//
+// This standardizes how hosts fail CI: by default, Annotations with SeverityError fail the
+// check, and Annotations with SeverityWarning or SeverityInfo are reported but do not.
+type Summary interface {
+	// ErrorCount returns the number of Annotations with SeverityError.
+	ErrorCount() int
+	// WarningCount returns the number of Annotations with SeverityWarning.
+	WarningCount() int
+	// InfoCount returns the number of Annotations with SeverityInfo.
+	InfoCount() int
+	// Failed returns true if any Annotation has the fail Severity, or a more severe Severity.
+	//
+	// See SummaryWithFailSeverity.
+	Failed() bool
+	// ExitCode returns ExitCodeAnnotations if the Summary failed, and ExitCodeSuccess otherwise.
+	ExitCode() int
+	// String returns a one-line summary for display.
+	//
+	// For example: "Found 3 issues (1 error, 2 warnings, 0 info)." The format may change.
+	String() string
+
+	isSummary()
+}
+
+// NewSummary returns a new Summary for the Annotations of the Response, with Severities
+// determined by the Policy.
+//
+// The Policy may be nil, in which case all Annotations have SeverityError. If the Policy has
+// Category Overrides, the Response must have been returned by a Client from WrapClient, or
+// the Check call must have used check.CheckCallWithRuleCategories.
+func NewSummary(response check.Response, policy *Policy, options ...SummaryOption) Summary {
+	summaryOptions := newSummaryOptions()
+	for _, option := range options {
+		option(summaryOptions)
+	}
+	summary := newSummary(summaryOptions.failSeverity)
+	for _, annotation := range response.Annotations() {
+		summary.add(policy.Severity(annotation))
+	}
+	return summary
+}
+
+// NewBundleSummary returns a new Summary for the BundleAnnotations from EvaluateBundle.
+func NewBundleSummary(bundleAnnotations []BundleAnnotation, options ...SummaryOption) Summary {
+	summaryOptions := newSummaryOptions()
+	for _, option := range options {
+		option(summaryOptions)
+	}
+	summary := newSummary(summaryOptions.failSeverity)
+	for _, bundleAnnotation := range bundleAnnotations {
+		summary.add(bundleAnnotation.Severity)
+	}
+	return summary
+}
+
+// SummaryOption is an option for NewSummary and NewBundleSummary.
+type SummaryOption func(*summaryOptions)
+
+// SummaryWithFailSeverity returns a new SummaryOption that sets the least severe Severity
+// that fails the Summary.
+//
+// For example, SummaryWithFailSeverity(SeverityWarning) results in Annotations with either
+// SeverityError or SeverityWarning failing the Summary.
+//
+// The default is SeverityError. A Severity that is not known has no effect.
+func SummaryWithFailSeverity(failSeverity Severity) SummaryOption {
+	return func(summaryOptions *summaryOptions) {
+		if validateSeverity(failSeverity) == nil {
+			summaryOptions.failSeverity = failSeverity
+		}
+	}
+}
+
+// *** PRIVATE ***
+
+type summary struct {
+	failSeverity Severity
+	errorCount   int
+	warningCount int
+	infoCount    int
+	failed       bool
+}
+
+func newSummary(failSeverity Severity) *summary {
+	return &summary{
+		failSeverity: failSeverity,
+	}
+}
+
+func (s *summary) ErrorCount() int {
+	return s.errorCount
+}
+
+func (s *summary) WarningCount() int {
+	return s.warningCount
+}
+
+func (s *summary) InfoCount() int {
+	return s.infoCount
+}
+
+func (s *summary) Failed() bool {
+	return s.failed
+}
+
+func (s *summary) ExitCode() int {
+	if s.failed {
+		return ExitCodeAnnotations
+	}
+	return ExitCodeSuccess
+}
+
+func (s *summary) String() string {
+	total := s.errorCount + s.warningCount + s.infoCount
+	if total == 0 {
+		return "No issues found."
+	}
+	return fmt.Sprintf(
+		"Found %s (%s, %s, %d info).",
+		pluralize(total, "issue"),
+		pluralize(s.errorCount, "error"),
+		pluralize(s.warningCount, "warning"),
+		s.infoCount,
+	)
+}
+
+func (s *summary) add(severity Severity) {
+	if severity == 0 {
+		// The Severity is not overridden.
+		severity = SeverityError
+	}
+	switch severity {
+	case SeverityError:
+		s.errorCount++
+	case SeverityWarning:
+		s.warningCount++
+	case SeverityInfo:
+		s.infoCount++
+	}
+	// More severe Severities have lower values.
+	if severity <= s.failSeverity {
+		s.failed = true
+	}
+}
+
+func (*summary) isSummary() {}
+
+type summaryOptions struct {
+	failSeverity Severity
+}
+
+func newSummaryOptions() *summaryOptions {
+	return &summaryOptions{
+		failSeverity: SeverityError,
+	}
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpolicy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSummary(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				testNewRuleSpec("RULE1", true),
+				testNewRuleSpec("RULE2", true),
+				testNewRuleSpec("RULE3", true),
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := check.NewRequest(nil)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+
+	summary := NewSummary(response, nil)
+	require.Equal(t, 3, summary.ErrorCount())
+	require.True(t, summary.Failed())
+	require.Equal(t, ExitCodeAnnotations, summary.ExitCode())
+	require.Equal(t, "Found 3 issues (3 errors, 0 warnings, 0 info).", summary.String())
+
+	policy := &Policy{
+		Rules: map[string]Override{
+			"RULE1": {Severity: SeverityWarning},
+			"RULE2": {Severity: SeverityInfo},
+			"RULE3": {Severity: SeverityInfo},
+		},
+	}
+	summary = NewSummary(response, policy)
+	require.Equal(t, 0, summary.ErrorCount())
+	require.Equal(t, 1, summary.WarningCount())
+	require.Equal(t, 2, summary.InfoCount())
+	require.False(t, summary.Failed())
+	require.Equal(t, ExitCodeSuccess, summary.ExitCode())
+	require.Equal(t, "Found 3 issues (0 errors, 1 warning, 2 info).", summary.String())
+
+	summary = NewSummary(response, policy, SummaryWithFailSeverity(SeverityWarning))
+	require.True(t, summary.Failed())
+	require.Equal(t, ExitCodeAnnotations, summary.ExitCode())
+	summary = NewSummary(response, policy, SummaryWithFailSeverity(SeverityInfo))
+	require.True(t, summary.Failed())
+
+	summary = NewBundleSummary(nil)
+	require.False(t, summary.Failed())
+	require.Equal(t, ExitCodeSuccess, summary.ExitCode())
+	require.Equal(t, "No issues found.", summary.String())
+	summary = NewBundleSummary([]BundleAnnotation{{Severity: SeverityWarning}})
+	require.False(t, summary.Failed())
+	require.Equal(t, "Found 1 issue (0 errors, 1 warning, 0 info).", summary.String())
+}
@@ -0,0 +1,226 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkregistry resolves plugins hosted in a remote registry to check.Clients.
+//
+// A Resolver takes a plugin Reference such as "buf.build/acme/myplugin:v1.2.3", uses a Fetcher
+// to find the plugin artifact for the Reference, downloads and verifies the artifact, and caches
+// it locally by digest. The returned check.Client verifies the digest of the cached artifact
+// again every time it is executed, with check.NewSHA256Runner.
+//
+// Registries differ in how artifacts are located, so the Fetcher is provided by the caller.
+// NewHTTPFetcher provides a Fetcher for registries that serve artifacts over HTTP alongside a
+// sha256sum-style digest file.
+//
+// Only native binary artifacts are supported. WASM artifacts would require a WASM runtime,
+// which this package does not depend on.
+package checkregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/bufplugin-go/check"
+)
+
+// Artifact is a plugin artifact found by a Fetcher.
+type Artifact struct {
+	// SHA256Digest is the hex-encoded SHA-256 digest of the artifact.
+	//
+	// Required. The artifact is cached by this digest, and the downloaded artifact is verified
+	// against it.
+	SHA256Digest string
+	// Open opens the artifact for reading.
+	//
+	// Required. Open is only called if the artifact is not already cached.
+	Open func(ctx context.Context) (io.ReadCloser, error)
+}
+
+// Fetcher finds the Artifact for a Reference.
+type Fetcher interface {
+	// Fetch returns the Artifact for the Reference.
+	//
+	// The Artifact should be for the current platform.
+	Fetch(ctx context.Context, reference Reference) (*Artifact, error)
+}
+
+// FetcherFunc is a function that implements Fetcher.
+type FetcherFunc func(ctx context.Context, reference Reference) (*Artifact, error)
+
+// Fetch implements Fetcher.
+func (f FetcherFunc) Fetch(ctx context.Context, reference Reference) (*Artifact, error) {
+	return f(ctx, reference)
+}
+
+// Resolver resolves plugin References to check.Clients.
+type Resolver interface {
+	// Resolve parses the Reference, fetches and caches its Artifact, and returns a new
+	// check.Client that executes the cached Artifact.
+	//
+	// The Client runs the cached Artifact with check.NewSHA256Runner, so that the Artifact is
+	// verified every time it is executed.
+	Resolve(ctx context.Context, reference string, options ...check.ClientOption) (check.Client, error)
+	// CachedArtifactPath returns the path to the cached Artifact for the Reference.
+	//
+	// This fetches the Artifact, downloading it if it is not already cached.
+	CachedArtifactPath(ctx context.Context, reference string) (string, error)
+
+	isResolver()
+}
+
+// NewResolver returns a new Resolver that uses the given Fetcher.
+func NewResolver(fetcher Fetcher, options ...ResolverOption) (Resolver, error) {
+	return newResolver(fetcher, options...)
+}
+
+// ResolverOption is an option for a new Resolver.
+type ResolverOption func(*resolverOptions)
+
+// ResolverWithCacheDir returns a new ResolverOption that sets the directory that Artifacts
+// are cached in.
+//
+// The default is the "bufplugin" directory within os.UserCacheDir.
+func ResolverWithCacheDir(cacheDirPath string) ResolverOption {
+	return func(resolverOptions *resolverOptions) {
+		resolverOptions.cacheDirPath = cacheDirPath
+	}
+}
+
+// *** PRIVATE ***
+
+type resolver struct {
+	fetcher      Fetcher
+	cacheDirPath string
+}
+
+func newResolver(fetcher Fetcher, options ...ResolverOption) (*resolver, error) {
+	resolverOptions := newResolverOptions()
+	for _, option := range options {
+		option(resolverOptions)
+	}
+	cacheDirPath := resolverOptions.cacheDirPath
+	if cacheDirPath == "" {
+		userCacheDirPath, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		cacheDirPath = filepath.Join(userCacheDirPath, "bufplugin")
+	}
+	return &resolver{
+		fetcher:      fetcher,
+		cacheDirPath: cacheDirPath,
+	}, nil
+}
+
+func (r *resolver) Resolve(ctx context.Context, reference string, options ...check.ClientOption) (check.Client, error) {
+	artifactPath, sha256Digest, err := r.fetchCached(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+	runner, err := check.NewSHA256Runner(artifactPath, sha256Digest)
+	if err != nil {
+		return nil, err
+	}
+	return check.NewClientForRunner(runner, options...), nil
+}
+
+func (r *resolver) CachedArtifactPath(ctx context.Context, reference string) (string, error) {
+	artifactPath, _, err := r.fetchCached(ctx, reference)
+	return artifactPath, err
+}
+
+func (*resolver) isResolver() {}
+
+// fetchCached returns the path to the cached Artifact and its digest.
+func (r *resolver) fetchCached(ctx context.Context, referenceString string) (string, string, error) {
+	reference, err := ParseReference(referenceString)
+	if err != nil {
+		return "", "", err
+	}
+	artifact, err := r.fetcher.Fetch(ctx, reference)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", reference.String(), err)
+	}
+	if artifact.Open == nil {
+		return "", "", fmt.Errorf("%s: Artifact.Open is required", reference.String())
+	}
+	sha256Digest := strings.ToLower(artifact.SHA256Digest)
+	if decoded, err := hex.DecodeString(sha256Digest); err != nil || len(decoded) != sha256.Size {
+		return "", "", fmt.Errorf("%s: invalid SHA-256 digest %q", reference.String(), artifact.SHA256Digest)
+	}
+	artifactPath := filepath.Join(r.cacheDirPath, "sha256", sha256Digest)
+	if _, err := os.Stat(artifactPath); err == nil {
+		return artifactPath, sha256Digest, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", "", err
+	}
+	if err := downloadArtifact(ctx, artifact, sha256Digest, artifactPath); err != nil {
+		return "", "", fmt.Errorf("%s: %w", reference.String(), err)
+	}
+	return artifactPath, sha256Digest, nil
+}
+
+// downloadArtifact downloads the Artifact to a temporary file, verifies it, and then renames
+// it to artifactPath, so that a partial or unverified download is never cached.
+func downloadArtifact(ctx context.Context, artifact *Artifact, sha256Digest string, artifactPath string) (retErr error) {
+	dirPath := filepath.Dir(artifactPath)
+	if err := os.MkdirAll(dirPath, 0o755); err != nil {
+		return err
+	}
+	readCloser, err := artifact.Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { retErr = errors.Join(retErr, readCloser.Close()) }()
+	file, err := os.CreateTemp(dirPath, ".download-*")
+	if err != nil {
+		return err
+	}
+	tempFilePath := file.Name()
+	defer func() {
+		if retErr != nil {
+			_ = os.Remove(tempFilePath)
+		}
+	}()
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), readCloser); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if actualDigest := hex.EncodeToString(hash.Sum(nil)); actualDigest != sha256Digest {
+		return fmt.Errorf("downloaded artifact has SHA-256 digest %s, expected %s", actualDigest, sha256Digest)
+	}
+	if err := os.Chmod(tempFilePath, 0o755); err != nil {
+		return err
+	}
+	return os.Rename(tempFilePath, artifactPath)
+}
+
+type resolverOptions struct {
+	cacheDirPath string
+}
+
+func newResolverOptions() *resolverOptions {
+	return &resolverOptions{}
+}
@@ -0,0 +1,128 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	t.Parallel()
+
+	reference, err := ParseReference("buf.build/acme/myplugin:v1.2.3")
+	require.NoError(t, err)
+	require.Equal(t, "buf.build", reference.Registry())
+	require.Equal(t, "acme", reference.Owner())
+	require.Equal(t, "myplugin", reference.Name())
+	require.Equal(t, "v1.2.3", reference.Version())
+	require.Equal(t, "buf.build/acme/myplugin:v1.2.3", reference.String())
+
+	for _, invalid := range []string{
+		"",
+		"buf.build/acme/myplugin",
+		"buf.build/acme/myplugin:",
+		"buf.build/myplugin:v1",
+		"buf.build//myplugin:v1",
+		"buf.build/acme/myplugin/extra:v1",
+		"buf.build/acme/myplugin:v1:v2",
+	} {
+		_, err := ParseReference(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestResolverHTTPFetcher(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	artifactData := []byte("artifact")
+	digest := sha256.Sum256(artifactData)
+	hexDigest := hex.EncodeToString(digest[:])
+	otherDigest := sha256.Sum256([]byte("other"))
+	otherHexDigest := hex.EncodeToString(otherDigest[:])
+	var artifactRequestCount int
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc(
+		"/acme/myplugin/v1/plugin",
+		func(responseWriter http.ResponseWriter, _ *http.Request) {
+			artifactRequestCount++
+			_, _ = responseWriter.Write(artifactData)
+		},
+	)
+	serveMux.HandleFunc(
+		"/acme/myplugin/v1/plugin.sha256",
+		func(responseWriter http.ResponseWriter, _ *http.Request) {
+			_, _ = responseWriter.Write([]byte(hexDigest + "  plugin\n"))
+		},
+	)
+	serveMux.HandleFunc(
+		"/acme/corrupt/v1/plugin",
+		func(responseWriter http.ResponseWriter, _ *http.Request) {
+			_, _ = responseWriter.Write([]byte("corrupt"))
+		},
+	)
+	serveMux.HandleFunc(
+		"/acme/corrupt/v1/plugin.sha256",
+		func(responseWriter http.ResponseWriter, _ *http.Request) {
+			_, _ = responseWriter.Write([]byte(otherHexDigest))
+		},
+	)
+	server := httptest.NewServer(serveMux)
+	t.Cleanup(server.Close)
+
+	cacheDirPath := t.TempDir()
+	resolver, err := NewResolver(
+		NewHTTPFetcher(
+			func(reference Reference) (string, error) {
+				return server.URL + "/" + reference.Owner() + "/" + reference.Name() + "/" + reference.Version() + "/plugin", nil
+			},
+			HTTPFetcherWithClient(server.Client()),
+		),
+		ResolverWithCacheDir(cacheDirPath),
+	)
+	require.NoError(t, err)
+
+	for range 2 {
+		artifactPath, err := resolver.CachedArtifactPath(ctx, "example.com/acme/myplugin:v1")
+		require.NoError(t, err)
+		data, err := os.ReadFile(artifactPath)
+		require.NoError(t, err)
+		require.Equal(t, artifactData, data)
+	}
+	// The Artifact is only downloaded once.
+	require.Equal(t, 1, artifactRequestCount)
+	_, err = resolver.Resolve(ctx, "example.com/acme/myplugin:v1")
+	require.NoError(t, err)
+
+	_, err = resolver.CachedArtifactPath(ctx, "example.com/acme/corrupt:v1")
+	require.ErrorContains(t, err, "expected "+otherHexDigest)
+	_, err = resolver.CachedArtifactPath(ctx, "example.com/acme/missing:v1")
+	require.ErrorContains(t, err, "404")
+	_, err = resolver.CachedArtifactPath(ctx, "example.com/acme/myplugin")
+	require.Error(t, err)
+	// Corrupt downloads are not cached.
+	entries, err := os.ReadDir(cacheDirPath + "/sha256")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, hexDigest, entries[0].Name())
+}
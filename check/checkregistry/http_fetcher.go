@@ -0,0 +1,117 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkregistry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// NewHTTPFetcher returns a new Fetcher that downloads Artifacts over HTTP.
+//
+// artifactURL returns the URL of the Artifact for a Reference, typically including the
+// current runtime.GOOS and runtime.GOARCH. The hex-encoded SHA-256 digest of the Artifact
+// must be served at the same URL with a ".sha256" suffix, in the format produced by sha256sum.
+func NewHTTPFetcher(artifactURL func(Reference) (string, error), options ...HTTPFetcherOption) Fetcher {
+	return newHTTPFetcher(artifactURL, options...)
+}
+
+// HTTPFetcherOption is an option for a new HTTP Fetcher.
+type HTTPFetcherOption func(*httpFetcherOptions)
+
+// HTTPFetcherWithClient returns a new HTTPFetcherOption that sets the http.Client to use.
+//
+// The default is http.DefaultClient.
+func HTTPFetcherWithClient(httpClient *http.Client) HTTPFetcherOption {
+	return func(httpFetcherOptions *httpFetcherOptions) {
+		httpFetcherOptions.httpClient = httpClient
+	}
+}
+
+// *** PRIVATE ***
+
+// maxDigestFileSize is the maximum size of a ".sha256" file that will be read.
+const maxDigestFileSize = 4096
+
+type httpFetcher struct {
+	artifactURL func(Reference) (string, error)
+	httpClient  *http.Client
+}
+
+func newHTTPFetcher(artifactURL func(Reference) (string, error), options ...HTTPFetcherOption) *httpFetcher {
+	httpFetcherOptions := newHTTPFetcherOptions()
+	for _, option := range options {
+		option(httpFetcherOptions)
+	}
+	return &httpFetcher{
+		artifactURL: artifactURL,
+		httpClient:  httpFetcherOptions.httpClient,
+	}
+}
+
+func (h *httpFetcher) Fetch(ctx context.Context, reference Reference) (*Artifact, error) {
+	artifactURL, err := h.artifactURL(reference)
+	if err != nil {
+		return nil, err
+	}
+	digestReadCloser, err := h.get(ctx, artifactURL+".sha256")
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(digestReadCloser, maxDigestFileSize))
+	_ = digestReadCloser.Close()
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%s.sha256 is empty", artifactURL)
+	}
+	return &Artifact{
+		SHA256Digest: fields[0],
+		Open: func(ctx context.Context) (io.ReadCloser, error) {
+			return h.get(ctx, artifactURL)
+		},
+	}, nil
+}
+
+func (h *httpFetcher) get(ctx context.Context, url string) (io.ReadCloser, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := h.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		_ = response.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", url, response.Status)
+	}
+	return response.Body, nil
+}
+
+type httpFetcherOptions struct {
+	httpClient *http.Client
+}
+
+func newHTTPFetcherOptions() *httpFetcherOptions {
+	return &httpFetcherOptions{
+		httpClient: http.DefaultClient,
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkregistry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reference is a reference to a plugin within a registry.
+//
+// References are of the form "<registry>/<owner>/<name>:<version>", for example
+// "buf.build/acme/myplugin:v1.2.3".
+type Reference interface {
+	// Registry returns the registry host, for example "buf.build".
+	Registry() string
+	// Owner returns the owner of the plugin, for example "acme".
+	Owner() string
+	// Name returns the name of the plugin, for example "myplugin".
+	Name() string
+	// Version returns the version of the plugin, for example "v1.2.3".
+	Version() string
+	// String returns the string form of the Reference.
+	String() string
+
+	isReference()
+}
+
+// ParseReference parses a Reference from its string form.
+//
+// The version is required, so that a Reference always resolves to the same Artifact.
+func ParseReference(s string) (Reference, error) {
+	fullName, version, ok := strings.Cut(s, ":")
+	if !ok || version == "" {
+		return nil, fmt.Errorf("invalid plugin reference %q: version is required", s)
+	}
+	components := strings.Split(fullName, "/")
+	if len(components) != 3 {
+		return nil, fmt.Errorf("invalid plugin reference %q: expected <registry>/<owner>/<name>:<version>", s)
+	}
+	for _, component := range components {
+		if component == "" {
+			return nil, fmt.Errorf("invalid plugin reference %q: empty component", s)
+		}
+	}
+	if strings.ContainsAny(version, "/:") {
+		return nil, fmt.Errorf("invalid plugin reference %q: invalid version", s)
+	}
+	return &reference{
+		registry: components[0],
+		owner:    components[1],
+		name:     components[2],
+		version:  version,
+	}, nil
+}
+
+// *** PRIVATE ***
+
+type reference struct {
+	registry string
+	owner    string
+	name     string
+	version  string
+}
+
+func (r *reference) Registry() string {
+	return r.registry
+}
+
+func (r *reference) Owner() string {
+	return r.owner
+}
+
+func (r *reference) Name() string {
+	return r.name
+}
+
+func (r *reference) Version() string {
+	return r.version
+}
+
+func (r *reference) String() string {
+	return r.registry + "/" + r.owner + "/" + r.name + ":" + r.version
+}
+
+func (*reference) isReference() {}
@@ -0,0 +1,337 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkrules implements declarative Rules for the check package.
+//
+// Rules are defined in a YAML or JSON configuration file and turned into a check.Spec at
+// startup. This allows a single generic plugin binary to have its rule set configured by
+// users who do not write Go. For example:
+//
+//	categories:
+//	  - id: NAMING
+//	    purpose: Checks naming conventions.
+//	rules:
+//	  - id: SERVICE_SUFFIX
+//	    purpose: Checks that all service names end in "Service".
+//	    categories: [NAMING]
+//	    default: true
+//	    target: service
+//	    match:
+//	      pattern: "Service$"
+//	    message: 'Service "{{.Name}}" should end in "Service".'
+//
+// A plugin can then embed this configuration and call check.Main:
+//
+//	//go:embed rules.yaml
+//	var rulesData []byte
+//
+//	func main() {
+//		config, err := checkrules.ParseConfig(rulesData)
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		spec, err := checkrules.NewSpec(config)
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		check.Main(spec)
+//	}
+package checkrules
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checknaming"
+	"github.com/bufbuild/bufplugin-go/check/checkutil"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseConfig parses a Config from YAML or JSON data.
+//
+// Unknown keys result in an error.
+func ParseConfig(data []byte) (*Config, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	config := &Config{}
+	if err := decoder.Decode(config); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("config is empty")
+		}
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return config, nil
+}
+
+// ReadConfigFile reads and parses a Config from the YAML or JSON file at the given path.
+func ReadConfigFile(filePath string) (*Config, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return config, nil
+}
+
+// NewSpec returns a new Spec for the given Config.
+//
+// Each RuleConfig is validated, and its patterns and message template compiled, when the Spec
+// is created. The returned Spec is further validated by check.Main as with any other Spec.
+func NewSpec(config *Config) (*check.Spec, error) {
+	if len(config.Rules) == 0 {
+		return nil, errors.New("config has no rules")
+	}
+	ruleSpecs := make([]*check.RuleSpec, 0, len(config.Rules))
+	for i, ruleConfig := range config.Rules {
+		ruleSpec, err := newRuleSpec(ruleConfig)
+		if err != nil {
+			if ruleConfig.ID == "" {
+				return nil, fmt.Errorf("rules[%d]: %w", i, err)
+			}
+			return nil, fmt.Errorf("rule %q: %w", ruleConfig.ID, err)
+		}
+		ruleSpecs = append(ruleSpecs, ruleSpec)
+	}
+	categorySpecs := make([]*check.CategorySpec, 0, len(config.Categories))
+	for i, categoryConfig := range config.Categories {
+		if categoryConfig.ID == "" {
+			return nil, fmt.Errorf("categories[%d]: id is required", i)
+		}
+		categorySpecs = append(
+			categorySpecs,
+			&check.CategorySpec{
+				ID:             categoryConfig.ID,
+				Purpose:        categoryConfig.Purpose,
+				Deprecated:     categoryConfig.Deprecated,
+				ReplacementIDs: categoryConfig.ReplacementIDs,
+			},
+		)
+	}
+	return &check.Spec{
+		Rules:      ruleSpecs,
+		Categories: categorySpecs,
+	}, nil
+}
+
+// *** PRIVATE ***
+
+const defaultMessageTemplateText = `{{.DisplayKind}} name "{{.Name}}" {{.Reason}}.`
+
+// matcher is a single Match condition.
+type matcher struct {
+	// newIsValid is called once per Check call.
+	newIsValid func(request check.Request) (isValidFunc, error)
+	reason     string
+}
+
+// isValidFunc validates a single descriptor.
+type isValidFunc func(ctx context.Context, name string, descriptor protoreflect.Descriptor) (bool, error)
+
+// defaultMessageData is the data for the default message template.
+type defaultMessageData struct {
+	MessageData
+
+	DisplayKind string
+}
+
+func newRuleSpec(ruleConfig RuleConfig) (*check.RuleSpec, error) {
+	if ruleConfig.ID == "" {
+		return nil, errors.New("id is required")
+	}
+	if ruleConfig.Target == "" {
+		return nil, errors.New("target is required")
+	}
+	descriptorKind, err := check.ParseDescriptorKind(ruleConfig.Target)
+	if err != nil {
+		return nil, err
+	}
+	matchers, err := newMatchers(descriptorKind, ruleConfig.Match)
+	if err != nil {
+		return nil, err
+	}
+	messageTemplateText := ruleConfig.Message
+	if messageTemplateText == "" {
+		messageTemplateText = defaultMessageTemplateText
+	}
+	messageTemplate, err := template.New(ruleConfig.ID).Option("missingkey=error").Parse(messageTemplateText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	isDefaultMessage := ruleConfig.Message == ""
+	return &check.RuleSpec{
+		ID:             ruleConfig.ID,
+		CategoryIDs:    ruleConfig.CategoryIDs,
+		IsDefault:      ruleConfig.Default,
+		Purpose:        ruleConfig.Purpose,
+		Type:           check.RuleTypeLint,
+		Deprecated:     ruleConfig.Deprecated,
+		ReplacementIDs: ruleConfig.ReplacementIDs,
+		Handler: check.RuleHandlerFunc(
+			func(ctx context.Context, responseWriter check.ResponseWriter, request check.Request) error {
+				isValidFuncs := make([]isValidFunc, len(matchers))
+				for i, matcher := range matchers {
+					isValidFunc, err := matcher.newIsValid(request)
+					if err != nil {
+						return fmt.Errorf("rule %q: %w", ruleConfig.ID, err)
+					}
+					isValidFuncs[i] = isValidFunc
+				}
+				return checkutil.NewDescriptorKindRuleHandler(
+					descriptorKind,
+					func(
+						ctx context.Context,
+						responseWriter check.ResponseWriter,
+						_ check.Request,
+						descriptor protoreflect.Descriptor,
+					) error {
+						name := checkutil.NameForDescriptorKind(descriptorKind, descriptor)
+						for i, matcher := range matchers {
+							isValid, err := isValidFuncs[i](ctx, name, descriptor)
+							if err != nil {
+								return fmt.Errorf("rule %q: %w", ruleConfig.ID, err)
+							}
+							if isValid {
+								continue
+							}
+							messageData := MessageData{
+								RuleID:   ruleConfig.ID,
+								Kind:     descriptorKind.String(),
+								Name:     name,
+								FullName: string(descriptor.FullName()),
+								FileName: descriptor.ParentFile().Path(),
+								Reason:   matcher.reason,
+							}
+							var data any = messageData
+							if isDefaultMessage {
+								data = defaultMessageData{
+									MessageData: messageData,
+									DisplayKind: displayKind(descriptorKind),
+								}
+							}
+							var message strings.Builder
+							if err := messageTemplate.Execute(&message, data); err != nil {
+								return fmt.Errorf("rule %q: failed to execute message template: %w", ruleConfig.ID, err)
+							}
+							responseWriter.AddAnnotation(
+								append(
+									checkutil.LocationOptionsForDescriptorKind(descriptorKind, descriptor),
+									check.WithMessage(message.String()),
+								)...,
+							)
+							// Only one Annotation per descriptor.
+							return nil
+						}
+						return nil
+					},
+				).Handle(ctx, responseWriter, request)
+			},
+		),
+	}, nil
+}
+
+func newMatchers(descriptorKind check.DescriptorKind, matchConfig MatchConfig) ([]*matcher, error) {
+	var matchers []*matcher
+	if matchConfig.Pattern != "" {
+		regex, err := regexp.Compile(matchConfig.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match.pattern: %w", err)
+		}
+		matchers = append(
+			matchers,
+			&matcher{
+				newIsValid: newNameIsValid(regex.MatchString),
+				reason:     fmt.Sprintf("should match the pattern %q", matchConfig.Pattern),
+			},
+		)
+	}
+	if matchConfig.NotPattern != "" {
+		regex, err := regexp.Compile(matchConfig.NotPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match.not_pattern: %w", err)
+		}
+		matchers = append(
+			matchers,
+			&matcher{
+				newIsValid: newNameIsValid(func(name string) bool { return !regex.MatchString(name) }),
+				reason:     fmt.Sprintf("should not match the pattern %q", matchConfig.NotPattern),
+			},
+		)
+	}
+	if matchConfig.Convention != "" {
+		convention, err := checknaming.ParseConvention(matchConfig.Convention)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match.convention: %w", err)
+		}
+		matchers = append(
+			matchers,
+			&matcher{
+				newIsValid: newNameIsValid(convention.IsValid),
+				reason:     fmt.Sprintf("should be %s", convention.String()),
+			},
+		)
+	}
+	if matchConfig.Expression != "" {
+		expression, err := CompileExpression(matchConfig.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match.expression: %w", err)
+		}
+		matchers = append(
+			matchers,
+			&matcher{
+				newIsValid: func(request check.Request) (isValidFunc, error) {
+					requestActivation, err := newRequestActivation(request)
+					if err != nil {
+						return nil, err
+					}
+					return func(ctx context.Context, _ string, descriptor protoreflect.Descriptor) (bool, error) {
+						return expression.eval(ctx, requestActivation, descriptorKind, descriptor)
+					}, nil
+				},
+				reason: fmt.Sprintf("should satisfy the expression %q", matchConfig.Expression),
+			},
+		)
+	}
+	if len(matchers) == 0 {
+		return nil, errors.New("match must set at least one of pattern, not_pattern, convention, or expression")
+	}
+	return matchers, nil
+}
+
+func newNameIsValid(isValid func(string) bool) func(check.Request) (isValidFunc, error) {
+	return func(check.Request) (isValidFunc, error) {
+		return func(_ context.Context, name string, _ protoreflect.Descriptor) (bool, error) {
+			return isValid(name), nil
+		}, nil
+	}
+}
+
+func displayKind(descriptorKind check.DescriptorKind) string {
+	s := strings.ReplaceAll(descriptorKind.String(), "_", " ")
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,173 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkrules
+
+import (
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check/checktest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfigFile(t *testing.T) {
+	t.Parallel()
+
+	config, err := ReadConfigFile("testdata/rules.yaml")
+	require.NoError(t, err)
+	spec, err := NewSpec(config)
+	require.NoError(t, err)
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+			RuleIDs: []string{
+				"SERVICE_SUFFIX",
+				"ENUM_VALUE_UPPER_SNAKE_CASE",
+				"MESSAGE_NO_DATA_SUFFIX",
+				"MESSAGE_HAS_FIELDS",
+			},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID:  "ENUM_VALUE_UPPER_SNAKE_CASE",
+				Message: `Enum value name "baz_one" should be UPPER_SNAKE_CASE.`,
+				Location: &checktest.ExpectedLocation{
+					FileName:    "simple.proto",
+					StartLine:   10,
+					StartColumn: 2,
+					EndLine:     10,
+					EndColumn:   14,
+				},
+			},
+			{
+				RuleID:  "MESSAGE_HAS_FIELDS",
+				Message: `Message "UserData" should have at least one field.`,
+				Location: &checktest.ExpectedLocation{
+					FileName:  "simple.proto",
+					StartLine: 13,
+					EndLine:   13,
+					EndColumn: 19,
+				},
+			},
+			{
+				RuleID:  "MESSAGE_NO_DATA_SUFFIX",
+				Message: `Message name "UserData" should not match the pattern "Data$".`,
+				Location: &checktest.ExpectedLocation{
+					FileName:  "simple.proto",
+					StartLine: 13,
+					EndLine:   13,
+					EndColumn: 19,
+				},
+			},
+			{
+				RuleID:  "SERVICE_SUFFIX",
+				Message: `Service "simple.Bar" should end in "Service".`,
+				Location: &checktest.ExpectedLocation{
+					FileName:  "simple.proto",
+					StartLine: 6,
+					EndLine:   6,
+					EndColumn: 14,
+				},
+			},
+		},
+	}.Run(t)
+}
+
+func TestExpressionOptions(t *testing.T) {
+	t.Parallel()
+
+	config, err := ReadConfigFile("testdata/rules.yaml")
+	require.NoError(t, err)
+	spec, err := NewSpec(config)
+	require.NoError(t, err)
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+			RuleIDs: []string{"MESSAGE_HAS_FIELDS"},
+			Options: map[string]any{
+				"allow_empty_messages": true,
+			},
+		},
+		Spec: spec,
+	}.Run(t)
+}
+
+func TestCompileExpression(t *testing.T) {
+	t.Parallel()
+
+	_, err := CompileExpression(`isUpperSnakeCase(name) && package_name.startsWith("foo.")`)
+	require.NoError(t, err)
+	_, err = CompileExpression(`!descriptor.options.deprecated`)
+	require.NoError(t, err)
+	// Does not parse.
+	_, err = CompileExpression(`name ==`)
+	require.Error(t, err)
+	// Unknown variable.
+	_, err = CompileExpression(`unknown == "foo"`)
+	require.Error(t, err)
+	// Not a bool.
+	_, err = CompileExpression(`name`)
+	require.Error(t, err)
+}
+
+func TestParseConfigJSON(t *testing.T) {
+	t.Parallel()
+
+	config, err := ParseConfig(
+		[]byte(`{"rules":[{"id":"FILE_LOWER_SNAKE_CASE","purpose":"Checks file names.","target":"file","match":{"convention":"lower_snake_case"}}]}`),
+	)
+	require.NoError(t, err)
+	require.Len(t, config.Rules, 1)
+	require.Equal(t, "file", config.Rules[0].Target)
+	require.Equal(t, "lower_snake_case", config.Rules[0].Match.Convention)
+	_, err = NewSpec(config)
+	require.NoError(t, err)
+}
+
+func TestParseConfigError(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParseConfig(nil)
+	require.Error(t, err)
+	_, err = ParseConfig([]byte("rules:\n  - id: FOO\n    unknown: true\n"))
+	require.Error(t, err)
+}
+
+func TestNewSpecError(t *testing.T) {
+	t.Parallel()
+
+	testNewSpecError(t, &Config{})
+	testNewSpecError(t, &Config{Rules: []RuleConfig{{Purpose: "Purpose.", Target: "message", Match: MatchConfig{Pattern: "Foo"}}}})
+	testNewSpecError(t, &Config{Rules: []RuleConfig{{ID: "FOO", Purpose: "Purpose.", Match: MatchConfig{Pattern: "Foo"}}}})
+	testNewSpecError(t, &Config{Rules: []RuleConfig{{ID: "FOO", Purpose: "Purpose.", Target: "unknown", Match: MatchConfig{Pattern: "Foo"}}}})
+	testNewSpecError(t, &Config{Rules: []RuleConfig{{ID: "FOO", Purpose: "Purpose.", Target: "message"}}})
+	testNewSpecError(t, &Config{Rules: []RuleConfig{{ID: "FOO", Purpose: "Purpose.", Target: "message", Match: MatchConfig{Pattern: "("}}}})
+	testNewSpecError(t, &Config{Rules: []RuleConfig{{ID: "FOO", Purpose: "Purpose.", Target: "message", Match: MatchConfig{Convention: "unknown"}}}})
+	testNewSpecError(t, &Config{Rules: []RuleConfig{{ID: "FOO", Purpose: "Purpose.", Target: "message", Match: MatchConfig{Expression: "name"}}}})
+	testNewSpecError(t, &Config{Rules: []RuleConfig{{ID: "FOO", Purpose: "Purpose.", Target: "message", Match: MatchConfig{Pattern: "Foo"}, Message: "{{.Name"}}})
+}
+
+func testNewSpecError(t *testing.T, config *Config) {
+	_, err := NewSpec(config)
+	require.Error(t, err)
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkrules
+
+// Config is the configuration for a set of declarative Rules and Categories.
+type Config struct {
+	// Rules are the Rules to create.
+	//
+	// Required.
+	Rules []RuleConfig `json:"rules,omitempty" yaml:"rules,omitempty"`
+	// Categories are the Categories to create.
+	//
+	// Required if any RuleConfig specifies a category.
+	Categories []CategoryConfig `json:"categories,omitempty" yaml:"categories,omitempty"`
+}
+
+// RuleConfig is the configuration for a single declarative Rule.
+//
+// All Rules created from a RuleConfig are lint Rules.
+type RuleConfig struct {
+	// ID is the ID of the Rule.
+	//
+	// Required.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	// Purpose is the purpose of the Rule.
+	//
+	// Required.
+	Purpose string `json:"purpose,omitempty" yaml:"purpose,omitempty"`
+	// CategoryIDs are the IDs of the Categories of the Rule.
+	CategoryIDs []string `json:"categories,omitempty" yaml:"categories,omitempty"`
+	// Default says whether or not the Rule is a default Rule.
+	Default bool `json:"default,omitempty" yaml:"default,omitempty"`
+	// Deprecated says whether or not the Rule is deprecated.
+	Deprecated bool `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	// ReplacementIDs are the IDs of the Rules that replace this Rule, if deprecated.
+	ReplacementIDs []string `json:"replacement_ids,omitempty" yaml:"replacement_ids,omitempty"`
+	// Target is the kind of descriptor that the Rule checks, as parsed by
+	// check.ParseDescriptorKind, for example "message" or "enum_value".
+	//
+	// Required.
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+	// Match determines which descriptors are valid.
+	//
+	// Required.
+	Match MatchConfig `json:"match,omitempty" yaml:"match,omitempty"`
+	// Message is a text/template that produces the message of each Annotation.
+	//
+	// The template is executed with a MessageData. If not set, a message will be
+	// generated from the Target and the failed Match condition.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// MatchConfig determines which descriptors are valid for a RuleConfig.
+//
+// The name checked is determined by checkutil.NameForDescriptorKind. All set conditions must
+// be satisfied for a descriptor to be valid. At least one condition must be set.
+type MatchConfig struct {
+	// Pattern is a regular expression that names must match.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// NotPattern is a regular expression that names must not match.
+	NotPattern string `json:"not_pattern,omitempty" yaml:"not_pattern,omitempty"`
+	// Convention is a naming convention that names must conform to, as parsed by
+	// checknaming.ParseConvention, for example "lower_snake_case".
+	Convention string `json:"convention,omitempty" yaml:"convention,omitempty"`
+	// Expression is a CEL expression that must evaluate to true.
+	//
+	// See Expression for the variables and functions available to the expression.
+	Expression string `json:"expression,omitempty" yaml:"expression,omitempty"`
+}
+
+// CategoryConfig is the configuration for a single Category.
+type CategoryConfig struct {
+	// ID is the ID of the Category.
+	//
+	// Required.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	// Purpose is the purpose of the Category.
+	//
+	// Required.
+	Purpose string `json:"purpose,omitempty" yaml:"purpose,omitempty"`
+	// Deprecated says whether or not the Category is deprecated.
+	Deprecated bool `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	// ReplacementIDs are the IDs of the Categories that replace this Category, if deprecated.
+	ReplacementIDs []string `json:"replacement_ids,omitempty" yaml:"replacement_ids,omitempty"`
+}
+
+// MessageData is the data that RuleConfig message templates are executed with.
+type MessageData struct {
+	// RuleID is the ID of the Rule.
+	RuleID string
+	// Kind is the kind of descriptor, for example "enum_value".
+	Kind string
+	// Name is the name that was checked.
+	Name string
+	// FullName is the fully-qualified name of the descriptor.
+	//
+	// For files, this is the package name.
+	FullName string
+	// FileName is the name of the file that contains the descriptor.
+	FileName string
+	// Reason describes the Match condition that failed, for example
+	// `should match the pattern "Service$"`.
+	Reason string
+}
@@ -0,0 +1,307 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkrules
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checknaming"
+	"github.com/bufbuild/bufplugin-go/check/checkutil"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/interpreter"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Expression is a compiled CEL expression that is evaluated against a single descriptor.
+//
+// The expression must evaluate to a bool, where true means that the descriptor is valid.
+// The following variables are available:
+//
+//   - name (string): the name as returned by checkutil.NameForDescriptorKind.
+//   - full_name (string): the fully-qualified name of the descriptor.
+//   - kind (string): the DescriptorKind of the descriptor, for example "enum_value".
+//   - file_name (string): the name of the file that contains the descriptor.
+//   - package_name (string): the package of the file that contains the descriptor.
+//   - leading_comment (string): the leading comment as returned by checkutil.LeadingComment.
+//   - descriptor (message): the descriptor as a google.protobuf descriptor proto, for example
+//     a google.protobuf.DescriptorProto for messages. For files and packages, this is a
+//     google.protobuf.FileDescriptorProto.
+//   - options (map): the options of the Request.
+//
+// The functions isLowerSnakeCase, isUpperSnakeCase, isPascalCase, and isCamelCase are available
+// in addition to the CEL standard library. For example:
+//
+//	name.endsWith("Service") && leading_comment != ""
+//	!descriptor.options.deprecated || isUpperSnakeCase(name)
+type Expression struct {
+	expression string
+	program    cel.Program
+}
+
+// CompileExpression compiles the given CEL expression.
+//
+// Returns error if the expression does not parse, does not type check, or does not evaluate
+// to a bool.
+func CompileExpression(expression string) (*Expression, error) {
+	env, err := getExpressionEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expression)
+	if err := issues.Err(); err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, err)
+	}
+	if !ast.OutputType().IsExactType(cel.BoolType) {
+		return nil, fmt.Errorf("expression %q must evaluate to a bool but evaluates to %v", expression, ast.OutputType())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, err)
+	}
+	return &Expression{
+		expression: expression,
+		program:    program,
+	}, nil
+}
+
+// Eval evaluates the Expression against the given descriptor of the given DescriptorKind.
+//
+// Returns true if the descriptor is valid.
+//
+// The options of the Request are converted on every call. When evaluating many descriptors
+// of the same Request, use NewExpressionRuleHandler, which converts the options once per
+// Check call.
+func (e *Expression) Eval(
+	ctx context.Context,
+	request check.Request,
+	descriptorKind check.DescriptorKind,
+	descriptor protoreflect.Descriptor,
+) (bool, error) {
+	requestActivation, err := newRequestActivation(request)
+	if err != nil {
+		return false, err
+	}
+	return e.eval(ctx, requestActivation, descriptorKind, descriptor)
+}
+
+// String returns the source of the Expression.
+func (e *Expression) String() string {
+	return e.expression
+}
+
+// NewExpressionRuleHandler returns a new RuleHandler that evaluates the CEL expression for
+// every descriptor of the given DescriptorKind within Files, and adds an Annotation for each
+// descriptor for which the expression evaluates to false.
+//
+// See Expression for the variables and functions available to the expression. The expression
+// is compiled when the RuleHandler is created.
+//
+// Imports are filtered. This is the standard case for lint rules.
+func NewExpressionRuleHandler(descriptorKind check.DescriptorKind, expression string) (check.RuleHandler, error) {
+	compiledExpression, err := CompileExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	return check.RuleHandlerFunc(
+		func(ctx context.Context, responseWriter check.ResponseWriter, request check.Request) error {
+			requestActivation, err := newRequestActivation(request)
+			if err != nil {
+				return err
+			}
+			return checkutil.NewDescriptorKindRuleHandler(
+				descriptorKind,
+				func(
+					ctx context.Context,
+					responseWriter check.ResponseWriter,
+					_ check.Request,
+					descriptor protoreflect.Descriptor,
+				) error {
+					isValid, err := compiledExpression.eval(ctx, requestActivation, descriptorKind, descriptor)
+					if err != nil {
+						return err
+					}
+					if !isValid {
+						responseWriter.AddAnnotation(
+							append(
+								checkutil.LocationOptionsForDescriptorKind(descriptorKind, descriptor),
+								check.WithMessagef(
+									"%s %q should satisfy the expression %q.",
+									displayKind(descriptorKind),
+									checkutil.NameForDescriptorKind(descriptorKind, descriptor),
+									expression,
+								),
+							)...,
+						)
+					}
+					return nil
+				},
+			).Handle(ctx, responseWriter, request)
+		},
+	), nil
+}
+
+// *** PRIVATE ***
+
+// getExpressionEnv returns the shared CEL environment.
+//
+// Creating the environment is expensive, so it is only done once.
+var getExpressionEnv = sync.OnceValues(
+	func() (*cel.Env, error) {
+		env, err := cel.NewEnv(
+			cel.Types(
+				&descriptorpb.FileDescriptorProto{},
+				&descriptorpb.DescriptorProto{},
+				&descriptorpb.FieldDescriptorProto{},
+				&descriptorpb.OneofDescriptorProto{},
+				&descriptorpb.EnumDescriptorProto{},
+				&descriptorpb.EnumValueDescriptorProto{},
+				&descriptorpb.ServiceDescriptorProto{},
+				&descriptorpb.MethodDescriptorProto{},
+			),
+			cel.Variable("name", cel.StringType),
+			cel.Variable("full_name", cel.StringType),
+			cel.Variable("kind", cel.StringType),
+			cel.Variable("file_name", cel.StringType),
+			cel.Variable("package_name", cel.StringType),
+			cel.Variable("leading_comment", cel.StringType),
+			cel.Variable("descriptor", cel.DynType),
+			cel.Variable("options", cel.MapType(cel.StringType, cel.DynType)),
+			newConventionFunction("isLowerSnakeCase", checknaming.IsLowerSnakeCase),
+			newConventionFunction("isUpperSnakeCase", checknaming.IsUpperSnakeCase),
+			newConventionFunction("isPascalCase", checknaming.IsPascalCase),
+			newConventionFunction("isCamelCase", checknaming.IsCamelCase),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create expression environment: %w", err)
+		}
+		return env, nil
+	},
+)
+
+// newRequestActivation returns the Activation for the variables that only depend on the Request.
+//
+// The result is the parent of the per-descriptor Activation created by eval.
+func newRequestActivation(request check.Request) (interpreter.Activation, error) {
+	env, err := getExpressionEnv()
+	if err != nil {
+		return nil, err
+	}
+	options := make(map[string]any)
+	request.Options().Range(
+		func(key string, value any) {
+			options[key] = value
+		},
+	)
+	return interpreter.NewActivation(
+		map[string]any{
+			"options": env.CELTypeAdapter().NativeToValue(options),
+		},
+	)
+}
+
+func (e *Expression) eval(
+	ctx context.Context,
+	requestActivation interpreter.Activation,
+	descriptorKind check.DescriptorKind,
+	descriptor protoreflect.Descriptor,
+) (bool, error) {
+	fileDescriptor := descriptor.ParentFile()
+	descriptorActivation, err := interpreter.NewActivation(
+		map[string]any{
+			"name":         checkutil.NameForDescriptorKind(descriptorKind, descriptor),
+			"full_name":    string(descriptor.FullName()),
+			"kind":         descriptorKind.String(),
+			"file_name":    fileDescriptor.Path(),
+			"package_name": string(fileDescriptor.Package()),
+			// The comment and the descriptor proto are only computed if the expression uses them.
+			"leading_comment": func() any {
+				return checkutil.LeadingComment(descriptor)
+			},
+			"descriptor": func() any {
+				descriptorMessage, err := descriptorProtoForDescriptor(descriptor)
+				if err != nil {
+					return types.NewErr("%s", err.Error())
+				}
+				return descriptorMessage
+			},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	out, _, err := e.program.ContextEval(
+		ctx,
+		interpreter.NewHierarchicalActivation(requestActivation, descriptorActivation),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate expression %q: %w", e.expression, err)
+	}
+	value, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q evaluated to %T instead of bool", e.expression, out.Value())
+	}
+	return value, nil
+}
+
+func newConventionFunction(name string, isValid func(string) bool) cel.EnvOption {
+	return cel.Function(
+		name,
+		cel.Overload(
+			name+"_string",
+			[]*cel.Type{cel.StringType},
+			cel.BoolType,
+			cel.UnaryBinding(
+				func(value ref.Val) ref.Val {
+					s, ok := value.(types.String)
+					if !ok {
+						return types.MaybeNoSuchOverloadErr(value)
+					}
+					return types.Bool(isValid(string(s)))
+				},
+			),
+		),
+	)
+}
+
+func descriptorProtoForDescriptor(descriptor protoreflect.Descriptor) (proto.Message, error) {
+	switch d := descriptor.(type) {
+	case protoreflect.FileDescriptor:
+		return protodesc.ToFileDescriptorProto(d), nil
+	case protoreflect.MessageDescriptor:
+		return protodesc.ToDescriptorProto(d), nil
+	case protoreflect.FieldDescriptor:
+		return protodesc.ToFieldDescriptorProto(d), nil
+	case protoreflect.OneofDescriptor:
+		return protodesc.ToOneofDescriptorProto(d), nil
+	case protoreflect.EnumDescriptor:
+		return protodesc.ToEnumDescriptorProto(d), nil
+	case protoreflect.EnumValueDescriptor:
+		return protodesc.ToEnumValueDescriptorProto(d), nil
+	case protoreflect.ServiceDescriptor:
+		return protodesc.ToServiceDescriptorProto(d), nil
+	case protoreflect.MethodDescriptor:
+		return protodesc.ToMethodDescriptorProto(d), nil
+	default:
+		return nil, fmt.Errorf("unknown descriptor type: %T", descriptor)
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkrun implements a local runner for plugins.
+//
+// The runner compiles .proto files, runs a plugin against them, and prints the resulting
+// Annotations in a lint-style format using checkformat. This allows plugin authors to try their Rules on real
+// files without buf.
+//
+// See cmd/bufplugin-run for a runner that invokes plugin binaries. To run a Spec in-process,
+// call Main with MainWithSpec from your own main function:
+//
+//	func main() {
+//		checkrun.Main(checkrun.MainWithSpec(spec))
+//	}
+package checkrun
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checkcompile"
+	"github.com/bufbuild/bufplugin-go/check/checkformat"
+	"github.com/bufbuild/pluginrpc-go"
+)
+
+const (
+	// ExitCodeAnnotations is the exit code of Main if the plugin produced any Annotations.
+	//
+	// This matches the exit code of buf lint and buf breaking.
+	ExitCodeAnnotations = 100
+	// ExitCodeError is the exit code of Main if the runner or the plugin failed.
+	ExitCodeError = 1
+)
+
+// Main is the main entrypoint for the runner.
+//
+// The runner is invoked as:
+//
+//	bufplugin-run [flags] <file.proto>...
+//
+// The .proto files are given relative to the include directories, as with protoc. Flags:
+//
+//	-I, --include DIR          A directory to resolve .proto files from. Repeatable. Defaults to ".".
+//	--against-include DIR      A directory to resolve the against .proto files from, for breaking
+//	                           change Rules. The same .proto files are compiled. Repeatable.
+//	--plugin PATH              The plugin binary to run. Required unless MainWithSpec is used.
+//	--plugin-arg ARG           An argument to pass to the plugin binary. Repeatable.
+//	--rule ID                  A Rule ID to run. Repeatable. Defaults to the default Rules.
+//	--option KEY=VALUE         An option to pass to the plugin. Repeatable. Values that parse as
+//	                           booleans, integers, or floats are passed as such, otherwise
+//	                           values are passed as strings.
+//	--color auto|always|never  Whether to colorize output. Defaults to auto.
+//	--source=false             Do not print source excerpts for Annotations.
+//	--list-rules               List the Rules of the plugin instead of running them.
+//
+// Main exits with ExitCodeAnnotations if any Annotations were produced, and ExitCodeError
+// if the runner or the plugin failed.
+func Main(options ...MainOption) {
+	mainOptions := newMainOptions()
+	for _, option := range options {
+		option(mainOptions)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	numAnnotations, err := run(ctx, os.Args[1:], os.Stdout, isTerminal(os.Stdout), mainOptions.spec)
+	if err != nil {
+		if errString := err.Error(); errString != "" {
+			_, _ = os.Stderr.Write([]byte(errString + "\n"))
+		}
+		cancel()
+		os.Exit(ExitCodeError)
+	}
+	if numAnnotations > 0 {
+		cancel()
+		os.Exit(ExitCodeAnnotations)
+	}
+}
+
+// MainOption is an option for Main.
+type MainOption func(*mainOptions)
+
+// MainWithSpec returns a new MainOption that runs the given Spec in-process instead of
+// invoking a plugin binary.
+//
+// If this option is set, the --plugin and --plugin-arg flags may not be used.
+func MainWithSpec(spec *check.Spec) MainOption {
+	return func(mainOptions *mainOptions) {
+		mainOptions.spec = spec
+	}
+}
+
+// *** PRIVATE ***
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+type mainOptions struct {
+	spec *check.Spec
+}
+
+func newMainOptions() *mainOptions {
+	return &mainOptions{}
+}
+
+type flags struct {
+	includeDirPaths        stringSliceFlag
+	againstIncludeDirPaths stringSliceFlag
+	pluginPath             string
+	pluginArgs             stringSliceFlag
+	ruleIDs                stringSliceFlag
+	options                stringSliceFlag
+	color                  string
+	source                 bool
+	listRules              bool
+	filePaths              []string
+}
+
+func parseFlags(args []string) (*flags, error) {
+	flags := &flags{}
+	flagSet := flag.NewFlagSet("bufplugin-run", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	flagSet.Var(&flags.includeDirPaths, "I", "")
+	flagSet.Var(&flags.includeDirPaths, "include", "")
+	flagSet.Var(&flags.againstIncludeDirPaths, "against-include", "")
+	flagSet.StringVar(&flags.pluginPath, "plugin", "", "")
+	flagSet.Var(&flags.pluginArgs, "plugin-arg", "")
+	flagSet.Var(&flags.ruleIDs, "rule", "")
+	flagSet.Var(&flags.options, "option", "")
+	flagSet.StringVar(&flags.color, "color", colorAuto, "")
+	flagSet.BoolVar(&flags.source, "source", true, "")
+	flagSet.BoolVar(&flags.listRules, "list-rules", false, "")
+	if err := flagSet.Parse(args); err != nil {
+		return nil, err
+	}
+	flags.filePaths = flagSet.Args()
+	if len(flags.includeDirPaths) == 0 {
+		flags.includeDirPaths = []string{"."}
+	}
+	switch flags.color {
+	case colorAuto, colorAlways, colorNever:
+	default:
+		return nil, fmt.Errorf("invalid value for --color: %q", flags.color)
+	}
+	if !flags.listRules && len(flags.filePaths) == 0 {
+		return nil, errors.New("at least one .proto file must be given")
+	}
+	return flags, nil
+}
+
+// run runs the runner, and returns the number of Annotations produced.
+func run(ctx context.Context, args []string, stdout io.Writer, stdoutIsTerminal bool, spec *check.Spec) (int, error) {
+	flags, err := parseFlags(args)
+	if err != nil {
+		return 0, err
+	}
+	client, err := newClient(flags, spec)
+	if err != nil {
+		return 0, err
+	}
+	if flags.listRules {
+		return 0, listRules(ctx, client, stdout)
+	}
+	request, err := newRequest(ctx, flags)
+	if err != nil {
+		return 0, err
+	}
+	response, err := client.Check(ctx, request)
+	if err != nil {
+		return 0, err
+	}
+	annotations := response.Annotations()
+	var terminalFormatterOptions []checkformat.TerminalFormatterOption
+	if flags.color == colorAlways || (flags.color == colorAuto && stdoutIsTerminal) {
+		terminalFormatterOptions = append(terminalFormatterOptions, checkformat.TerminalFormatterWithColor())
+	}
+	if flags.source {
+		terminalFormatterOptions = append(
+			terminalFormatterOptions,
+			checkformat.TerminalFormatterWithSource(
+				func(path string) ([]byte, error) {
+					return readSource(flags.includeDirPaths, path)
+				},
+			),
+		)
+	}
+	if err := checkformat.NewTerminalFormatter(terminalFormatterOptions...).WriteAnnotations(stdout, annotations); err != nil {
+		return 0, err
+	}
+	return len(annotations), nil
+}
+
+func newClient(flags *flags, spec *check.Spec) (check.Client, error) {
+	if spec != nil {
+		if flags.pluginPath != "" || len(flags.pluginArgs) > 0 {
+			return nil, errors.New("--plugin and --plugin-arg cannot be used when running a Spec in-process")
+		}
+		return check.NewClientForSpec(spec)
+	}
+	if flags.pluginPath == "" {
+		return nil, errors.New("--plugin is required")
+	}
+	return check.NewClientForRunner(
+		pluginrpc.NewExecRunner(
+			flags.pluginPath,
+			pluginrpc.ExecRunnerWithArgs(flags.pluginArgs...),
+		),
+	), nil
+}
+
+func newRequest(ctx context.Context, flags *flags) (check.Request, error) {
+	keyToValue := make(map[string]any, len(flags.options))
+	for _, option := range flags.options {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid value for --option: %q, must be of the form KEY=VALUE", option)
+		}
+		keyToValue[key] = parseOptionValue(value)
+	}
+	options, err := check.NewOptions(keyToValue)
+	if err != nil {
+		return nil, err
+	}
+	files, err := checkcompile.Compile(ctx, flags.includeDirPaths, flags.filePaths)
+	if err != nil {
+		return nil, err
+	}
+	var againstFiles []check.File
+	if len(flags.againstIncludeDirPaths) > 0 {
+		againstFiles, err = checkcompile.Compile(ctx, flags.againstIncludeDirPaths, flags.filePaths)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return check.NewRequest(
+		files,
+		check.WithAgainstFiles(againstFiles),
+		check.WithOptions(options),
+		check.WithRuleIDs(flags.ruleIDs...),
+	)
+}
+
+func listRules(ctx context.Context, client check.Client, stdout io.Writer) error {
+	rules, err := client.ListRules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		var attributes []string
+		if rule.IsDefault() {
+			attributes = append(attributes, "default")
+		}
+		if rule.Deprecated() {
+			attributes = append(attributes, "deprecated")
+		}
+		line := rule.ID() + " (" + rule.Type().String()
+		if len(attributes) > 0 {
+			line += ", " + strings.Join(attributes, ", ")
+		}
+		line += "): " + rule.Purpose() + "\n"
+		if provenance := rule.Provenance(); !provenance.IsZero() {
+			line += "  " + provenance.String() + "\n"
+		}
+		if _, err := io.WriteString(stdout, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseOptionValue parses the value of an --option flag.
+func parseOptionValue(value string) any {
+	if boolValue, err := strconv.ParseBool(value); err == nil {
+		return boolValue
+	}
+	if int64Value, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return int64Value
+	}
+	if float64Value, err := strconv.ParseFloat(value, 64); err == nil {
+		return float64Value
+	}
+	return value
+}
+
+// readSource reads the .proto file at the path relative to the first include directory that
+// contains it.
+func readSource(includeDirPaths []string, path string) ([]byte, error) {
+	for _, includeDirPath := range includeDirPaths {
+		data, err := os.ReadFile(filepath.Join(includeDirPath, filepath.FromSlash(path)))
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+func isTerminal(file *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return fileInfo.Mode()&os.ModeCharDevice != 0
+}
+
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkrun
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check/internal/examples/fieldlowersnakecase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	stdout := bytes.NewBuffer(nil)
+	numAnnotations, err := run(
+		ctx,
+		[]string{"-I", "testdata/simple", "--source=false", "simple.proto"},
+		stdout,
+		false,
+		fieldlowersnakecase.Spec,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, numAnnotations)
+	require.Equal(
+		t,
+		`simple.proto:7:3: [FIELD_LOWER_SNAKE_CASE] Field name "barBaz2" should be lower_snake_case, such as "bar_baz2".`+"\n",
+		stdout.String(),
+	)
+
+	stdout.Reset()
+	numAnnotations, err = run(
+		ctx,
+		[]string{"--include", "testdata/simple", "--color", "always", "simple.proto"},
+		stdout,
+		false,
+		fieldlowersnakecase.Spec,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, numAnnotations)
+	require.Contains(t, stdout.String(), "\x1b[1msimple.proto:7:3:\x1b[0m \x1b[33m[FIELD_LOWER_SNAKE_CASE]\x1b[0m")
+	require.Contains(t, stdout.String(), "string barBaz2 = 2;")
+
+	stdout.Reset()
+	numAnnotations, err = run(
+		ctx,
+		[]string{"-I", "testdata/other", "-I", "testdata/simple", "simple.proto"},
+		stdout,
+		false,
+		fieldlowersnakecase.Spec,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, numAnnotations)
+	require.Equal(
+		t,
+		`simple.proto:7:3: [FIELD_LOWER_SNAKE_CASE] Field name "barBaz2" should be lower_snake_case, such as "bar_baz2".
+   7 |   string barBaz2 = 2;
+     |   ^^^^^^^^^^^^^^^^^^^
+`,
+		stdout.String(),
+	)
+
+	stdout.Reset()
+	numAnnotations, err = run(ctx, []string{"--list-rules"}, stdout, false, fieldlowersnakecase.Spec)
+	require.NoError(t, err)
+	require.Equal(t, 0, numAnnotations)
+	require.Equal(t, "FIELD_LOWER_SNAKE_CASE (lint, default): Checks that all field names are lower_snake_case.\n", stdout.String())
+
+	_, err = run(ctx, []string{"simple.proto"}, stdout, false, nil)
+	require.ErrorContains(t, err, "--plugin is required")
+	_, err = run(ctx, []string{"--plugin", "foo", "simple.proto"}, stdout, false, fieldlowersnakecase.Spec)
+	require.Error(t, err)
+	_, err = run(ctx, []string{"-I", "testdata/simple"}, stdout, false, fieldlowersnakecase.Spec)
+	require.ErrorContains(t, err, "at least one .proto file")
+	_, err = run(ctx, []string{"--option", "foo", "simple.proto"}, stdout, false, fieldlowersnakecase.Spec)
+	require.ErrorContains(t, err, "KEY=VALUE")
+}
+
+func TestParseOptionValue(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, true, parseOptionValue("true"))
+	require.Equal(t, int64(5), parseOptionValue("5"))
+	require.Equal(t, 1.5, parseOptionValue("1.5"))
+	require.Equal(t, "foo", parseOptionValue("foo"))
+}
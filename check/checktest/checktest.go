@@ -21,21 +21,18 @@ package checktest
 import (
 	"context"
 	"errors"
-	"path/filepath"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"testing"
 
-	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
 	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checkcompile"
 	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
-	"github.com/bufbuild/protocompile"
-	"github.com/bufbuild/protocompile/linker"
-	"github.com/bufbuild/protocompile/parser"
-	"github.com/bufbuild/protocompile/protoutil"
-	"github.com/bufbuild/protocompile/reporter"
-	"github.com/bufbuild/protocompile/wellknownimports"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -45,10 +42,63 @@ type CheckTest struct {
 	Request *RequestSpec
 	// Spec is the Spec to test.
 	//
-	// Required.
+	// Exactly one of Spec and Client is required.
 	Spec *check.Spec
+	// Client is the Client to test.
+	//
+	// This allows testing a plugin binary end-to-end, see NewGoBuildClient.
+	//
+	// Exactly one of Spec and Client is required.
+	Client check.Client
 	// ExpectedAnnotations are the expected Annotations that should be returned.
 	ExpectedAnnotations []ExpectedAnnotation
+	// UnorderedAnnotations compares the ExpectedAnnotations without regard to order.
+	//
+	// See AnnotationsEqualWithUnordered.
+	UnorderedAnnotations bool
+	// IgnoreFileNames does not compare the FileNames of the ExpectedLocations.
+	//
+	// See AnnotationsEqualWithoutFileNames.
+	IgnoreFileNames bool
+	// ExpectedRules are the expected Rules that should be returned by ListRules.
+	//
+	// This catches regressions in how the Spec is wired, such as a Rule that is no longer
+	// a default Rule or is no longer part of a Category. Order does not matter.
+	//
+	// If nil, ListRules is not checked.
+	ExpectedRules []ExpectedRule
+	// ExpectedCategoryIDs are the IDs of the expected Categories that should be returned
+	// by ListCategories.
+	//
+	// Order does not matter. If nil, ListCategories is not checked. Set to an empty non-nil
+	// slice to check that there are no Categories.
+	ExpectedCategoryIDs []string
+	// DetectRequestMutation fails the test if a RuleHandler or Finalize modifies the
+	// FileDescriptorProtos of the Request.
+	//
+	// See check.ServerWithRequestMutationDetection. This can only be set if Spec is set.
+	DetectRequestMutation bool
+	// DetectGoroutineLeaks fails the test if any goroutines started during the Check call,
+	// for example by Before or a RuleHandler, are still running five seconds after the Check
+	// call has completed.
+	//
+	// Goroutines started by goroutines that were started during the Check call are also
+	// detected. Goroutines started before the Check call, for example when the Spec was
+	// constructed, are not. This can only be set if Spec is set.
+	DetectGoroutineLeaks bool
+	// DetectOrderDependence fails the test if the Annotations depend on the order in which
+	// Rules are run or Files are iterated.
+	//
+	// The Check call is repeated with the Rules and Files shuffled with random seeds, and the
+	// Annotations of each call are compared against those of the unshuffled call. On failure,
+	// the seed is reported, and can be set as ShuffleSeed to reproduce the failure. See
+	// check.ServerWithShuffle. This can only be set if Spec is set.
+	DetectOrderDependence bool
+	// ShuffleSeed is the only seed to shuffle with if DetectOrderDependence is set, instead
+	// of random seeds.
+	//
+	// If zero, random seeds are used.
+	ShuffleSeed int64
 }
 
 // Run runs the test.
@@ -57,22 +107,69 @@ type CheckTest struct {
 //
 //   - Build the Files and AgainstFiles.
 //   - Create a new Request.
-//   - Create a new Client based on the Spec.
+//   - Create a new Client based on the Spec, if Client is not set.
 //   - Call Check on the Client.
+//   - If DetectGoroutineLeaks is set, wait for all goroutines started during the Check call to
+//     complete, failing if they do not.
 //   - Compare the resulting Annotations with the ExpectedAnnotations, failing if there is a mismatch.
+//   - If DetectOrderDependence is set, call Check again with the Rules and Files shuffled,
+//     failing if the Annotations differ.
+//   - If ExpectedRules or ExpectedCategoryIDs are set, call ListRules or ListCategories on the
+//     Client, and compare the results, failing if there is a mismatch.
 func (c CheckTest) Run(t *testing.T) {
 	ctx := context.Background()
 
 	require.NotNil(t, c.Request)
-	require.NotNil(t, c.Spec)
+	require.True(t, (c.Spec == nil) != (c.Client == nil), "exactly one of Spec and Client must be set")
+	require.False(t, c.DetectRequestMutation && c.Spec == nil, "DetectRequestMutation requires Spec to be set")
+	require.False(t, c.DetectGoroutineLeaks && c.Spec == nil, "DetectGoroutineLeaks requires Spec to be set")
+	require.False(t, c.DetectOrderDependence && c.Spec == nil, "DetectOrderDependence requires Spec to be set")
 
 	request, err := c.Request.ToRequest(ctx)
 	require.NoError(t, err)
-	client, err := check.NewClientForSpec(c.Spec)
-	require.NoError(t, err)
-	response, err := client.Check(ctx, request)
-	require.NoError(t, err)
-	AssertAnnotationsEqual(t, c.ExpectedAnnotations, response.Annotations())
+	var serverOptions []check.ServerOption
+	if c.DetectRequestMutation {
+		serverOptions = append(serverOptions, check.ServerWithRequestMutationDetection())
+	}
+	client := c.Client
+	if client == nil {
+		client, err = check.NewClientForSpec(c.Spec, check.ClientWithSpecServerOptions(serverOptions...))
+		require.NoError(t, err)
+	}
+	var response check.Response
+	callCheck := func(ctx context.Context) error {
+		response, err = client.Check(ctx, request)
+		return err
+	}
+	if c.DetectGoroutineLeaks {
+		require.NoError(t, runWithGoroutineLeakDetection(ctx, goroutineLeakTimeout, callCheck))
+	} else {
+		require.NoError(t, callCheck(ctx))
+	}
+	var annotationsEqualOptions []AnnotationsEqualOption
+	if c.UnorderedAnnotations {
+		annotationsEqualOptions = append(annotationsEqualOptions, AnnotationsEqualWithUnordered())
+	}
+	if c.IgnoreFileNames {
+		annotationsEqualOptions = append(annotationsEqualOptions, AnnotationsEqualWithoutFileNames())
+	}
+	AssertAnnotationsEqual(t, c.ExpectedAnnotations, response.Annotations(), annotationsEqualOptions...)
+	if c.DetectOrderDependence {
+		require.NoError(
+			t,
+			checkOrderDependence(ctx, c.Spec, serverOptions, request, response, shuffleSeedsForShuffleSeed(c.ShuffleSeed)),
+		)
+	}
+	if c.ExpectedRules != nil {
+		rules, err := client.ListRules(ctx)
+		require.NoError(t, err)
+		AssertRulesEqual(t, c.ExpectedRules, rules)
+	}
+	if c.ExpectedCategoryIDs != nil {
+		categories, err := client.ListCategories(ctx)
+		require.NoError(t, err)
+		AssertCategoryIDsEqual(t, c.ExpectedCategoryIDs, categories)
+	}
 }
 
 // RequestSpec specifies request parameters to be compiled for testing.
@@ -133,18 +230,49 @@ type ProtoFileSpec struct {
 	// DirPaths are the paths where .proto files are contained.
 	//
 	// Imports within .proto files should derive from one of these directories.
-	// This must contain at least one element.
+	// This must contain at least one element, unless Modules is set.
 	//
 	// This corresponds to the -I flag in protoc.
 	DirPaths []string
 	// FilePaths are the specific paths to build within the DirPaths.
 	//
 	// Any imports of the FilePaths will be built as well, and marked as imports.
-	// This must contain at least one element.
+	// This must contain at least one element, unless Modules is set.
 	// Paths should be relative to DirPaths.
 	//
 	// This corresponds to arguments passed to protoc.
 	FilePaths []string
+	// Modules are the Modules to build, each with its own import resolution.
+	//
+	// This simulates a buf workspace, so that cross-Module scenarios such as imports
+	// from another Module being marked as imports can be tested. See checkcompile.CompileModules.
+	//
+	// If set, DirPaths and FilePaths must not be set.
+	Modules []checkcompile.Module
+	// Dependencies are precompiled FileDescriptorProtos that are available as imports.
+	//
+	// This avoids checking whole dependency trees, such as large vendored third-party APIs,
+	// into testdata. See checkcompile.CompileWithDependencies.
+	Dependencies []*descriptorpb.FileDescriptorProto
+	// WithoutSourceCodeInfo builds the files without SourceCodeInfo.
+	//
+	// This tests that Rules degrade gracefully when a host strips source code info. Annotations
+	// will then have Locations that only reference a file, so ExpectedLocations should only set
+	// FileName. See checkcompile.CompileWithoutSourceCodeInfo and check.Location.
+	WithoutSourceCodeInfo bool
+	// WellKnownImports are precompiled FileDescriptorProtos to use for the well-known imports,
+	// instead of the source files that are shipped with protoc.
+	//
+	// This tests Rules against modified or older definitions of the well-known types. See
+	// checkcompile.CompileWithWellKnownImports.
+	//
+	// If set, WithoutWellKnownImports must not be set.
+	WellKnownImports []*descriptorpb.FileDescriptorProto
+	// WithoutWellKnownImports builds the files without the well-known imports, so that they
+	// must be resolved from the DirPaths or Dependencies.
+	//
+	// See checkcompile.CompileWithoutWellKnownImports.
+	WithoutWellKnownImports bool
 }
 
 // ToFiles compiles the files into check.Files.
@@ -157,7 +285,46 @@ func (p *ProtoFileSpec) ToFiles(ctx context.Context) ([]check.File, error) {
 	if err := validateProtoFileSpec(p); err != nil {
 		return nil, err
 	}
-	return compile(ctx, p.DirPaths, p.FilePaths)
+	var compileOptions []checkcompile.CompileOption
+	if len(p.Dependencies) > 0 {
+		compileOptions = append(compileOptions, checkcompile.CompileWithDependencies(p.Dependencies...))
+	}
+	if p.WithoutSourceCodeInfo {
+		compileOptions = append(compileOptions, checkcompile.CompileWithoutSourceCodeInfo())
+	}
+	if len(p.WellKnownImports) > 0 {
+		compileOptions = append(compileOptions, checkcompile.CompileWithWellKnownImports(p.WellKnownImports...))
+	}
+	if p.WithoutWellKnownImports {
+		compileOptions = append(compileOptions, checkcompile.CompileWithoutWellKnownImports())
+	}
+	if len(p.Modules) > 0 {
+		return checkcompile.CompileModules(ctx, p.Modules, compileOptions...)
+	}
+	return checkcompile.Compile(ctx, p.DirPaths, p.FilePaths, compileOptions...)
+}
+
+// ToFileDescriptorSet compiles the files into a FileDescriptorSet.
+//
+// The FileDescriptorSet includes imports, and is in topological order, matching the output of
+// protoc with --include_imports and --include_source_info. This allows tests to persist the
+// compiled files as fixtures, or to pass the same files to other tools for cross-verification.
+// The FileDescriptorProtos are copies, and can be modified.
+//
+// If p is nil, this returns an empty FileDescriptorSet.
+func (p *ProtoFileSpec) ToFileDescriptorSet(ctx context.Context) (*descriptorpb.FileDescriptorSet, error) {
+	files, err := p.ToFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files = sortFilesTopologically(files)
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{
+		File: make([]*descriptorpb.FileDescriptorProto, len(files)),
+	}
+	for i, file := range files {
+		fileDescriptorSet.File[i] = proto.Clone(file.FileDescriptorProto()).(*descriptorpb.FileDescriptorProto)
+	}
+	return fileDescriptorSet, nil
 }
 
 // ExpectedAnnotation contains the values expected from an Annotation.
@@ -179,8 +346,14 @@ type ExpectedAnnotation struct {
 }
 
 // ExpectedLocation contains the values expected from a Location.
+//
+// For a Location that does not have a position, only FileName should be set.
+// See check.Location.HasPosition.
 type ExpectedLocation struct {
 	// FileName is the name of the file.
+	//
+	// FileName is always compared against the path of the file of the Location, unless
+	// AnnotationsEqualWithoutFileNames is used.
 	FileName string
 	// StartLine is the zero-indexed start line.
 	StartLine int
@@ -190,10 +363,38 @@ type ExpectedLocation struct {
 	EndLine int
 	// EndColumn is the zero-indexed end column.
 	EndColumn int
+	// LeadingComments are the leading comments of the location.
+	//
+	// As with ExpectedAnnotation.Message, if LeadingComments is not set, this field will
+	// *not* be compared against the value in Location.
+	LeadingComments string
+	// TrailingComments are the trailing comments of the location.
+	//
+	// If TrailingComments is not set, this field will *not* be compared against the value
+	// in Location.
+	TrailingComments string
+	// LeadingDetachedComments are the leading detached comments of the location.
+	//
+	// If LeadingDetachedComments is empty, this field will *not* be compared against the
+	// value in Location.
+	LeadingDetachedComments []string
 }
 
 // AssertAnnotationsEqual asserts that the Annotations equal the expected Annotations.
-func AssertAnnotationsEqual(t *testing.T, expectedAnnotations []ExpectedAnnotation, actualAnnotations []check.Annotation) {
+//
+// By default, Annotations are compared in order. The Annotations returned by a Client are
+// sorted by check.CompareAnnotations, so the expected Annotations must be given in the same
+// order. Use AnnotationsEqualWithUnordered to compare without regard to order.
+func AssertAnnotationsEqual(
+	t *testing.T,
+	expectedAnnotations []ExpectedAnnotation,
+	actualAnnotations []check.Annotation,
+	options ...AnnotationsEqualOption,
+) bool {
+	annotationsEqualOptions := newAnnotationsEqualOptions()
+	for _, option := range options {
+		option(annotationsEqualOptions)
+	}
 	if len(expectedAnnotations) == 0 {
 		expectedAnnotations = nil
 	}
@@ -201,34 +402,209 @@ func AssertAnnotationsEqual(t *testing.T, expectedAnnotations []ExpectedAnnotati
 		actualAnnotations = nil
 	}
 	actualExpectedAnnotations := expectedAnnotationsForAnnotations(actualAnnotations)
-	for i, expectedAnnotation := range expectedAnnotations {
-		if expectedAnnotation.Message == "" {
-			actualExpectedAnnotations[i].Message = ""
+	if annotationsEqualOptions.ignoreFileNames {
+		expectedAnnotations = clearFileNames(xslices.Map(expectedAnnotations, cloneExpectedAnnotation))
+		actualExpectedAnnotations = clearFileNames(actualExpectedAnnotations)
+	}
+	if annotationsEqualOptions.unordered {
+		return assertAnnotationsEqualUnordered(t, expectedAnnotations, actualExpectedAnnotations)
+	}
+	clearUnexpectedFields(expectedAnnotations, actualExpectedAnnotations)
+	return assert.Equal(
+		t,
+		expectedAnnotations,
+		actualExpectedAnnotations,
+		"Annotations are compared in order, sorted by check.CompareAnnotations. Use AnnotationsEqualWithUnordered to compare without regard to order.",
+	)
+}
+
+// RequireAnnotationsEqual requires that the Annotations equal the expected Annotations.
+//
+// See AssertAnnotationsEqual for how Annotations are compared.
+func RequireAnnotationsEqual(
+	t *testing.T,
+	expectedAnnotations []ExpectedAnnotation,
+	actualAnnotations []check.Annotation,
+	options ...AnnotationsEqualOption,
+) {
+	if !AssertAnnotationsEqual(t, expectedAnnotations, actualAnnotations, options...) {
+		t.FailNow()
+	}
+}
+
+// AnnotationsEqualOption is an option for AssertAnnotationsEqual and RequireAnnotationsEqual.
+type AnnotationsEqualOption func(*annotationsEqualOptions)
+
+// AnnotationsEqualWithUnordered returns a new AnnotationsEqualOption that compares the
+// Annotations without regard to order.
+//
+// Each expected Annotation must match exactly one actual Annotation, and vice versa. On
+// failure, the expected Annotations that were not found and the actual Annotations that were
+// not expected are reported.
+func AnnotationsEqualWithUnordered() AnnotationsEqualOption {
+	return func(annotationsEqualOptions *annotationsEqualOptions) {
+		annotationsEqualOptions.unordered = true
+	}
+}
+
+// AnnotationsEqualWithoutFileNames returns a new AnnotationsEqualOption that does not
+// compare the FileNames of the Locations and AgainstLocations.
+//
+// By default, FileNames are compared, as Annotations in different files would otherwise be
+// equal if they have the same positions. This option is useful for tests that only check a
+// single file and do not want to repeat its name on every ExpectedLocation.
+func AnnotationsEqualWithoutFileNames() AnnotationsEqualOption {
+	return func(annotationsEqualOptions *annotationsEqualOptions) {
+		annotationsEqualOptions.ignoreFileNames = true
+	}
+}
+
+// *** PRIVATE ***
+
+type annotationsEqualOptions struct {
+	unordered       bool
+	ignoreFileNames bool
+}
+
+func newAnnotationsEqualOptions() *annotationsEqualOptions {
+	return &annotationsEqualOptions{}
+}
+
+// assertAnnotationsEqualUnordered asserts that there is a one-to-one matching between the
+// expected and actual ExpectedAnnotations.
+func assertAnnotationsEqualUnordered(
+	t *testing.T,
+	expectedAnnotations []ExpectedAnnotation,
+	actualExpectedAnnotations []ExpectedAnnotation,
+) bool {
+	// actualIndexToExpectedIndex is the current matching, found with augmenting paths, so that
+	// an expected Annotation that does not specify a Message does not take the only actual
+	// Annotation that another expected Annotation could match.
+	actualIndexToExpectedIndex := make(map[int]int)
+	var tryMatch func(expectedIndex int, visited map[int]struct{}) bool
+	tryMatch = func(expectedIndex int, visited map[int]struct{}) bool {
+		for actualIndex, actualExpectedAnnotation := range actualExpectedAnnotations {
+			if _, ok := visited[actualIndex]; ok {
+				continue
+			}
+			if !expectedAnnotationMatches(expectedAnnotations[expectedIndex], actualExpectedAnnotation) {
+				continue
+			}
+			visited[actualIndex] = struct{}{}
+			otherExpectedIndex, ok := actualIndexToExpectedIndex[actualIndex]
+			if !ok || tryMatch(otherExpectedIndex, visited) {
+				actualIndexToExpectedIndex[actualIndex] = expectedIndex
+				return true
+			}
 		}
+		return false
 	}
-	assert.Equal(t, expectedAnnotations, actualExpectedAnnotations)
+	var missingAnnotations []ExpectedAnnotation
+	for expectedIndex, expectedAnnotation := range expectedAnnotations {
+		if !tryMatch(expectedIndex, make(map[int]struct{})) {
+			missingAnnotations = append(missingAnnotations, expectedAnnotation)
+		}
+	}
+	var unexpectedAnnotations []ExpectedAnnotation
+	for actualIndex, actualExpectedAnnotation := range actualExpectedAnnotations {
+		if _, ok := actualIndexToExpectedIndex[actualIndex]; !ok {
+			clearUnexpectedLocationFields(nil, actualExpectedAnnotation.Location)
+			clearUnexpectedLocationFields(nil, actualExpectedAnnotation.AgainstLocation)
+			unexpectedAnnotations = append(unexpectedAnnotations, actualExpectedAnnotation)
+		}
+	}
+	if len(missingAnnotations) == 0 && len(unexpectedAnnotations) == 0 {
+		return true
+	}
+	var sb strings.Builder
+	if len(missingAnnotations) > 0 {
+		_, _ = sb.WriteString("Expected Annotations that were not found:\n")
+		for _, missingAnnotation := range missingAnnotations {
+			_, _ = fmt.Fprintf(&sb, "\t%s\n", expectedAnnotationString(missingAnnotation))
+		}
+	}
+	if len(unexpectedAnnotations) > 0 {
+		_, _ = sb.WriteString("Actual Annotations that were not expected:\n")
+		for _, unexpectedAnnotation := range unexpectedAnnotations {
+			_, _ = fmt.Fprintf(&sb, "\t%s\n", expectedAnnotationString(unexpectedAnnotation))
+		}
+	}
+	return assert.Fail(t, "Annotations are not equal (unordered)", sb.String())
 }
 
-// RequireAnnotationsEqual requires that the Annotations equal the expected Annotations.
-func RequireAnnotationsEqual(t *testing.T, expectedAnnotations []ExpectedAnnotation, actualAnnotations []check.Annotation) {
-	if len(expectedAnnotations) == 0 {
-		expectedAnnotations = nil
+// expectedAnnotationMatches returns true if the actual ExpectedAnnotation matches the
+// expected ExpectedAnnotation, ignoring the fields that are not set on the expected
+// ExpectedAnnotation.
+func expectedAnnotationMatches(expectedAnnotation ExpectedAnnotation, actualExpectedAnnotation ExpectedAnnotation) bool {
+	actualExpectedAnnotation = cloneExpectedAnnotation(actualExpectedAnnotation)
+	actualExpectedAnnotations := []ExpectedAnnotation{actualExpectedAnnotation}
+	clearUnexpectedFields([]ExpectedAnnotation{expectedAnnotation}, actualExpectedAnnotations)
+	return assert.ObjectsAreEqual(expectedAnnotation, actualExpectedAnnotations[0])
+}
+
+func cloneExpectedAnnotation(expectedAnnotation ExpectedAnnotation) ExpectedAnnotation {
+	if expectedAnnotation.Location != nil {
+		location := *expectedAnnotation.Location
+		expectedAnnotation.Location = &location
 	}
-	if len(actualAnnotations) == 0 {
-		actualAnnotations = nil
+	if expectedAnnotation.AgainstLocation != nil {
+		againstLocation := *expectedAnnotation.AgainstLocation
+		expectedAnnotation.AgainstLocation = &againstLocation
 	}
-	actualExpectedAnnotations := expectedAnnotationsForAnnotations(actualAnnotations)
-	for i, expectedAnnotation := range expectedAnnotations {
-		if expectedAnnotation.Message == "" {
-			actualExpectedAnnotations[i].Message = ""
+	return expectedAnnotation
+}
+
+// clearFileNames clears the FileNames of the Locations and AgainstLocations in place.
+func clearFileNames(expectedAnnotations []ExpectedAnnotation) []ExpectedAnnotation {
+	for _, expectedAnnotation := range expectedAnnotations {
+		if expectedAnnotation.Location != nil {
+			expectedAnnotation.Location.FileName = ""
+		}
+		if expectedAnnotation.AgainstLocation != nil {
+			expectedAnnotation.AgainstLocation.FileName = ""
 		}
 	}
-	require.Equal(t, expectedAnnotations, actualExpectedAnnotations)
+	return expectedAnnotations
 }
 
-// *** PRIVATE ***
+func expectedAnnotationString(expectedAnnotation ExpectedAnnotation) string {
+	var sb strings.Builder
+	_, _ = sb.WriteString(expectedAnnotation.RuleID)
+	if expectedAnnotation.Location != nil {
+		_, _ = fmt.Fprintf(&sb, " at %s", expectedLocationString(expectedAnnotation.Location))
+	}
+	if expectedAnnotation.AgainstLocation != nil {
+		_, _ = fmt.Fprintf(&sb, " against %s", expectedLocationString(expectedAnnotation.AgainstLocation))
+	}
+	if expectedAnnotation.Message != "" {
+		_, _ = fmt.Fprintf(&sb, ": %q", expectedAnnotation.Message)
+	}
+	return sb.String()
+}
+
+// expectedLocationString returns the ExpectedLocation as file:line:column-line:column, with
+// one-indexed lines and columns.
+func expectedLocationString(expectedLocation *ExpectedLocation) string {
+	return fmt.Sprintf(
+		"%s:%d:%d-%d:%d",
+		expectedLocation.FileName,
+		expectedLocation.StartLine+1,
+		expectedLocation.StartColumn+1,
+		expectedLocation.EndLine+1,
+		expectedLocation.EndColumn+1,
+	)
+}
 
 func validateProtoFileSpec(protoFileSpec *ProtoFileSpec) error {
+	if len(protoFileSpec.WellKnownImports) > 0 && protoFileSpec.WithoutWellKnownImports {
+		return errors.New("cannot specify both WellKnownImports and WithoutWellKnownImports on ProtoFileSpec")
+	}
+	if len(protoFileSpec.Modules) > 0 {
+		if len(protoFileSpec.DirPaths) > 0 || len(protoFileSpec.FilePaths) > 0 {
+			return errors.New("cannot specify DirPaths or FilePaths with Modules on ProtoFileSpec")
+		}
+		return nil
+	}
 	if len(protoFileSpec.DirPaths) == 0 {
 		return errors.New("no DirPaths specified on ProtoFileSpec")
 	}
@@ -238,6 +614,39 @@ func validateProtoFileSpec(protoFileSpec *ProtoFileSpec) error {
 	return nil
 }
 
+// sortFilesTopologically sorts the Files so that each File comes after its imports.
+//
+// The order of Files returned from ToFiles is not deterministic, so Files are visited in
+// order of their paths to make the result deterministic.
+func sortFilesTopologically(files []check.File) []check.File {
+	pathToFile := make(map[string]check.File, len(files))
+	for _, file := range files {
+		pathToFile[file.FileDescriptor().Path()] = file
+	}
+	sortedFiles := make([]check.File, 0, len(files))
+	visitedPaths := make(map[string]struct{}, len(files))
+	var visit func(path string)
+	visit = func(path string) {
+		if _, ok := visitedPaths[path]; ok {
+			return
+		}
+		visitedPaths[path] = struct{}{}
+		file, ok := pathToFile[path]
+		if !ok {
+			return
+		}
+		imports := file.FileDescriptor().Imports()
+		for i := range imports.Len() {
+			visit(imports.Get(i).Path())
+		}
+		sortedFiles = append(sortedFiles, file)
+	}
+	for _, path := range slices.Sorted(maps.Keys(pathToFile)) {
+		visit(path)
+	}
+	return sortedFiles
+}
+
 // expectedAnnotationsForAnnotations returns ExpectedAnnotations for the given Annotations.
 //
 // Callers will need to filter out the Messages from the returned ExpectedAnnotations to conform
@@ -251,157 +660,72 @@ func expectedAnnotationsForAnnotations(annotations []check.Annotation) []Expecte
 // Callers will need to filter out the Messages from the returned ExpectedAnnotations to conform
 // to the ExpectedAnnotations that are being compared against. See the note on ExpectedAnnotation.Message.
 func expectedAnnotationForAnnotation(annotation check.Annotation) ExpectedAnnotation {
-	expectedAnnotation := ExpectedAnnotation{
-		RuleID:  annotation.RuleID(),
-		Message: annotation.Message(),
-	}
-	if location := annotation.Location(); location != nil {
-		expectedAnnotation.Location = &ExpectedLocation{
-			FileName:    location.File().FileDescriptor().Path(),
-			StartLine:   location.StartLine(),
-			StartColumn: location.StartColumn(),
-			EndLine:     location.EndLine(),
-			EndColumn:   location.EndColumn(),
-		}
-	}
-	if againstLocation := annotation.AgainstLocation(); againstLocation != nil {
-		expectedAnnotation.AgainstLocation = &ExpectedLocation{
-			FileName:    againstLocation.File().FileDescriptor().Path(),
-			StartLine:   againstLocation.StartLine(),
-			StartColumn: againstLocation.StartColumn(),
-			EndLine:     againstLocation.EndLine(),
-			EndColumn:   againstLocation.EndColumn(),
-		}
+	return ExpectedAnnotation{
+		RuleID:          annotation.RuleID(),
+		Message:         annotation.Message(),
+		Location:        expectedLocationForLocation(annotation.Location()),
+		AgainstLocation: expectedLocationForLocation(annotation.AgainstLocation()),
 	}
-	return expectedAnnotation
 }
 
-func compile(ctx context.Context, dirPaths []string, filePaths []string) ([]check.File, error) {
-	dirPaths = fromSlashPaths(dirPaths)
-	filePaths = fromSlashPaths(filePaths)
-	toSlashFilePathMap := make(map[string]struct{}, len(filePaths))
-	for _, filePath := range filePaths {
-		toSlashFilePathMap[filepath.ToSlash(filePath)] = struct{}{}
-	}
-
-	var warningErrorsWithPos []reporter.ErrorWithPos
-	compiler := protocompile.Compiler{
-		Resolver: wellknownimports.WithStandardImports(
-			&protocompile.SourceResolver{
-				ImportPaths: dirPaths,
-			},
-		),
-		Reporter: reporter.NewReporter(
-			func(reporter.ErrorWithPos) error {
-				return nil
-			},
-			func(errorWithPos reporter.ErrorWithPos) {
-				warningErrorsWithPos = append(warningErrorsWithPos, errorWithPos)
-			},
-		),
-		// This is what buf uses.
-		SourceInfoMode: protocompile.SourceInfoExtraOptionLocations,
-	}
-	files, err := compiler.Compile(ctx, filePaths...)
-	if err != nil {
-		return nil, err
-	}
-	syntaxUnspecifiedFilePaths := make(map[string]struct{})
-	filePathToUnusedDependencyFilePaths := make(map[string]map[string]struct{})
-	for _, warningErrorWithPos := range warningErrorsWithPos {
-		maybeAddSyntaxUnspecified(syntaxUnspecifiedFilePaths, warningErrorWithPos)
-		maybeAddUnusedDependency(filePathToUnusedDependencyFilePaths, warningErrorWithPos)
-	}
-	fileDescriptorSet := fileDescriptorSetForFileDescriptors(files)
-
-	protoFiles := make([]*checkv1beta1.File, len(fileDescriptorSet.GetFile()))
-	for i, fileDescriptorProto := range fileDescriptorSet.GetFile() {
-		_, isNotImport := toSlashFilePathMap[fileDescriptorProto.GetName()]
-		_, isSyntaxUnspecified := syntaxUnspecifiedFilePaths[fileDescriptorProto.GetName()]
-		unusedDependencyIndexes := unusedDependencyIndexesForFilePathToUnusedDependencyFilePaths(
-			fileDescriptorProto,
-			filePathToUnusedDependencyFilePaths[fileDescriptorProto.GetName()],
-		)
-		protoFiles[i] = &checkv1beta1.File{
-			FileDescriptorProto: fileDescriptorProto,
-			IsImport:            !isNotImport,
-			IsSyntaxUnspecified: isSyntaxUnspecified,
-			UnusedDependency:    unusedDependencyIndexes,
+// expectedLocationForLocation returns an ExpectedLocation for the given Location.
+//
+// Returns nil if location is nil.
+func expectedLocationForLocation(location check.Location) *ExpectedLocation {
+	if location == nil {
+		return nil
+	}
+	if !location.HasPosition() {
+		// Only the FileName is set, rather than check.UnknownPosition for each of the
+		// lines and columns.
+		return &ExpectedLocation{
+			FileName: location.File().FileDescriptor().Path(),
 		}
 	}
-	return check.FilesForProtoFiles(protoFiles)
+	return &ExpectedLocation{
+		FileName:                location.File().FileDescriptor().Path(),
+		StartLine:               location.StartLine(),
+		StartColumn:             location.StartColumn(),
+		EndLine:                 location.EndLine(),
+		EndColumn:               location.EndColumn(),
+		LeadingComments:         location.LeadingComments(),
+		TrailingComments:        location.TrailingComments(),
+		LeadingDetachedComments: location.LeadingDetachedComments(),
+	}
 }
 
-func unusedDependencyIndexesForFilePathToUnusedDependencyFilePaths(
-	fileDescriptorProto *descriptorpb.FileDescriptorProto,
-	unusedDependencyFilePaths map[string]struct{},
-) []int32 {
-	unusedDependencyIndexes := make([]int32, 0, len(unusedDependencyFilePaths))
-	if len(unusedDependencyFilePaths) == 0 {
-		return unusedDependencyIndexes
-	}
-	dependencyFilePaths := fileDescriptorProto.GetDependency()
-	for i := range len(dependencyFilePaths) {
-		if _, ok := unusedDependencyFilePaths[dependencyFilePaths[i]]; ok {
-			unusedDependencyIndexes = append(unusedDependencyIndexes, int32(i))
+// clearUnexpectedFields clears the fields of the actual ExpectedAnnotations that are not set on
+// the corresponding expected ExpectedAnnotations, and therefore should not be compared.
+func clearUnexpectedFields(expectedAnnotations []ExpectedAnnotation, actualExpectedAnnotations []ExpectedAnnotation) {
+	for i, expectedAnnotation := range expectedAnnotations {
+		if i >= len(actualExpectedAnnotations) {
+			return
 		}
+		if expectedAnnotation.Message == "" {
+			actualExpectedAnnotations[i].Message = ""
+		}
+		clearUnexpectedLocationFields(expectedAnnotation.Location, actualExpectedAnnotations[i].Location)
+		clearUnexpectedLocationFields(expectedAnnotation.AgainstLocation, actualExpectedAnnotations[i].AgainstLocation)
 	}
-	return unusedDependencyIndexes
-}
-
-func maybeAddSyntaxUnspecified(
-	syntaxUnspecifiedFilePaths map[string]struct{},
-	errorWithPos reporter.ErrorWithPos,
-) {
-	if !errors.Is(errorWithPos, parser.ErrNoSyntax) {
-		return
+	// Actual Annotations without a corresponding expected Annotation will fail the comparison
+	// regardless, but clear their comments so that the failure output focuses on the mismatch.
+	for i := len(expectedAnnotations); i < len(actualExpectedAnnotations); i++ {
+		clearUnexpectedLocationFields(nil, actualExpectedAnnotations[i].Location)
+		clearUnexpectedLocationFields(nil, actualExpectedAnnotations[i].AgainstLocation)
 	}
-	syntaxUnspecifiedFilePaths[errorWithPos.GetPosition().Filename] = struct{}{}
 }
 
-func maybeAddUnusedDependency(
-	filePathToUnusedDependencyFilePaths map[string]map[string]struct{},
-	errorWithPos reporter.ErrorWithPos,
-) {
-	var errorUnusedImport linker.ErrorUnusedImport
-	if !errors.As(errorWithPos, &errorUnusedImport) {
+func clearUnexpectedLocationFields(expectedLocation *ExpectedLocation, actualExpectedLocation *ExpectedLocation) {
+	if actualExpectedLocation == nil {
 		return
 	}
-	pos := errorWithPos.GetPosition()
-	unusedDependencyFilePaths, ok := filePathToUnusedDependencyFilePaths[pos.Filename]
-	if !ok {
-		unusedDependencyFilePaths = make(map[string]struct{})
-		filePathToUnusedDependencyFilePaths[pos.Filename] = unusedDependencyFilePaths
-	}
-	unusedDependencyFilePaths[errorUnusedImport.UnusedImport()] = struct{}{}
-}
-
-func fileDescriptorSetForFileDescriptors[D protoreflect.FileDescriptor](files []D) *descriptorpb.FileDescriptorSet {
-	soFar := make(map[string]struct{}, len(files))
-	slice := make([]*descriptorpb.FileDescriptorProto, 0, len(files))
-	for _, file := range files {
-		toFileDescriptorProtoSlice(file, &slice, soFar)
+	if expectedLocation == nil || expectedLocation.LeadingComments == "" {
+		actualExpectedLocation.LeadingComments = ""
 	}
-	return &descriptorpb.FileDescriptorSet{File: slice}
-}
-
-func toFileDescriptorProtoSlice(file protoreflect.FileDescriptor, results *[]*descriptorpb.FileDescriptorProto, soFar map[string]struct{}) {
-	if _, exists := soFar[file.Path()]; exists {
-		return
-	}
-	soFar[file.Path()] = struct{}{}
-	// Add dependencies first so the resulting slice is in topological order
-	imports := file.Imports()
-	for i, length := 0, imports.Len(); i < length; i++ {
-		toFileDescriptorProtoSlice(imports.Get(i).FileDescriptor, results, soFar)
+	if expectedLocation == nil || expectedLocation.TrailingComments == "" {
+		actualExpectedLocation.TrailingComments = ""
 	}
-	*results = append(*results, protoutil.ProtoFromFileDescriptor(file))
-}
-
-func fromSlashPaths(paths []string) []string {
-	fromSlashPaths := make([]string, len(paths))
-	for i, path := range paths {
-		fromSlashPaths[i] = filepath.Clean(filepath.FromSlash(path))
+	if expectedLocation == nil || len(expectedLocation.LeadingDetachedComments) == 0 {
+		actualExpectedLocation.LeadingDetachedComments = nil
 	}
-	return fromSlashPaths
 }
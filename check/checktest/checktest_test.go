@@ -0,0 +1,577 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checkcompile"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestExpectedLocationComments(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	request, err := (&RequestSpec{
+		Files: &ProtoFileSpec{
+			DirPaths:  []string{"testdata/comments"},
+			FilePaths: []string{"comments.proto"},
+		},
+	}).ToRequest(ctx)
+	require.NoError(t, err)
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				{
+					ID:        "MESSAGE",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      check.RuleTypeLint,
+					Handler: check.RuleHandlerFunc(
+						func(_ context.Context, responseWriter check.ResponseWriter, request check.Request) error {
+							messageDescriptor := request.Files()[0].FileDescriptor().Messages().Get(0)
+							responseWriter.AddAnnotation(check.WithDescriptor(messageDescriptor))
+							return nil
+						},
+					),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+
+	location := &ExpectedLocation{
+		FileName:    "comments.proto",
+		StartLine:   7,
+		StartColumn: 0,
+		EndLine:     8,
+		EndColumn:   1,
+	}
+	// Comments are not compared if not set.
+	RequireAnnotationsEqual(t, []ExpectedAnnotation{{RuleID: "MESSAGE", Location: location}}, response.Annotations())
+
+	location.LeadingComments = " Leading.\n"
+	location.TrailingComments = " Trailing.\n"
+	location.LeadingDetachedComments = []string{" Detached.\n"}
+	RequireAnnotationsEqual(t, []ExpectedAnnotation{{RuleID: "MESSAGE", Location: location}}, response.Annotations())
+
+	actualExpectedAnnotations := expectedAnnotationsForAnnotations(response.Annotations())
+	location.TrailingComments = " Other.\n"
+	clearUnexpectedFields([]ExpectedAnnotation{{RuleID: "MESSAGE", Location: location}}, actualExpectedAnnotations)
+	require.NotEqual(t, location, actualExpectedAnnotations[0].Location)
+}
+
+func TestAnnotationsEqualUnordered(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	request, err := (&RequestSpec{
+		Files: &ProtoFileSpec{
+			DirPaths:  []string{"testdata/comments"},
+			FilePaths: []string{"comments.proto"},
+		},
+	}).ToRequest(ctx)
+	require.NoError(t, err)
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				testNewMessageRuleSpec("A", "first"),
+				testNewMessageRuleSpec("B", "second"),
+			},
+		},
+	)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+
+	location := &ExpectedLocation{
+		FileName:    "comments.proto",
+		StartLine:   7,
+		StartColumn: 0,
+		EndLine:     8,
+		EndColumn:   1,
+	}
+	reversedAnnotations := []ExpectedAnnotation{
+		{RuleID: "B", Location: location},
+		{RuleID: "A", Location: location, Message: "first"},
+	}
+	RequireAnnotationsEqual(t, reversedAnnotations, response.Annotations(), AnnotationsEqualWithUnordered())
+	require.False(t, AssertAnnotationsEqual(&testing.T{}, reversedAnnotations, response.Annotations()))
+	require.False(
+		t,
+		AssertAnnotationsEqual(
+			&testing.T{},
+			[]ExpectedAnnotation{{RuleID: "B", Location: location}},
+			response.Annotations(),
+			AnnotationsEqualWithUnordered(),
+		),
+	)
+
+	// An expected Annotation without a Message should not take the only actual Annotation
+	// that a more specific expected Annotation matches.
+	actualExpectedAnnotations := expectedAnnotationsForAnnotations(response.Annotations())
+	actualExpectedAnnotations[1].RuleID = "A"
+	actualExpectedAnnotations[1].Message = "other"
+	require.True(
+		t,
+		assertAnnotationsEqualUnordered(
+			t,
+			[]ExpectedAnnotation{
+				{RuleID: "A", Location: location},
+				{RuleID: "A", Location: location, Message: "first"},
+			},
+			actualExpectedAnnotations,
+		),
+	)
+}
+
+func testNewMessageRuleSpec(id string, message string) *check.RuleSpec {
+	return &check.RuleSpec{
+		ID:        id,
+		IsDefault: true,
+		Purpose:   "Test rule.",
+		Type:      check.RuleTypeLint,
+		Handler: check.RuleHandlerFunc(
+			func(_ context.Context, responseWriter check.ResponseWriter, request check.Request) error {
+				messageDescriptor := request.Files()[0].FileDescriptor().Messages().Get(0)
+				responseWriter.AddAnnotation(check.WithDescriptor(messageDescriptor), check.WithMessage(message))
+				return nil
+			},
+		),
+	}
+}
+
+func TestAnnotationsEqualWithoutFileNames(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	request, err := (&RequestSpec{
+		Files: &ProtoFileSpec{
+			DirPaths:  []string{"testdata/comments"},
+			FilePaths: []string{"comments.proto"},
+		},
+	}).ToRequest(ctx)
+	require.NoError(t, err)
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				testNewMessageRuleSpec("A", "first"),
+			},
+		},
+	)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+
+	location := &ExpectedLocation{
+		FileName:    "other.proto",
+		StartLine:   7,
+		StartColumn: 0,
+		EndLine:     8,
+		EndColumn:   1,
+	}
+	expectedAnnotations := []ExpectedAnnotation{{RuleID: "A", Location: location}}
+	require.False(t, AssertAnnotationsEqual(&testing.T{}, expectedAnnotations, response.Annotations()))
+	RequireAnnotationsEqual(t, expectedAnnotations, response.Annotations(), AnnotationsEqualWithoutFileNames())
+	RequireAnnotationsEqual(
+		t,
+		expectedAnnotations,
+		response.Annotations(),
+		AnnotationsEqualWithoutFileNames(),
+		AnnotationsEqualWithUnordered(),
+	)
+	// The expected Annotations are not modified.
+	require.Equal(t, "other.proto", location.FileName)
+	location.FileName = ""
+	require.False(t, AssertAnnotationsEqual(&testing.T{}, expectedAnnotations, response.Annotations()))
+}
+
+func TestProtoFileSpecModules(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	files, err := (&ProtoFileSpec{
+		Modules: []checkcompile.Module{
+			{
+				Name:      "a",
+				DirPath:   "testdata/modules/a",
+				FilePaths: []string{"a.proto"},
+				DepNames:  []string{"b"},
+			},
+			{
+				Name:     "b",
+				DirPath:  "testdata/modules/b",
+				DepNames: []string{"c"},
+			},
+			{
+				Name:      "c",
+				DirPath:   "testdata/modules/c",
+				FilePaths: []string{"c.proto"},
+			},
+		},
+	}).ToFiles(ctx)
+	require.NoError(t, err)
+	filePathToIsImport := make(map[string]bool)
+	for _, file := range files {
+		filePathToIsImport[file.FileDescriptor().Path()] = file.IsImport()
+	}
+	require.Equal(
+		t,
+		map[string]bool{
+			"a.proto": false,
+			// b.proto is only imported from another Module.
+			"b.proto": true,
+			"c.proto": false,
+		},
+		filePathToIsImport,
+	)
+
+	// Files cannot import from Modules that are not dependencies.
+	_, err = (&ProtoFileSpec{
+		Modules: []checkcompile.Module{
+			{
+				Name:      "a",
+				DirPath:   "testdata/modules/a",
+				FilePaths: []string{"a.proto"},
+			},
+			{
+				Name:    "b",
+				DirPath: "testdata/modules/b",
+			},
+		},
+	}).ToFiles(ctx)
+	require.Error(t, err)
+	_, err = (&ProtoFileSpec{
+		Modules: []checkcompile.Module{
+			{
+				Name:      "a",
+				DirPath:   "testdata/modules/a",
+				FilePaths: []string{"a.proto"},
+				DepNames:  []string{"b"},
+			},
+		},
+	}).ToFiles(ctx)
+	require.EqualError(t, err, `module "a" has unknown dependency "b"`)
+	_, err = (&ProtoFileSpec{
+		DirPaths: []string{"testdata/modules/a"},
+		Modules: []checkcompile.Module{
+			{
+				Name:      "c",
+				DirPath:   "testdata/modules/c",
+				FilePaths: []string{"c.proto"},
+			},
+		},
+	}).ToFiles(ctx)
+	require.Error(t, err)
+}
+
+func TestProtoFileSpecDependencies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	vendorFileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("vendor/vendor.proto"),
+		Package: proto.String("vendor"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Vendor"),
+			},
+		},
+	}
+	files, err := (&ProtoFileSpec{
+		DirPaths:     []string{"testdata/dependencies"},
+		FilePaths:    []string{"dependencies.proto"},
+		Dependencies: []*descriptorpb.FileDescriptorProto{vendorFileDescriptorProto},
+	}).ToFiles(ctx)
+	require.NoError(t, err)
+	filePathToIsImport := make(map[string]bool)
+	for _, file := range files {
+		filePathToIsImport[file.FileDescriptor().Path()] = file.IsImport()
+	}
+	require.Equal(
+		t,
+		map[string]bool{
+			"dependencies.proto":  false,
+			"vendor/vendor.proto": true,
+		},
+		filePathToIsImport,
+	)
+
+	_, err = (&ProtoFileSpec{
+		DirPaths:  []string{"testdata/dependencies"},
+		FilePaths: []string{"dependencies.proto"},
+	}).ToFiles(ctx)
+	require.Error(t, err)
+	_, err = (&ProtoFileSpec{
+		DirPaths:     []string{"testdata/dependencies"},
+		FilePaths:    []string{"dependencies.proto"},
+		Dependencies: []*descriptorpb.FileDescriptorProto{vendorFileDescriptorProto, vendorFileDescriptorProto},
+	}).ToFiles(ctx)
+	require.EqualError(t, err, `duplicate dependency FileDescriptorProto "vendor/vendor.proto"`)
+}
+
+func TestProtoFileSpecCompileError(t *testing.T) {
+	t.Parallel()
+
+	_, err := (&ProtoFileSpec{
+		DirPaths:  []string{"testdata/compile_error"},
+		FilePaths: []string{"compile_error.proto"},
+	}).ToFiles(context.Background())
+	compileErr := &checkcompile.CompileError{}
+	require.ErrorAs(t, err, &compileErr)
+	require.Len(t, compileErr.Diagnostics, 2)
+	require.Equal(t, "compile_error.proto", compileErr.Diagnostics[0].FileName)
+	require.Equal(t, 6, compileErr.Diagnostics[0].Line)
+	require.Equal(t, 3, compileErr.Diagnostics[0].Column)
+	require.Equal(t, "  strin name = 1;", compileErr.Diagnostics[0].SourceLine)
+	require.EqualError(
+		t,
+		err,
+		`2 errors compiling .proto files:
+
+compile_error.proto:6:3: field compile_error.Foo.name: unknown type strin
+ 6 |   strin name = 1;
+   |   ^
+
+compile_error.proto:7:9: field compile_error.Foo.bar: unknown type Bar
+ 7 | `+"\t"+`Bar bar = 2;
+   | `+"\t"+`^`,
+	)
+}
+
+func TestProtoFileSpecWellKnownImports(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	timestampFieldNames := func(files []check.File) []string {
+		var fieldNames []string
+		for _, file := range files {
+			if file.FileDescriptor().Path() != "google/protobuf/timestamp.proto" {
+				continue
+			}
+			require.True(t, file.IsImport())
+			fields := file.FileDescriptor().Messages().ByName("Timestamp").Fields()
+			for i := range fields.Len() {
+				fieldNames = append(fieldNames, string(fields.Get(i).Name()))
+			}
+		}
+		return fieldNames
+	}
+
+	files, err := (&ProtoFileSpec{
+		DirPaths:  []string{"testdata/well_known_imports"},
+		FilePaths: []string{"well_known_imports.proto"},
+	}).ToFiles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"seconds", "nanos"}, timestampFieldNames(files))
+
+	// An older definition of Timestamp, without nanos.
+	files, err = (&ProtoFileSpec{
+		DirPaths:  []string{"testdata/well_known_imports"},
+		FilePaths: []string{"well_known_imports.proto"},
+		WellKnownImports: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    proto.String("google/protobuf/timestamp.proto"),
+				Package: proto.String("google.protobuf"),
+				Syntax:  proto.String("proto3"),
+				MessageType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("Timestamp"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{
+								Name:     proto.String("seconds"),
+								Number:   proto.Int32(1),
+								Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(),
+								JsonName: proto.String("seconds"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}).ToFiles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"seconds"}, timestampFieldNames(files))
+
+	_, err = (&ProtoFileSpec{
+		DirPaths:                []string{"testdata/well_known_imports"},
+		FilePaths:               []string{"well_known_imports.proto"},
+		WithoutWellKnownImports: true,
+	}).ToFiles(ctx)
+	require.Error(t, err)
+
+	_, err = (&ProtoFileSpec{
+		DirPaths:                []string{"testdata/well_known_imports"},
+		FilePaths:               []string{"well_known_imports.proto"},
+		WellKnownImports:        []*descriptorpb.FileDescriptorProto{{Name: proto.String("google/protobuf/timestamp.proto")}},
+		WithoutWellKnownImports: true,
+	}).ToFiles(ctx)
+	require.EqualError(t, err, "cannot specify both WellKnownImports and WithoutWellKnownImports on ProtoFileSpec")
+}
+
+func TestProtoFileSpecToFileDescriptorSet(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	protoFileSpec := &ProtoFileSpec{
+		DirPaths:  []string{"testdata/well_known_imports"},
+		FilePaths: []string{"well_known_imports.proto"},
+	}
+	fileDescriptorSet, err := protoFileSpec.ToFileDescriptorSet(ctx)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			"google/protobuf/timestamp.proto",
+			"well_known_imports.proto",
+		},
+		xslices.Map(fileDescriptorSet.GetFile(), (*descriptorpb.FileDescriptorProto).GetName),
+	)
+	require.NotNil(t, fileDescriptorSet.GetFile()[1].GetSourceCodeInfo())
+	// The FileDescriptorSet can be fed into other tools.
+	_, err = protodesc.NewFiles(fileDescriptorSet)
+	require.NoError(t, err)
+
+	fileDescriptorSet, err = (*ProtoFileSpec)(nil).ToFileDescriptorSet(ctx)
+	require.NoError(t, err)
+	require.Empty(t, fileDescriptorSet.GetFile())
+}
+
+func TestProtoFileSpecWithoutSourceCodeInfo(t *testing.T) {
+	t.Parallel()
+
+	CheckTest{
+		Request: &RequestSpec{
+			Files: &ProtoFileSpec{
+				DirPaths:              []string{"testdata/comments"},
+				FilePaths:             []string{"comments.proto"},
+				WithoutSourceCodeInfo: true,
+			},
+		},
+		Spec: &check.Spec{
+			Rules: []*check.RuleSpec{
+				testNewMessageRuleSpec("MESSAGE", "message"),
+			},
+		},
+		ExpectedAnnotations: []ExpectedAnnotation{
+			{
+				RuleID: "MESSAGE",
+				Location: &ExpectedLocation{
+					FileName: "comments.proto",
+				},
+			},
+		},
+		DetectRequestMutation: true,
+		DetectGoroutineLeaks:  true,
+		DetectOrderDependence: true,
+	}.Run(t)
+
+	ctx := context.Background()
+	vendorFileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:           proto.String("vendor/vendor.proto"),
+		Package:        proto.String("vendor"),
+		Syntax:         proto.String("proto3"),
+		MessageType:    []*descriptorpb.DescriptorProto{{Name: proto.String("Vendor")}},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+	}
+	files, err := (&ProtoFileSpec{
+		DirPaths:              []string{"testdata/dependencies"},
+		FilePaths:             []string{"dependencies.proto"},
+		Dependencies:          []*descriptorpb.FileDescriptorProto{vendorFileDescriptorProto},
+		WithoutSourceCodeInfo: true,
+	}).ToFiles(ctx)
+	require.NoError(t, err)
+	for _, file := range files {
+		require.Nil(t, file.FileDescriptorProto().GetSourceCodeInfo(), file.FileDescriptor().Path())
+	}
+	// The given dependency is not modified.
+	require.NotNil(t, vendorFileDescriptorProto.GetSourceCodeInfo())
+}
+
+func TestDescriptorLocationFallback(t *testing.T) {
+	t.Parallel()
+
+	newSpec := func(addAnnotationOptions ...check.AddAnnotationOption) *check.Spec {
+		return &check.Spec{
+			Rules: []*check.RuleSpec{
+				{
+					ID:        "MAP_ENTRY",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      check.RuleTypeLint,
+					Handler: check.RuleHandlerFunc(
+						func(_ context.Context, responseWriter check.ResponseWriter, request check.Request) error {
+							// The map entry message is synthesized, and has no span.
+							mapEntryDescriptor := request.Files()[0].FileDescriptor().Messages().Get(0).Messages().Get(0)
+							responseWriter.AddAnnotation(
+								append(
+									[]check.AddAnnotationOption{check.WithDescriptor(mapEntryDescriptor)},
+									addAnnotationOptions...,
+								)...,
+							)
+							return nil
+						},
+					),
+				},
+			},
+		}
+	}
+	request := &RequestSpec{
+		Files: &ProtoFileSpec{
+			DirPaths:  []string{"testdata/maps"},
+			FilePaths: []string{"maps.proto"},
+		},
+	}
+	CheckTest{
+		Request: request,
+		Spec:    newSpec(),
+		ExpectedAnnotations: []ExpectedAnnotation{
+			{
+				RuleID: "MAP_ENTRY",
+				Location: &ExpectedLocation{
+					FileName: "maps.proto",
+				},
+			},
+		},
+	}.Run(t)
+	CheckTest{
+		Request: request,
+		Spec:    newSpec(check.WithDescriptorLocationFallback()),
+		ExpectedAnnotations: []ExpectedAnnotation{
+			{
+				RuleID: "MAP_ENTRY",
+				// The Location of the message Foo.
+				Location: &ExpectedLocation{
+					FileName:    "maps.proto",
+					StartLine:   4,
+					StartColumn: 0,
+					EndLine:     6,
+					EndColumn:   1,
+				},
+			},
+		},
+	}.Run(t)
+}
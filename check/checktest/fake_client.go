@@ -0,0 +1,232 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/pluginrpc-go"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FakeClient is a check.Client that returns scripted Responses instead of running Rules.
+//
+// This is for testing hosts that drive plugins, such as orchestrators that run many plugins
+// and aggregate their results. The FakeClient serves ListRules, ListCategories, and Check over
+// the plugin protocol, so the behavior of the underlying check.Client, such as pagination,
+// caching, and error handling, is the same as for a real plugin.
+type FakeClient struct {
+	check.Client
+
+	scriptedResponses []FakeResponse
+
+	lock     sync.Mutex
+	requests []check.Request
+}
+
+// NewFakeClient returns a new FakeClient for the given RuleSpecs that returns the given
+// scripted Responses.
+//
+// Each call to Check returns the next FakeResponse, in order. If there are more calls to Check
+// than FakeResponses, Check returns an error with pluginrpc.CodeFailedPrecondition.
+//
+// The Handlers of the RuleSpecs are ignored, and do not need to be set. The RuleSpecs are
+// not modified.
+func NewFakeClient(
+	ruleSpecs []*check.RuleSpec,
+	scriptedResponses []FakeResponse,
+	options ...FakeClientOption,
+) (*FakeClient, error) {
+	fakeClientOptions := newFakeClientOptions()
+	for _, option := range options {
+		option(fakeClientOptions)
+	}
+	fakeClient := &FakeClient{
+		scriptedResponses: scriptedResponses,
+	}
+	fakeRuleSpecs := make([]*check.RuleSpec, len(ruleSpecs))
+	for i, ruleSpec := range ruleSpecs {
+		fakeRuleSpec := *ruleSpec
+		fakeRuleSpec.Handler = newFakeRuleHandler(ruleSpec.ID)
+		fakeRuleSpecs[i] = &fakeRuleSpec
+	}
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules:      fakeRuleSpecs,
+			Categories: fakeClientOptions.categorySpecs,
+			Before:     fakeClient.before,
+		},
+		append(
+			[]check.ClientOption{
+				check.ClientWithSpecServerOptions(check.ServerWithMaxPageSize(fakeClientOptions.pageSize)),
+			},
+			fakeClientOptions.clientOptions...,
+		)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	fakeClient.Client = client
+	return fakeClient, nil
+}
+
+// Requests returns the Requests received by Check, in order.
+//
+// The Requests are as received by the plugin, after any processing by the check.Client,
+// such as the resolution of Rule ID selectors.
+func (f *FakeClient) Requests() []check.Request {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]check.Request(nil), f.requests...)
+}
+
+// FakeResponse is a scripted response to a Check call on a FakeClient.
+type FakeResponse struct {
+	// Annotations are the Annotations to return.
+	//
+	// Annotations for Rules that are not run by the Check call are dropped. Locations must
+	// refer to Files within the Request.
+	Annotations []FakeAnnotation
+	// Err is the error to return, if any.
+	//
+	// If Err is a *pluginrpc.Error, its Code is preserved across the plugin protocol.
+	Err error
+	// Latency is the duration to wait before returning.
+	//
+	// If the context is done before Latency elapses, Check returns the context's error.
+	Latency time.Duration
+}
+
+// FakeAnnotation is an Annotation within a FakeResponse.
+type FakeAnnotation struct {
+	// RuleID is the ID of the Rule.
+	//
+	// Required.
+	RuleID string
+	// Message is the message of the Annotation.
+	Message string
+	// FileName is the name of the File of the Location, if any.
+	FileName string
+	// SourcePath is the source path within the File of the Location, if any.
+	SourcePath protoreflect.SourcePath
+	// AgainstFileName is the name of the File of the AgainstLocation, if any.
+	AgainstFileName string
+	// AgainstSourcePath is the source path within the File of the AgainstLocation, if any.
+	AgainstSourcePath protoreflect.SourcePath
+}
+
+// FakeClientOption is an option for NewFakeClient.
+type FakeClientOption func(*fakeClientOptions)
+
+// FakeClientWithCategories returns a new FakeClientOption that adds the given CategorySpecs.
+//
+// Categories are required if any RuleSpec specifies a Category ID.
+func FakeClientWithCategories(categorySpecs ...*check.CategorySpec) FakeClientOption {
+	return func(fakeClientOptions *fakeClientOptions) {
+		fakeClientOptions.categorySpecs = append(fakeClientOptions.categorySpecs, categorySpecs...)
+	}
+}
+
+// FakeClientWithPageSize returns a new FakeClientOption that limits the number of Rules and
+// Categories returned per page by ListRules and ListCategories.
+//
+// This allows testing that hosts correctly handle multiple pages. The default is to return
+// the page size requested by the client.
+func FakeClientWithPageSize(pageSize int) FakeClientOption {
+	return func(fakeClientOptions *fakeClientOptions) {
+		fakeClientOptions.pageSize = pageSize
+	}
+}
+
+// FakeClientWithClientOptions returns a new FakeClientOption that passes the given
+// ClientOptions to the underlying check.Client.
+func FakeClientWithClientOptions(clientOptions ...check.ClientOption) FakeClientOption {
+	return func(fakeClientOptions *fakeClientOptions) {
+		fakeClientOptions.clientOptions = append(fakeClientOptions.clientOptions, clientOptions...)
+	}
+}
+
+// *** PRIVATE ***
+
+type fakeResponseContextKey struct{}
+
+type fakeClientOptions struct {
+	categorySpecs []*check.CategorySpec
+	pageSize      int
+	clientOptions []check.ClientOption
+}
+
+func newFakeClientOptions() *fakeClientOptions {
+	return &fakeClientOptions{}
+}
+
+// before selects the next FakeResponse, and passes it to the fake RuleHandlers via the context.
+func (f *FakeClient) before(ctx context.Context, request check.Request) (context.Context, check.Request, error) {
+	f.lock.Lock()
+	index := len(f.requests)
+	f.requests = append(f.requests, request)
+	f.lock.Unlock()
+	if index >= len(f.scriptedResponses) {
+		return nil, nil, pluginrpc.NewErrorf(
+			pluginrpc.CodeFailedPrecondition,
+			"no scripted response for Check call %d, only %d responses were scripted",
+			index+1,
+			len(f.scriptedResponses),
+		)
+	}
+	scriptedResponse := f.scriptedResponses[index]
+	if scriptedResponse.Latency > 0 {
+		timer := time.NewTimer(scriptedResponse.Latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if scriptedResponse.Err != nil {
+		return nil, nil, scriptedResponse.Err
+	}
+	return context.WithValue(ctx, fakeResponseContextKey{}, &scriptedResponse), request, nil
+}
+
+func newFakeRuleHandler(ruleID string) check.RuleHandler {
+	return check.RuleHandlerFunc(
+		func(ctx context.Context, responseWriter check.ResponseWriter, _ check.Request) error {
+			scriptedResponse, ok := ctx.Value(fakeResponseContextKey{}).(*FakeResponse)
+			if !ok {
+				// This should never happen.
+				return fmt.Errorf("no scripted response for Rule %q", ruleID)
+			}
+			for _, fakeAnnotation := range scriptedResponse.Annotations {
+				if fakeAnnotation.RuleID != ruleID {
+					continue
+				}
+				responseWriter.AddAnnotation(
+					check.WithMessage(fakeAnnotation.Message),
+					check.WithFileName(fakeAnnotation.FileName),
+					check.WithSourcePath(fakeAnnotation.SourcePath),
+					check.WithAgainstFileName(fakeAnnotation.AgainstFileName),
+					check.WithAgainstSourcePath(fakeAnnotation.AgainstSourcePath),
+				)
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/bufbuild/pluginrpc-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeClient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fakeClient, err := NewFakeClient(
+		[]*check.RuleSpec{
+			testFakeRuleSpec("RULE1", "CATEGORY1"),
+			testFakeRuleSpec("RULE2"),
+			testFakeRuleSpec("RULE3"),
+		},
+		[]FakeResponse{
+			{
+				Annotations: []FakeAnnotation{
+					{
+						RuleID:  "RULE1",
+						Message: "Rule1 failed.",
+					},
+					{
+						RuleID:  "RULE3",
+						Message: "Rule3 failed.",
+					},
+				},
+			},
+			{
+				Err: pluginrpc.NewError(pluginrpc.CodeInvalidArgument, errors.New("bad option")),
+			},
+			{
+				Latency: time.Minute,
+			},
+		},
+		FakeClientWithCategories(
+			&check.CategorySpec{
+				ID:      "CATEGORY1",
+				Purpose: "Test category1.",
+			},
+		),
+		FakeClientWithPageSize(1),
+	)
+	require.NoError(t, err)
+	require.Implements(t, (*check.Client)(nil), fakeClient)
+
+	// Pagination is handled by the check.Client.
+	rules, err := fakeClient.ListRules(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE1", "RULE2", "RULE3"}, xslices.Map(rules, check.Rule.ID))
+
+	request, err := check.NewRequest(nil, check.WithRuleIDs("RULE1", "RULE2"))
+	require.NoError(t, err)
+	response, err := fakeClient.Check(ctx, request)
+	require.NoError(t, err)
+	// RULE3 was not run, so its Annotation is dropped.
+	AssertAnnotationsEqual(
+		t,
+		[]ExpectedAnnotation{
+			{
+				RuleID:  "RULE1",
+				Message: "Rule1 failed.",
+			},
+		},
+		response.Annotations(),
+	)
+
+	_, err = fakeClient.Check(ctx, request)
+	require.Error(t, err)
+	require.Equal(t, pluginrpc.CodeInvalidArgument, pluginrpc.WrapError(err).Code())
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = fakeClient.Check(timeoutCtx, request)
+	require.Error(t, err)
+
+	_, err = fakeClient.Check(ctx, request)
+	require.Error(t, err)
+	require.Equal(t, pluginrpc.CodeFailedPrecondition, pluginrpc.WrapError(err).Code())
+
+	requests := fakeClient.Requests()
+	require.Len(t, requests, 4)
+	require.Equal(t, []string{"RULE1", "RULE2"}, requests[0].RuleIDs())
+}
+
+func testFakeRuleSpec(id string, categoryIDs ...string) *check.RuleSpec {
+	return &check.RuleSpec{
+		ID:          id,
+		CategoryIDs: categoryIDs,
+		IsDefault:   true,
+		Purpose:     "Test " + id + ".",
+		Type:        check.RuleTypeLint,
+	}
+}
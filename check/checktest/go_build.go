@@ -0,0 +1,110 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/pluginrpc-go"
+	"github.com/stretchr/testify/require"
+)
+
+// GoBuildPlugin builds the Go main package at the given package path into a binary within a
+// temporary directory, and returns the path to the binary.
+//
+// The package path is anything accepted by go build, for example "./cmd/buf-plugin-foo"
+// relative to the directory of the test, or a full import path. The go command is found on
+// the PATH, and the environment of the test, such as GOFLAGS, is used. The binary is removed
+// when the test completes. The test fails if the build fails, with the output of go build.
+func GoBuildPlugin(t *testing.T, packagePath string) string {
+	binaryPath := filepath.Join(t.TempDir(), "plugin")
+	if runtime.GOOS == "windows" {
+		binaryPath += ".exe"
+	}
+	output, err := exec.CommandContext(
+		context.Background(),
+		"go",
+		"build",
+		"-o",
+		binaryPath,
+		packagePath,
+	).CombinedOutput()
+	require.NoError(t, err, "go build %s failed:\n%s", packagePath, string(output))
+	return binaryPath
+}
+
+// NewGoBuildClient builds the Go main package at the given package path with GoBuildPlugin,
+// and returns a new check.Client that invokes the resulting binary.
+//
+// The check.Client communicates with the binary over stdio using the plugin protocol, in the
+// same way as a host such as buf, so this allows end-to-end tests of a plugin with one call:
+//
+//	checktest.CheckTest{
+//		Request: ...,
+//		Client:  checktest.NewGoBuildClient(t, "./cmd/buf-plugin-timestamp-suffix"),
+//		ExpectedAnnotations: ...,
+//	}.Run(t)
+func NewGoBuildClient(t *testing.T, packagePath string, options ...GoBuildClientOption) check.Client {
+	goBuildClientOptions := newGoBuildClientOptions()
+	for _, option := range options {
+		option(goBuildClientOptions)
+	}
+	return check.NewClient(
+		pluginrpc.NewClient(
+			pluginrpc.NewExecRunner(
+				GoBuildPlugin(t, packagePath),
+				pluginrpc.ExecRunnerWithArgs(goBuildClientOptions.args...),
+			),
+		),
+		goBuildClientOptions.clientOptions...,
+	)
+}
+
+// GoBuildClientOption is an option for NewGoBuildClient.
+type GoBuildClientOption func(*goBuildClientOptions)
+
+// GoBuildClientWithArgs returns a new GoBuildClientOption that invokes the binary with the
+// given args before the plugin protocol args.
+//
+// This is useful for binaries that serve multiple plugins with check.MainMulti.
+func GoBuildClientWithArgs(args ...string) GoBuildClientOption {
+	return func(goBuildClientOptions *goBuildClientOptions) {
+		goBuildClientOptions.args = append(goBuildClientOptions.args, args...)
+	}
+}
+
+// GoBuildClientWithClientOptions returns a new GoBuildClientOption that passes the given
+// ClientOptions to the check.Client.
+func GoBuildClientWithClientOptions(clientOptions ...check.ClientOption) GoBuildClientOption {
+	return func(goBuildClientOptions *goBuildClientOptions) {
+		goBuildClientOptions.clientOptions = append(goBuildClientOptions.clientOptions, clientOptions...)
+	}
+}
+
+// *** PRIVATE ***
+
+type goBuildClientOptions struct {
+	args          []string
+	clientOptions []check.ClientOption
+}
+
+func newGoBuildClientOptions() *goBuildClientOptions {
+	return &goBuildClientOptions{}
+}
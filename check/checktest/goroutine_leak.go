@@ -0,0 +1,106 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// *** PRIVATE ***
+
+const (
+	goroutineLeakLabelKey = "bufplugin_checktest_goroutine_leak"
+	goroutineLeakTimeout  = 5 * time.Second
+)
+
+var goroutineLeakLabelCounter atomic.Uint64
+
+// runWithGoroutineLeakDetection calls f, and returns an error if any goroutines started by f,
+// directly or transitively, are still running once timeout has elapsed after f returns.
+//
+// Goroutines started by f are identified by a pprof label that is unique to this call, as
+// goroutines inherit the labels of the goroutine that starts them. Unlike comparing all
+// goroutines before and after f, this is not affected by tests running in parallel.
+func runWithGoroutineLeakDetection(ctx context.Context, timeout time.Duration, f func(context.Context) error) error {
+	labelValue := strconv.FormatUint(goroutineLeakLabelCounter.Add(1), 10)
+	var err error
+	pprof.Do(
+		ctx,
+		pprof.Labels(goroutineLeakLabelKey, labelValue),
+		func(ctx context.Context) {
+			err = f(ctx)
+		},
+	)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		count, stacks, err := labeledGoroutines(goroutineLeakLabelKey, labelValue)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%d goroutine(s) leaked:\n\n%s", count, strings.Join(stacks, "\n\n"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// labeledGoroutines returns the number of running goroutines with the given label, and the
+// stacks of these goroutines.
+func labeledGoroutines(labelKey string, labelValue string) (int, []string, error) {
+	var buffer bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buffer, 1); err != nil {
+		return 0, nil, err
+	}
+	labelString := strconv.Quote(labelKey) + ":" + strconv.Quote(labelValue)
+	var count int
+	var stacks []string
+	// With debug=1, the profile consists of a header line, followed by records separated by
+	// blank lines. Each record starts with "<count> @ <pcs>", followed by an optional
+	// "# labels: {...}" line and the stack.
+	for _, record := range strings.Split(strings.TrimSpace(buffer.String()), "\n\n") {
+		lines := strings.Split(record, "\n")
+		if strings.HasPrefix(lines[0], "goroutine profile:") {
+			lines = lines[1:]
+		}
+		if len(lines) < 2 || !strings.HasPrefix(lines[1], "# labels: ") || !strings.Contains(lines[1], labelString) {
+			continue
+		}
+		countString, _, ok := strings.Cut(lines[0], " @ ")
+		if !ok {
+			return 0, nil, errors.New("malformed goroutine profile")
+		}
+		recordCount, err := strconv.Atoi(countString)
+		if err != nil {
+			return 0, nil, fmt.Errorf("malformed goroutine profile: %w", err)
+		}
+		count += recordCount
+		stacks = append(stacks, strings.Join(lines[2:], "\n"))
+	}
+	return count, stacks, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWithGoroutineLeakDetection(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := runWithGoroutineLeakDetection(
+		ctx,
+		time.Second,
+		func(context.Context) error {
+			go func() { time.Sleep(10 * time.Millisecond) }()
+			return nil
+		},
+	)
+	require.NoError(t, err)
+
+	err = runWithGoroutineLeakDetection(
+		ctx,
+		time.Second,
+		func(context.Context) error {
+			return errors.New("foo")
+		},
+	)
+	require.EqualError(t, err, "foo")
+
+	doneC := make(chan struct{})
+	defer close(doneC)
+	err = runWithGoroutineLeakDetection(
+		ctx,
+		100*time.Millisecond,
+		func(context.Context) error {
+			go func() {
+				go func() { <-doneC }()
+				<-doneC
+			}()
+			return nil
+		},
+	)
+	require.ErrorContains(t, err, "2 goroutine(s) leaked")
+	require.ErrorContains(t, err, "TestRunWithGoroutineLeakDetection")
+}
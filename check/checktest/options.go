@@ -0,0 +1,91 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+// Option is a single option key and value to pass to a plugin.
+//
+// Options are created with the typed constructors such as StringOption and Int64Option, which
+// encode values as the types that the check.GetXValue functions expect, and are combined with
+// OptionsMap into the value for RequestSpec.Options.
+type Option struct {
+	key   string
+	value any
+}
+
+// BoolOption returns a new Option for a value read with check.GetBoolValue.
+func BoolOption(key string, value bool) Option {
+	return Option{key: key, value: value}
+}
+
+// Int64Option returns a new Option for a value read with check.GetInt64Value.
+func Int64Option(key string, value int64) Option {
+	return Option{key: key, value: value}
+}
+
+// Float64Option returns a new Option for a value read with check.GetFloat64Value.
+func Float64Option(key string, value float64) Option {
+	return Option{key: key, value: value}
+}
+
+// StringOption returns a new Option for a value read with check.GetStringValue.
+func StringOption(key string, value string) Option {
+	return Option{key: key, value: value}
+}
+
+// BytesOption returns a new Option for a value read with check.GetBytesValue.
+func BytesOption(key string, value []byte) Option {
+	return Option{key: key, value: value}
+}
+
+// Int64SliceOption returns a new Option for a value read with check.GetInt64SliceValue.
+func Int64SliceOption(key string, value ...int64) Option {
+	return Option{key: key, value: value}
+}
+
+// Float64SliceOption returns a new Option for a value read with check.GetFloat64SliceValue.
+func Float64SliceOption(key string, value ...float64) Option {
+	return Option{key: key, value: value}
+}
+
+// StringSliceOption returns a new Option for a value read with check.GetStringSliceValue.
+func StringSliceOption(key string, value ...string) Option {
+	return Option{key: key, value: value}
+}
+
+// Key returns the key of the Option.
+func (o Option) Key() string {
+	return o.key
+}
+
+// Value returns the value of the Option.
+func (o Option) Value() any {
+	return o.value
+}
+
+// OptionsMap returns a new key/value map for the given Options, suitable for
+// RequestSpec.Options or check.NewOptions.
+//
+// If multiple Options have the same key, the last Option wins. Values are not validated
+// here; invalid values such as empty strings result in an error when the Request is built.
+func OptionsMap(options ...Option) map[string]any {
+	if len(options) == 0 {
+		return nil
+	}
+	keyToValue := make(map[string]any, len(options))
+	for _, option := range options {
+		keyToValue[option.key] = option.value
+	}
+	return keyToValue
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsMap(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var actualOptions check.Options
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				{
+					ID:        "OPTIONS",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      check.RuleTypeLint,
+					Handler: check.RuleHandlerFunc(
+						func(_ context.Context, _ check.ResponseWriter, request check.Request) error {
+							actualOptions = request.Options()
+							return nil
+						},
+					),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := (&RequestSpec{
+		Files: &ProtoFileSpec{
+			DirPaths:  []string{"testdata/comments"},
+			FilePaths: []string{"comments.proto"},
+		},
+		Options: OptionsMap(
+			BoolOption("bool_value", true),
+			Int64Option("int_value", 1),
+			Float64Option("float_value", 1.5),
+			StringOption("string_value", "foo"),
+			BytesOption("bytes_value", []byte("bar")),
+			Int64SliceOption("int_slice_value", 1, 2),
+			Float64SliceOption("float_slice_value", 1.5, 2.5),
+			StringSliceOption("string_slice_value", "foo", "bar"),
+		),
+	}).ToRequest(ctx)
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.NoError(t, err)
+
+	boolValue, err := check.GetBoolValue(actualOptions, "bool_value")
+	require.NoError(t, err)
+	require.True(t, boolValue)
+	int64Value, err := check.GetInt64Value(actualOptions, "int_value")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), int64Value)
+	float64Value, err := check.GetFloat64Value(actualOptions, "float_value")
+	require.NoError(t, err)
+	require.InDelta(t, 1.5, float64Value, 0)
+	stringValue, err := check.GetStringValue(actualOptions, "string_value")
+	require.NoError(t, err)
+	require.Equal(t, "foo", stringValue)
+	bytesValue, err := check.GetBytesValue(actualOptions, "bytes_value")
+	require.NoError(t, err)
+	require.Equal(t, []byte("bar"), bytesValue)
+	int64SliceValue, err := check.GetInt64SliceValue(actualOptions, "int_slice_value")
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, int64SliceValue)
+	float64SliceValue, err := check.GetFloat64SliceValue(actualOptions, "float_slice_value")
+	require.NoError(t, err)
+	require.Equal(t, []float64{1.5, 2.5}, float64SliceValue)
+	stringSliceValue, err := check.GetStringSliceValue(actualOptions, "string_slice_value")
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo", "bar"}, stringSliceValue)
+
+	require.Equal(
+		t,
+		map[string]any{"string_value": "bar"},
+		OptionsMap(StringOption("string_value", "foo"), StringOption("string_value", "bar")),
+	)
+	require.Nil(t, OptionsMap())
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/stretchr/testify/assert"
+)
+
+// *** PRIVATE ***
+
+// orderDependenceShuffles is the number of random seeds that DetectOrderDependence shuffles with.
+const orderDependenceShuffles = 5
+
+// checkOrderDependence calls Check on the Spec with the Rules and Files shuffled with each of the
+// seeds, and returns an error if the Annotations differ from those of the unshuffled Response.
+func checkOrderDependence(
+	ctx context.Context,
+	spec *check.Spec,
+	serverOptions []check.ServerOption,
+	request check.Request,
+	response check.Response,
+	shuffleSeeds []int64,
+) error {
+	expectedAnnotations := expectedAnnotationsForAnnotations(response.Annotations())
+	for _, shuffleSeed := range shuffleSeeds {
+		client, err := check.NewClientForSpec(
+			spec,
+			check.ClientWithSpecServerOptions(
+				slices.Concat(serverOptions, []check.ServerOption{check.ServerWithShuffle(shuffleSeed)})...,
+			),
+		)
+		if err != nil {
+			return err
+		}
+		shuffledResponse, err := client.Check(ctx, request)
+		if err != nil {
+			return fmt.Errorf("check failed with Rules and Files shuffled with ShuffleSeed %d: %w", shuffleSeed, err)
+		}
+		shuffledExpectedAnnotations := expectedAnnotationsForAnnotations(shuffledResponse.Annotations())
+		if !assert.ObjectsAreEqual(expectedAnnotations, shuffledExpectedAnnotations) {
+			return fmt.Errorf(
+				"annotations depend on the order of Rules or Files, set ShuffleSeed to %d to reproduce:\nunshuffled:%s\nshuffled:%s",
+				shuffleSeed,
+				expectedAnnotationsListString(expectedAnnotations),
+				expectedAnnotationsListString(shuffledExpectedAnnotations),
+			)
+		}
+	}
+	return nil
+}
+
+// shuffleSeedsForShuffleSeed returns the seeds to shuffle with for CheckTest.ShuffleSeed.
+func shuffleSeedsForShuffleSeed(shuffleSeed int64) []int64 {
+	if shuffleSeed != 0 {
+		return []int64{shuffleSeed}
+	}
+	shuffleSeeds := make([]int64, orderDependenceShuffles)
+	for i := range shuffleSeeds {
+		shuffleSeeds[i] = rand.Int64()
+	}
+	return shuffleSeeds
+}
+
+func expectedAnnotationsListString(expectedAnnotations []ExpectedAnnotation) string {
+	if len(expectedAnnotations) == 0 {
+		return " none"
+	}
+	var sb strings.Builder
+	for _, expectedAnnotation := range expectedAnnotations {
+		_, _ = sb.WriteString("\n  ")
+		_, _ = sb.WriteString(expectedAnnotationString(expectedAnnotation))
+	}
+	return sb.String()
+}
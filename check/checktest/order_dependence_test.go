@@ -0,0 +1,86 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestCheckOrderDependence(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var protoFiles []*checkv1beta1.File
+	for _, fileName := range []string{"a.proto", "b.proto", "c.proto", "d.proto", "e.proto"} {
+		protoFiles = append(
+			protoFiles,
+			&checkv1beta1.File{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:   proto.String(fileName),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		)
+	}
+	files, err := check.FilesForProtoFiles(protoFiles)
+	require.NoError(t, err)
+	request, err := check.NewRequest(files)
+	require.NoError(t, err)
+	shuffleSeeds := []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	checkOrderDependenceForSpec := func(onlyFirstFile bool) error {
+		spec := &check.Spec{
+			Rules: []*check.RuleSpec{
+				{
+					ID:        "FILE",
+					IsDefault: true,
+					Purpose:   "Checks files.",
+					Type:      check.RuleTypeLint,
+					Handler: check.RuleHandlerFunc(
+						func(_ context.Context, responseWriter check.ResponseWriter, request check.Request) error {
+							for _, file := range request.Files() {
+								responseWriter.AddAnnotation(check.WithDescriptor(file.FileDescriptor()))
+								if onlyFirstFile {
+									break
+								}
+							}
+							return nil
+						},
+					),
+				},
+			},
+		}
+		client, err := check.NewClientForSpec(spec)
+		require.NoError(t, err)
+		response, err := client.Check(ctx, request)
+		require.NoError(t, err)
+		return checkOrderDependence(ctx, spec, nil, request, response, shuffleSeeds)
+	}
+
+	require.NoError(t, checkOrderDependenceForSpec(false))
+	// Only annotating the first of the Files depends on the order of the Files.
+	require.ErrorContains(
+		t,
+		checkOrderDependenceForSpec(true),
+		"annotations depend on the order of Rules or Files, set ShuffleSeed to",
+	)
+}
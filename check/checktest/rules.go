@@ -0,0 +1,100 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/stretchr/testify/assert"
+)
+
+// ExpectedRule is an expected Rule returned by ListRules.
+type ExpectedRule struct {
+	// ID is the ID of the Rule.
+	ID string
+	// IsDefault is whether or not the Rule is a default Rule.
+	IsDefault bool
+	// CategoryIDs are the IDs of the Categories that the Rule is a part of.
+	//
+	// Order does not matter.
+	CategoryIDs []string
+}
+
+// AssertRulesEqual asserts that the Rules equal the expected Rules.
+//
+// Rules are compared by ID, IsDefault, and Category IDs. Order does not matter.
+func AssertRulesEqual(t *testing.T, expectedRules []ExpectedRule, actualRules []check.Rule) bool {
+	return assert.Equal(
+		t,
+		normalizeExpectedRules(expectedRules),
+		normalizeExpectedRules(xslices.Map(actualRules, expectedRuleForRule)),
+		"Rules returned by ListRules do not match",
+	)
+}
+
+// AssertCategoryIDsEqual asserts that the IDs of the Categories equal the expected Category IDs.
+//
+// Order does not matter.
+func AssertCategoryIDsEqual(t *testing.T, expectedCategoryIDs []string, actualCategories []check.Category) bool {
+	return assert.Equal(
+		t,
+		normalizeIDs(expectedCategoryIDs),
+		normalizeIDs(xslices.Map(actualCategories, check.Category.ID)),
+		"Categories returned by ListCategories do not match",
+	)
+}
+
+// *** PRIVATE ***
+
+func expectedRuleForRule(rule check.Rule) ExpectedRule {
+	return ExpectedRule{
+		ID:          rule.ID(),
+		IsDefault:   rule.IsDefault(),
+		CategoryIDs: xslices.Map(rule.UnclonedCategories(), check.Category.ID),
+	}
+}
+
+// normalizeExpectedRules returns a copy of the ExpectedRules sorted by ID, with sorted
+// CategoryIDs and empty CategoryIDs set to nil.
+func normalizeExpectedRules(expectedRules []ExpectedRule) []ExpectedRule {
+	if len(expectedRules) == 0 {
+		return nil
+	}
+	expectedRules = slices.Clone(expectedRules)
+	for i, expectedRule := range expectedRules {
+		expectedRules[i].CategoryIDs = normalizeIDs(expectedRule.CategoryIDs)
+	}
+	slices.SortFunc(
+		expectedRules,
+		func(one ExpectedRule, two ExpectedRule) int {
+			return strings.Compare(one.ID, two.ID)
+		},
+	)
+	return expectedRules
+}
+
+// normalizeIDs returns a sorted copy of the IDs, with empty IDs set to nil.
+func normalizeIDs(ids []string) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulesEqual(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				{
+					ID:          "RULE_B",
+					CategoryIDs: []string{"CATEGORY_B", "CATEGORY_A"},
+					Purpose:     "Test rule.",
+					Type:        check.RuleTypeLint,
+					Handler:     testNopRuleHandler,
+				},
+				{
+					ID:        "RULE_A",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      check.RuleTypeLint,
+					Handler:   testNopRuleHandler,
+				},
+			},
+			Categories: []*check.CategorySpec{
+				{
+					ID:      "CATEGORY_A",
+					Purpose: "Test category.",
+				},
+				{
+					ID:      "CATEGORY_B",
+					Purpose: "Test category.",
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	rules, err := client.ListRules(ctx)
+	require.NoError(t, err)
+	categories, err := client.ListCategories(ctx)
+	require.NoError(t, err)
+
+	require.True(
+		t,
+		AssertRulesEqual(
+			t,
+			[]ExpectedRule{
+				{ID: "RULE_B", CategoryIDs: []string{"CATEGORY_A", "CATEGORY_B"}},
+				{ID: "RULE_A", IsDefault: true, CategoryIDs: []string{}},
+			},
+			rules,
+		),
+	)
+	require.False(
+		t,
+		AssertRulesEqual(
+			&testing.T{},
+			[]ExpectedRule{
+				{ID: "RULE_A", IsDefault: true},
+				{ID: "RULE_B", IsDefault: true, CategoryIDs: []string{"CATEGORY_A", "CATEGORY_B"}},
+			},
+			rules,
+		),
+	)
+	require.False(t, AssertRulesEqual(&testing.T{}, []ExpectedRule{{ID: "RULE_A", IsDefault: true}}, rules))
+	require.True(t, AssertCategoryIDsEqual(t, []string{"CATEGORY_B", "CATEGORY_A"}, categories))
+	require.False(t, AssertCategoryIDsEqual(&testing.T{}, []string{"CATEGORY_A"}, categories))
+}
@@ -0,0 +1,235 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/thread"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	defaultSoakSequentialCalls    = 1000
+	defaultSoakConcurrentCalls    = 1000
+	defaultSoakMaxHeapGrowthBytes = 32 << 20
+	soakWarmupCalls               = 10
+	soakGoroutineSettleTimeout    = 5 * time.Second
+)
+
+// SoakTest sends many sequential and concurrent Check calls through a single long-lived
+// Client, and asserts that the Client is stable over time.
+//
+// This validates that a Client that is reused across calls, such as one created with
+// check.NewClientForSpec, does not accumulate memory or goroutines, and returns the same
+// Annotations for every call.
+//
+// Goroutine and heap measurements are process-wide, so SoakTest should not be run within a
+// test that calls t.Parallel, or alongside other tests within the same package that do.
+type SoakTest struct {
+	// Request is the request spec to send on every call.
+	//
+	// Required.
+	Request *RequestSpec
+	// Spec is the Spec to test.
+	//
+	// Exactly one of Spec and Client is required.
+	Spec *check.Spec
+	// Client is the Client to test.
+	//
+	// Exactly one of Spec and Client is required.
+	Client check.Client
+	// SequentialCalls is the number of Check calls to make one after another.
+	//
+	// If 0, 1000 calls are made. If negative, no sequential calls are made.
+	SequentialCalls int
+	// ConcurrentCalls is the number of Check calls to make concurrently.
+	//
+	// If 0, 1000 calls are made. If negative, no concurrent calls are made.
+	ConcurrentCalls int
+	// Parallelism is the number of concurrent Check calls that are in flight at once.
+	//
+	// If 0, runtime.GOMAXPROCS(0) is used.
+	Parallelism int
+	// MaxHeapGrowthBytes is the maximum number of bytes by which the live heap may grow over
+	// the course of the test.
+	//
+	// If 0, 32MiB is used.
+	MaxHeapGrowthBytes uint64
+}
+
+// Run runs the test.
+//
+// This will:
+//
+//   - Build the Files and AgainstFiles.
+//   - Create a new Request.
+//   - Create a new Client based on the Spec, if Client is not set.
+//   - Call Check on the Client a small number of times to warm up any caches, and record the
+//     number of goroutines, the size of the live heap, and the resulting Annotations.
+//   - Call Check on the Client SequentialCalls times, and then ConcurrentCalls times, failing
+//     if any call errors or returns different Annotations than the warm-up calls.
+//   - Fail if the number of goroutines does not return to the recorded number within five
+//     seconds, or if the live heap has grown by more than MaxHeapGrowthBytes.
+func (s SoakTest) Run(t *testing.T) {
+	require.NoError(t, s.run(context.Background(), soakGoroutineSettleTimeout))
+}
+
+// *** PRIVATE ***
+
+func (s SoakTest) run(ctx context.Context, goroutineSettleTimeout time.Duration) error {
+	if s.Request == nil {
+		return errors.New("SoakTest.Request not set")
+	}
+	if (s.Spec == nil) == (s.Client == nil) {
+		return errors.New("exactly one of SoakTest.Spec and SoakTest.Client must be set")
+	}
+	request, err := s.Request.ToRequest(ctx)
+	if err != nil {
+		return err
+	}
+	client := s.Client
+	if client == nil {
+		client, err = check.NewClientForSpec(s.Spec)
+		if err != nil {
+			return err
+		}
+	}
+	sequentialCalls := soakCallCount(s.SequentialCalls, defaultSoakSequentialCalls)
+	concurrentCalls := soakCallCount(s.ConcurrentCalls, defaultSoakConcurrentCalls)
+	maxHeapGrowthBytes := s.MaxHeapGrowthBytes
+	if maxHeapGrowthBytes == 0 {
+		maxHeapGrowthBytes = defaultSoakMaxHeapGrowthBytes
+	}
+
+	var expectedAnnotationStrings []string
+	for i := range soakWarmupCalls {
+		annotationStrings, err := soakCheck(ctx, client, request)
+		if err != nil {
+			return fmt.Errorf("warm-up call %d: %w", i, err)
+		}
+		if i == 0 {
+			expectedAnnotationStrings = annotationStrings
+		} else if err := validateSoakAnnotationStrings(expectedAnnotationStrings, annotationStrings); err != nil {
+			return fmt.Errorf("warm-up call %d: %w", i, err)
+		}
+	}
+	baselineGoroutines := runtime.NumGoroutine()
+	baselineHeapBytes := liveHeapBytes()
+
+	for i := range sequentialCalls {
+		annotationStrings, err := soakCheck(ctx, client, request)
+		if err != nil {
+			return fmt.Errorf("sequential call %d: %w", i, err)
+		}
+		if err := validateSoakAnnotationStrings(expectedAnnotationStrings, annotationStrings); err != nil {
+			return fmt.Errorf("sequential call %d: %w", i, err)
+		}
+	}
+	jobs := make([]func(context.Context) error, concurrentCalls)
+	for i := range concurrentCalls {
+		jobs[i] = func(ctx context.Context) error {
+			annotationStrings, err := soakCheck(ctx, client, request)
+			if err != nil {
+				return fmt.Errorf("concurrent call %d: %w", i, err)
+			}
+			if err := validateSoakAnnotationStrings(expectedAnnotationStrings, annotationStrings); err != nil {
+				return fmt.Errorf("concurrent call %d: %w", i, err)
+			}
+			return nil
+		}
+	}
+	if err := thread.Parallelize(ctx, jobs, thread.WithParallelism(s.Parallelism)); err != nil {
+		return err
+	}
+
+	if goroutines := waitForGoroutines(baselineGoroutines, goroutineSettleTimeout); goroutines > baselineGoroutines {
+		return fmt.Errorf(
+			"goroutine leak: %d goroutines after %d calls, expected at most %d",
+			goroutines,
+			sequentialCalls+concurrentCalls,
+			baselineGoroutines,
+		)
+	}
+	if heapBytes := liveHeapBytes(); heapBytes > baselineHeapBytes && heapBytes-baselineHeapBytes > maxHeapGrowthBytes {
+		return fmt.Errorf(
+			"live heap grew by %d bytes after %d calls, expected at most %d",
+			heapBytes-baselineHeapBytes,
+			sequentialCalls+concurrentCalls,
+			maxHeapGrowthBytes,
+		)
+	}
+	// Keep the Client reachable until the heap has been measured, so that memory held by
+	// the Client is included in the measurement.
+	runtime.KeepAlive(client)
+	return nil
+}
+
+func soakCallCount(count int, defaultCount int) int {
+	switch {
+	case count == 0:
+		return defaultCount
+	case count < 0:
+		return 0
+	default:
+		return count
+	}
+}
+
+func soakCheck(ctx context.Context, client check.Client, request check.Request) ([]string, error) {
+	response, err := client.Check(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return xslices.Map(
+		expectedAnnotationsForAnnotations(response.Annotations()),
+		expectedAnnotationString,
+	), nil
+}
+
+func validateSoakAnnotationStrings(expected []string, actual []string) error {
+	if !slices.Equal(expected, actual) {
+		return fmt.Errorf("annotations changed between calls: expected %v, got %v", expected, actual)
+	}
+	return nil
+}
+
+// waitForGoroutines waits until there are at most maxGoroutines goroutines, or the timeout
+// elapses, and returns the last observed number of goroutines.
+func waitForGoroutines(maxGoroutines int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		goroutines := runtime.NumGoroutine()
+		if goroutines <= maxGoroutines || !time.Now().Before(deadline) {
+			return goroutines
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func liveHeapBytes() uint64 {
+	runtime.GC()
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return memStats.HeapAlloc
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/stretchr/testify/require"
+)
+
+const testSoakEnvKey = "BUFPLUGIN_TEST_SOAK_CHILD"
+
+func TestSoakTest(t *testing.T) {
+	t.Parallel()
+
+	if os.Getenv(testSoakEnvKey) != "" {
+		testSoakTestChild(t)
+		return
+	}
+	// Goroutine and heap measurements are process-wide, so the soak test is run within a
+	// child test process that does not run other tests in parallel.
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSoakTest$", "-test.v")
+	cmd.Env = append(os.Environ(), testSoakEnvKey+"=1")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func testSoakTestChild(t *testing.T) {
+	ctx := context.Background()
+	requestSpec := &RequestSpec{
+		Files: &ProtoFileSpec{
+			DirPaths:  []string{"testdata/comments"},
+			FilePaths: []string{"comments.proto"},
+		},
+	}
+	spec := &check.Spec{
+		Rules: []*check.RuleSpec{
+			testNewMessageRuleSpec("MESSAGE", "message"),
+		},
+	}
+	SoakTest{
+		Request:         requestSpec,
+		Spec:            spec,
+		SequentialCalls: 200,
+		ConcurrentCalls: 200,
+	}.Run(t)
+
+	client, err := check.NewClientForSpec(spec)
+	require.NoError(t, err)
+	doneC := make(chan struct{})
+	defer close(doneC)
+	err = SoakTest{
+		Request:         requestSpec,
+		Client:          &testLeakingClient{Client: client, doneC: doneC},
+		SequentialCalls: 10,
+		ConcurrentCalls: -1,
+	}.run(ctx, 100*time.Millisecond)
+	require.ErrorContains(t, err, "goroutine leak")
+}
+
+// testLeakingClient leaks a goroutine on every Check call until doneC is closed.
+type testLeakingClient struct {
+	check.Client
+
+	doneC chan struct{}
+}
+
+func (c *testLeakingClient) Check(ctx context.Context, request check.Request, options ...check.CheckCallOption) (check.Response, error) {
+	go func() { <-c.doneC }()
+	return c.Client.Check(ctx, request, options...)
+}
@@ -0,0 +1,265 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+)
+
+// UpdateSpecGoldenEnvKey is the environment variable that, if set to a true value such as "1"
+// or "true", causes SpecGoldenTest to write its golden file instead of comparing against it.
+//
+//	BUFPLUGIN_UPDATE_SPEC_GOLDEN=1 go test ./...
+const UpdateSpecGoldenEnvKey = "BUFPLUGIN_UPDATE_SPEC_GOLDEN"
+
+// SpecGoldenTest asserts that the Rules and Categories of a Spec have not changed.
+//
+// The IDs, types, defaults, categories, and deprecations of all Rules and Categories are
+// snapshotted into a golden file. Renaming or removing a Rule, or changing whether it is a
+// default Rule, is a breaking change for plugin consumers that reference Rules by ID in their
+// configuration. This test fails if any of these change without the golden file being
+// explicitly updated by running the tests with UpdateSpecGoldenEnvKey set.
+//
+// Purposes are not part of the golden file, as changing them does not affect consumers.
+type SpecGoldenTest struct {
+	// Spec is the Spec to test.
+	//
+	// Required.
+	Spec *check.Spec
+	// GoldenFilePath is the path to the golden file.
+	//
+	// Typically within a testdata directory. Required.
+	GoldenFilePath string
+}
+
+// Run runs the test.
+//
+// If UpdateSpecGoldenEnvKey is set, the golden file is written, creating it if it does not
+// exist. Otherwise, the golden file is read, and the test fails with a description of all
+// changes if it does not match the Spec.
+func (s SpecGoldenTest) Run(t *testing.T) {
+	ctx := context.Background()
+
+	require.NotNil(t, s.Spec)
+	require.NotEmpty(t, s.GoldenFilePath)
+
+	actualData, err := specGoldenDataForSpec(ctx, s.Spec)
+	require.NoError(t, err)
+	update, err := shouldUpdateSpecGolden()
+	require.NoError(t, err)
+	if update {
+		require.NoError(t, os.MkdirAll(filepath.Dir(s.GoldenFilePath), 0o755))
+		require.NoError(t, os.WriteFile(s.GoldenFilePath, actualData, 0o600))
+		return
+	}
+	expectedData, err := os.ReadFile(s.GoldenFilePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		require.FailNowf(
+			t,
+			"golden file does not exist",
+			"%s does not exist, run with %s=1 to create it",
+			s.GoldenFilePath,
+			UpdateSpecGoldenEnvKey,
+		)
+	}
+	require.NoError(t, err)
+	changes, err := specGoldenChanges(expectedData, actualData)
+	require.NoError(t, err)
+	if len(changes) > 0 {
+		require.FailNowf(
+			t,
+			"Spec does not match golden file",
+			"%s:\n  %s\nif these changes are intended, run with %s=1 to update the golden file",
+			s.GoldenFilePath,
+			strings.Join(changes, "\n  "),
+			UpdateSpecGoldenEnvKey,
+		)
+	}
+}
+
+// *** PRIVATE ***
+
+type externalSpecGolden struct {
+	Rules      []externalSpecGoldenRule     `json:"rules,omitempty"`
+	Categories []externalSpecGoldenCategory `json:"categories,omitempty"`
+}
+
+type externalSpecGoldenRule struct {
+	ID             string   `json:"id"`
+	Type           string   `json:"type"`
+	Default        bool     `json:"default,omitempty"`
+	CategoryIDs    []string `json:"category_ids,omitempty"`
+	Deprecated     bool     `json:"deprecated,omitempty"`
+	ReplacementIDs []string `json:"replacement_ids,omitempty"`
+}
+
+type externalSpecGoldenCategory struct {
+	ID             string   `json:"id"`
+	Deprecated     bool     `json:"deprecated,omitempty"`
+	ReplacementIDs []string `json:"replacement_ids,omitempty"`
+}
+
+func shouldUpdateSpecGolden() (bool, error) {
+	value := os.Getenv(UpdateSpecGoldenEnvKey)
+	if value == "" {
+		return false, nil
+	}
+	update, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %s: %q", UpdateSpecGoldenEnvKey, value)
+	}
+	return update, nil
+}
+
+func specGoldenDataForSpec(ctx context.Context, spec *check.Spec) ([]byte, error) {
+	client, err := check.NewClientForSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := client.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := client.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	externalSpecGolden := &externalSpecGolden{
+		Rules: xslices.Map(
+			rules,
+			func(rule check.Rule) externalSpecGoldenRule {
+				return externalSpecGoldenRule{
+					ID:             rule.ID(),
+					Type:           rule.Type().String(),
+					Default:        rule.IsDefault(),
+					CategoryIDs:    xslices.Map(rule.Categories(), check.Category.ID),
+					Deprecated:     rule.Deprecated(),
+					ReplacementIDs: rule.ReplacementIDs(),
+				}
+			},
+		),
+		Categories: xslices.Map(
+			categories,
+			func(category check.Category) externalSpecGoldenCategory {
+				return externalSpecGoldenCategory{
+					ID:             category.ID(),
+					Deprecated:     category.Deprecated(),
+					ReplacementIDs: category.ReplacementIDs(),
+				}
+			},
+		),
+	}
+	data, err := json.MarshalIndent(externalSpecGolden, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// specGoldenChanges returns human-readable descriptions of the changes from the expected
+// golden data to the actual golden data.
+//
+// Returns an empty slice if there are no changes.
+func specGoldenChanges(expectedData []byte, actualData []byte) ([]string, error) {
+	expected := &externalSpecGolden{}
+	if err := json.Unmarshal(expectedData, expected); err != nil {
+		return nil, fmt.Errorf("invalid golden file: %w", err)
+	}
+	actual := &externalSpecGolden{}
+	if err := json.Unmarshal(actualData, actual); err != nil {
+		return nil, err
+	}
+	var changes []string
+	expectedIDToRule := idToValue(expected.Rules, func(rule externalSpecGoldenRule) string { return rule.ID })
+	actualIDToRule := idToValue(actual.Rules, func(rule externalSpecGoldenRule) string { return rule.ID })
+	for _, expectedRule := range expected.Rules {
+		actualRule, ok := actualIDToRule[expectedRule.ID]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("rule %s was removed", expectedRule.ID))
+			continue
+		}
+		if expectedRule.Type != actualRule.Type {
+			changes = append(changes, fmt.Sprintf("rule %s type changed from %s to %s", expectedRule.ID, expectedRule.Type, actualRule.Type))
+		}
+		if expectedRule.Default != actualRule.Default {
+			changes = append(changes, fmt.Sprintf("rule %s default changed from %t to %t", expectedRule.ID, expectedRule.Default, actualRule.Default))
+		}
+		if !slices.Equal(expectedRule.CategoryIDs, actualRule.CategoryIDs) {
+			changes = append(changes, fmt.Sprintf("rule %s categories changed from %v to %v", expectedRule.ID, expectedRule.CategoryIDs, actualRule.CategoryIDs))
+		}
+		changes = append(changes, deprecationChanges("rule", expectedRule.ID, expectedRule.Deprecated, expectedRule.ReplacementIDs, actualRule.Deprecated, actualRule.ReplacementIDs)...)
+	}
+	for _, actualRule := range actual.Rules {
+		if _, ok := expectedIDToRule[actualRule.ID]; !ok {
+			changes = append(changes, fmt.Sprintf("rule %s was added", actualRule.ID))
+		}
+	}
+	expectedIDToCategory := idToValue(expected.Categories, func(category externalSpecGoldenCategory) string { return category.ID })
+	actualIDToCategory := idToValue(actual.Categories, func(category externalSpecGoldenCategory) string { return category.ID })
+	for _, expectedCategory := range expected.Categories {
+		actualCategory, ok := actualIDToCategory[expectedCategory.ID]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("category %s was removed", expectedCategory.ID))
+			continue
+		}
+		changes = append(changes, deprecationChanges("category", expectedCategory.ID, expectedCategory.Deprecated, expectedCategory.ReplacementIDs, actualCategory.Deprecated, actualCategory.ReplacementIDs)...)
+	}
+	for _, actualCategory := range actual.Categories {
+		if _, ok := expectedIDToCategory[actualCategory.ID]; !ok {
+			changes = append(changes, fmt.Sprintf("category %s was added", actualCategory.ID))
+		}
+	}
+	return changes, nil
+}
+
+func deprecationChanges(
+	kind string,
+	id string,
+	expectedDeprecated bool,
+	expectedReplacementIDs []string,
+	actualDeprecated bool,
+	actualReplacementIDs []string,
+) []string {
+	var changes []string
+	if expectedDeprecated != actualDeprecated {
+		changes = append(changes, fmt.Sprintf("%s %s deprecated changed from %t to %t", kind, id, expectedDeprecated, actualDeprecated))
+	}
+	if !slices.Equal(expectedReplacementIDs, actualReplacementIDs) {
+		changes = append(changes, fmt.Sprintf("%s %s replacement IDs changed from %v to %v", kind, id, expectedReplacementIDs, actualReplacementIDs))
+	}
+	return changes
+}
+
+func idToValue[T any](values []T, getID func(T) string) map[string]T {
+	idToValue := make(map[string]T, len(values))
+	for _, value := range values {
+		idToValue[getID(value)] = value
+	}
+	return idToValue
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checktest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecGoldenTest(t *testing.T) {
+	t.Parallel()
+
+	SpecGoldenTest{
+		Spec:           testSpecGoldenSpec(),
+		GoldenFilePath: "testdata/spec_golden/spec.json",
+	}.Run(t)
+}
+
+func TestSpecGoldenChanges(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	expectedData, err := specGoldenDataForSpec(ctx, testSpecGoldenSpec())
+	require.NoError(t, err)
+
+	spec := testSpecGoldenSpec()
+	spec.Rules[0].Purpose = "Changed purpose."
+	actualData, err := specGoldenDataForSpec(ctx, spec)
+	require.NoError(t, err)
+	changes, err := specGoldenChanges(expectedData, actualData)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	spec = testSpecGoldenSpec()
+	spec.Rules[0].ID = "RULE3"
+	spec.Rules[0].CategoryIDs = []string{"CATEGORY1"}
+	spec.Rules[1].IsDefault = true
+	spec.Rules[1].CategoryIDs = []string{"CATEGORY2"}
+	spec.Categories[0].Deprecated = true
+	spec.Categories[0].ReplacementIDs = []string{"CATEGORY2"}
+	spec.Categories = append(
+		spec.Categories,
+		&check.CategorySpec{
+			ID:      "CATEGORY2",
+			Purpose: "Test category2.",
+		},
+	)
+	actualData, err = specGoldenDataForSpec(ctx, spec)
+	require.NoError(t, err)
+	changes, err = specGoldenChanges(expectedData, actualData)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			"rule RULE1 was removed",
+			"rule RULE2 default changed from false to true",
+			"rule RULE2 categories changed from [CATEGORY1] to [CATEGORY2]",
+			"rule RULE3 was added",
+			"category CATEGORY1 deprecated changed from false to true",
+			"category CATEGORY1 replacement IDs changed from [] to [CATEGORY2]",
+			"category CATEGORY2 was added",
+		},
+		changes,
+	)
+}
+
+func testSpecGoldenSpec() *check.Spec {
+	return &check.Spec{
+		Rules: []*check.RuleSpec{
+			{
+				ID:        "RULE1",
+				IsDefault: true,
+				Purpose:   "Test rule1.",
+				Type:      check.RuleTypeLint,
+				Handler:   testNopRuleHandler,
+			},
+			{
+				ID:          "RULE2",
+				CategoryIDs: []string{"CATEGORY1"},
+				Purpose:     "Test rule2.",
+				Type:        check.RuleTypeBreaking,
+				Handler:     testNopRuleHandler,
+			},
+		},
+		Categories: []*check.CategorySpec{
+			{
+				ID:      "CATEGORY1",
+				Purpose: "Test category1.",
+			},
+		},
+	}
+}
+
+var testNopRuleHandler = check.RuleHandlerFunc(func(context.Context, check.ResponseWriter, check.Request) error { return nil })
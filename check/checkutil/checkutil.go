@@ -20,6 +20,7 @@ package checkutil
 
 import (
 	"context"
+	"sort"
 
 	"github.com/bufbuild/bufplugin-go/check"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -27,7 +28,13 @@ import (
 
 // NewFileRuleHandler returns a new RuleHandler that will call f for every file within Files.
 //
+// If the context is done, no further files are visited and the context's error is returned.
+// The other RuleHandlers in this package are built on NewFileRuleHandler and share this behavior.
+//
 // Imports are filtered. This is the standard case for lint rules.
+//
+// The RuleHandlers in this package call check.RecordVisit for every File and descriptor they
+// visit, so that their traversal can be verified with a check.CoverageRecorder.
 func NewFileRuleHandler(
 	f func(context.Context, check.ResponseWriter, check.Request, check.File) error,
 ) check.RuleHandler {
@@ -37,10 +44,11 @@ func NewFileRuleHandler(
 			responseWriter check.ResponseWriter,
 			request check.Request,
 		) error {
-			for _, file := range request.Files() {
-				if file.IsImport() {
-					continue
+			for file := range NonImportFiles(request) {
+				if err := ctx.Err(); err != nil {
+					return err
 				}
+				check.RecordVisit(ctx, file.FileDescriptor())
 				if err := f(ctx, responseWriter, request, file); err != nil {
 					return err
 				}
@@ -50,12 +58,69 @@ func NewFileRuleHandler(
 	)
 }
 
+// NewPackageRuleHandler returns a new RuleHandler that will call f once for every package
+// within Files, with all of the Files that are part of the package.
+//
+// Packages are visited in sorted order, and the Files for each package are sorted by path.
+// Files without a package are grouped under the empty package name. If the context is done,
+// no further packages are visited and the context's error is returned.
+//
+// Imports are filtered. This is the standard case for lint rules.
+func NewPackageRuleHandler(
+	f func(context.Context, check.ResponseWriter, check.Request, protoreflect.FullName, []check.File) error,
+) check.RuleHandler {
+	return check.RuleHandlerFunc(
+		func(
+			ctx context.Context,
+			responseWriter check.ResponseWriter,
+			request check.Request,
+		) error {
+			packageToFiles := make(map[protoreflect.FullName][]check.File)
+			for file := range NonImportFiles(request) {
+				pkg := file.FileDescriptor().Package()
+				packageToFiles[pkg] = append(packageToFiles[pkg], file)
+			}
+			packages := make([]protoreflect.FullName, 0, len(packageToFiles))
+			for pkg := range packageToFiles {
+				packages = append(packages, pkg)
+			}
+			sort.Slice(packages, func(i int, j int) bool { return packages[i] < packages[j] })
+			for _, pkg := range packages {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				files := packageToFiles[pkg]
+				sort.Slice(
+					files,
+					func(i int, j int) bool {
+						return files[i].FileDescriptor().Path() < files[j].FileDescriptor().Path()
+					},
+				)
+				for _, file := range files {
+					check.RecordVisit(ctx, file.FileDescriptor())
+				}
+				if err := f(ctx, responseWriter, request, pkg, files); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+}
+
 // NewMessageRuleHandler returns a new RuleHandler that will call f for every message within Files.
 //
 // Imports are filtered. This is the standard case for lint rules.
+//
+// Map entry messages are visited unless TraversalWithoutMapEntries is used.
 func NewMessageRuleHandler(
 	f func(context.Context, check.ResponseWriter, check.Request, protoreflect.MessageDescriptor) error,
+	options ...TraversalOption,
 ) check.RuleHandler {
+	traversalOptions := newTraversalOptions()
+	for _, option := range options {
+		option(traversalOptions)
+	}
 	return NewFileRuleHandler(
 		func(
 			ctx context.Context,
@@ -66,6 +131,10 @@ func NewMessageRuleHandler(
 			return forEachMessage(
 				file.FileDescriptor().Messages(),
 				func(messageDescriptor protoreflect.MessageDescriptor) error {
+					if !traversalOptions.shouldVisitMessage(messageDescriptor) {
+						return nil
+					}
+					check.RecordVisit(ctx, messageDescriptor)
 					return f(ctx, responseWriter, request, messageDescriptor)
 				},
 			)
@@ -77,9 +146,18 @@ func NewMessageRuleHandler(
 // the messages within Files.
 //
 // Imports are filtered. This is the standard case for lint rules.
+//
+// The fields of map entry messages are visited unless TraversalWithoutMapEntries is used, and
+// the fields within synthetic oneofs are visited unless TraversalWithoutSyntheticOneofFields
+// is used.
 func NewFieldRuleHandler(
 	f func(context.Context, check.ResponseWriter, check.Request, protoreflect.FieldDescriptor) error,
+	options ...TraversalOption,
 ) check.RuleHandler {
+	traversalOptions := newTraversalOptions()
+	for _, option := range options {
+		option(traversalOptions)
+	}
 	return NewMessageRuleHandler(
 		func(
 			ctx context.Context,
@@ -89,7 +167,52 @@ func NewFieldRuleHandler(
 		) error {
 			fields := messageDescriptor.Fields()
 			for i := range fields.Len() {
-				if err := f(ctx, responseWriter, request, fields.Get(i)); err != nil {
+				fieldDescriptor := fields.Get(i)
+				if !traversalOptions.shouldVisitField(fieldDescriptor) {
+					continue
+				}
+				check.RecordVisit(ctx, fieldDescriptor)
+				if err := f(ctx, responseWriter, request, fieldDescriptor); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		options...,
+	)
+}
+
+// NewDescriptorKindRuleHandler returns a new RuleHandler that will call f for every descriptor
+// of the given DescriptorKind within Files.
+//
+// For check.DescriptorKindFile and check.DescriptorKindPackage, f is called with the FileDescriptor of each File.
+// For check.DescriptorKindPackage, Files without a package are skipped.
+//
+// Imports are filtered. This is the standard case for lint rules.
+func NewDescriptorKindRuleHandler(
+	descriptorKind check.DescriptorKind,
+	f func(context.Context, check.ResponseWriter, check.Request, protoreflect.Descriptor) error,
+) check.RuleHandler {
+	return NewFileRuleHandler(
+		func(
+			ctx context.Context,
+			responseWriter check.ResponseWriter,
+			request check.Request,
+			file check.File,
+		) error {
+			fileDescriptor := file.FileDescriptor()
+			if descriptorKind == check.DescriptorKindPackage {
+				if fileDescriptor.Package() == "" {
+					return nil
+				}
+				return f(ctx, responseWriter, request, fileDescriptor)
+			}
+			for descriptor := range allDescriptors(fileDescriptor) {
+				if kind, ok := DescriptorKindForDescriptor(descriptor); !ok || kind != descriptorKind {
+					continue
+				}
+				check.RecordVisit(ctx, descriptor)
+				if err := f(ctx, responseWriter, request, descriptor); err != nil {
 					return err
 				}
 			}
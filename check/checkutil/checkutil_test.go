@@ -0,0 +1,136 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checktest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestRuleHandlerCoverage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	request, err := (&checktest.RequestSpec{
+		Files: &checktest.ProtoFileSpec{
+			DirPaths:  []string{"testdata/coverage"},
+			FilePaths: []string{"coverage.proto"},
+		},
+	}).ToRequest(ctx)
+	require.NoError(t, err)
+	coverageRecorder := check.NewCoverageRecorder()
+	client, err := check.NewClientForSpec(
+		&check.Spec{
+			Rules: []*check.RuleSpec{
+				{
+					ID:        "FIELD",
+					IsDefault: true,
+					Purpose:   "Test field.",
+					Type:      check.RuleTypeLint,
+					Handler: NewFieldRuleHandler(
+						func(context.Context, check.ResponseWriter, check.Request, protoreflect.FieldDescriptor) error {
+							return nil
+						},
+					),
+				},
+				{
+					ID:        "EXTENSION",
+					IsDefault: true,
+					Purpose:   "Test extension.",
+					Type:      check.RuleTypeLint,
+					Handler: NewDescriptorKindRuleHandler(
+						check.DescriptorKindExtension,
+						func(context.Context, check.ResponseWriter, check.Request, protoreflect.Descriptor) error {
+							return nil
+						},
+					),
+				},
+			},
+		},
+		check.ClientWithSpecServerOptions(check.ServerWithCoverageRecorder(coverageRecorder)),
+	)
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.NoError(t, err)
+
+	coverage := coverageRecorder.Coverage()
+	require.Equal(t, []string{"EXTENSION", "FIELD"}, coverage.RuleIDs())
+	require.Equal(t, []string{"coverage.proto"}, coverage.FileNames("FIELD"))
+	// NewFieldRuleHandler visits nested messages, but not extensions.
+	require.Equal(
+		t,
+		[]protoreflect.FullName{
+			"coverage.Foo",
+			"coverage.Foo.Bar",
+			"coverage.Foo.Bar.two",
+			"coverage.Foo.one",
+		},
+		coverage.FullNames("FIELD"),
+	)
+	require.False(t, coverage.Visited("FIELD", "coverage.three"))
+	require.Equal(t, []protoreflect.FullName{"coverage.three"}, coverage.FullNames("EXTENSION"))
+	require.True(t, coverage.Visited("EXTENSION", "coverage.three"))
+}
+
+func TestPackageRuleHandler(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	request, err := (&checktest.RequestSpec{
+		Files: &checktest.ProtoFileSpec{
+			DirPaths:  []string{"testdata/packages"},
+			FilePaths: []string{"a1.proto", "a2.proto", "b.proto", "empty.proto"},
+		},
+		AgainstFiles: &checktest.ProtoFileSpec{
+			DirPaths:  []string{"testdata/packages_against"},
+			FilePaths: []string{"d.proto"},
+		},
+	}).ToRequest(ctx)
+	require.NoError(t, err)
+	var packages []protoreflect.FullName
+	packageToFileNames := make(map[protoreflect.FullName][]string)
+	ruleHandler := NewPackageRuleHandler(
+		func(_ context.Context, _ check.ResponseWriter, _ check.Request, pkg protoreflect.FullName, files []check.File) error {
+			packages = append(packages, pkg)
+			for _, file := range files {
+				packageToFileNames[pkg] = append(packageToFileNames[pkg], file.FileDescriptor().Path())
+			}
+			return nil
+		},
+	)
+	require.NoError(t, ruleHandler.Handle(ctx, nil, request))
+	// The import c.proto and the against file d.proto are not visited.
+	require.Equal(t, []protoreflect.FullName{"", "a", "b"}, packages)
+	require.Equal(
+		t,
+		map[protoreflect.FullName][]string{
+			"":  {"empty.proto"},
+			"a": {"a1.proto", "a2.proto"},
+			"b": {"b.proto"},
+		},
+		packageToFileNames,
+	)
+
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	packages = nil
+	require.ErrorIs(t, ruleHandler.Handle(canceledCtx, nil, request), context.Canceled)
+	require.Empty(t, packages)
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DefaultCommentDirectivePrefix is the default prefix of comment lines that are considered
+// directives rather than documentation, for example "buf:lint:ignore FIELD_LOWER_SNAKE_CASE".
+const DefaultCommentDirectivePrefix = "buf:"
+
+// HasLeadingComment returns true if the descriptor has a leading comment with content
+// after directives and whitespace are stripped.
+//
+// By default, only the leading comment attached to the descriptor is considered. Use
+// CommentWithDetached to also consider leading detached comments.
+func HasLeadingComment(descriptor protoreflect.Descriptor, options ...CommentOption) bool {
+	return LeadingComment(descriptor, options...) != ""
+}
+
+// LeadingComment returns the leading comment of the descriptor, with directives and
+// surrounding whitespace stripped.
+//
+// If CommentWithDetached is used, leading detached comments are included before the
+// leading comment, separated by blank lines.
+//
+// Returns the empty string if the descriptor has no leading comment, or if the File
+// has no source code information.
+func LeadingComment(descriptor protoreflect.Descriptor, options ...CommentOption) string {
+	commentOptions := newCommentOptions()
+	for _, option := range options {
+		option(commentOptions)
+	}
+	fileDescriptor := descriptor.ParentFile()
+	if fileDescriptor == nil {
+		return ""
+	}
+	sourceLocation := fileDescriptor.SourceLocations().ByDescriptor(descriptor)
+	var comments []string
+	if commentOptions.includeDetached {
+		for _, detachedComment := range sourceLocation.LeadingDetachedComments {
+			if comment := stripComment(detachedComment, commentOptions.directivePrefixes); comment != "" {
+				comments = append(comments, comment)
+			}
+		}
+	}
+	if comment := stripComment(sourceLocation.LeadingComments, commentOptions.directivePrefixes); comment != "" {
+		comments = append(comments, comment)
+	}
+	return strings.Join(comments, "\n\n")
+}
+
+// CommentOption is an option for HasLeadingComment and LeadingComment.
+type CommentOption func(*commentOptions)
+
+// CommentWithDetached returns a new CommentOption that will result in leading detached
+// comments being considered in addition to the leading comment.
+//
+// Detached comments are comments separated from the declaration by a blank line.
+func CommentWithDetached() CommentOption {
+	return func(commentOptions *commentOptions) {
+		commentOptions.includeDetached = true
+	}
+}
+
+// CommentWithDirectivePrefixes returns a new CommentOption that sets the prefixes of
+// lines that are considered directives and are stripped from comments.
+//
+// Lines are matched after leading whitespace is trimmed. The default is
+// DefaultCommentDirectivePrefix. Calling this with no prefixes disables directive stripping.
+func CommentWithDirectivePrefixes(directivePrefixes ...string) CommentOption {
+	return func(commentOptions *commentOptions) {
+		commentOptions.directivePrefixes = directivePrefixes
+	}
+}
+
+// *** PRIVATE ***
+
+type commentOptions struct {
+	includeDetached   bool
+	directivePrefixes []string
+}
+
+func newCommentOptions() *commentOptions {
+	return &commentOptions{
+		directivePrefixes: []string{DefaultCommentDirectivePrefix},
+	}
+}
+
+func stripComment(comment string, directivePrefixes []string) string {
+	lines := strings.Split(comment, "\n")
+	keptLines := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if isCommentDirective(strings.TrimSpace(line), directivePrefixes) {
+			continue
+		}
+		keptLines = append(keptLines, line)
+	}
+	return strings.TrimSpace(strings.Join(keptLines, "\n"))
+}
+
+func isCommentDirective(line string, directivePrefixes []string) bool {
+	for _, directivePrefix := range directivePrefixes {
+		if directivePrefix != "" && strings.HasPrefix(line, directivePrefix) {
+			return true
+		}
+	}
+	return false
+}
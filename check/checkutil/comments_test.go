@@ -0,0 +1,127 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check/checktest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestLeadingComment(t *testing.T) {
+	t.Parallel()
+
+	messageDescriptor := testCommentsMessageDescriptor(t, false)
+	fields := messageDescriptor.Fields()
+	testCases := []struct {
+		descriptor                protoreflect.Descriptor
+		options                   []CommentOption
+		expectedLeadingComment    string
+		expectedHasLeadingComment bool
+	}{
+		{
+			descriptor:                messageDescriptor,
+			expectedLeadingComment:    "Foo is documented.",
+			expectedHasLeadingComment: true,
+		},
+		{
+			descriptor:                fields.ByName("Directive_Only"),
+			expectedLeadingComment:    "",
+			expectedHasLeadingComment: false,
+		},
+		{
+			descriptor:                fields.ByName("Directive_Only"),
+			options:                   []CommentOption{CommentWithDirectivePrefixes()},
+			expectedLeadingComment:    "buf:lint:ignore FIELD_LOWER_SNAKE_CASE",
+			expectedHasLeadingComment: true,
+		},
+		{
+			// Directives are stripped after leading whitespace is trimmed.
+			descriptor:                fields.ByName("name"),
+			expectedLeadingComment:    "The name.",
+			expectedHasLeadingComment: true,
+		},
+		{
+			descriptor:                fields.ByName("value"),
+			expectedLeadingComment:    "nolint:field\n The value.",
+			expectedHasLeadingComment: true,
+		},
+		{
+			descriptor:                fields.ByName("value"),
+			options:                   []CommentOption{CommentWithDirectivePrefixes("buf:", "nolint:")},
+			expectedLeadingComment:    "The value.",
+			expectedHasLeadingComment: true,
+		},
+		{
+			descriptor:                fields.ByName("value"),
+			options:                   []CommentOption{CommentWithDetached()},
+			expectedLeadingComment:    "Detached comment.\n\nnolint:field\n The value.",
+			expectedHasLeadingComment: true,
+		},
+		{
+			descriptor:                fields.ByName("detached_only"),
+			expectedLeadingComment:    "",
+			expectedHasLeadingComment: false,
+		},
+		{
+			descriptor:                fields.ByName("detached_only"),
+			options:                   []CommentOption{CommentWithDetached()},
+			expectedLeadingComment:    "Only detached.",
+			expectedHasLeadingComment: true,
+		},
+		{
+			// Trailing comments are not leading comments.
+			descriptor:                fields.ByName("undocumented"),
+			options:                   []CommentOption{CommentWithDetached()},
+			expectedLeadingComment:    "",
+			expectedHasLeadingComment: false,
+		},
+		{
+			// Descriptors of a File without source code information have no comments.
+			descriptor:                testCommentsMessageDescriptor(t, true),
+			options:                   []CommentOption{CommentWithDetached()},
+			expectedLeadingComment:    "",
+			expectedHasLeadingComment: false,
+		},
+	}
+	for _, testCase := range testCases {
+		require.Equal(
+			t,
+			testCase.expectedLeadingComment,
+			LeadingComment(testCase.descriptor, testCase.options...),
+			testCase.descriptor.FullName(),
+		)
+		require.Equal(
+			t,
+			testCase.expectedHasLeadingComment,
+			HasLeadingComment(testCase.descriptor, testCase.options...),
+			testCase.descriptor.FullName(),
+		)
+	}
+}
+
+func testCommentsMessageDescriptor(t *testing.T, withoutSourceCodeInfo bool) protoreflect.MessageDescriptor {
+	files, err := (&checktest.ProtoFileSpec{
+		DirPaths:              []string{"testdata/comments"},
+		FilePaths:             []string{"comments.proto"},
+		WithoutSourceCodeInfo: withoutSourceCodeInfo,
+	}).ToFiles(context.Background())
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	return files[0].FileDescriptor().Messages().ByName("Foo")
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"github.com/bufbuild/bufplugin-go/check"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DescriptorKindForDescriptor returns the check.DescriptorKind for the given descriptor.
+//
+// A FileDescriptor is always check.DescriptorKindFile, never check.DescriptorKindPackage.
+// Returns false if the descriptor is not of a known kind.
+func DescriptorKindForDescriptor(descriptor protoreflect.Descriptor) (check.DescriptorKind, bool) {
+	switch d := descriptor.(type) {
+	case protoreflect.FileDescriptor:
+		return check.DescriptorKindFile, true
+	case protoreflect.MessageDescriptor:
+		return check.DescriptorKindMessage, true
+	case protoreflect.FieldDescriptor:
+		if d.IsExtension() {
+			return check.DescriptorKindExtension, true
+		}
+		return check.DescriptorKindField, true
+	case protoreflect.OneofDescriptor:
+		return check.DescriptorKindOneof, true
+	case protoreflect.EnumDescriptor:
+		return check.DescriptorKindEnum, true
+	case protoreflect.EnumValueDescriptor:
+		return check.DescriptorKindEnumValue, true
+	case protoreflect.ServiceDescriptor:
+		return check.DescriptorKindService, true
+	case protoreflect.MethodDescriptor:
+		return check.DescriptorKindMethod, true
+	default:
+		return 0, false
+	}
+}
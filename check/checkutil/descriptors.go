@@ -0,0 +1,263 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"iter"
+	"slices"
+	"sort"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Duplicate is a group of descriptors that share the same key.
+type Duplicate[D protoreflect.Descriptor] struct {
+	// Key is the key shared by all Descriptors.
+	Key string
+	// Descriptors are the descriptors that share the key.
+	//
+	// This will always contain at least two elements, and will be in the order that the
+	// descriptors were given.
+	Descriptors []D
+}
+
+// CollectDescriptors returns all descriptors of type D within the given Files.
+//
+// D may be any protoreflect descriptor interface, for example protoreflect.MessageDescriptor,
+// protoreflect.FieldDescriptor, protoreflect.ServiceDescriptor, or protoreflect.Descriptor for
+// all descriptors. Nested messages, enums, and extensions are included.
+//
+// Files are visited in order of their path, and descriptors are returned in declaration order
+// within each File, so the result is deterministic.
+//
+// Imports are filtered by default. Use CollectDescriptorsWithImports to include them.
+func CollectDescriptors[D protoreflect.Descriptor](files []check.File, options ...CollectDescriptorsOption) []D {
+	collectDescriptorsOptions := newCollectDescriptorsOptions()
+	for _, option := range options {
+		option(collectDescriptorsOptions)
+	}
+	var result []D
+	for _, file := range sortedFiles(files) {
+		if file.IsImport() && !collectDescriptorsOptions.includeImports {
+			continue
+		}
+		for descriptor := range descriptorsOfType[D](file.FileDescriptor()) {
+			result = append(result, descriptor)
+		}
+	}
+	return result
+}
+
+// CollectDescriptorsOption is an option for CollectDescriptors.
+type CollectDescriptorsOption func(*collectDescriptorsOptions)
+
+// CollectDescriptorsWithImports returns a new CollectDescriptorsOption that will result in
+// descriptors within imports being included.
+func CollectDescriptorsWithImports() CollectDescriptorsOption {
+	return func(collectDescriptorsOptions *collectDescriptorsOptions) {
+		collectDescriptorsOptions.includeImports = true
+	}
+}
+
+// FindDuplicatesByFullName returns the groups of descriptors that share the same full name.
+//
+// The returned Duplicates are sorted by key.
+func FindDuplicatesByFullName[D protoreflect.Descriptor](descriptors []D) []Duplicate[D] {
+	return FindDuplicates(
+		descriptors,
+		func(descriptor D) (string, bool) {
+			return string(descriptor.FullName()), true
+		},
+	)
+}
+
+// FindDuplicatesByName returns the groups of descriptors that share the same short name,
+// regardless of their package or parent.
+//
+// This is useful for rules such as "message names must be globally unique".
+//
+// The returned Duplicates are sorted by key.
+func FindDuplicatesByName[D protoreflect.Descriptor](descriptors []D) []Duplicate[D] {
+	return FindDuplicates(
+		descriptors,
+		func(descriptor D) (string, bool) {
+			return string(descriptor.Name()), true
+		},
+	)
+}
+
+// FindDuplicates returns the groups of descriptors that share the same key, as determined
+// by the key function.
+//
+// If the key function returns false, the descriptor is skipped. This allows for rules such as
+// "a custom option value must be unique across services" where not all services set the option.
+//
+// The returned Duplicates are sorted by key.
+func FindDuplicates[D protoreflect.Descriptor](descriptors []D, key func(D) (string, bool)) []Duplicate[D] {
+	keyToDescriptors := make(map[string][]D)
+	for _, descriptor := range descriptors {
+		k, ok := key(descriptor)
+		if !ok {
+			continue
+		}
+		keyToDescriptors[k] = append(keyToDescriptors[k], descriptor)
+	}
+	var duplicates []Duplicate[D]
+	for k, keyDescriptors := range keyToDescriptors {
+		if len(keyDescriptors) > 1 {
+			duplicates = append(
+				duplicates,
+				Duplicate[D]{
+					Key:         k,
+					Descriptors: keyDescriptors,
+				},
+			)
+		}
+	}
+	sort.Slice(duplicates, func(i int, j int) bool { return duplicates[i].Key < duplicates[j].Key })
+	return duplicates
+}
+
+// *** PRIVATE ***
+
+type collectDescriptorsOptions struct {
+	includeImports bool
+}
+
+func newCollectDescriptorsOptions() *collectDescriptorsOptions {
+	return &collectDescriptorsOptions{}
+}
+
+// sortedFiles returns a copy of the Files sorted by path.
+func sortedFiles(files []check.File) []check.File {
+	files = slices.Clone(files)
+	sort.Slice(
+		files,
+		func(i int, j int) bool {
+			return files[i].FileDescriptor().Path() < files[j].FileDescriptor().Path()
+		},
+	)
+	return files
+}
+
+// allDescriptors returns an iterator over the FileDescriptor and every descriptor within it,
+// in declaration order.
+func allDescriptors(fileDescriptor protoreflect.FileDescriptor) iter.Seq[protoreflect.Descriptor] {
+	return func(yield func(protoreflect.Descriptor) bool) {
+		_ = yieldFileDescriptor(fileDescriptor, yield)
+	}
+}
+
+// descriptorsOfType returns an iterator over every descriptor of type D within the FileDescriptor,
+// in declaration order.
+func descriptorsOfType[D protoreflect.Descriptor](fileDescriptor protoreflect.FileDescriptor) iter.Seq[D] {
+	return func(yield func(D) bool) {
+		for descriptor := range allDescriptors(fileDescriptor) {
+			if d, ok := descriptor.(D); ok {
+				if !yield(d) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// The yield functions below return false if iteration should stop.
+
+func yieldFileDescriptor(fileDescriptor protoreflect.FileDescriptor, yield func(protoreflect.Descriptor) bool) bool {
+	if !yield(fileDescriptor) {
+		return false
+	}
+	if !yieldMessageDescriptors(fileDescriptor.Messages(), yield) {
+		return false
+	}
+	if !yieldEnumDescriptors(fileDescriptor.Enums(), yield) {
+		return false
+	}
+	if !yieldExtensionDescriptors(fileDescriptor.Extensions(), yield) {
+		return false
+	}
+	services := fileDescriptor.Services()
+	for i := range services.Len() {
+		serviceDescriptor := services.Get(i)
+		if !yield(serviceDescriptor) {
+			return false
+		}
+		methods := serviceDescriptor.Methods()
+		for j := range methods.Len() {
+			if !yield(methods.Get(j)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func yieldMessageDescriptors(messages protoreflect.MessageDescriptors, yield func(protoreflect.Descriptor) bool) bool {
+	for i := range messages.Len() {
+		messageDescriptor := messages.Get(i)
+		if !yield(messageDescriptor) {
+			return false
+		}
+		fields := messageDescriptor.Fields()
+		for j := range fields.Len() {
+			if !yield(fields.Get(j)) {
+				return false
+			}
+		}
+		oneofs := messageDescriptor.Oneofs()
+		for j := range oneofs.Len() {
+			if !yield(oneofs.Get(j)) {
+				return false
+			}
+		}
+		if !yieldMessageDescriptors(messageDescriptor.Messages(), yield) {
+			return false
+		}
+		if !yieldEnumDescriptors(messageDescriptor.Enums(), yield) {
+			return false
+		}
+		if !yieldExtensionDescriptors(messageDescriptor.Extensions(), yield) {
+			return false
+		}
+	}
+	return true
+}
+
+func yieldEnumDescriptors(enums protoreflect.EnumDescriptors, yield func(protoreflect.Descriptor) bool) bool {
+	for i := range enums.Len() {
+		enumDescriptor := enums.Get(i)
+		if !yield(enumDescriptor) {
+			return false
+		}
+		values := enumDescriptor.Values()
+		for j := range values.Len() {
+			if !yield(values.Get(j)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func yieldExtensionDescriptors(extensions protoreflect.ExtensionDescriptors, yield func(protoreflect.Descriptor) bool) bool {
+	for i := range extensions.Len() {
+		if !yield(extensions.Get(i)) {
+			return false
+		}
+	}
+	return true
+}
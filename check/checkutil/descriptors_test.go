@@ -0,0 +1,222 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checktest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestCollectDescriptors(t *testing.T) {
+	t.Parallel()
+
+	files := testDescriptorsFiles(t)
+	testCases := []struct {
+		description       string
+		fullNames         []protoreflect.FullName
+		expectedFullNames []protoreflect.FullName
+	}{
+		{
+			description: "messages include nested messages and map entries",
+			fullNames:   testDescriptorFullNames(CollectDescriptors[protoreflect.MessageDescriptor](files)),
+			expectedFullNames: []protoreflect.FullName{
+				"a.Foo",
+				"a.Foo.LabelsEntry",
+				"a.Foo.Bar",
+				"b.Bar",
+			},
+		},
+		{
+			description: "fields include map entry fields and extensions",
+			fullNames:   testDescriptorFullNames(CollectDescriptors[protoreflect.FieldDescriptor](files)),
+			expectedFullNames: []protoreflect.FullName{
+				"a.Foo.name",
+				"a.Foo.labels",
+				"a.Foo.LabelsEntry.key",
+				"a.Foo.LabelsEntry.value",
+				"a.Foo.Bar.name",
+				"a.Foo.nested_ext",
+				"a.ext",
+				"b.Bar.name",
+			},
+		},
+		{
+			description: "enum values of nested enums are scoped to the parent message",
+			fullNames:   testDescriptorFullNames(CollectDescriptors[protoreflect.EnumValueDescriptor](files)),
+			expectedFullNames: []protoreflect.FullName{
+				"a.Foo.KIND_UNSPECIFIED",
+				"b.KIND_UNSPECIFIED",
+			},
+		},
+		{
+			description: "methods",
+			fullNames:   testDescriptorFullNames(CollectDescriptors[protoreflect.MethodDescriptor](files)),
+			expectedFullNames: []protoreflect.FullName{
+				"a.FooService.GetFoo",
+			},
+		},
+		{
+			description: "all descriptors of a file in declaration order",
+			fullNames:   testDescriptorFullNames(CollectDescriptors[protoreflect.Descriptor](files[:1])),
+			expectedFullNames: []protoreflect.FullName{
+				// The full name of a FileDescriptor is its package.
+				"a",
+				"a.Foo",
+				"a.Foo.name",
+				"a.Foo.labels",
+				"a.Foo.LabelsEntry",
+				"a.Foo.LabelsEntry.key",
+				"a.Foo.LabelsEntry.value",
+				"a.Foo.Bar",
+				"a.Foo.Bar.name",
+				"a.Foo.Kind",
+				"a.Foo.KIND_UNSPECIFIED",
+				"a.Foo.nested_ext",
+				"a.ext",
+				"a.FooService",
+				"a.FooService.GetFoo",
+			},
+		},
+		{
+			description: "imports are included with CollectDescriptorsWithImports",
+			fullNames: testDescriptorFullNames(
+				CollectDescriptors[protoreflect.MessageDescriptor](files, CollectDescriptorsWithImports()),
+			),
+			expectedFullNames: []protoreflect.FullName{
+				"a.Foo",
+				"a.Foo.LabelsEntry",
+				"a.Foo.Bar",
+				"b.Bar",
+				"imp.Base",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.expectedFullNames, testCase.fullNames, testCase.description)
+	}
+
+	// Iteration stops when yield returns false.
+	var fullNames []protoreflect.FullName
+	for descriptor := range descriptorsOfType[protoreflect.FieldDescriptor](files[0].FileDescriptor()) {
+		fullNames = append(fullNames, descriptor.FullName())
+		if len(fullNames) == 2 {
+			break
+		}
+	}
+	require.Equal(t, []protoreflect.FullName{"a.Foo.name", "a.Foo.labels"}, fullNames)
+}
+
+func TestFindDuplicates(t *testing.T) {
+	t.Parallel()
+
+	files := testDescriptorsFiles(t)
+	messageDescriptors := CollectDescriptors[protoreflect.MessageDescriptor](files)
+	enumDescriptors := CollectDescriptors[protoreflect.EnumDescriptor](files)
+	fieldDescriptors := CollectDescriptors[protoreflect.FieldDescriptor](files)
+	testCases := []struct {
+		description        string
+		duplicates         []Duplicate[protoreflect.Descriptor]
+		expectedDuplicates map[string][]protoreflect.FullName
+	}{
+		{
+			description: "nested and top-level messages with the same name",
+			duplicates:  testToDescriptorDuplicates(FindDuplicatesByName(messageDescriptors)),
+			expectedDuplicates: map[string][]protoreflect.FullName{
+				"Bar": {"a.Foo.Bar", "b.Bar"},
+			},
+		},
+		{
+			description: "nested and top-level enums with the same name",
+			duplicates:  testToDescriptorDuplicates(FindDuplicatesByName(enumDescriptors)),
+			expectedDuplicates: map[string][]protoreflect.FullName{
+				"Kind": {"a.Foo.Kind", "b.Kind"},
+			},
+		},
+		{
+			description:        "distinct full names",
+			duplicates:         testToDescriptorDuplicates(FindDuplicatesByFullName(messageDescriptors)),
+			expectedDuplicates: map[string][]protoreflect.FullName{},
+		},
+		{
+			description: "the same descriptors given twice",
+			duplicates: testToDescriptorDuplicates(
+				FindDuplicatesByFullName(append(messageDescriptors[:1:1], messageDescriptors[0])),
+			),
+			expectedDuplicates: map[string][]protoreflect.FullName{
+				"a.Foo": {"a.Foo", "a.Foo"},
+			},
+		},
+		{
+			description: "fields with the same name, skipping extensions",
+			duplicates: testToDescriptorDuplicates(
+				FindDuplicates(
+					fieldDescriptors,
+					func(fieldDescriptor protoreflect.FieldDescriptor) (string, bool) {
+						return string(fieldDescriptor.Name()), !fieldDescriptor.IsExtension()
+					},
+				),
+			),
+			expectedDuplicates: map[string][]protoreflect.FullName{
+				"name": {"a.Foo.name", "a.Foo.Bar.name", "b.Bar.name"},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		duplicates := make(map[string][]protoreflect.FullName)
+		var keys []string
+		for _, duplicate := range testCase.duplicates {
+			keys = append(keys, duplicate.Key)
+			duplicates[duplicate.Key] = testDescriptorFullNames(duplicate.Descriptors)
+		}
+		require.IsIncreasing(t, keys, testCase.description)
+		require.Equal(t, testCase.expectedDuplicates, duplicates, testCase.description)
+	}
+}
+
+// testDescriptorsFiles returns the Files for testdata/descriptors, sorted by path.
+//
+// imp.proto is an import.
+func testDescriptorsFiles(t *testing.T) []check.File {
+	files, err := (&checktest.ProtoFileSpec{
+		DirPaths:  []string{"testdata/descriptors"},
+		FilePaths: []string{"a.proto", "b.proto"},
+	}).ToFiles(context.Background())
+	require.NoError(t, err)
+	return sortedFiles(files)
+}
+
+func testDescriptorFullNames[D protoreflect.Descriptor](descriptors []D) []protoreflect.FullName {
+	fullNames := make([]protoreflect.FullName, len(descriptors))
+	for i, descriptor := range descriptors {
+		fullNames[i] = descriptor.FullName()
+	}
+	return fullNames
+}
+
+func testToDescriptorDuplicates[D protoreflect.Descriptor](duplicates []Duplicate[D]) []Duplicate[protoreflect.Descriptor] {
+	descriptorDuplicates := make([]Duplicate[protoreflect.Descriptor], len(duplicates))
+	for i, duplicate := range duplicates {
+		descriptorDuplicates[i].Key = duplicate.Key
+		for _, descriptor := range duplicate.Descriptors {
+			descriptorDuplicates[i].Descriptors = append(descriptorDuplicates[i].Descriptors, descriptor)
+		}
+	}
+	return descriptorDuplicates
+}
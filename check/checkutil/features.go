@@ -0,0 +1,205 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var editionToDefaultFeatureSet = map[descriptorpb.Edition]*descriptorpb.FeatureSet{
+	descriptorpb.Edition_EDITION_PROTO2: {
+		FieldPresence:         descriptorpb.FeatureSet_EXPLICIT.Enum(),
+		EnumType:              descriptorpb.FeatureSet_CLOSED.Enum(),
+		RepeatedFieldEncoding: descriptorpb.FeatureSet_EXPANDED.Enum(),
+		Utf8Validation:        descriptorpb.FeatureSet_NONE.Enum(),
+		MessageEncoding:       descriptorpb.FeatureSet_LENGTH_PREFIXED.Enum(),
+		JsonFormat:            descriptorpb.FeatureSet_LEGACY_BEST_EFFORT.Enum(),
+	},
+	descriptorpb.Edition_EDITION_PROTO3: {
+		FieldPresence:         descriptorpb.FeatureSet_IMPLICIT.Enum(),
+		EnumType:              descriptorpb.FeatureSet_OPEN.Enum(),
+		RepeatedFieldEncoding: descriptorpb.FeatureSet_PACKED.Enum(),
+		Utf8Validation:        descriptorpb.FeatureSet_VERIFY.Enum(),
+		MessageEncoding:       descriptorpb.FeatureSet_LENGTH_PREFIXED.Enum(),
+		JsonFormat:            descriptorpb.FeatureSet_ALLOW.Enum(),
+	},
+	descriptorpb.Edition_EDITION_2023: {
+		FieldPresence:         descriptorpb.FeatureSet_EXPLICIT.Enum(),
+		EnumType:              descriptorpb.FeatureSet_OPEN.Enum(),
+		RepeatedFieldEncoding: descriptorpb.FeatureSet_PACKED.Enum(),
+		Utf8Validation:        descriptorpb.FeatureSet_VERIFY.Enum(),
+		MessageEncoding:       descriptorpb.FeatureSet_LENGTH_PREFIXED.Enum(),
+		JsonFormat:            descriptorpb.FeatureSet_ALLOW.Enum(),
+	},
+}
+
+// Edition returns the edition of the given File.
+//
+// Files with syntax "proto2" (or no syntax) return EDITION_PROTO2, and files with syntax "proto3"
+// return EDITION_PROTO3. Files that use editions return their declared edition.
+func Edition(fileDescriptor protoreflect.FileDescriptor) descriptorpb.Edition {
+	switch fileDescriptor.Syntax() {
+	case protoreflect.Proto3:
+		return descriptorpb.Edition_EDITION_PROTO3
+	case protoreflect.Editions:
+		if editionFileDescriptor, ok := fileDescriptor.(editionFileDescriptor); ok {
+			return descriptorpb.Edition(editionFileDescriptor.Edition())
+		}
+		// FileDescriptors that were not created by protobuf-go do not expose their edition,
+		// so we fall back to converting the whole File.
+		return protodesc.ToFileDescriptorProto(fileDescriptor).GetEdition()
+	default:
+		return descriptorpb.Edition_EDITION_PROTO2
+	}
+}
+
+// ResolveFeatures returns the effective features for the given descriptor.
+//
+// The defaults for the File's edition are merged with any features explicitly set on the
+// File and each enclosing descriptor, with the most specific setting winning. Fields within
+// a oneof inherit the features of the oneof. All fields of the returned FeatureSet are set.
+//
+// Note that the returned FeatureSet reflects the declared features. Some properties of a field are
+// not governed by features alone, for example message fields always have explicit presence, and
+// repeated fields never have presence. Use FieldPresence, RepeatedFieldEncoding, MessageEncoding,
+// and EnumType for the semantics that are actually applied.
+//
+// Returns error if the File's edition is not known.
+func ResolveFeatures(descriptor protoreflect.Descriptor) (*descriptorpb.FeatureSet, error) {
+	fileDescriptor := descriptor.ParentFile()
+	if fileDescriptor == nil {
+		return nil, fmt.Errorf("no file for descriptor %q", descriptor.FullName())
+	}
+	edition := Edition(fileDescriptor)
+	defaultFeatureSet, ok := editionToDefaultFeatureSet[edition]
+	if !ok {
+		return nil, fmt.Errorf("unknown edition %v for file %q", edition, fileDescriptor.Path())
+	}
+	featureSet, ok := proto.Clone(defaultFeatureSet).(*descriptorpb.FeatureSet)
+	if !ok {
+		// This should never happen.
+		return nil, fmt.Errorf("unexpected type from proto.Clone: %T", featureSet)
+	}
+	// Features are only ever explicitly set in files using editions.
+	if fileDescriptor.Syntax() != protoreflect.Editions {
+		return featureSet, nil
+	}
+	for _, chainDescriptor := range featureChain(descriptor) {
+		if explicitFeatureSet := explicitFeatureSetForDescriptor(chainDescriptor); explicitFeatureSet != nil {
+			proto.Merge(featureSet, explicitFeatureSet)
+		}
+	}
+	return featureSet, nil
+}
+
+// FieldPresence returns the effective field presence of the given field.
+//
+// Required fields return LEGACY_REQUIRED, fields that distinguish between unpopulated and
+// default values return EXPLICIT, and all other fields (including repeated fields) return IMPLICIT.
+func FieldPresence(fieldDescriptor protoreflect.FieldDescriptor) descriptorpb.FeatureSet_FieldPresence {
+	switch {
+	case fieldDescriptor.Cardinality() == protoreflect.Required:
+		return descriptorpb.FeatureSet_LEGACY_REQUIRED
+	case fieldDescriptor.HasPresence():
+		return descriptorpb.FeatureSet_EXPLICIT
+	default:
+		return descriptorpb.FeatureSet_IMPLICIT
+	}
+}
+
+// RepeatedFieldEncoding returns the effective repeated field encoding of the given field.
+//
+// Only repeated scalar numeric fields can be PACKED. All other fields return EXPANDED.
+func RepeatedFieldEncoding(fieldDescriptor protoreflect.FieldDescriptor) descriptorpb.FeatureSet_RepeatedFieldEncoding {
+	if fieldDescriptor.IsPacked() {
+		return descriptorpb.FeatureSet_PACKED
+	}
+	return descriptorpb.FeatureSet_EXPANDED
+}
+
+// MessageEncoding returns the effective message encoding of the given field.
+//
+// Groups in proto2 and fields with the DELIMITED message encoding under editions return DELIMITED.
+// All other fields return LENGTH_PREFIXED.
+func MessageEncoding(fieldDescriptor protoreflect.FieldDescriptor) descriptorpb.FeatureSet_MessageEncoding {
+	if fieldDescriptor.Kind() == protoreflect.GroupKind {
+		return descriptorpb.FeatureSet_DELIMITED
+	}
+	return descriptorpb.FeatureSet_LENGTH_PREFIXED
+}
+
+// EnumType returns the effective enum type of the given enum.
+func EnumType(enumDescriptor protoreflect.EnumDescriptor) descriptorpb.FeatureSet_EnumType {
+	if enumDescriptor.IsClosed() {
+		return descriptorpb.FeatureSet_CLOSED
+	}
+	return descriptorpb.FeatureSet_OPEN
+}
+
+// *** PRIVATE ***
+
+// editionFileDescriptor is implemented by the FileDescriptors created by protobuf-go, but is
+// not part of protoreflect.FileDescriptor.
+type editionFileDescriptor interface {
+	Edition() int32
+}
+
+// featureChain returns the descriptors from the File down to the given descriptor, in the
+// order that their features should be merged.
+func featureChain(descriptor protoreflect.Descriptor) []protoreflect.Descriptor {
+	var chain []protoreflect.Descriptor
+	for current := descriptor; current != nil; current = current.Parent() {
+		chain = append(chain, current)
+		// Fields within a oneof inherit the features of the oneof, which is
+		// then parented by the message.
+		if fieldDescriptor, ok := current.(protoreflect.FieldDescriptor); ok {
+			if oneofDescriptor := fieldDescriptor.ContainingOneof(); oneofDescriptor != nil {
+				chain = append(chain, oneofDescriptor)
+			}
+		}
+	}
+	// Reverse so that we go from least specific to most specific.
+	slices.Reverse(chain)
+	return chain
+}
+
+func explicitFeatureSetForDescriptor(descriptor protoreflect.Descriptor) *descriptorpb.FeatureSet {
+	switch options := descriptor.Options().(type) {
+	case *descriptorpb.FileOptions:
+		return options.GetFeatures()
+	case *descriptorpb.MessageOptions:
+		return options.GetFeatures()
+	case *descriptorpb.FieldOptions:
+		return options.GetFeatures()
+	case *descriptorpb.OneofOptions:
+		return options.GetFeatures()
+	case *descriptorpb.EnumOptions:
+		return options.GetFeatures()
+	case *descriptorpb.EnumValueOptions:
+		return options.GetFeatures()
+	case *descriptorpb.ServiceOptions:
+		return options.GetFeatures()
+	case *descriptorpb.MethodOptions:
+		return options.GetFeatures()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check/checktest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestResolveFeaturesEditions(t *testing.T) {
+	t.Parallel()
+
+	files, err := (&checktest.ProtoFileSpec{
+		DirPaths:  []string{"testdata/features"},
+		FilePaths: []string{"editions.proto"},
+	}).ToFiles(context.Background())
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	fileDescriptor := files[0].FileDescriptor()
+	require.Equal(t, descriptorpb.Edition_EDITION_2023, Edition(fileDescriptor))
+	// The edition is read from the FileDescriptor without converting it to a FileDescriptorProto.
+	_, ok := fileDescriptor.(editionFileDescriptor)
+	require.True(t, ok)
+
+	fields := fileDescriptor.Messages().ByName("Foo").Fields()
+
+	implicitFeatureSet, err := ResolveFeatures(fields.ByName("implicit"))
+	require.NoError(t, err)
+	require.Equal(t, descriptorpb.FeatureSet_IMPLICIT, implicitFeatureSet.GetFieldPresence())
+	require.Equal(t, descriptorpb.FeatureSet_OPEN, implicitFeatureSet.GetEnumType())
+	require.Equal(t, descriptorpb.FeatureSet_IMPLICIT, FieldPresence(fields.ByName("implicit")))
+
+	explicitFeatureSet, err := ResolveFeatures(fields.ByName("explicit"))
+	require.NoError(t, err)
+	require.Equal(t, descriptorpb.FeatureSet_EXPLICIT, explicitFeatureSet.GetFieldPresence())
+	require.Equal(t, descriptorpb.FeatureSet_EXPLICIT, FieldPresence(fields.ByName("explicit")))
+
+	require.Equal(t, descriptorpb.FeatureSet_EXPANDED, RepeatedFieldEncoding(fields.ByName("expanded")))
+	require.Equal(t, descriptorpb.FeatureSet_PACKED, RepeatedFieldEncoding(fields.ByName("packed")))
+	require.Equal(t, descriptorpb.FeatureSet_DELIMITED, MessageEncoding(fields.ByName("delimited")))
+	require.Equal(t, descriptorpb.FeatureSet_CLOSED, EnumType(fileDescriptor.Enums().ByName("Closed")))
+
+	closedFeatureSet, err := ResolveFeatures(fileDescriptor.Enums().ByName("Closed").Values().Get(0))
+	require.NoError(t, err)
+	require.Equal(t, descriptorpb.FeatureSet_CLOSED, closedFeatureSet.GetEnumType())
+}
+
+func TestResolveFeaturesSyntax(t *testing.T) {
+	t.Parallel()
+
+	files, err := (&checktest.ProtoFileSpec{
+		DirPaths:  []string{"testdata/importgraph"},
+		FilePaths: []string{"c.proto"},
+	}).ToFiles(context.Background())
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	fileDescriptor := files[0].FileDescriptor()
+	require.Equal(t, descriptorpb.Edition_EDITION_PROTO3, Edition(fileDescriptor))
+	featureSet, err := ResolveFeatures(fileDescriptor.Messages().ByName("C"))
+	require.NoError(t, err)
+	require.Equal(t, descriptorpb.FeatureSet_IMPLICIT, featureSet.GetFieldPresence())
+	require.Equal(t, descriptorpb.FeatureSet_PACKED, featureSet.GetRepeatedFieldEncoding())
+}
@@ -0,0 +1,252 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/bufbuild/bufplugin-go/check"
+)
+
+// Import is a single import statement within a File.
+type Import struct {
+	// FileName is the name of the imported File.
+	FileName string
+	// IsPublic denotes whether the import is a public import.
+	IsPublic bool
+	// IsWeak denotes whether the import is a weak import.
+	IsWeak bool
+	// IsUnused denotes whether the import is not used by the importing File.
+	//
+	// This is derived from File.UnusedDependencyIndexes.
+	IsUnused bool
+}
+
+// ImportGraph is the graph of imports between a set of Files.
+//
+// All file names are the paths of the Files, that is FileDescriptor().Path().
+// All returned slices of file names are sorted.
+//
+// This is typically built once per RuleHandler invocation via NewImportGraph(request.Files()),
+// so that rules about layering (for example, "api files must not import internal files") can
+// be written over the graph instead of ad-hoc traversal.
+type ImportGraph interface {
+	// FileNames returns the names of all Files in the graph.
+	FileNames() []string
+	// File returns the File for the given file name, if it is in the graph.
+	File(fileName string) (check.File, bool)
+	// Imports returns the direct imports of the given file, in declaration order.
+	//
+	// Returns nil if the file is not in the graph.
+	Imports(fileName string) []Import
+	// UnusedImports returns the names of the directly-imported files that are not used by the given file.
+	UnusedImports(fileName string) []string
+	// Dependencies returns the names of the files directly imported by the given file.
+	Dependencies(fileName string) []string
+	// TransitiveDependencies returns the names of all files directly or indirectly imported by the given file.
+	//
+	// The given file is never included.
+	TransitiveDependencies(fileName string) []string
+	// Dependents returns the names of the files that directly import the given file.
+	Dependents(fileName string) []string
+	// TransitiveDependents returns the names of all files that directly or indirectly import the given file.
+	//
+	// The given file is never included.
+	TransitiveDependents(fileName string) []string
+	// ImportPath returns the shortest chain of imports from one file to another, starting with
+	// from and ending with to.
+	//
+	// Returns nil if to is not reachable from from. This is useful for constructing
+	// messages for layering violations that are the result of transitive imports.
+	ImportPath(from string, to string) []string
+
+	isImportGraph()
+}
+
+// NewImportGraph returns a new ImportGraph for the given Files.
+//
+// Both imports and non-imports are included in the graph. Returns error if there
+// are duplicate file names.
+func NewImportGraph(files []check.File) (ImportGraph, error) {
+	return newImportGraph(files)
+}
+
+// *** PRIVATE ***
+
+type importGraph struct {
+	fileNameToFile       map[string]check.File
+	fileNameToImports    map[string][]Import
+	fileNameToDependents map[string][]string
+	fileNames            []string
+}
+
+func newImportGraph(files []check.File) (*importGraph, error) {
+	fileNameToFile := make(map[string]check.File, len(files))
+	fileNameToImports := make(map[string][]Import, len(files))
+	fileNameToDependentMap := make(map[string]map[string]struct{})
+	for _, file := range files {
+		fileDescriptor := file.FileDescriptor()
+		fileName := fileDescriptor.Path()
+		if _, ok := fileNameToFile[fileName]; ok {
+			return nil, fmt.Errorf("duplicate file name: %q", fileName)
+		}
+		fileNameToFile[fileName] = file
+		unusedDependencyIndexes := file.UnusedDependencyIndexes()
+		fileImports := fileDescriptor.Imports()
+		imports := make([]Import, fileImports.Len())
+		for i := range fileImports.Len() {
+			fileImport := fileImports.Get(i)
+			importFileName := fileImport.Path()
+			imports[i] = Import{
+				FileName: importFileName,
+				IsPublic: fileImport.IsPublic,
+				IsWeak:   fileImport.IsWeak,
+				IsUnused: slices.Contains(unusedDependencyIndexes, int32(i)),
+			}
+			dependentMap, ok := fileNameToDependentMap[importFileName]
+			if !ok {
+				dependentMap = make(map[string]struct{})
+				fileNameToDependentMap[importFileName] = dependentMap
+			}
+			dependentMap[fileName] = struct{}{}
+		}
+		fileNameToImports[fileName] = imports
+	}
+	fileNameToDependents := make(map[string][]string, len(fileNameToDependentMap))
+	for fileName, dependentMap := range fileNameToDependentMap {
+		dependents := make([]string, 0, len(dependentMap))
+		for dependent := range dependentMap {
+			dependents = append(dependents, dependent)
+		}
+		sort.Strings(dependents)
+		fileNameToDependents[fileName] = dependents
+	}
+	fileNames := make([]string, 0, len(fileNameToFile))
+	for fileName := range fileNameToFile {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+	return &importGraph{
+		fileNameToFile:       fileNameToFile,
+		fileNameToImports:    fileNameToImports,
+		fileNameToDependents: fileNameToDependents,
+		fileNames:            fileNames,
+	}, nil
+}
+
+func (i *importGraph) FileNames() []string {
+	return slices.Clone(i.fileNames)
+}
+
+func (i *importGraph) File(fileName string) (check.File, bool) {
+	file, ok := i.fileNameToFile[fileName]
+	return file, ok
+}
+
+func (i *importGraph) Imports(fileName string) []Import {
+	return slices.Clone(i.fileNameToImports[fileName])
+}
+
+func (i *importGraph) UnusedImports(fileName string) []string {
+	var unusedImports []string
+	for _, fileImport := range i.fileNameToImports[fileName] {
+		if fileImport.IsUnused {
+			unusedImports = append(unusedImports, fileImport.FileName)
+		}
+	}
+	sort.Strings(unusedImports)
+	return unusedImports
+}
+
+func (i *importGraph) Dependencies(fileName string) []string {
+	return i.dependencies(fileName)
+}
+
+func (i *importGraph) TransitiveDependencies(fileName string) []string {
+	return walkImportGraph(fileName, i.dependencies)
+}
+
+func (i *importGraph) Dependents(fileName string) []string {
+	return slices.Clone(i.fileNameToDependents[fileName])
+}
+
+func (i *importGraph) TransitiveDependents(fileName string) []string {
+	return walkImportGraph(fileName, i.Dependents)
+}
+
+func (i *importGraph) ImportPath(from string, to string) []string {
+	if from == to {
+		return []string{from}
+	}
+	// Breadth-first search so that we find the shortest path. We iterate over dependencies
+	// in sorted order so that the result is deterministic.
+	fileNameToParent := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		fileName := queue[0]
+		queue = queue[1:]
+		for _, dependency := range i.dependencies(fileName) {
+			if _, ok := fileNameToParent[dependency]; ok {
+				continue
+			}
+			fileNameToParent[dependency] = fileName
+			if dependency == to {
+				path := []string{to}
+				for parent := fileName; parent != ""; parent = fileNameToParent[parent] {
+					path = append(path, parent)
+				}
+				slices.Reverse(path)
+				return path
+			}
+			queue = append(queue, dependency)
+		}
+	}
+	return nil
+}
+
+func (i *importGraph) dependencies(fileName string) []string {
+	imports := i.fileNameToImports[fileName]
+	if len(imports) == 0 {
+		return nil
+	}
+	dependencies := make([]string, len(imports))
+	for j, fileImport := range imports {
+		dependencies[j] = fileImport.FileName
+	}
+	sort.Strings(dependencies)
+	return dependencies
+}
+
+func (*importGraph) isImportGraph() {}
+
+func walkImportGraph(fileName string, next func(string) []string) []string {
+	seen := map[string]struct{}{fileName: {}}
+	var result []string
+	stack := next(fileName)
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := seen[current]; ok {
+			continue
+		}
+		seen[current] = struct{}{}
+		result = append(result, current)
+		stack = append(stack, next(current)...)
+	}
+	sort.Strings(result)
+	return result
+}
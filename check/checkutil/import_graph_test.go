@@ -0,0 +1,55 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check/checktest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportGraph(t *testing.T) {
+	t.Parallel()
+
+	files, err := (&checktest.ProtoFileSpec{
+		DirPaths:  []string{"testdata/importgraph"},
+		FilePaths: []string{"a.proto"},
+	}).ToFiles(context.Background())
+	require.NoError(t, err)
+	importGraph, err := NewImportGraph(files)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"a.proto", "b.proto", "c.proto"}, importGraph.FileNames())
+	require.Equal(
+		t,
+		[]Import{
+			{FileName: "b.proto"},
+			{FileName: "c.proto", IsUnused: true},
+		},
+		importGraph.Imports("a.proto"),
+	)
+	require.Equal(t, []string{"c.proto"}, importGraph.UnusedImports("a.proto"))
+	require.Empty(t, importGraph.UnusedImports("b.proto"))
+	require.Equal(t, []string{"b.proto", "c.proto"}, importGraph.Dependencies("a.proto"))
+	require.Equal(t, []string{"c.proto"}, importGraph.TransitiveDependencies("b.proto"))
+	require.Equal(t, []string{"a.proto", "b.proto"}, importGraph.Dependents("c.proto"))
+	require.Equal(t, []string{"a.proto", "b.proto"}, importGraph.TransitiveDependents("c.proto"))
+	require.Empty(t, importGraph.TransitiveDependents("a.proto"))
+	require.Equal(t, []string{"a.proto", "c.proto"}, importGraph.ImportPath("a.proto", "c.proto"))
+	require.Equal(t, []string{"b.proto", "c.proto"}, importGraph.ImportPath("b.proto", "c.proto"))
+	require.Nil(t, importGraph.ImportPath("c.proto", "a.proto"))
+}
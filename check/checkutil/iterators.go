@@ -0,0 +1,124 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"iter"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// NonImportFiles returns an iterator over the Files of the Request that are not imports.
+//
+// This is the standard case for lint rules.
+func NonImportFiles(request check.Request) iter.Seq[check.File] {
+	return func(yield func(check.File) bool) {
+		for file := range request.AllFiles() {
+			if file.IsImport() {
+				continue
+			}
+			if !yield(file) {
+				return
+			}
+		}
+	}
+}
+
+// Descriptors returns an iterator over the FileDescriptor of the File and every descriptor
+// within it, in declaration order.
+//
+// Descriptors are visited lazily, so breaking out of the loop stops the traversal.
+func Descriptors(file check.File) iter.Seq[protoreflect.Descriptor] {
+	return allDescriptors(file.FileDescriptor())
+}
+
+// Messages returns an iterator over every message within the File, including nested messages.
+func Messages(file check.File) iter.Seq[protoreflect.MessageDescriptor] {
+	return descriptorsOfType[protoreflect.MessageDescriptor](file.FileDescriptor())
+}
+
+// Fields returns an iterator over every field of the messages within the File.
+//
+// Extensions are not included, see Extensions.
+func Fields(file check.File) iter.Seq[protoreflect.FieldDescriptor] {
+	return func(yield func(protoreflect.FieldDescriptor) bool) {
+		for fieldDescriptor := range descriptorsOfType[protoreflect.FieldDescriptor](file.FileDescriptor()) {
+			if fieldDescriptor.IsExtension() {
+				continue
+			}
+			if !yield(fieldDescriptor) {
+				return
+			}
+		}
+	}
+}
+
+// Oneofs returns an iterator over every oneof of the messages within the File.
+func Oneofs(file check.File) iter.Seq[protoreflect.OneofDescriptor] {
+	return descriptorsOfType[protoreflect.OneofDescriptor](file.FileDescriptor())
+}
+
+// RealOneofs returns an iterator over every oneof of the messages within the File that is
+// not synthetic.
+//
+// Synthetic oneofs are created for proto3 fields with the optional keyword, see
+// IsProto3Optional.
+func RealOneofs(file check.File) iter.Seq[protoreflect.OneofDescriptor] {
+	return func(yield func(protoreflect.OneofDescriptor) bool) {
+		for oneofDescriptor := range Oneofs(file) {
+			if oneofDescriptor.IsSynthetic() {
+				continue
+			}
+			if !yield(oneofDescriptor) {
+				return
+			}
+		}
+	}
+}
+
+// Enums returns an iterator over every enum within the File, including nested enums.
+func Enums(file check.File) iter.Seq[protoreflect.EnumDescriptor] {
+	return descriptorsOfType[protoreflect.EnumDescriptor](file.FileDescriptor())
+}
+
+// EnumValues returns an iterator over every value of the enums within the File.
+func EnumValues(file check.File) iter.Seq[protoreflect.EnumValueDescriptor] {
+	return descriptorsOfType[protoreflect.EnumValueDescriptor](file.FileDescriptor())
+}
+
+// Services returns an iterator over every service within the File.
+func Services(file check.File) iter.Seq[protoreflect.ServiceDescriptor] {
+	return descriptorsOfType[protoreflect.ServiceDescriptor](file.FileDescriptor())
+}
+
+// Methods returns an iterator over every method of the services within the File.
+func Methods(file check.File) iter.Seq[protoreflect.MethodDescriptor] {
+	return descriptorsOfType[protoreflect.MethodDescriptor](file.FileDescriptor())
+}
+
+// Extensions returns an iterator over every extension within the File, including nested extensions.
+func Extensions(file check.File) iter.Seq[protoreflect.ExtensionDescriptor] {
+	return func(yield func(protoreflect.ExtensionDescriptor) bool) {
+		for fieldDescriptor := range descriptorsOfType[protoreflect.FieldDescriptor](file.FileDescriptor()) {
+			if !fieldDescriptor.IsExtension() {
+				continue
+			}
+			if !yield(fieldDescriptor) {
+				return
+			}
+		}
+	}
+}
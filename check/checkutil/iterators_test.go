@@ -0,0 +1,87 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"iter"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checktest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestIterators(t *testing.T) {
+	t.Parallel()
+
+	files, err := (&checktest.ProtoFileSpec{
+		DirPaths:  []string{"testdata/symbolindex"},
+		FilePaths: []string{"a.proto"},
+	}).ToFiles(context.Background())
+	require.NoError(t, err)
+	request, err := check.NewRequest(files)
+	require.NoError(t, err)
+
+	var nonImportFiles []check.File
+	for file := range NonImportFiles(request) {
+		nonImportFiles = append(nonImportFiles, file)
+	}
+	require.Len(t, nonImportFiles, 1)
+	require.Equal(t, "a.proto", nonImportFiles[0].FileDescriptor().Path())
+
+	var bFile check.File
+	for file := range request.AllFiles() {
+		if file.FileDescriptor().Path() == "b.proto" {
+			bFile = file
+			break
+		}
+	}
+	require.NotNil(t, bFile)
+
+	require.Equal(t, []protoreflect.FullName{"a.A.b", "a.A.e"}, testFullNames(Fields(nonImportFiles[0])))
+	require.Equal(t, []protoreflect.FullName{"b.B"}, testFullNames(Messages(bFile)))
+	require.Empty(t, testFullNames(Fields(bFile)))
+	require.Empty(t, testFullNames(Oneofs(bFile)))
+	require.Equal(t, []protoreflect.FullName{"b.E"}, testFullNames(Enums(bFile)))
+	require.Equal(t, []protoreflect.FullName{"b.E_UNSPECIFIED"}, testFullNames(EnumValues(bFile)))
+	require.Equal(t, []protoreflect.FullName{"b.S"}, testFullNames(Services(bFile)))
+	require.Equal(t, []protoreflect.FullName{"b.S.M"}, testFullNames(Methods(bFile)))
+	require.Equal(t, []protoreflect.FullName{"b.ext"}, testFullNames(Extensions(bFile)))
+	require.Equal(
+		t,
+		[]protoreflect.FullName{"b", "b.B", "b.E", "b.E_UNSPECIFIED", "b.ext", "b.S", "b.S.M"},
+		testFullNames(Descriptors(bFile)),
+	)
+
+	// Breaking out of the loop stops the traversal.
+	var visited int
+	for range Descriptors(bFile) {
+		visited++
+		if visited == 2 {
+			break
+		}
+	}
+	require.Equal(t, 2, visited)
+}
+
+func testFullNames[D protoreflect.Descriptor](seq iter.Seq[D]) []protoreflect.FullName {
+	var fullNames []protoreflect.FullName
+	for descriptor := range seq {
+		fullNames = append(fullNames, descriptor.FullName())
+	}
+	return fullNames
+}
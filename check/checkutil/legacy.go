@@ -0,0 +1,169 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"iter"
+	"slices"
+	"strconv"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// LegacyConstructKindGroup is a group field.
+	//
+	// This includes groups in proto2, and fields with the DELIMITED message encoding under
+	// editions. See MessageEncoding.
+	LegacyConstructKindGroup LegacyConstructKind = 1
+	// LegacyConstructKindRequired is a required field.
+	//
+	// This includes required fields in proto2, and fields with the LEGACY_REQUIRED field
+	// presence under editions. See FieldPresence.
+	LegacyConstructKindRequired LegacyConstructKind = 2
+	// LegacyConstructKindClosedEnum is a closed enum.
+	//
+	// This includes all enums in proto2, and enums with the CLOSED enum type under editions.
+	// See EnumType.
+	LegacyConstructKindClosedEnum LegacyConstructKind = 3
+
+	// The field numbers of label and type within FieldDescriptorProto.
+	fieldLabelSourcePathElement int32 = 4
+	fieldTypeSourcePathElement  int32 = 5
+)
+
+var (
+	legacyConstructKindToString = map[LegacyConstructKind]string{
+		LegacyConstructKindGroup:      "group",
+		LegacyConstructKindRequired:   "required",
+		LegacyConstructKindClosedEnum: "closed enum",
+	}
+)
+
+// LegacyConstructKind is a kind of legacy construct.
+type LegacyConstructKind int
+
+// String implements fmt.Stringer.
+func (k LegacyConstructKind) String() string {
+	if s, ok := legacyConstructKindToString[k]; ok {
+		return s
+	}
+	return strconv.Itoa(int(k))
+}
+
+// LegacyConstruct is a legacy construct within a File.
+//
+// Legacy constructs are constructs that are discouraged in new schemas, but are still
+// supported for compatibility, such as groups, required fields, and closed enums. These are
+// useful for modernization lint rules across codebases that mix proto2, proto3, and editions.
+type LegacyConstruct struct {
+	// Kind is the kind of legacy construct.
+	Kind LegacyConstructKind
+	// Descriptor is the field for LegacyConstructKindGroup and LegacyConstructKindRequired,
+	// and the enum for LegacyConstructKindClosedEnum.
+	Descriptor protoreflect.Descriptor
+	// SourcePath is the most specific source path for the legacy construct within the File of
+	// the Descriptor.
+	//
+	// For groups, this is the group keyword, and for required fields, this is the required
+	// label, if these exist within the source. Otherwise, this is the source path of the
+	// Descriptor. This is nil if the File has no source code info.
+	SourcePath protoreflect.SourcePath
+}
+
+// LocationOptions returns the AddAnnotationOptions that set the Location of an Annotation
+// to the legacy construct.
+func (l LegacyConstruct) LocationOptions() []check.AddAnnotationOption {
+	if len(l.SourcePath) == 0 {
+		return []check.AddAnnotationOption{
+			check.WithDescriptor(l.Descriptor),
+		}
+	}
+	return []check.AddAnnotationOption{
+		check.WithFileName(l.Descriptor.ParentFile().Path()),
+		check.WithSourcePath(l.SourcePath),
+	}
+}
+
+// LegacyConstructs returns an iterator over every legacy construct within the File, in
+// declaration order.
+//
+// A field may be both a LegacyConstructKindGroup and a LegacyConstructKindRequired, in which
+// case the group is returned first.
+func LegacyConstructs(file check.File) iter.Seq[LegacyConstruct] {
+	return func(yield func(LegacyConstruct) bool) {
+		for descriptor := range allDescriptors(file.FileDescriptor()) {
+			for _, legacyConstruct := range legacyConstructsForDescriptor(descriptor) {
+				if !yield(legacyConstruct) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// *** PRIVATE ***
+
+func legacyConstructsForDescriptor(descriptor protoreflect.Descriptor) []LegacyConstruct {
+	switch descriptor := descriptor.(type) {
+	case protoreflect.FieldDescriptor:
+		var legacyConstructs []LegacyConstruct
+		if descriptor.Kind() == protoreflect.GroupKind {
+			legacyConstructs = append(
+				legacyConstructs,
+				newLegacyConstruct(LegacyConstructKindGroup, descriptor, fieldTypeSourcePathElement),
+			)
+		}
+		if descriptor.Cardinality() == protoreflect.Required {
+			legacyConstructs = append(
+				legacyConstructs,
+				newLegacyConstruct(LegacyConstructKindRequired, descriptor, fieldLabelSourcePathElement),
+			)
+		}
+		return legacyConstructs
+	case protoreflect.EnumDescriptor:
+		if descriptor.IsClosed() {
+			return []LegacyConstruct{
+				newLegacyConstruct(LegacyConstructKindClosedEnum, descriptor),
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// newLegacyConstruct returns a new LegacyConstruct, with the SourcePath of the descriptor
+// suffixed with the given elements if a source location exists for this path.
+func newLegacyConstruct(
+	kind LegacyConstructKind,
+	descriptor protoreflect.Descriptor,
+	sourcePathElements ...int32,
+) LegacyConstruct {
+	sourceLocations := descriptor.ParentFile().SourceLocations()
+	sourcePath := sourceLocations.ByDescriptor(descriptor).Path
+	if len(sourcePath) > 0 && len(sourcePathElements) > 0 {
+		specificSourcePath := append(slices.Clone(sourcePath), sourcePathElements...)
+		if len(sourceLocations.ByPath(specificSourcePath).Path) > 0 {
+			sourcePath = specificSourcePath
+		}
+	}
+	return LegacyConstruct{
+		Kind:       kind,
+		Descriptor: descriptor,
+		SourcePath: sourcePath,
+	}
+}
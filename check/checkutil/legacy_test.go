@@ -0,0 +1,121 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checktest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestLegacyConstructs(t *testing.T) {
+	t.Parallel()
+
+	files, err := (&checktest.ProtoFileSpec{
+		DirPaths:  []string{"testdata/legacy"},
+		FilePaths: []string{"legacy.proto", "modern.proto"},
+	}).ToFiles(context.Background())
+	require.NoError(t, err)
+	fileNameToFile := make(map[string]check.File)
+	for _, file := range files {
+		fileNameToFile[file.FileDescriptor().Path()] = file
+	}
+	require.Equal(
+		t,
+		[]testLegacyConstruct{
+			{
+				Kind:       LegacyConstructKindRequired,
+				FullName:   "legacy.Foo.name",
+				SourcePath: protoreflect.SourcePath{4, 0, 2, 0, 4},
+			},
+			{
+				Kind:       LegacyConstructKindGroup,
+				FullName:   "legacy.Foo.bar",
+				SourcePath: protoreflect.SourcePath{4, 0, 2, 1, 5},
+			},
+			{
+				Kind:       LegacyConstructKindGroup,
+				FullName:   "legacy.Foo.baz",
+				SourcePath: protoreflect.SourcePath{4, 0, 2, 2, 5},
+			},
+			{
+				Kind:       LegacyConstructKindRequired,
+				FullName:   "legacy.Foo.baz",
+				SourcePath: protoreflect.SourcePath{4, 0, 2, 2, 4},
+			},
+			{
+				Kind:       LegacyConstructKindClosedEnum,
+				FullName:   "legacy.Closed",
+				SourcePath: protoreflect.SourcePath{5, 0},
+			},
+		},
+		testLegacyConstructs(fileNameToFile["legacy.proto"]),
+	)
+	require.Empty(t, testLegacyConstructs(fileNameToFile["modern.proto"]))
+	require.Equal(t, "closed enum", LegacyConstructKindClosedEnum.String())
+}
+
+func TestLegacyConstructsEditions(t *testing.T) {
+	t.Parallel()
+
+	files, err := (&checktest.ProtoFileSpec{
+		DirPaths:  []string{"testdata/features"},
+		FilePaths: []string{"editions.proto"},
+	}).ToFiles(context.Background())
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	// Under editions, there is no group keyword, so the SourcePath is that of the field.
+	require.Equal(
+		t,
+		[]testLegacyConstruct{
+			{
+				Kind:       LegacyConstructKindGroup,
+				FullName:   "features.Foo.delimited",
+				SourcePath: protoreflect.SourcePath{4, 0, 2, 4},
+			},
+			{
+				Kind:       LegacyConstructKindClosedEnum,
+				FullName:   "features.Closed",
+				SourcePath: protoreflect.SourcePath{5, 0},
+			},
+		},
+		testLegacyConstructs(files[0]),
+	)
+}
+
+type testLegacyConstruct struct {
+	Kind       LegacyConstructKind
+	FullName   protoreflect.FullName
+	SourcePath protoreflect.SourcePath
+}
+
+func testLegacyConstructs(file check.File) []testLegacyConstruct {
+	var result []testLegacyConstruct
+	for legacyConstruct := range LegacyConstructs(file) {
+		result = append(
+			result,
+			testLegacyConstruct{
+				Kind:       legacyConstruct.Kind,
+				FullName:   legacyConstruct.Descriptor.FullName(),
+				SourcePath: legacyConstruct.SourcePath,
+			},
+		)
+	}
+	return result
+}
@@ -0,0 +1,254 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checknaming"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// packageSourcePath is the source path of the package declaration within a FileDescriptorProto.
+var packageSourcePath = protoreflect.SourcePath{2}
+
+// NamingRuleConfig is the configuration for a naming Rule created with NewNamingRuleSpec.
+type NamingRuleConfig struct {
+	// Required.
+	ID          string
+	CategoryIDs []string
+	IsDefault   bool
+	// Purpose is the purpose of the Rule.
+	//
+	// If not set, a Purpose will be generated from the DescriptorKind and the Pattern or Convention.
+	Purpose string
+	// DescriptorKind is the kind of descriptor whose names are checked.
+	//
+	// For check.DescriptorKindFile, the name checked is the base name of the file path without
+	// the ".proto" extension. For check.DescriptorKindPackage, the name checked is the full package name.
+	//
+	// Required.
+	DescriptorKind check.DescriptorKind
+	// Pattern is a regular expression that names must match.
+	//
+	// Exactly one of Pattern or Convention must be set.
+	Pattern string
+	// Convention is a naming convention that names must conform to.
+	//
+	// Exactly one of Pattern or Convention must be set.
+	Convention checknaming.Convention
+	// OptionKey is an option key that, if set on a Request, overrides the Pattern or
+	// Convention with the regular expression given as the option's string value.
+	//
+	// Optional.
+	OptionKey string
+}
+
+// NewNamingRuleSpec returns a new lint RuleSpec that checks that the names of all descriptors of
+// the configured kind match a regular expression or naming convention.
+//
+// This allows simple naming rules to be defined without any handler code:
+//
+//	ruleSpec, err := checkutil.NewNamingRuleSpec(
+//		checkutil.NamingRuleConfig{
+//			ID:             "SERVICE_SUFFIX",
+//			IsDefault:      true,
+//			DescriptorKind: check.DescriptorKindService,
+//			Pattern:        "Service$",
+//			OptionKey:      "service_pattern",
+//		},
+//	)
+//
+// The configuration is validated, and the Pattern compiled, when the RuleSpec is created. A pattern
+// given with the OptionKey is compiled once per Check call.
+func NewNamingRuleSpec(config NamingRuleConfig) (*check.RuleSpec, error) {
+	if config.ID == "" {
+		return nil, errors.New("NamingRuleConfig: ID is required")
+	}
+	if _, err := check.ParseDescriptorKind(config.DescriptorKind.String()); err != nil {
+		return nil, fmt.Errorf("NamingRuleConfig: unknown DescriptorKind for ID %q: %v", config.ID, config.DescriptorKind)
+	}
+	if (config.Pattern == "") == (config.Convention == 0) {
+		return nil, fmt.Errorf("NamingRuleConfig: exactly one of Pattern or Convention must be set for ID %q", config.ID)
+	}
+	var matcher *namingMatcher
+	if config.Pattern != "" {
+		regex, err := regexp.Compile(config.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("NamingRuleConfig: invalid Pattern for ID %q: %w", config.ID, err)
+		}
+		matcher = newRegexNamingMatcher(regex)
+	} else {
+		if _, err := checknaming.ParseConvention(config.Convention.String()); err != nil {
+			return nil, fmt.Errorf("NamingRuleConfig: invalid Convention for ID %q: %w", config.ID, err)
+		}
+		matcher = newConventionNamingMatcher(config.Convention)
+	}
+	purpose := config.Purpose
+	if purpose == "" {
+		purpose = fmt.Sprintf("Checks that all %s names %s.", displayNameForDescriptorKind(config.DescriptorKind), matcher.purposeDescription)
+	}
+	return &check.RuleSpec{
+		ID:          config.ID,
+		CategoryIDs: config.CategoryIDs,
+		IsDefault:   config.IsDefault,
+		Purpose:     purpose,
+		Type:        check.RuleTypeLint,
+		Handler: check.RuleHandlerFunc(
+			func(
+				ctx context.Context,
+				responseWriter check.ResponseWriter,
+				request check.Request,
+			) error {
+				// The matcher is resolved once per Check call, not once per descriptor.
+				requestMatcher, err := namingMatcherForRequest(matcher, request, config.OptionKey)
+				if err != nil {
+					return err
+				}
+				return newNamingRuleHandler(config.DescriptorKind, requestMatcher).Handle(ctx, responseWriter, request)
+			},
+		),
+	}, nil
+}
+
+// NameForDescriptorKind returns the name of the descriptor as checked by naming rules for
+// the given DescriptorKind.
+//
+// For check.DescriptorKindFile, this is the base name of the file path without the ".proto" extension.
+// For check.DescriptorKindPackage, this is the full package name of the descriptor's file.
+// For all other kinds, this is the short name of the descriptor.
+func NameForDescriptorKind(descriptorKind check.DescriptorKind, descriptor protoreflect.Descriptor) string {
+	switch descriptorKind {
+	case check.DescriptorKindFile:
+		fileDescriptor, ok := descriptor.(protoreflect.FileDescriptor)
+		if !ok {
+			return string(descriptor.Name())
+		}
+		return strings.TrimSuffix(path.Base(fileDescriptor.Path()), ".proto")
+	case check.DescriptorKindPackage:
+		return string(descriptor.ParentFile().Package())
+	default:
+		return string(descriptor.Name())
+	}
+}
+
+// LocationOptionsForDescriptorKind returns the AddAnnotationOptions that set the Location of an
+// Annotation for a descriptor of the given DescriptorKind.
+//
+// For check.DescriptorKindPackage, the Location is the package declaration of the descriptor's file.
+// For all other kinds, this is equivalent to check.WithDescriptor.
+func LocationOptionsForDescriptorKind(descriptorKind check.DescriptorKind, descriptor protoreflect.Descriptor) []check.AddAnnotationOption {
+	if descriptorKind == check.DescriptorKindPackage {
+		return []check.AddAnnotationOption{
+			check.WithFileName(descriptor.ParentFile().Path()),
+			check.WithSourcePath(packageSourcePath),
+		}
+	}
+	return []check.AddAnnotationOption{
+		check.WithDescriptor(descriptor),
+	}
+}
+
+// *** PRIVATE ***
+
+type namingMatcher struct {
+	isValid func(string) bool
+	// purposeDescription completes the sentence "Checks that all message names ...".
+	purposeDescription string
+	// messageDescription completes the sentence "Message name "foo" should ...".
+	messageDescription string
+}
+
+func newRegexNamingMatcher(regex *regexp.Regexp) *namingMatcher {
+	return &namingMatcher{
+		isValid:            regex.MatchString,
+		purposeDescription: fmt.Sprintf("match the pattern %q", regex.String()),
+		messageDescription: fmt.Sprintf("match the pattern %q", regex.String()),
+	}
+}
+
+func newConventionNamingMatcher(convention checknaming.Convention) *namingMatcher {
+	return &namingMatcher{
+		isValid:            convention.IsValid,
+		purposeDescription: "are " + convention.String(),
+		messageDescription: "be " + convention.String(),
+	}
+}
+
+// newNamingRuleHandler returns a new RuleHandler that checks the names of all descriptors of
+// the DescriptorKind with the namingMatcher.
+func newNamingRuleHandler(descriptorKind check.DescriptorKind, matcher *namingMatcher) check.RuleHandler {
+	return NewDescriptorKindRuleHandler(
+		descriptorKind,
+		func(
+			_ context.Context,
+			responseWriter check.ResponseWriter,
+			_ check.Request,
+			descriptor protoreflect.Descriptor,
+		) error {
+			name := NameForDescriptorKind(descriptorKind, descriptor)
+			if matcher.isValid(name) {
+				return nil
+			}
+			responseWriter.AddAnnotation(
+				append(
+					LocationOptionsForDescriptorKind(descriptorKind, descriptor),
+					check.WithMessagef(
+						"%s name %q should %s.",
+						capitalizeFirst(displayNameForDescriptorKind(descriptorKind)),
+						name,
+						matcher.messageDescription,
+					),
+				)...,
+			)
+			return nil
+		},
+	)
+}
+
+func namingMatcherForRequest(matcher *namingMatcher, request check.Request, optionKey string) (*namingMatcher, error) {
+	if optionKey == "" {
+		return matcher, nil
+	}
+	pattern, err := check.GetStringValue(request.Options(), optionKey)
+	if err != nil {
+		return nil, err
+	}
+	if pattern == "" {
+		return matcher, nil
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern for option %q: %w", optionKey, err)
+	}
+	return newRegexNamingMatcher(regex), nil
+}
+
+func displayNameForDescriptorKind(descriptorKind check.DescriptorKind) string {
+	return strings.ReplaceAll(descriptorKind.String(), "_", " ")
+}
+
+func capitalizeFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checknaming"
+	"github.com/bufbuild/bufplugin-go/check/checktest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamingRuleSpec(t *testing.T) {
+	t.Parallel()
+
+	serviceSuffixRuleSpec, err := NewNamingRuleSpec(
+		NamingRuleConfig{
+			ID:             "SERVICE_SUFFIX",
+			IsDefault:      true,
+			DescriptorKind: check.DescriptorKindService,
+			Pattern:        "Service$",
+			OptionKey:      "service_pattern",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, `Checks that all service names match the pattern "Service$".`, serviceSuffixRuleSpec.Purpose)
+	enumValueUpperSnakeCaseRuleSpec, err := NewNamingRuleSpec(
+		NamingRuleConfig{
+			ID:             "ENUM_VALUE_UPPER_SNAKE_CASE",
+			IsDefault:      true,
+			DescriptorKind: check.DescriptorKindEnumValue,
+			Convention:     checknaming.ConventionUpperSnakeCase,
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "Checks that all enum value names are UPPER_SNAKE_CASE.", enumValueUpperSnakeCaseRuleSpec.Purpose)
+	spec := &check.Spec{
+		Rules: []*check.RuleSpec{
+			serviceSuffixRuleSpec,
+			enumValueUpperSnakeCaseRuleSpec,
+		},
+	}
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/naming"},
+				FilePaths: []string{"naming.proto"},
+			},
+		},
+		Spec: spec,
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID:  "ENUM_VALUE_UPPER_SNAKE_CASE",
+				Message: `Enum value name "baz_one" should be UPPER_SNAKE_CASE.`,
+				Location: &checktest.ExpectedLocation{
+					FileName:    "naming.proto",
+					StartLine:   10,
+					StartColumn: 2,
+					EndLine:     10,
+					EndColumn:   14,
+				},
+			},
+			{
+				RuleID:  "SERVICE_SUFFIX",
+				Message: `Service name "Bar" should match the pattern "Service$".`,
+				Location: &checktest.ExpectedLocation{
+					FileName:  "naming.proto",
+					StartLine: 6,
+					EndLine:   6,
+					EndColumn: 14,
+				},
+			},
+		},
+	}.Run(t)
+
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/naming"},
+				FilePaths: []string{"naming.proto"},
+			},
+			RuleIDs: []string{"SERVICE_SUFFIX"},
+			Options: map[string]any{
+				"service_pattern": "^[A-Z]",
+			},
+		},
+		Spec: spec,
+	}.Run(t)
+
+	// The pattern given with the OptionKey is compiled once per Check call, even if no
+	// descriptors are checked.
+	options, err := check.NewOptions(map[string]any{"service_pattern": "("})
+	require.NoError(t, err)
+	request, err := check.NewRequest(nil, check.WithOptions(options))
+	require.NoError(t, err)
+	err = serviceSuffixRuleSpec.Handler.Handle(context.Background(), nil, request)
+	require.ErrorContains(t, err, `invalid pattern for option "service_pattern"`)
+
+	_, err = NewNamingRuleSpec(
+		NamingRuleConfig{
+			ID:             "INVALID",
+			DescriptorKind: check.DescriptorKindService,
+			Pattern:        "Service$",
+			Convention:     checknaming.ConventionPascalCase,
+		},
+	)
+	require.Error(t, err)
+	_, err = NewNamingRuleSpec(
+		NamingRuleConfig{
+			ID:             "INVALID",
+			DescriptorKind: check.DescriptorKindService,
+			Pattern:        "(",
+		},
+	)
+	require.Error(t, err)
+}
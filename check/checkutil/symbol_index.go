@@ -0,0 +1,171 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// SymbolIndex is an index of all descriptors within a set of Files by full name.
+//
+// Imports are included, so that type references can always be resolved.
+type SymbolIndex interface {
+	// Descriptor returns the descriptor with the given full name.
+	//
+	// All descriptors except FileDescriptors are indexed, including fields, oneofs,
+	// enum values, and methods.
+	Descriptor(fullName protoreflect.FullName) (protoreflect.Descriptor, bool)
+	// Message returns the message with the given full name.
+	Message(fullName protoreflect.FullName) (protoreflect.MessageDescriptor, bool)
+	// Enum returns the enum with the given full name.
+	Enum(fullName protoreflect.FullName) (protoreflect.EnumDescriptor, bool)
+	// Service returns the service with the given full name.
+	Service(fullName protoreflect.FullName) (protoreflect.ServiceDescriptor, bool)
+	// Extension returns the extension with the given full name.
+	Extension(fullName protoreflect.FullName) (protoreflect.ExtensionDescriptor, bool)
+	// File returns the File that contains the descriptor with the given full name.
+	File(fullName protoreflect.FullName) (check.File, bool)
+	// FullNames returns the full names of all indexed descriptors, sorted.
+	FullNames() []protoreflect.FullName
+
+	isSymbolIndex()
+}
+
+// NewSymbolIndex returns a new SymbolIndex for the given Files.
+//
+// If multiple descriptors have the same full name, which should not happen for compiled
+// Files, the descriptor within the File with the lowest path is indexed.
+func NewSymbolIndex(files []check.File) SymbolIndex {
+	return newSymbolIndex(files)
+}
+
+// SymbolIndexForRequest returns the SymbolIndex for the Files of the Request.
+//
+// Within a Check call, the SymbolIndex is built once and shared between all RuleHandlers via
+// the check.RequestStore, so that rules resolving type references do not each walk all Files.
+// Outside of a Check call, a new SymbolIndex is built on every call.
+func SymbolIndexForRequest(ctx context.Context, request check.Request) (SymbolIndex, error) {
+	return check.LoadOrComputeForRequest(
+		ctx,
+		request,
+		symbolIndexKey{},
+		func() (SymbolIndex, error) {
+			return NewSymbolIndex(request.UnclonedFiles()), nil
+		},
+	)
+}
+
+// AgainstSymbolIndexForRequest returns the SymbolIndex for the AgainstFiles of the Request.
+//
+// Within a Check call, the SymbolIndex is built once and shared between all RuleHandlers via
+// the check.RequestStore. Outside of a Check call, a new SymbolIndex is built on every call.
+func AgainstSymbolIndexForRequest(ctx context.Context, request check.Request) (SymbolIndex, error) {
+	return check.LoadOrComputeForRequest(
+		ctx,
+		request,
+		symbolIndexKey{against: true},
+		func() (SymbolIndex, error) {
+			return NewSymbolIndex(request.UnclonedAgainstFiles()), nil
+		},
+	)
+}
+
+// *** PRIVATE ***
+
+// symbolIndexKey is the check.RequestStore key for SymbolIndexes.
+type symbolIndexKey struct {
+	against bool
+}
+
+type symbolIndex struct {
+	fullNameToDescriptor map[protoreflect.FullName]protoreflect.Descriptor
+	fullNameToFile       map[protoreflect.FullName]check.File
+	fullNames            []protoreflect.FullName
+}
+
+func newSymbolIndex(files []check.File) *symbolIndex {
+	symbolIndex := &symbolIndex{
+		fullNameToDescriptor: make(map[protoreflect.FullName]protoreflect.Descriptor),
+		fullNameToFile:       make(map[protoreflect.FullName]check.File),
+	}
+	// Sort for determinism in the case of duplicates.
+	for _, file := range sortedFiles(files) {
+		for descriptor := range allDescriptors(file.FileDescriptor()) {
+			if _, ok := descriptor.(protoreflect.FileDescriptor); ok {
+				continue
+			}
+			fullName := descriptor.FullName()
+			if _, ok := symbolIndex.fullNameToDescriptor[fullName]; ok {
+				continue
+			}
+			symbolIndex.fullNameToDescriptor[fullName] = descriptor
+			symbolIndex.fullNameToFile[fullName] = file
+			symbolIndex.fullNames = append(symbolIndex.fullNames, fullName)
+		}
+	}
+	sort.Slice(
+		symbolIndex.fullNames,
+		func(i int, j int) bool {
+			return symbolIndex.fullNames[i] < symbolIndex.fullNames[j]
+		},
+	)
+	return symbolIndex
+}
+
+func (s *symbolIndex) Descriptor(fullName protoreflect.FullName) (protoreflect.Descriptor, bool) {
+	descriptor, ok := s.fullNameToDescriptor[fullName]
+	return descriptor, ok
+}
+
+func (s *symbolIndex) Message(fullName protoreflect.FullName) (protoreflect.MessageDescriptor, bool) {
+	return symbolIndexGet[protoreflect.MessageDescriptor](s, fullName)
+}
+
+func (s *symbolIndex) Enum(fullName protoreflect.FullName) (protoreflect.EnumDescriptor, bool) {
+	return symbolIndexGet[protoreflect.EnumDescriptor](s, fullName)
+}
+
+func (s *symbolIndex) Service(fullName protoreflect.FullName) (protoreflect.ServiceDescriptor, bool) {
+	return symbolIndexGet[protoreflect.ServiceDescriptor](s, fullName)
+}
+
+func (s *symbolIndex) Extension(fullName protoreflect.FullName) (protoreflect.ExtensionDescriptor, bool) {
+	fieldDescriptor, ok := symbolIndexGet[protoreflect.FieldDescriptor](s, fullName)
+	if !ok || !fieldDescriptor.IsExtension() {
+		return nil, false
+	}
+	return fieldDescriptor, true
+}
+
+func (s *symbolIndex) File(fullName protoreflect.FullName) (check.File, bool) {
+	file, ok := s.fullNameToFile[fullName]
+	return file, ok
+}
+
+func (s *symbolIndex) FullNames() []protoreflect.FullName {
+	return slices.Clone(s.fullNames)
+}
+
+func (*symbolIndex) isSymbolIndex() {}
+
+func symbolIndexGet[D protoreflect.Descriptor](s *symbolIndex, fullName protoreflect.FullName) (D, bool) {
+	descriptor, ok := s.fullNameToDescriptor[fullName].(D)
+	return descriptor, ok
+}
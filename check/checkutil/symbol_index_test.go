@@ -0,0 +1,136 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checktest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestSymbolIndex(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	files, err := (&checktest.ProtoFileSpec{
+		DirPaths:  []string{"testdata/symbolindex"},
+		FilePaths: []string{"a.proto"},
+	}).ToFiles(ctx)
+	require.NoError(t, err)
+	request, err := check.NewRequest(files)
+	require.NoError(t, err)
+	symbolIndex, err := SymbolIndexForRequest(ctx, request)
+	require.NoError(t, err)
+
+	require.Equal(
+		t,
+		[]protoreflect.FullName{
+			"a.A",
+			"a.A.b",
+			"a.A.e",
+			"b.B",
+			"b.E",
+			"b.E_UNSPECIFIED",
+			"b.S",
+			"b.S.M",
+			"b.ext",
+		},
+		symbolIndex.FullNames(),
+	)
+	messageDescriptor, ok := symbolIndex.Message("b.B")
+	require.True(t, ok)
+	require.Equal(t, protoreflect.FullName("b.B"), messageDescriptor.FullName())
+	_, ok = symbolIndex.Message("b.E")
+	require.False(t, ok)
+	_, ok = symbolIndex.Enum("b.E")
+	require.True(t, ok)
+	_, ok = symbolIndex.Service("b.S")
+	require.True(t, ok)
+	_, ok = symbolIndex.Extension("b.ext")
+	require.True(t, ok)
+	_, ok = symbolIndex.Extension("a.A.b")
+	require.False(t, ok)
+	descriptor, ok := symbolIndex.Descriptor("b.S.M")
+	require.True(t, ok)
+	require.Equal(t, protoreflect.Name("M"), descriptor.Name())
+	file, ok := symbolIndex.File("b.E_UNSPECIFIED")
+	require.True(t, ok)
+	require.Equal(t, "b.proto", file.FileDescriptor().Path())
+	require.True(t, file.IsImport())
+	_, ok = symbolIndex.Descriptor("a")
+	require.False(t, ok)
+
+	againstSymbolIndex, err := AgainstSymbolIndexForRequest(ctx, request)
+	require.NoError(t, err)
+	require.Empty(t, againstSymbolIndex.FullNames())
+}
+
+func TestSymbolIndexSharedWithinCheck(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	var symbolIndexes []SymbolIndex
+	ruleHandler := check.RuleHandlerFunc(
+		func(ctx context.Context, _ check.ResponseWriter, request check.Request) error {
+			symbolIndex, err := SymbolIndexForRequest(ctx, request)
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			symbolIndexes = append(symbolIndexes, symbolIndex)
+			return nil
+		},
+	)
+	spec := &check.Spec{
+		Rules: []*check.RuleSpec{
+			{
+				ID:        "RULE1",
+				IsDefault: true,
+				Purpose:   "Test rule.",
+				Type:      check.RuleTypeLint,
+				Handler:   ruleHandler,
+			},
+			{
+				ID:        "RULE2",
+				IsDefault: true,
+				Purpose:   "Test rule.",
+				Type:      check.RuleTypeLint,
+				Handler:   ruleHandler,
+			},
+		},
+	}
+	// Request mutation detection gives each RuleHandler its own copy of the Request.
+	for _, detectRequestMutation := range []bool{false, true} {
+		symbolIndexes = nil
+		checktest.CheckTest{
+			Request: &checktest.RequestSpec{
+				Files: &checktest.ProtoFileSpec{
+					DirPaths:  []string{"testdata/symbolindex"},
+					FilePaths: []string{"a.proto"},
+				},
+			},
+			Spec:                  spec,
+			DetectRequestMutation: detectRequestMutation,
+		}.Run(t)
+		require.Len(t, symbolIndexes, 2)
+		require.Same(t, symbolIndexes[0], symbolIndexes[1], "detectRequestMutation: %v", detectRequestMutation)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// TraversalOption is an option for NewMessageRuleHandler and NewFieldRuleHandler.
+type TraversalOption func(*traversalOptions)
+
+// TraversalWithoutMapEntries returns a new TraversalOption that skips map entry messages.
+//
+// Map fields are represented as repeated fields of synthesized map entry messages, which have
+// a key field named "key" and a value field named "value". These are rarely of interest to
+// rules, and may surprise rules that do not expect them. When set, NewMessageRuleHandler does
+// not visit map entry messages, and NewFieldRuleHandler does not visit their fields. The map
+// fields themselves are still visited, see MapKeyValueDescriptors.
+//
+// The default is to visit map entries.
+func TraversalWithoutMapEntries() TraversalOption {
+	return func(traversalOptions *traversalOptions) {
+		traversalOptions.skipMapEntries = true
+	}
+}
+
+// TraversalWithoutSyntheticOneofFields returns a new TraversalOption that skips fields that
+// are within synthetic oneofs.
+//
+// In proto3, fields with the optional keyword are placed within a synthesized oneof with a
+// single field, so that older runtimes can track their presence. Rules that inspect oneofs
+// generally should not treat these fields as members of a real oneof, and rules that inspect
+// presence generally should treat them the same as proto2 optional fields and editions fields
+// with explicit presence. When set, NewFieldRuleHandler does not visit these fields.
+//
+// The default is to visit fields within synthetic oneofs. See also IsProto3Optional,
+// RealContainingOneof, and RealOneofs.
+func TraversalWithoutSyntheticOneofFields() TraversalOption {
+	return func(traversalOptions *traversalOptions) {
+		traversalOptions.skipSyntheticOneofFields = true
+	}
+}
+
+// IsProto3Optional returns true if the field is a proto3 field with the optional keyword.
+//
+// These fields have explicit presence, and are within a synthetic oneof.
+func IsProto3Optional(fieldDescriptor protoreflect.FieldDescriptor) bool {
+	containingOneof := fieldDescriptor.ContainingOneof()
+	return containingOneof != nil && containingOneof.IsSynthetic()
+}
+
+// RealContainingOneof returns the oneof that contains the field, if the oneof is not
+// synthetic.
+//
+// Returns nil if the field is not within a oneof, or is within a synthetic oneof.
+func RealContainingOneof(fieldDescriptor protoreflect.FieldDescriptor) protoreflect.OneofDescriptor {
+	containingOneof := fieldDescriptor.ContainingOneof()
+	if containingOneof == nil || containingOneof.IsSynthetic() {
+		return nil
+	}
+	return containingOneof
+}
+
+// IsMapEntry returns true if the descriptor is a synthesized map entry message, or the key or
+// value field of a synthesized map entry message.
+func IsMapEntry(descriptor protoreflect.Descriptor) bool {
+	switch descriptor := descriptor.(type) {
+	case protoreflect.MessageDescriptor:
+		return descriptor.IsMapEntry()
+	case protoreflect.FieldDescriptor:
+		if descriptor.IsExtension() {
+			return false
+		}
+		return descriptor.ContainingMessage().IsMapEntry()
+	default:
+		return false
+	}
+}
+
+// MapKeyValueDescriptors returns the key and value fields of the map field.
+//
+// Returns false if the field is not a map field.
+func MapKeyValueDescriptors(
+	fieldDescriptor protoreflect.FieldDescriptor,
+) (protoreflect.FieldDescriptor, protoreflect.FieldDescriptor, bool) {
+	if !fieldDescriptor.IsMap() {
+		return nil, nil, false
+	}
+	return fieldDescriptor.MapKey(), fieldDescriptor.MapValue(), true
+}
+
+// *** PRIVATE ***
+
+type traversalOptions struct {
+	skipMapEntries           bool
+	skipSyntheticOneofFields bool
+}
+
+func newTraversalOptions() *traversalOptions {
+	return &traversalOptions{}
+}
+
+func (t *traversalOptions) shouldVisitMessage(messageDescriptor protoreflect.MessageDescriptor) bool {
+	return !t.skipMapEntries || !messageDescriptor.IsMapEntry()
+}
+
+func (t *traversalOptions) shouldVisitField(fieldDescriptor protoreflect.FieldDescriptor) bool {
+	return !t.skipSyntheticOneofFields || !IsProto3Optional(fieldDescriptor)
+}
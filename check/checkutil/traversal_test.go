@@ -0,0 +1,165 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkutil
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checktest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestTraversalMapEntries(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(
+		t,
+		[]protoreflect.FullName{
+			"traversal.Foo",
+			"traversal.Foo.LabelsEntry",
+		},
+		testTraversalMessages(t),
+	)
+	require.Equal(
+		t,
+		[]protoreflect.FullName{
+			"traversal.Foo",
+		},
+		testTraversalMessages(t, TraversalWithoutMapEntries()),
+	)
+	require.Equal(
+		t,
+		[]protoreflect.FullName{
+			"traversal.Foo.labels",
+			"traversal.Foo.name",
+			"traversal.Foo.a",
+			"traversal.Foo.b",
+			"traversal.Foo.tags",
+			"traversal.Foo.LabelsEntry.key",
+			"traversal.Foo.LabelsEntry.value",
+		},
+		testTraversalFields(t),
+	)
+	require.Equal(
+		t,
+		[]protoreflect.FullName{
+			"traversal.Foo.labels",
+			"traversal.Foo.name",
+			"traversal.Foo.a",
+			"traversal.Foo.b",
+			"traversal.Foo.tags",
+		},
+		testTraversalFields(t, TraversalWithoutMapEntries()),
+	)
+}
+
+func TestTraversalSyntheticOneofs(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(
+		t,
+		[]protoreflect.FullName{
+			"traversal.Foo.labels",
+			"traversal.Foo.a",
+			"traversal.Foo.b",
+			"traversal.Foo.tags",
+		},
+		testTraversalFields(t, TraversalWithoutMapEntries(), TraversalWithoutSyntheticOneofFields()),
+	)
+}
+
+func TestSyntheticOneofHelpers(t *testing.T) {
+	t.Parallel()
+
+	file := testTraversalFile(t)
+	fields := file.FileDescriptor().Messages().ByName("Foo").Fields()
+	require.True(t, IsProto3Optional(fields.ByName("name")))
+	require.Nil(t, RealContainingOneof(fields.ByName("name")))
+	require.False(t, IsProto3Optional(fields.ByName("a")))
+	require.Equal(t, protoreflect.FullName("traversal.Foo.kind"), RealContainingOneof(fields.ByName("a")).FullName())
+	require.False(t, IsProto3Optional(fields.ByName("tags")))
+	require.Nil(t, RealContainingOneof(fields.ByName("tags")))
+	require.Equal(t, []protoreflect.FullName{"traversal.Foo.kind", "traversal.Foo._name"}, testFullNames(Oneofs(file)))
+	require.Equal(t, []protoreflect.FullName{"traversal.Foo.kind"}, testFullNames(RealOneofs(file)))
+}
+
+func TestMapHelpers(t *testing.T) {
+	t.Parallel()
+
+	messageDescriptor := testTraversalFile(t).FileDescriptor().Messages().ByName("Foo")
+	require.False(t, IsMapEntry(messageDescriptor))
+	labelsFieldDescriptor := messageDescriptor.Fields().ByName("labels")
+	require.False(t, IsMapEntry(labelsFieldDescriptor))
+	require.True(t, IsMapEntry(labelsFieldDescriptor.Message()))
+	require.True(t, IsMapEntry(labelsFieldDescriptor.MapKey()))
+	keyFieldDescriptor, valueFieldDescriptor, ok := MapKeyValueDescriptors(labelsFieldDescriptor)
+	require.True(t, ok)
+	require.Equal(t, protoreflect.FullName("traversal.Foo.LabelsEntry.key"), keyFieldDescriptor.FullName())
+	require.Equal(t, protoreflect.StringKind, keyFieldDescriptor.Kind())
+	require.Equal(t, protoreflect.FullName("traversal.Foo.LabelsEntry.value"), valueFieldDescriptor.FullName())
+	require.Equal(t, protoreflect.Int64Kind, valueFieldDescriptor.Kind())
+	_, _, ok = MapKeyValueDescriptors(messageDescriptor.Fields().ByName("tags"))
+	require.False(t, ok)
+}
+
+func testTraversalMessages(t *testing.T, options ...TraversalOption) []protoreflect.FullName {
+	var fullNames []protoreflect.FullName
+	testRunRuleHandler(
+		t,
+		NewMessageRuleHandler(
+			func(_ context.Context, _ check.ResponseWriter, _ check.Request, messageDescriptor protoreflect.MessageDescriptor) error {
+				fullNames = append(fullNames, messageDescriptor.FullName())
+				return nil
+			},
+			options...,
+		),
+	)
+	return fullNames
+}
+
+func testTraversalFields(t *testing.T, options ...TraversalOption) []protoreflect.FullName {
+	var fullNames []protoreflect.FullName
+	testRunRuleHandler(
+		t,
+		NewFieldRuleHandler(
+			func(_ context.Context, _ check.ResponseWriter, _ check.Request, fieldDescriptor protoreflect.FieldDescriptor) error {
+				fullNames = append(fullNames, fieldDescriptor.FullName())
+				return nil
+			},
+			options...,
+		),
+	)
+	return fullNames
+}
+
+// testRunRuleHandler runs the RuleHandler on testdata/traversal.
+func testRunRuleHandler(t *testing.T, ruleHandler check.RuleHandler) {
+	request, err := check.NewRequest([]check.File{testTraversalFile(t)})
+	require.NoError(t, err)
+	require.NoError(t, ruleHandler.Handle(context.Background(), nil, request))
+}
+
+func testTraversalFile(t *testing.T) check.File {
+	files, err := (&checktest.ProtoFileSpec{
+		DirPaths:  []string{"testdata/traversal"},
+		FilePaths: []string{"traversal.proto"},
+	}).ToFiles(context.Background())
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	return files[0]
+}
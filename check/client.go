@@ -16,12 +16,16 @@ package check
 
 import (
 	"context"
+	"slices"
 	"sync"
+	"time"
 
 	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
 	"github.com/bufbuild/bufplugin-go/internal/gen/buf/plugin/check/v1beta1/v1beta1pluginrpc"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/thread"
 	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
 	"github.com/bufbuild/pluginrpc-go"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -30,12 +34,17 @@ const (
 )
 
 // Client is a client for a custom lint or breaking change plugin.
+//
+// A Client is safe for concurrent use. A single Client may be used from multiple goroutines
+// for overlapping calls, including when ClientWithCacheRulesAndCategories is used, and
+// ClearCache may be called while other calls are in progress. The values returned by a
+// Client are not shared between calls, and may be modified by the caller.
 type Client interface {
 	// Check invokes a check using the plugin..
 	Check(ctx context.Context, request Request, options ...CheckCallOption) (Response, error)
 	// ListRules lists all available Rules from the plugin.
 	//
-	// The Rules will be sorted by Rule ID.
+	// The Rules will be sorted by Rule ID, unless ListRulesCallWithCategoryOrder is used.
 	// Returns error if duplicate Rule IDs were detected from the underlying source.
 	ListRules(ctx context.Context, options ...ListRulesCallOption) ([]Rule, error)
 	// ListCategories lists all available Categories from the plugin.
@@ -43,11 +52,28 @@ type Client interface {
 	// The Categories will be sorted by Category ID.
 	// Returns error if duplicate Category IDs were detected from the underlying source.
 	ListCategories(ctx context.Context, options ...ListCategoriesCallOption) ([]Category, error)
+	// ListAll lists the Rules and Categories of the plugin together, and returns them with
+	// the other metadata of the plugin.
+	//
+	// The Rules and Categories are fetched with one coordinated set of calls to the plugin,
+	// and are cached if ClientWithCacheRulesAndCategories is used, in which case ListRules
+	// and ListCategories share the same cache.
+	ListAll(ctx context.Context, options ...ListAllCallOption) (PluginMetadata, error)
+	// ClearCache clears the Rules and Categories cached by ClientWithCacheRulesAndCategories.
+	//
+	// The next call that needs the Rules or Categories will list them from the plugin again.
+	// This should be called by long-lived hosts when a plugin is upgraded. This is a no-op
+	// if ClientWithCacheRulesAndCategories is not used.
+	ClearCache()
 
 	isClient()
 }
 
 // NewClient returns a new Client for the given pluginrpc.Client.
+//
+// The pluginrpc.Client determines how the plugin is invoked. Typically, this is a
+// pluginrpc.Client for a pluginrpc.NewExecRunner, but any pluginrpc.Runner can be used,
+// for example to run plugins within a container or over SSH.
 func NewClient(pluginrpcClient pluginrpc.Client, options ...ClientOption) Client {
 	return newClient(pluginrpcClient, options...)
 }
@@ -58,49 +84,188 @@ type ClientOption func(*clientOptions)
 // ClientWithCacheRulesAndCategories returns a new ClientOption that will result in the Rules from
 // ListRules and the Categories from ListCategories being cached.
 //
-// The default is to not cache Rules or Categories.
+// The default is to not cache Rules or Categories. See also Client.ClearCache and
+// ClientWithCacheTTL.
 func ClientWithCacheRulesAndCategories() ClientOption {
 	return func(clientOptions *clientOptions) {
 		clientOptions.cacheRulesAndCategories = true
 	}
 }
 
+// ClientWithCacheTTL returns a new ClientOption that will result in the Rules and Categories
+// cached by ClientWithCacheRulesAndCategories expiring after the given duration.
+//
+// Errors from listing the Rules or Categories are cached and expire in the same way. This
+// has no effect unless ClientWithCacheRulesAndCategories is also used.
+//
+// The default is for cached Rules and Categories to never expire. A TTL <= 0 results in the
+// default.
+func ClientWithCacheTTL(ttl time.Duration) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.cacheTTL = ttl
+	}
+}
+
+// ClientWithRequestRedactors returns a new ClientOption that applies the given
+// RequestRedactors to each CheckRequest before it is sent to the plugin.
+//
+// This allows hosts to avoid sending sensitive schema content to third-party plugins. The
+// RequestRedactors are declared per Client, and therefore per plugin. Built-in
+// RequestRedactors include RedactComments, RedactSourceCodeInfo, RedactCustomOptions, and
+// RedactDefaultValues.
+//
+// RuleHandlers that depend on the redacted content will not see it, and Annotations for
+// redacted content may be less precise.
+func ClientWithRequestRedactors(redactors ...RequestRedactor) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.requestRedactors = append(clientOptions.requestRedactors, redactors...)
+	}
+}
+
+// ClientWithUnknownFilePolicy returns a new ClientOption that sets the policy for Annotations
+// returned by the plugin for files that are not in the Request.
+//
+// Plugins that use UnknownFilePolicyFileNameOnly return Locations for files that are not in
+// the Request, which result in an error unless this is set to UnknownFilePolicyFileNameOnly.
+// NewClientForSpec uses the UnknownFilePolicy of the Spec unless this is set.
+//
+// The default is UnknownFilePolicyError.
+func ClientWithUnknownFilePolicy(unknownFilePolicy UnknownFilePolicy) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.unknownFilePolicy = unknownFilePolicy
+	}
+}
+
+// ClientWithRuleHitReporter returns a new ClientOption that reports the Rules run by each
+// successful Check call, and the number of Annotations returned for each Rule, to the given
+// RuleHitReporter.
+//
+// If the Request does not specify Rule IDs, the default Rules are run, in which case the Rules
+// are listed to determine the default Rules. Use ClientWithCacheRulesAndCategories to avoid
+// listing the Rules for every Check call. See RuleHitReporter for what is reported.
+//
+// The default is to not report anything.
+func ClientWithRuleHitReporter(ruleHitReporter RuleHitReporter) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.ruleHitReporter = ruleHitReporter
+	}
+}
+
 // NewClientForSpec return a new Client that directly uses the given Spec.
 //
 // This should primarily be used for testing.
 func NewClientForSpec(spec *Spec, options ...ClientOption) (Client, error) {
-	checkServiceHandler, err := newCheckServiceHandler(spec, 0)
-	if err != nil {
-		return nil, err
+	clientOptions := newClientOptions()
+	for _, option := range options {
+		option(clientOptions)
 	}
-	checkServer, err := newCheckServer(checkServiceHandler)
+	checkServer, err := NewServer(spec, clientOptions.specServerOptions...)
 	if err != nil {
 		return nil, err
 	}
-	return newClient(pluginrpc.NewClient(pluginrpc.NewServerRunner(checkServer)), options...), nil
+	// Rule metadata is not part of the plugin protocol, so we take it from the Spec directly.
+	ruleIDToMetadata := make(map[string]ruleMetadata, len(spec.Rules))
+	for _, ruleSpec := range spec.Rules {
+		ruleIDToMetadata[ruleSpec.ID] = ruleSpecToRuleMetadata(ruleSpec)
+	}
+	runner := pluginrpc.NewServerRunner(checkServer)
+	return newClient(
+		pluginrpc.NewClient(runner),
+		append(
+			// The policy of the Spec is applied first, so that it can be overridden.
+			[]ClientOption{ClientWithUnknownFilePolicy(spec.UnknownFilePolicy)},
+			append(
+				slices.Clone(options),
+				clientWithRuleIDToMetadata(ruleIDToMetadata),
+				clientWithRunner(runner),
+			)...,
+		)...,
+	), nil
+}
+
+// ClientWithSpecServerOptions returns a new ClientOption that passes the given ServerOptions
+// to the server that NewClientForSpec creates for the Spec.
+//
+// This allows tests to use ServerOptions such as ServerWithCoverageRecorder. This has no
+// effect on Clients other than those created by NewClientForSpec.
+func ClientWithSpecServerOptions(serverOptions ...ServerOption) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.specServerOptions = append(clientOptions.specServerOptions, serverOptions...)
+	}
 }
 
 // CheckCallOption is an option for a Client.Check call.
 type CheckCallOption func(*checkCallOptions)
 
+// CheckCallWithRuleCategories returns a new CheckCallOption that will result in the
+// RuleCategories of the returned Annotations being populated.
+//
+// The Categories are joined from ListRules, which results in an additional call to the plugin
+// unless ClientWithCacheRulesAndCategories is used.
+func CheckCallWithRuleCategories() CheckCallOption {
+	return func(checkCallOptions *checkCallOptions) {
+		checkCallOptions.ruleCategories = true
+	}
+}
+
+// CheckCallWithoutImportAnnotations returns a new CheckCallOption that will result in
+// Annotations whose Location is within an import being removed from the Response.
+//
+// See Annotation.IsImport for more details.
+func CheckCallWithoutImportAnnotations() CheckCallOption {
+	return func(checkCallOptions *checkCallOptions) {
+		checkCallOptions.withoutImportAnnotations = true
+	}
+}
+
 // ListRulesCallOption is an option for a Client.ListRules call.
 type ListRulesCallOption func(*listRulesCallOptions)
 
+// ListRulesCallWithCategoryOrder returns a new ListRulesCallOption that will result in the
+// returned Rules being grouped by Category.
+//
+// Rules are sorted by the lowest ID of their Categories, and then by Rule ID, so that the Rules
+// of each Category are adjacent. A Rule with multiple Categories is grouped under the Category
+// with the lowest ID. Rules without Categories are returned last. This is useful for hosts
+// that render Rules per Category.
+//
+// The default is to sort Rules by Rule ID.
+func ListRulesCallWithCategoryOrder() ListRulesCallOption {
+	return func(listRulesCallOptions *listRulesCallOptions) {
+		listRulesCallOptions.categoryOrder = true
+	}
+}
+
 // ListCategoriesCallOption is an option for a Client.ListCategories call.
 type ListCategoriesCallOption func(*listCategoriesCallOptions)
 
+// ListAllCallOption is an option for a Client.ListAll call.
+type ListAllCallOption func(*listAllCallOptions)
+
 // *** PRIVATE ***
 
 type client struct {
 	pluginrpcClient pluginrpc.Client
+	// verifier is nil if there is nothing to verify before invoking the plugin.
+	verifier *onceVerifier
 
 	cacheRulesAndCategories bool
-
-	cachedRules    []Rule
-	cachedRulesErr error
-
-	cachedCategories    []Category
-	cachedCategoriesErr error
+	// cacheTTL is 0 if cached Rules and Categories never expire.
+	cacheTTL          time.Duration
+	requestRedactors  []RequestRedactor
+	unknownFilePolicy UnknownFilePolicy
+	// ruleIDToMetadata contains the metadata of the Rules, if known.
+	ruleIDToMetadata map[string]ruleMetadata
+	// ruleHitReporter is nil if rule hits are not reported.
+	ruleHitReporter RuleHitReporter
+
+	cachedRules     []Rule
+	cachedRulesErr  error
+	cachedRulesTime time.Time
+
+	cachedCategories     []Category
+	cachedCategoriesErr  error
+	cachedCategoriesTime time.Time
 
 	// Lock ordering: rulesLock -> categoriesLock
 	rulesLock      sync.RWMutex
@@ -115,112 +280,240 @@ func newClient(
 	for _, option := range options {
 		option(clientOptions)
 	}
+	var verifiers []Verifier
+	if clientOptions.verifier != nil {
+		verifiers = append(verifiers, newPluginVerifier(clientOptions.verifier))
+	}
+	if clientOptions.requiredProtocolVersion != "" {
+		verifiers = append(
+			verifiers,
+			newProtocolVersionVerifier(clientOptions.runner, clientOptions.requiredProtocolVersion),
+		)
+	}
+	var verifier *onceVerifier
+	if len(verifiers) > 0 {
+		verifier = newOnceVerifier(verifiers...)
+	}
 	return &client{
 		pluginrpcClient:         pluginrpcClient,
+		verifier:                verifier,
 		cacheRulesAndCategories: clientOptions.cacheRulesAndCategories,
+		cacheTTL:                clientOptions.cacheTTL,
+		requestRedactors:        clientOptions.requestRedactors,
+		unknownFilePolicy:       clientOptions.unknownFilePolicy,
+		ruleIDToMetadata:        clientOptions.ruleIDToMetadata,
+		ruleHitReporter:         clientOptions.ruleHitReporter,
 	}
 }
 
-func (c *client) Check(ctx context.Context, request Request, _ ...CheckCallOption) (Response, error) {
-	checkServiceClient, err := c.newCheckServiceClient()
+func (c *client) Check(ctx context.Context, request Request, options ...CheckCallOption) (Response, error) {
+	checkCallOptions := newCheckCallOptions()
+	for _, option := range options {
+		option(checkCallOptions)
+	}
+	checkServiceClient, err := c.newCheckServiceClient(ctx)
 	if err != nil {
 		return nil, err
 	}
-	multiResponseWriter, err := newMultiResponseWriter(request)
+	hasSelectors := hasRuleIDSelectors(request.RuleIDs())
+	var rules []Rule
+	if checkCallOptions.ruleCategories || hasSelectors {
+		rules, err = c.listRules(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if hasSelectors {
+		request, err = resolveRequestRuleIDSelectors(request, rules)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var ruleIDToRule map[string]Rule
+	if checkCallOptions.ruleCategories {
+		ruleIDToRule = make(map[string]Rule, len(rules))
+		for _, rule := range rules {
+			ruleIDToRule[rule.ID()] = rule
+		}
+	}
+	multiResponseWriter, err := newMultiResponseWriter(request, ruleIDToRule, c.unknownFilePolicy)
 	if err != nil {
 		return nil, err
 	}
+	multiResponseWriter.isClient = true
 	protoRequests, err := request.toProtos()
 	if err != nil {
 		return nil, err
 	}
 	for _, protoRequest := range protoRequests {
+		if len(c.requestRedactors) > 0 {
+			// The proto requests share FileDescriptorProtos with the Request, so we
+			// redact a copy.
+			protoRequest, _ = proto.Clone(protoRequest).(*checkv1beta1.CheckRequest)
+			for _, redactor := range c.requestRedactors {
+				redactor(protoRequest)
+			}
+		}
 		protoResponse, err := checkServiceClient.Check(ctx, protoRequest)
 		if err != nil {
 			return nil, err
 		}
 		for _, protoAnnotation := range protoResponse.GetAnnotations() {
-			multiResponseWriter.addAnnotation(
+			if err := multiResponseWriter.addAnnotation(
 				protoAnnotation.GetRuleId(),
 				WithMessage(protoAnnotation.GetMessage()),
 				WithFileName(protoAnnotation.GetLocation().GetFileName()),
 				WithSourcePath(protoAnnotation.GetLocation().GetSourcePath()),
 				WithAgainstFileName(protoAnnotation.GetAgainstLocation().GetFileName()),
 				WithAgainstSourcePath(protoAnnotation.GetAgainstLocation().GetSourcePath()),
-			)
+			); err != nil {
+				// The call site within the plugin is unknown.
+				multiResponseWriter.addError(protoAnnotation.GetRuleId(), "", err)
+			}
+		}
+	}
+	response, err := multiResponseWriter.toResponse()
+	if err != nil {
+		return nil, err
+	}
+	if checkCallOptions.withoutImportAnnotations {
+		response, err = newResponse(
+			xslices.Filter(
+				response.Annotations(),
+				func(annotation Annotation) bool {
+					return !annotation.IsImport()
+				},
+			),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.ruleHitReporter != nil {
+		ruleIDs := request.RuleIDs()
+		if len(ruleIDs) == 0 {
+			if rules == nil {
+				rules, err = c.listRules(ctx)
+				if err != nil {
+					return nil, err
+				}
+			}
+			ruleIDs = xslices.Map(xslices.Filter(rules, Rule.IsDefault), Rule.ID)
 		}
+		c.ruleHitReporter.ReportRuleHits(getRuleHits(ruleIDs, response.Annotations()))
 	}
-	return multiResponseWriter.toResponse()
+	return response, nil
 }
 
-func (c *client) ListRules(ctx context.Context, _ ...ListRulesCallOption) ([]Rule, error) {
+func (c *client) ListRules(ctx context.Context, options ...ListRulesCallOption) ([]Rule, error) {
+	listRulesCallOptions := newListRulesCallOptions()
+	for _, option := range options {
+		option(listRulesCallOptions)
+	}
+	rules, err := c.listRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Do not share the cached Rules with the caller.
+	rules = slices.Clone(rules)
+	if listRulesCallOptions.categoryOrder {
+		sortRulesByCategory(rules)
+	}
+	return rules, nil
+}
+
+func (c *client) listRules(ctx context.Context) ([]Rule, error) {
 	if !c.cacheRulesAndCategories {
 		return c.listRulesUncached(ctx)
 	}
 	c.rulesLock.RLock()
-	if len(c.cachedRules) > 0 || c.cachedRulesErr != nil {
+	if c.isCached(len(c.cachedRules) > 0 || c.cachedRulesErr != nil, c.cachedRulesTime) {
+		cachedRules, cachedRulesErr := c.cachedRules, c.cachedRulesErr
 		c.rulesLock.RUnlock()
-		return c.cachedRules, c.cachedRulesErr
+		return cachedRules, cachedRulesErr
 	}
 	c.rulesLock.RUnlock()
 
 	c.rulesLock.Lock()
 	defer c.rulesLock.Unlock()
-	if len(c.cachedRules) == 0 && c.cachedRulesErr == nil {
-		c.cachedRules, c.cachedRulesErr = c.listRulesUncached(ctx)
+	if !c.isCached(len(c.cachedRules) > 0 || c.cachedRulesErr != nil, c.cachedRulesTime) {
+		var categories []Category
+		c.cachedRules, categories, c.cachedRulesErr = c.listRulesAndCategoriesUncached(ctx)
+		c.cachedRulesTime = time.Now()
+		if c.cachedRulesErr == nil {
+			// The Categories were listed alongside the Rules, so cache them too.
+			c.categoriesLock.Lock()
+			if !c.isCached(len(c.cachedCategories) > 0 || c.cachedCategoriesErr != nil, c.cachedCategoriesTime) {
+				c.cachedCategories = categories
+				c.cachedCategoriesErr = nil
+				c.cachedCategoriesTime = c.cachedRulesTime
+			}
+			c.categoriesLock.Unlock()
+		}
 	}
 	return c.cachedRules, c.cachedRulesErr
 }
 
 func (c *client) ListCategories(ctx context.Context, _ ...ListCategoriesCallOption) ([]Category, error) {
+	categories, err := c.listCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Do not share the cached Categories with the caller.
+	return slices.Clone(categories), nil
+}
+
+func (c *client) listCategories(ctx context.Context) ([]Category, error) {
 	if !c.cacheRulesAndCategories {
 		return c.listCategoriesUncached(ctx)
 	}
 	c.categoriesLock.RLock()
-	if len(c.cachedCategories) > 0 || c.cachedCategoriesErr != nil {
+	if c.isCached(len(c.cachedCategories) > 0 || c.cachedCategoriesErr != nil, c.cachedCategoriesTime) {
+		cachedCategories, cachedCategoriesErr := c.cachedCategories, c.cachedCategoriesErr
 		c.categoriesLock.RUnlock()
-		return c.cachedCategories, c.cachedCategoriesErr
+		return cachedCategories, cachedCategoriesErr
 	}
 	c.categoriesLock.RUnlock()
 
 	c.categoriesLock.Lock()
 	defer c.categoriesLock.Unlock()
-	if len(c.cachedCategories) == 0 && c.cachedCategoriesErr == nil {
+	if !c.isCached(len(c.cachedCategories) > 0 || c.cachedCategoriesErr != nil, c.cachedCategoriesTime) {
 		c.cachedCategories, c.cachedCategoriesErr = c.listCategoriesUncached(ctx)
+		c.cachedCategoriesTime = time.Now()
 	}
 	return c.cachedCategories, c.cachedCategoriesErr
 }
 
-func (c *client) listRulesUncached(ctx context.Context) ([]Rule, error) {
-	checkServiceClient, err := c.newCheckServiceClient()
-	if err != nil {
-		return nil, err
-	}
-	var protoRules []*checkv1beta1.Rule
-	var pageToken string
-	for {
-		response, err := checkServiceClient.ListRules(
-			ctx,
-			&checkv1beta1.ListRulesRequest{
-				PageSize:  listRulesPageSize,
-				PageToken: pageToken,
-			},
-		)
+func (c *client) ListAll(ctx context.Context, _ ...ListAllCallOption) (PluginMetadata, error) {
+	var rules []Rule
+	var categories []Category
+	var err error
+	if c.cacheRulesAndCategories {
+		// Listing the Rules also lists and caches the Categories.
+		rules, err = c.listRules(ctx)
 		if err != nil {
 			return nil, err
 		}
-		protoRules = append(protoRules, response.GetRules()...)
-		pageToken = response.GetNextPageToken()
-		if pageToken == "" {
-			break
-		}
+		categories, err = c.listCategories(ctx)
+	} else {
+		rules, categories, err = c.listRulesAndCategoriesUncached(ctx)
 	}
-
-	// We acquire rulesLock before categoriesLock.
-	categories, err := c.ListCategories(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return newPluginMetadata(rules, categories), nil
+}
+
+func (c *client) listRulesUncached(ctx context.Context) ([]Rule, error) {
+	rules, _, err := c.listRulesAndCategoriesUncached(ctx)
+	return rules, err
+}
+
+func (c *client) listRulesAndCategoriesUncached(ctx context.Context) ([]Rule, []Category, error) {
+	protoRules, categories, err := c.listProtoRulesAndCategories(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
 	categoryIDToCategory := make(map[string]Category)
 	for _, category := range categories {
 		// We know there are no duplicate IDs from validation.
@@ -233,17 +526,57 @@ func (c *client) listRulesUncached(ctx context.Context) ([]Rule, error) {
 		},
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if err := validateNoDuplicateRules(rules); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	for i, rule := range rules {
+		if metadata, ok := c.ruleIDToMetadata[rule.ID()]; ok {
+			rules[i] = ruleWithMetadata(rule, metadata)
+		}
 	}
 	sortRules(rules)
-	return rules, nil
+	return rules, categories, nil
+}
+
+// listProtoRulesAndCategories lists the Rules and the Categories of the plugin concurrently.
+//
+// Rules reference Categories by ID, so both are required to build the Rules. The Categories
+// are listed with ListCategories, and are therefore cached if caching is enabled. This is the
+// single place that fetches the metadata of a plugin, so that a combined procedure can be
+// used here if a future protocol version provides one.
+func (c *client) listProtoRulesAndCategories(ctx context.Context) ([]*checkv1beta1.Rule, []Category, error) {
+	checkServiceClient, err := c.newCheckServiceClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var protoRules []*checkv1beta1.Rule
+	var categories []Category
+	if err := thread.Parallelize(
+		ctx,
+		[]func(context.Context) error{
+			func(ctx context.Context) error {
+				var err error
+				protoRules, err = listProtoRules(ctx, checkServiceClient)
+				return err
+			},
+			func(ctx context.Context) error {
+				var err error
+				// We acquire rulesLock before categoriesLock.
+				categories, err = c.listCategories(ctx)
+				return err
+			},
+		},
+		thread.WithParallelism(2),
+	); err != nil {
+		return nil, nil, err
+	}
+	return protoRules, categories, nil
 }
 
 func (c *client) listCategoriesUncached(ctx context.Context) ([]Category, error) {
-	checkServiceClient, err := c.newCheckServiceClient()
+	checkServiceClient, err := c.newCheckServiceClient(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -277,22 +610,108 @@ func (c *client) listCategoriesUncached(ctx context.Context) ([]Category, error)
 	return categories, nil
 }
 
-func (c *client) newCheckServiceClient() (v1beta1pluginrpc.CheckServiceClient, error) {
+func (c *client) newCheckServiceClient(ctx context.Context) (v1beta1pluginrpc.CheckServiceClient, error) {
+	if err := c.verifier.Verify(ctx); err != nil {
+		return nil, err
+	}
 	return v1beta1pluginrpc.NewCheckServiceClient(c.pluginrpcClient)
 }
 
+func (c *client) ClearCache() {
+	c.rulesLock.Lock()
+	defer c.rulesLock.Unlock()
+	c.categoriesLock.Lock()
+	defer c.categoriesLock.Unlock()
+	c.cachedRules = nil
+	c.cachedRulesErr = nil
+	c.cachedRulesTime = time.Time{}
+	c.cachedCategories = nil
+	c.cachedCategoriesErr = nil
+	c.cachedCategoriesTime = time.Time{}
+}
+
+// isCached returns true if a cached value or error is present, and was not cached
+// longer than the cache TTL ago.
+func (c *client) isCached(present bool, cachedTime time.Time) bool {
+	return present && (c.cacheTTL <= 0 || time.Since(cachedTime) < c.cacheTTL)
+}
+
 func (*client) isClient() {}
 
+func listProtoRules(ctx context.Context, checkServiceClient v1beta1pluginrpc.CheckServiceClient) ([]*checkv1beta1.Rule, error) {
+	var protoRules []*checkv1beta1.Rule
+	var pageToken string
+	for {
+		response, err := checkServiceClient.ListRules(
+			ctx,
+			&checkv1beta1.ListRulesRequest{
+				PageSize:  listRulesPageSize,
+				PageToken: pageToken,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		protoRules = append(protoRules, response.GetRules()...)
+		pageToken = response.GetNextPageToken()
+		if pageToken == "" {
+			return protoRules, nil
+		}
+	}
+}
+
 type clientOptions struct {
 	cacheRulesAndCategories bool
+	cacheTTL                time.Duration
+	verifier                Verifier
+	requiredProtocolVersion string
+	specServerOptions       []ServerOption
+	requestRedactors        []RequestRedactor
+	unknownFilePolicy       UnknownFilePolicy
+	// ruleIDToMetadata is only set by NewClientForSpec.
+	ruleIDToMetadata map[string]ruleMetadata
+	ruleHitReporter  RuleHitReporter
+	// runner is only set by NewClientForRunner and NewClientForSpec.
+	runner pluginrpc.Runner
 }
 
 func newClientOptions() *clientOptions {
 	return &clientOptions{}
 }
 
-type checkCallOptions struct{}
+func clientWithRuleIDToMetadata(ruleIDToMetadata map[string]ruleMetadata) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.ruleIDToMetadata = ruleIDToMetadata
+	}
+}
 
-type listRulesCallOptions struct{}
+// clientWithRunner returns a new ClientOption that sets the pluginrpc.Runner that the
+// pluginrpc.Client of the Client uses.
+//
+// This is used to read the pluginrpc Spec of the plugin for ClientWithRequiredProtocolVersion.
+func clientWithRunner(runner pluginrpc.Runner) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.runner = runner
+	}
+}
+
+type checkCallOptions struct {
+	ruleCategories           bool
+	withoutImportAnnotations bool
+}
+
+func newCheckCallOptions() *checkCallOptions {
+	return &checkCallOptions{}
+}
+
+type listRulesCallOptions struct {
+	categoryOrder bool
+}
+
+func newListRulesCallOptions() *listRulesCallOptions {
+	return &listRulesCallOptions{}
+}
 
 type listCategoriesCallOptions struct{}
+
+type listAllCallOptions struct{}
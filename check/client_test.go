@@ -16,12 +16,22 @@ package check
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/thread"
 	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/bufbuild/pluginrpc-go"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 func TestClientListRulesCategoriesSimple(t *testing.T) {
@@ -118,6 +128,64 @@ func testClientListRulesCategoriesSimple(t *testing.T, options ...ClientOption)
 	)
 }
 
+func TestClientListRulesCategoryOrder(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:      "RULE_A",
+					Purpose: "Test rule A.",
+					Type:    RuleTypeLint,
+					Handler: nopRuleHandler,
+				},
+				{
+					ID:          "RULE_B",
+					CategoryIDs: []string{"CATEGORY_2"},
+					Purpose:     "Test rule B.",
+					Type:        RuleTypeLint,
+					Handler:     nopRuleHandler,
+				},
+				{
+					ID:          "RULE_C",
+					CategoryIDs: []string{"CATEGORY_2", "CATEGORY_1"},
+					Purpose:     "Test rule C.",
+					Type:        RuleTypeLint,
+					Handler:     nopRuleHandler,
+				},
+				{
+					ID:          "RULE_D",
+					CategoryIDs: []string{"CATEGORY_1"},
+					Purpose:     "Test rule D.",
+					Type:        RuleTypeLint,
+					Handler:     nopRuleHandler,
+				},
+			},
+			Categories: []*CategorySpec{
+				{
+					ID:      "CATEGORY_1",
+					Purpose: "Test category 1.",
+				},
+				{
+					ID:      "CATEGORY_2",
+					Purpose: "Test category 2.",
+				},
+			},
+		},
+		ClientWithCacheRulesAndCategories(),
+	)
+	require.NoError(t, err)
+	rules, err := client.ListRules(ctx, ListRulesCallWithCategoryOrder())
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE_C", "RULE_D", "RULE_B", "RULE_A"}, xslices.Map(rules, Rule.ID))
+	// The cached Rules are not modified.
+	rules, err = client.ListRules(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE_A", "RULE_B", "RULE_C", "RULE_D"}, xslices.Map(rules, Rule.ID))
+}
+
 func TestClientListRulesCount(t *testing.T) {
 	t.Parallel()
 
@@ -152,3 +220,406 @@ func testClientListRulesCount(t *testing.T, count int) {
 		require.Equal(t, ruleSpecs[i].ID, rules[i].ID())
 	}
 }
+
+func TestClientCheckRuleCategories(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	spec := &Spec{
+		Rules: []*RuleSpec{
+			{
+				ID:          "RULE1",
+				CategoryIDs: []string{"CATEGORY1"},
+				IsDefault:   true,
+				Purpose:     "Test rule.",
+				Type:        RuleTypeLint,
+				Handler: RuleHandlerFunc(
+					func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+						responseWriter.AddAnnotation()
+						return nil
+					},
+				),
+			},
+		},
+		Categories: []*CategorySpec{
+			{
+				ID:      "CATEGORY1",
+				Purpose: "Test category.",
+			},
+		},
+		Finalize: func(_ context.Context, _ FinalizeResponseWriter, _ Request, annotations []Annotation) error {
+			// Categories are always available on the server-side.
+			for _, annotation := range annotations {
+				if len(annotation.RuleCategories()) != 1 {
+					return fmt.Errorf("expected one category for %q", annotation.RuleID())
+				}
+			}
+			return nil
+		},
+	}
+	client, err := NewClientForSpec(spec)
+	require.NoError(t, err)
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	annotations := response.Annotations()
+	require.Len(t, annotations, 1)
+	require.Empty(t, annotations[0].RuleCategories())
+
+	response, err = client.Check(ctx, request, CheckCallWithRuleCategories())
+	require.NoError(t, err)
+	annotations = response.Annotations()
+	require.Len(t, annotations, 1)
+	require.Equal(t, []string{"CATEGORY1"}, xslices.Map(annotations[0].RuleCategories(), Category.ID))
+
+	stats := response.Stats()
+	require.Equal(t, 1, stats.TotalCount())
+	require.Equal(t, map[string]int{"RULE1": 1}, stats.RuleIDToCount())
+	require.Equal(t, map[string]int{"": 1}, stats.FileNameToCount())
+	require.Equal(t, map[string]int{"CATEGORY1": 1}, stats.CategoryIDToCount())
+}
+
+func TestClientListRulesConcurrentCategories(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server, err := NewServer(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewSimpleLintRuleSpec("RULE1", []string{"CATEGORY1"}, true, false, nil),
+				testNewSimpleLintRuleSpec("RULE2", nil, true, false, nil),
+			},
+			Categories: []*CategorySpec{
+				testNewSimpleCategorySpec("CATEGORY1", false, nil),
+			},
+		},
+		// Each Rule is listed with a separate call.
+		ServerWithMaxPageSize(1),
+	)
+	require.NoError(t, err)
+	runner := &testListRulesBlockingRunner{
+		delegate:                pluginrpc.NewServerRunner(server),
+		listCategoriesStartedC:  make(chan struct{}),
+		listCategoriesStartOnce: &sync.Once{},
+	}
+	client := NewClient(pluginrpc.NewClient(runner))
+	// ListRules blocks until ListCategories is called, so this only succeeds if the Rules and
+	// Categories are listed concurrently.
+	rules, err := client.ListRules(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE1", "RULE2"}, xslices.Map(rules, Rule.ID))
+	require.Equal(t, []string{"CATEGORY1"}, xslices.Map(rules[0].Categories(), Category.ID))
+}
+
+func TestClientListAll(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server, err := NewServer(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewSimpleLintRuleSpec("RULE2", []string{"CATEGORY1"}, true, false, nil),
+				testNewSimpleLintRuleSpec("RULE1", nil, true, false, nil),
+			},
+			Categories: []*CategorySpec{
+				testNewSimpleCategorySpec("CATEGORY1", false, nil),
+			},
+		},
+	)
+	require.NoError(t, err)
+	runner := &testListCategoriesCountingRunner{
+		delegate: pluginrpc.NewServerRunner(server),
+	}
+	client := NewClient(pluginrpc.NewClient(runner), ClientWithCacheRulesAndCategories())
+	pluginMetadata, err := client.ListAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE1", "RULE2"}, xslices.Map(pluginMetadata.Rules(), Rule.ID))
+	require.Equal(t, []string{"CATEGORY1"}, xslices.Map(pluginMetadata.Categories(), Category.ID))
+	require.Equal(t, []RuleType{RuleTypeLint}, pluginMetadata.RuleTypes())
+	// The Rules and Categories share the cache with ListRules and ListCategories.
+	_, err = client.ListRules(ctx)
+	require.NoError(t, err)
+	categories, err := client.ListCategories(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"CATEGORY1"}, xslices.Map(categories, Category.ID))
+	require.Equal(t, int32(1), runner.listCategoriesCount.Load())
+}
+
+func TestClientClearCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, runner := testNewCountingClient(t, ClientWithCacheRulesAndCategories())
+	_, err := client.ListAll(ctx)
+	require.NoError(t, err)
+	_, err = client.ListAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), runner.listCategoriesCount.Load())
+	client.ClearCache()
+	pluginMetadata, err := client.ListAll(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE1"}, xslices.Map(pluginMetadata.Rules(), Rule.ID))
+	require.Equal(t, int32(2), runner.listCategoriesCount.Load())
+}
+
+func TestClientCacheTTL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, runner := testNewCountingClient(
+		t,
+		ClientWithCacheRulesAndCategories(),
+		ClientWithCacheTTL(time.Hour),
+	)
+	_, err := client.ListCategories(ctx)
+	require.NoError(t, err)
+	_, err = client.ListCategories(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), runner.listCategoriesCount.Load())
+
+	client, runner = testNewCountingClient(
+		t,
+		ClientWithCacheRulesAndCategories(),
+		ClientWithCacheTTL(time.Millisecond),
+	)
+	_, err = client.ListCategories(ctx)
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	categories, err := client.ListCategories(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"CATEGORY1"}, xslices.Map(categories, Category.ID))
+	require.Equal(t, int32(2), runner.listCategoriesCount.Load())
+}
+
+func testNewCountingClient(t *testing.T, options ...ClientOption) (Client, *testListCategoriesCountingRunner) {
+	server, err := NewServer(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewSimpleLintRuleSpec("RULE1", []string{"CATEGORY1"}, true, false, nil),
+			},
+			Categories: []*CategorySpec{
+				testNewSimpleCategorySpec("CATEGORY1", false, nil),
+			},
+		},
+	)
+	require.NoError(t, err)
+	runner := &testListCategoriesCountingRunner{
+		delegate: pluginrpc.NewServerRunner(server),
+	}
+	return NewClient(pluginrpc.NewClient(runner), options...), runner
+}
+
+type testListCategoriesCountingRunner struct {
+	delegate            pluginrpc.Runner
+	listCategoriesCount atomic.Int32
+}
+
+func (r *testListCategoriesCountingRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	if slices.ContainsFunc(env.Args, func(arg string) bool { return strings.HasSuffix(arg, "/ListCategories") }) {
+		r.listCategoriesCount.Add(1)
+	}
+	return r.delegate.Run(ctx, env)
+}
+
+func TestClientConcurrentCalls(t *testing.T) {
+	t.Parallel()
+
+	testClientConcurrentCalls(t)
+	testClientConcurrentCalls(t, ClientWithCacheRulesAndCategories())
+	testClientConcurrentCalls(t, ClientWithCacheRulesAndCategories(), ClientWithCacheTTL(time.Microsecond))
+}
+
+func testClientConcurrentCalls(t *testing.T, options ...ClientOption) {
+	ctx := context.Background()
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:          "RULE1",
+					CategoryIDs: []string{"CATEGORY1"},
+					IsDefault:   true,
+					Purpose:     "Test rule.",
+					Type:        RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+							responseWriter.AddAnnotation(WithMessage("message"))
+							return nil
+						},
+					),
+				},
+				testNewSimpleLintRuleSpec("RULE2", []string{"CATEGORY2"}, true, false, nil),
+				testNewSimpleLintRuleSpec("RULE3", nil, true, false, nil),
+			},
+			Categories: []*CategorySpec{
+				testNewSimpleCategorySpec("CATEGORY1", false, nil),
+				testNewSimpleCategorySpec("CATEGORY2", false, nil),
+			},
+		},
+		options...,
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	selectorRequest, err := NewRequest(nil, WithRuleIDs(RuleIDCategoryPrefix+"CATEGORY1", "RULE*"))
+	require.NoError(t, err)
+
+	var jobs []func(context.Context) error
+	for i := range 64 {
+		switch i % 6 {
+		case 0:
+			jobs = append(jobs, func(ctx context.Context) error {
+				response, err := client.Check(ctx, request, CheckCallWithRuleCategories())
+				if err != nil {
+					return err
+				}
+				if len(response.Annotations()) != 1 {
+					return fmt.Errorf("expected one annotation, got %d", len(response.Annotations()))
+				}
+				return nil
+			})
+		case 1:
+			jobs = append(jobs, func(ctx context.Context) error {
+				_, err := client.Check(ctx, selectorRequest)
+				return err
+			})
+		case 2:
+			jobs = append(jobs, func(ctx context.Context) error {
+				rules, err := client.ListRules(ctx, ListRulesCallWithCategoryOrder())
+				if err != nil {
+					return err
+				}
+				// The returned Rules must not be shared with other calls.
+				slices.Reverse(rules)
+				return nil
+			})
+		case 3:
+			jobs = append(jobs, func(ctx context.Context) error {
+				rules, err := client.ListRules(ctx)
+				if err != nil {
+					return err
+				}
+				if ruleIDs := xslices.Map(rules, Rule.ID); !slices.Equal(ruleIDs, []string{"RULE1", "RULE2", "RULE3"}) {
+					return fmt.Errorf("unexpected Rule IDs: %v", ruleIDs)
+				}
+				return nil
+			})
+		case 4:
+			jobs = append(jobs, func(ctx context.Context) error {
+				categories, err := client.ListCategories(ctx)
+				if err != nil {
+					return err
+				}
+				slices.Reverse(categories)
+				pluginMetadata, err := client.ListAll(ctx)
+				if err != nil {
+					return err
+				}
+				if categoryIDs := xslices.Map(pluginMetadata.Categories(), Category.ID); !slices.Equal(categoryIDs, []string{"CATEGORY1", "CATEGORY2"}) {
+					return fmt.Errorf("unexpected Category IDs: %v", categoryIDs)
+				}
+				return nil
+			})
+		case 5:
+			jobs = append(jobs, func(context.Context) error {
+				client.ClearCache()
+				return nil
+			})
+		}
+	}
+	require.NoError(t, thread.Parallelize(ctx, jobs, thread.WithParallelism(16)))
+}
+
+func TestClientCheckWithoutImportAnnotations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, responseWriter ResponseWriter, request Request) error {
+							// Imports are annotated as well.
+							for _, file := range request.Files() {
+								messages := file.FileDescriptor().Messages()
+								for i := range messages.Len() {
+									message := messages.Get(i)
+									responseWriter.AddAnnotation(WithDescriptor(message), WithMessage(string(message.FullName())))
+								}
+							}
+							return nil
+						},
+					),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	files, err := FilesForProtoFiles(
+		[]*checkv1beta1.File{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:        proto.String("dep.proto"),
+					Package:     proto.String("dep"),
+					Syntax:      proto.String("proto3"),
+					MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Dep")}},
+				},
+				IsImport: true,
+			},
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:        proto.String("a.proto"),
+					Package:     proto.String("a"),
+					Syntax:      proto.String("proto3"),
+					Dependency:  []string{"dep.proto"},
+					MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Foo")}},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(files)
+	require.NoError(t, err)
+
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	annotations := response.Annotations()
+	require.Len(t, annotations, 2)
+	require.Equal(t, "a.Foo", annotations[0].Message())
+	require.False(t, annotations[0].IsImport())
+	require.Equal(t, "dep.Dep", annotations[1].Message())
+	require.True(t, annotations[1].IsImport())
+
+	response, err = client.Check(ctx, request, CheckCallWithoutImportAnnotations())
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.Foo"}, xslices.Map(response.Annotations(), Annotation.Message))
+}
+
+type testListRulesBlockingRunner struct {
+	delegate                pluginrpc.Runner
+	listCategoriesStartedC  chan struct{}
+	listCategoriesStartOnce *sync.Once
+}
+
+func (r *testListRulesBlockingRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	switch {
+	// ListCategories is served at its default path.
+	case slices.ContainsFunc(env.Args, func(arg string) bool { return strings.HasSuffix(arg, "/ListCategories") }):
+		r.listCategoriesStartOnce.Do(func() { close(r.listCategoriesStartedC) })
+	case slices.Contains(env.Args, "list-rules"):
+		select {
+		case <-r.listCategoriesStartedC:
+		case <-time.After(10 * time.Second):
+			return errors.New("ListCategories was not called concurrently with ListRules")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return r.delegate.Run(ctx, env)
+}
@@ -75,7 +75,7 @@ func CompareLocations(one Location, two Location) int {
 		return compare
 	}
 
-	if compare := slices.Compare(one.unclonedSourcePath(), two.unclonedSourcePath()); compare != 0 {
+	if compare := slices.Compare(one.UnclonedSourcePath(), two.UnclonedSourcePath()); compare != 0 {
 		return compare
 	}
 
@@ -0,0 +1,124 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"slices"
+
+	"github.com/bufbuild/pluginrpc-go"
+)
+
+// NewClientForRunner returns a new Client for a plugin invoked by the given pluginrpc.Runner.
+//
+// This is equivalent to NewClient(pluginrpc.NewClient(runner), options...), except that the
+// Client can also read the pluginrpc Spec of the plugin, as required by
+// ClientWithRequiredProtocolVersion. For example, to run a plugin distributed as a container
+// image:
+//
+//	client := check.NewClientForRunner(check.NewContainerRunner("ghcr.io/acme/buf-plugin-acme:v1.0.0"))
+func NewClientForRunner(runner pluginrpc.Runner, options ...ClientOption) Client {
+	return newClient(pluginrpc.NewClient(runner), append(slices.Clone(options), clientWithRunner(runner))...)
+}
+
+// NewContainerRunner returns a new pluginrpc.Runner that runs the plugin within a container
+// created from the given image.
+//
+// Each invocation of the plugin runs "docker run --rm -i --network=none <image> <args...>",
+// with stdin, stdout, and stderr attached. The image's entrypoint must be the plugin. This
+// allows plugins to be distributed as images rather than as platform-specific binaries.
+//
+// The container runtime program is run with no environment variables, as with
+// pluginrpc.NewExecRunner.
+func NewContainerRunner(image string, options ...ContainerRunnerOption) pluginrpc.Runner {
+	return newContainerRunner(image, options...)
+}
+
+// ContainerRunnerOption is an option for a new container Runner.
+type ContainerRunnerOption func(*containerRunnerOptions)
+
+// ContainerRunnerWithRuntime returns a new ContainerRunnerOption that sets the container runtime
+// program to invoke.
+//
+// The program must accept docker-compatible "run" arguments, such as "podman" or "nerdctl".
+// The default is "docker".
+func ContainerRunnerWithRuntime(runtime string) ContainerRunnerOption {
+	return func(containerRunnerOptions *containerRunnerOptions) {
+		containerRunnerOptions.runtime = runtime
+	}
+}
+
+// ContainerRunnerWithNetwork returns a new ContainerRunnerOption that sets the network the
+// container is attached to.
+//
+// Plugins communicate over stdio, so the default is "none".
+func ContainerRunnerWithNetwork(network string) ContainerRunnerOption {
+	return func(containerRunnerOptions *containerRunnerOptions) {
+		containerRunnerOptions.network = network
+	}
+}
+
+// ContainerRunnerWithRunArgs returns a new ContainerRunnerOption that adds arguments to the
+// "run" command before the image, for example "--pull=never" or "--memory=512m".
+func ContainerRunnerWithRunArgs(runArgs ...string) ContainerRunnerOption {
+	return func(containerRunnerOptions *containerRunnerOptions) {
+		containerRunnerOptions.runArgs = append(containerRunnerOptions.runArgs, runArgs...)
+	}
+}
+
+// *** PRIVATE ***
+
+const (
+	defaultContainerRuntime = "docker"
+	defaultContainerNetwork = "none"
+)
+
+type containerRunner struct {
+	runtime    string
+	args       []string
+	execRunner pluginrpc.Runner
+}
+
+func newContainerRunner(image string, options ...ContainerRunnerOption) *containerRunner {
+	containerRunnerOptions := newContainerRunnerOptions()
+	for _, option := range options {
+		option(containerRunnerOptions)
+	}
+	args := []string{"run", "--rm", "-i", "--network=" + containerRunnerOptions.network}
+	args = append(args, containerRunnerOptions.runArgs...)
+	args = append(args, image)
+	return &containerRunner{
+		runtime:    containerRunnerOptions.runtime,
+		args:       args,
+		execRunner: pluginrpc.NewExecRunner(containerRunnerOptions.runtime, pluginrpc.ExecRunnerWithArgs(args...)),
+	}
+}
+
+func (c *containerRunner) Run(ctx context.Context, env pluginrpc.Env) error {
+	return c.execRunner.Run(ctx, env)
+}
+
+type containerRunnerOptions struct {
+	runtime string
+	network string
+	runArgs []string
+}
+
+func newContainerRunnerOptions() *containerRunnerOptions {
+	return &containerRunnerOptions{
+		runtime: defaultContainerRuntime,
+		network: defaultContainerNetwork,
+	}
+}
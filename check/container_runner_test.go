@@ -0,0 +1,46 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewContainerRunner(t *testing.T) {
+	t.Parallel()
+
+	containerRunner := newContainerRunner("example.com/plugin:v1")
+	require.Equal(t, "docker", containerRunner.runtime)
+	require.Equal(
+		t,
+		[]string{"run", "--rm", "-i", "--network=none", "example.com/plugin:v1"},
+		containerRunner.args,
+	)
+	containerRunner = newContainerRunner(
+		"example.com/plugin:v1",
+		ContainerRunnerWithRuntime("podman"),
+		ContainerRunnerWithNetwork("host"),
+		ContainerRunnerWithRunArgs("--pull=never"),
+		ContainerRunnerWithRunArgs("--memory=512m"),
+	)
+	require.Equal(t, "podman", containerRunner.runtime)
+	require.Equal(
+		t,
+		[]string{"run", "--rm", "-i", "--network=host", "--pull=never", "--memory=512m", "example.com/plugin:v1"},
+		containerRunner.args,
+	)
+}
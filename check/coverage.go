@@ -0,0 +1,180 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// CoverageRecorder records the Files and descriptors visited by each Rule.
+//
+// This is an instrumentation mode for plugin authors, to verify that traversal helpers
+// actually reach all intended descriptors, such as nested messages or extensions. Pass
+// a CoverageRecorder to ServerWithCoverageRecorder, or to ClientWithSpecServerOptions
+// for NewClientForSpec, and call RecordVisit from RuleHandlers. The RuleHandlers in
+// checkutil call RecordVisit for every File and descriptor they visit.
+//
+// A CoverageRecorder is safe to use concurrently, and accumulates visits across all
+// Check calls.
+type CoverageRecorder interface {
+	// Coverage returns a snapshot of the visits recorded so far.
+	Coverage() Coverage
+
+	isCoverageRecorder()
+}
+
+// NewCoverageRecorder returns a new CoverageRecorder.
+func NewCoverageRecorder() CoverageRecorder {
+	return newCoverageRecorder()
+}
+
+// Coverage is a report of the Files and descriptors visited by each Rule.
+type Coverage interface {
+	// RuleIDs returns the sorted IDs of all Rules that visited any File or descriptor.
+	RuleIDs() []string
+	// FileNames returns the sorted names of the Files visited by the Rule.
+	//
+	// A File is considered visited if the File itself or any descriptor within it
+	// was visited.
+	FileNames(ruleID string) []string
+	// FullNames returns the sorted full names of the descriptors visited by the Rule.
+	//
+	// FileDescriptors are not included, see FileNames.
+	FullNames(ruleID string) []protoreflect.FullName
+	// Visited returns true if the Rule visited the descriptor with the given full name.
+	Visited(ruleID string, fullName protoreflect.FullName) bool
+
+	isCoverage()
+}
+
+// RecordVisit records that the Rule being handled visited the descriptor.
+//
+// This is a no-op unless the context is within a Check call on a server with a
+// CoverageRecorder, so traversal helpers can call RecordVisit unconditionally.
+func RecordVisit(ctx context.Context, descriptor protoreflect.Descriptor) {
+	coverageVisitor, ok := ctx.Value(coverageVisitorContextKey{}).(*coverageVisitor)
+	if !ok || descriptor == nil {
+		return
+	}
+	coverageVisitor.recorder.recordVisit(coverageVisitor.ruleID, descriptor)
+}
+
+// *** PRIVATE ***
+
+type coverageVisitorContextKey struct{}
+
+// coverageVisitor is stored on the context of each RuleHandler when coverage is recorded.
+type coverageVisitor struct {
+	recorder *coverageRecorder
+	ruleID   string
+}
+
+func withCoverageVisitor(ctx context.Context, recorder *coverageRecorder, ruleID string) context.Context {
+	if recorder == nil {
+		return ctx
+	}
+	return context.WithValue(
+		ctx,
+		coverageVisitorContextKey{},
+		&coverageVisitor{
+			recorder: recorder,
+			ruleID:   ruleID,
+		},
+	)
+}
+
+type coverageRecorder struct {
+	ruleIDToFileNames map[string]map[string]struct{}
+	ruleIDToFullNames map[string]map[protoreflect.FullName]struct{}
+	lock              sync.Mutex
+}
+
+func newCoverageRecorder() *coverageRecorder {
+	return &coverageRecorder{
+		ruleIDToFileNames: make(map[string]map[string]struct{}),
+		ruleIDToFullNames: make(map[string]map[protoreflect.FullName]struct{}),
+	}
+}
+
+func (c *coverageRecorder) Coverage() Coverage {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	coverage := &coverage{
+		ruleIDToFileNames: make(map[string][]string, len(c.ruleIDToFileNames)),
+		ruleIDToFullNames: make(map[string][]protoreflect.FullName, len(c.ruleIDToFullNames)),
+	}
+	for ruleID, fileNames := range c.ruleIDToFileNames {
+		coverage.ruleIDToFileNames[ruleID] = xslices.MapKeysToSortedSlice(fileNames)
+	}
+	for ruleID, fullNames := range c.ruleIDToFullNames {
+		coverage.ruleIDToFullNames[ruleID] = xslices.MapKeysToSortedSlice(fullNames)
+	}
+	coverage.ruleIDs = xslices.MapKeysToSortedSlice(coverage.ruleIDToFileNames)
+	return coverage
+}
+
+func (c *coverageRecorder) recordVisit(ruleID string, descriptor protoreflect.Descriptor) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if parentFile := descriptor.ParentFile(); parentFile != nil {
+		addToSet(c.ruleIDToFileNames, ruleID, parentFile.Path())
+	}
+	if _, ok := descriptor.(protoreflect.FileDescriptor); !ok {
+		addToSet(c.ruleIDToFullNames, ruleID, descriptor.FullName())
+	}
+}
+
+func (*coverageRecorder) isCoverageRecorder() {}
+
+type coverage struct {
+	ruleIDs           []string
+	ruleIDToFileNames map[string][]string
+	ruleIDToFullNames map[string][]protoreflect.FullName
+}
+
+func (c *coverage) RuleIDs() []string {
+	return slices.Clone(c.ruleIDs)
+}
+
+func (c *coverage) FileNames(ruleID string) []string {
+	return slices.Clone(c.ruleIDToFileNames[ruleID])
+}
+
+func (c *coverage) FullNames(ruleID string) []protoreflect.FullName {
+	return slices.Clone(c.ruleIDToFullNames[ruleID])
+}
+
+func (c *coverage) Visited(ruleID string, fullName protoreflect.FullName) bool {
+	_, found := slices.BinarySearch(c.ruleIDToFullNames[ruleID], fullName)
+	return found
+}
+
+func (*coverage) isCoverage() {}
+
+func addToSet[K comparable, V comparable](m map[K]map[V]struct{}, key K, value V) {
+	set, ok := m[key]
+	if !ok {
+		set = make(map[V]struct{})
+		m[key] = set
+	}
+	set[value] = struct{}{}
+}
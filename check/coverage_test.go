@@ -0,0 +1,75 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestCoverageRecorder(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	// RecordVisit is a no-op outside of a Check call with a CoverageRecorder.
+	RecordVisit(ctx, descriptorpb.File_google_protobuf_descriptor_proto)
+
+	coverageRecorder := NewCoverageRecorder()
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule1.",
+					Type:      RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(ctx context.Context, _ ResponseWriter, _ Request) error {
+							fileDescriptor := descriptorpb.File_google_protobuf_descriptor_proto
+							RecordVisit(ctx, fileDescriptor)
+							RecordVisit(ctx, fileDescriptor.Messages().ByName("FileDescriptorSet"))
+							return nil
+						},
+					),
+				},
+				{
+					ID:        "RULE2",
+					IsDefault: true,
+					Purpose:   "Test rule2.",
+					Type:      RuleTypeLint,
+					Handler:   nopRuleHandler,
+				},
+			},
+		},
+		ClientWithSpecServerOptions(ServerWithCoverageRecorder(coverageRecorder)),
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.NoError(t, err)
+
+	coverage := coverageRecorder.Coverage()
+	require.Equal(t, []string{"RULE1"}, coverage.RuleIDs())
+	require.Equal(t, []string{"google/protobuf/descriptor.proto"}, coverage.FileNames("RULE1"))
+	require.Equal(t, []protoreflect.FullName{"google.protobuf.FileDescriptorSet"}, coverage.FullNames("RULE1"))
+	require.True(t, coverage.Visited("RULE1", "google.protobuf.FileDescriptorSet"))
+	require.False(t, coverage.Visited("RULE2", "google.protobuf.FileDescriptorSet"))
+	require.Empty(t, coverage.FileNames("RULE2"))
+}
@@ -0,0 +1,264 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// DescriptorKindFile is a file.
+	//
+	// File-level content, such as the name, package, imports, and options of a file, is never
+	// pruned, so declaring DescriptorKindFile is equivalent to declaring that a Rule inspects
+	// no other descriptor kinds.
+	DescriptorKindFile DescriptorKind = 1
+	// DescriptorKindPackage is the package of a file.
+	DescriptorKindPackage DescriptorKind = 2
+	// DescriptorKindMessage is a message, including nested messages.
+	DescriptorKindMessage DescriptorKind = 3
+	// DescriptorKindField is a field of a message. Extensions are not included.
+	DescriptorKindField DescriptorKind = 4
+	// DescriptorKindOneof is a oneof.
+	DescriptorKindOneof DescriptorKind = 5
+	// DescriptorKindEnum is an enum, including nested enums.
+	DescriptorKindEnum DescriptorKind = 6
+	// DescriptorKindEnumValue is a value of an enum.
+	DescriptorKindEnumValue DescriptorKind = 7
+	// DescriptorKindService is a service.
+	DescriptorKindService DescriptorKind = 8
+	// DescriptorKindMethod is a method of a service.
+	DescriptorKindMethod DescriptorKind = 9
+	// DescriptorKindExtension is an extension, including nested extensions.
+	DescriptorKindExtension DescriptorKind = 10
+)
+
+var (
+	descriptorKindToString = map[DescriptorKind]string{
+		DescriptorKindFile:      "file",
+		DescriptorKindPackage:   "package",
+		DescriptorKindMessage:   "message",
+		DescriptorKindField:     "field",
+		DescriptorKindOneof:     "oneof",
+		DescriptorKindEnum:      "enum",
+		DescriptorKindEnumValue: "enum_value",
+		DescriptorKindService:   "service",
+		DescriptorKindMethod:    "method",
+		DescriptorKindExtension: "extension",
+	}
+	stringToDescriptorKind = map[string]DescriptorKind{
+		"file":       DescriptorKindFile,
+		"package":    DescriptorKindPackage,
+		"message":    DescriptorKindMessage,
+		"field":      DescriptorKindField,
+		"oneof":      DescriptorKindOneof,
+		"enum":       DescriptorKindEnum,
+		"enum_value": DescriptorKindEnumValue,
+		"service":    DescriptorKindService,
+		"method":     DescriptorKindMethod,
+		"extension":  DescriptorKindExtension,
+	}
+)
+
+// DescriptorKind is a kind of descriptor.
+//
+// DescriptorKinds are used to declare the descriptors that a Rule inspects, see
+// RuleSpec.DescriptorKinds, and by checkutil to target descriptors of a given kind.
+type DescriptorKind int
+
+// ParseDescriptorKind parses the DescriptorKind from its string representation.
+//
+// The string representation is the same as returned from String, for example "enum_value".
+func ParseDescriptorKind(s string) (DescriptorKind, error) {
+	descriptorKind, ok := stringToDescriptorKind[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown descriptor kind: %q", s)
+	}
+	return descriptorKind, nil
+}
+
+// String implements fmt.Stringer.
+func (k DescriptorKind) String() string {
+	if s, ok := descriptorKindToString[k]; ok {
+		return s
+	}
+	return strconv.Itoa(int(k))
+}
+
+// *** PRIVATE ***
+
+// descriptorKindsForRuleSpecs returns the union of the DescriptorKinds of the RuleSpecs.
+//
+// Returns false if any RuleSpec does not declare its DescriptorKinds, in which case nothing
+// can be pruned.
+func descriptorKindsForRuleSpecs(ruleSpecs []*RuleSpec) (map[DescriptorKind]struct{}, bool) {
+	descriptorKinds := make(map[DescriptorKind]struct{})
+	for _, ruleSpec := range ruleSpecs {
+		if len(ruleSpec.DescriptorKinds) == 0 {
+			return nil, false
+		}
+		for _, descriptorKind := range ruleSpec.DescriptorKinds {
+			descriptorKinds[descriptorKind] = struct{}{}
+		}
+	}
+	return descriptorKinds, true
+}
+
+// pruneCheckRequest returns a copy of the CheckRequest with the descriptors that are not
+// of the given DescriptorKinds removed from all Files.
+//
+// Pruning is best-effort: descriptors are only removed if the result is still a valid set of
+// Files. For example, messages are kept if fields, extensions, enums, or services are kept, as
+// fields and methods may refer to messages, and enums may be nested within messages. Whole lists
+// are always removed, so that the paths of the remaining descriptors are unchanged. The
+// SourceCodeInfo locations of removed descriptors are removed as well.
+//
+// Returns the CheckRequest itself if nothing would be pruned.
+func pruneCheckRequest(
+	checkRequest *checkv1beta1.CheckRequest,
+	descriptorKinds map[DescriptorKind]struct{},
+) *checkv1beta1.CheckRequest {
+	has := func(descriptorKind DescriptorKind) bool {
+		_, ok := descriptorKinds[descriptorKind]
+		return ok
+	}
+	pruneServices := !has(DescriptorKindService) && !has(DescriptorKindMethod)
+	// Fields refer to oneofs by index, so oneofs are only kept with fields.
+	pruneFields := !has(DescriptorKindField) && !has(DescriptorKindOneof)
+	pruneExtensions := !has(DescriptorKindExtension)
+	pruneEnums := pruneFields && pruneExtensions && !has(DescriptorKindEnum) && !has(DescriptorKindEnumValue)
+	pruneMessages := pruneFields && pruneExtensions && pruneEnums && pruneServices && !has(DescriptorKindMessage)
+	if !pruneServices && !pruneFields && !pruneExtensions {
+		return checkRequest
+	}
+	descriptorProtoPruner := &descriptorProtoPruner{
+		pruneFields:     pruneFields,
+		pruneExtensions: pruneExtensions,
+		pruneEnums:      pruneEnums,
+	}
+	checkRequest, _ = proto.Clone(checkRequest).(*checkv1beta1.CheckRequest)
+	rangeRequestFileDescriptorProtos(
+		checkRequest,
+		func(fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+			var prunedPaths [][]int32
+			prune := func(isEmpty bool, fieldNumber int32, clear func()) {
+				if !isEmpty {
+					clear()
+					prunedPaths = append(prunedPaths, []int32{fieldNumber})
+				}
+			}
+			if pruneServices {
+				prune(len(fileDescriptorProto.GetService()) == 0, fileDescriptorProtoServiceFieldNumber, func() { fileDescriptorProto.Service = nil })
+			}
+			if pruneMessages {
+				prune(len(fileDescriptorProto.GetMessageType()) == 0, fileDescriptorProtoMessageTypeFieldNumber, func() { fileDescriptorProto.MessageType = nil })
+			}
+			if pruneEnums {
+				prune(len(fileDescriptorProto.GetEnumType()) == 0, fileDescriptorProtoEnumTypeFieldNumber, func() { fileDescriptorProto.EnumType = nil })
+			}
+			if pruneExtensions {
+				prune(len(fileDescriptorProto.GetExtension()) == 0, fileDescriptorProtoExtensionFieldNumber, func() { fileDescriptorProto.Extension = nil })
+			}
+			for i, descriptorProto := range fileDescriptorProto.GetMessageType() {
+				prunedPaths = descriptorProtoPruner.prune(
+					descriptorProto,
+					[]int32{fileDescriptorProtoMessageTypeFieldNumber, int32(i)},
+					prunedPaths,
+				)
+			}
+			pruneSourceCodeInfo(fileDescriptorProto.GetSourceCodeInfo(), prunedPaths)
+		},
+	)
+	return checkRequest
+}
+
+// The field numbers of the lists of descriptors, as used in SourceCodeInfo paths.
+const (
+	fileDescriptorProtoMessageTypeFieldNumber = 4
+	fileDescriptorProtoEnumTypeFieldNumber    = 5
+	fileDescriptorProtoServiceFieldNumber     = 6
+	fileDescriptorProtoExtensionFieldNumber   = 7
+	descriptorProtoFieldFieldNumber           = 2
+	descriptorProtoNestedTypeFieldNumber      = 3
+	descriptorProtoEnumTypeFieldNumber        = 4
+	descriptorProtoExtensionFieldNumber       = 6
+	descriptorProtoOneofDeclFieldNumber       = 8
+	enumDescriptorProtoValueFieldNumber       = 2
+	serviceDescriptorProtoMethodFieldNumber   = 2
+)
+
+type descriptorProtoPruner struct {
+	pruneFields     bool
+	pruneExtensions bool
+	pruneEnums      bool
+}
+
+// prune prunes the DescriptorProto at the given path and its nested DescriptorProtos, and
+// returns prunedPaths with the paths of the removed lists appended.
+func (p *descriptorProtoPruner) prune(
+	descriptorProto *descriptorpb.DescriptorProto,
+	path []int32,
+	prunedPaths [][]int32,
+) [][]int32 {
+	prune := func(isEmpty bool, fieldNumber int32, clear func()) {
+		if !isEmpty {
+			clear()
+			prunedPaths = append(prunedPaths, append(slices.Clone(path), fieldNumber))
+		}
+	}
+	if p.pruneFields {
+		prune(len(descriptorProto.GetField()) == 0, descriptorProtoFieldFieldNumber, func() { descriptorProto.Field = nil })
+		prune(len(descriptorProto.GetOneofDecl()) == 0, descriptorProtoOneofDeclFieldNumber, func() { descriptorProto.OneofDecl = nil })
+	}
+	if p.pruneExtensions {
+		prune(len(descriptorProto.GetExtension()) == 0, descriptorProtoExtensionFieldNumber, func() { descriptorProto.Extension = nil })
+	}
+	if p.pruneEnums {
+		prune(len(descriptorProto.GetEnumType()) == 0, descriptorProtoEnumTypeFieldNumber, func() { descriptorProto.EnumType = nil })
+	}
+	for i, nestedDescriptorProto := range descriptorProto.GetNestedType() {
+		prunedPaths = p.prune(
+			nestedDescriptorProto,
+			append(slices.Clone(path), descriptorProtoNestedTypeFieldNumber, int32(i)),
+			prunedPaths,
+		)
+	}
+	return prunedPaths
+}
+
+// pruneSourceCodeInfo removes the locations that are within any of the pruned paths.
+func pruneSourceCodeInfo(sourceCodeInfo *descriptorpb.SourceCodeInfo, prunedPaths [][]int32) {
+	if sourceCodeInfo == nil || len(prunedPaths) == 0 {
+		return
+	}
+	sourceCodeInfo.Location = slices.DeleteFunc(
+		sourceCodeInfo.GetLocation(),
+		func(location *descriptorpb.SourceCodeInfo_Location) bool {
+			return slices.ContainsFunc(
+				prunedPaths,
+				func(prunedPath []int32) bool {
+					path := location.GetPath()
+					return len(path) >= len(prunedPath) && slices.Equal(path[:len(prunedPath)], prunedPath)
+				},
+			)
+		},
+	)
+}
@@ -0,0 +1,555 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestDescriptorKindsPruning(t *testing.T) {
+	t.Parallel()
+
+	testDescriptorKindsPruning(
+		t,
+		[][]DescriptorKind{{DescriptorKindFile}},
+		testDescriptorCounts{},
+	)
+	testDescriptorKindsPruning(
+		t,
+		[][]DescriptorKind{{DescriptorKindMessage}},
+		testDescriptorCounts{
+			// Struct, Struct.FieldsEntry, Value, ListValue.
+			Messages: 4,
+		},
+	)
+	testDescriptorKindsPruning(
+		t,
+		[][]DescriptorKind{{DescriptorKindEnum}},
+		testDescriptorCounts{
+			Messages: 4,
+			Enums:    1,
+		},
+	)
+	testDescriptorKindsPruning(
+		t,
+		[][]DescriptorKind{{DescriptorKindService}},
+		testDescriptorCounts{
+			Messages: 4,
+			Services: 1,
+		},
+	)
+	testDescriptorKindsPruning(
+		t,
+		[][]DescriptorKind{{DescriptorKindMessage}, {DescriptorKindField}},
+		testDescriptorCounts{
+			Messages: 4,
+			Fields:   10,
+			Enums:    1,
+		},
+	)
+	// A Rule that does not declare its DescriptorKinds results in nothing being pruned.
+	testDescriptorKindsPruning(
+		t,
+		[][]DescriptorKind{{DescriptorKindFile}, nil},
+		testDescriptorCounts{
+			Messages: 4,
+			Fields:   10,
+			Enums:    1,
+			Services: 1,
+		},
+	)
+}
+
+func TestPruneCheckRequest(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		descriptorKinds []DescriptorKind
+		// expectedFullNames are the full names of all descriptors in the pruned File.
+		expectedFullNames []string
+		// expectedPaths are the paths of the SourceCodeInfo locations in the pruned File.
+		expectedPaths [][]int32
+	}
+	testCases := []testCase{
+		{
+			descriptorKinds: []DescriptorKind{DescriptorKindFile},
+			expectedPaths: [][]int32{
+				{2},
+			},
+		},
+		{
+			descriptorKinds: []DescriptorKind{DescriptorKindMessage},
+			expectedFullNames: []string{
+				"a.Foo",
+				"a.Foo.LabelsEntry",
+				"a.Foo.Bar",
+			},
+			expectedPaths: [][]int32{
+				{2},
+				{4, 0},
+				{4, 0, 3, 0},
+				{4, 0, 3, 1},
+			},
+		},
+		{
+			descriptorKinds: []DescriptorKind{DescriptorKindEnumValue},
+			expectedFullNames: []string{
+				"a.Foo",
+				"a.Foo.LabelsEntry",
+				"a.Foo.Bar",
+				"a.Foo.Bar.Kind",
+				"a.Foo.Bar.KIND_UNSPECIFIED",
+			},
+			expectedPaths: [][]int32{
+				{2},
+				{4, 0},
+				{4, 0, 3, 0},
+				{4, 0, 3, 1},
+				{4, 0, 3, 1, 4, 0},
+			},
+		},
+		{
+			descriptorKinds: []DescriptorKind{DescriptorKindOneof},
+			expectedFullNames: []string{
+				"a.Foo",
+				"a.Foo.labels",
+				"a.Foo.name",
+				"a.Foo.value",
+				"a.Foo.LabelsEntry",
+				"a.Foo.LabelsEntry.key",
+				"a.Foo.LabelsEntry.value",
+				"a.Foo.Bar",
+				"a.Foo.Bar.kind",
+				"a.Foo.Bar.Kind",
+				"a.Foo.Bar.KIND_UNSPECIFIED",
+			},
+			expectedPaths: [][]int32{
+				{2},
+				{4, 0},
+				{4, 0, 2, 0},
+				{4, 0, 2, 1},
+				{4, 0, 8, 0},
+				{4, 0, 3, 0},
+				{4, 0, 3, 1},
+				{4, 0, 3, 1, 2, 0},
+				{4, 0, 3, 1, 4, 0},
+			},
+		},
+		{
+			descriptorKinds: []DescriptorKind{DescriptorKindExtension},
+			expectedFullNames: []string{
+				"a.Foo",
+				"a.Foo.LabelsEntry",
+				"a.Foo.Bar",
+				"a.Foo.Bar.Kind",
+				"a.Foo.Bar.KIND_UNSPECIFIED",
+				"a.Foo.Bar.nested_ext",
+				"a.ext",
+			},
+			expectedPaths: [][]int32{
+				{2},
+				{4, 0},
+				{4, 0, 3, 0},
+				{4, 0, 3, 1},
+				{4, 0, 3, 1, 4, 0},
+				{4, 0, 3, 1, 6},
+				{4, 0, 3, 1, 6, 0},
+				{7},
+				{7, 0},
+			},
+		},
+		{
+			descriptorKinds: []DescriptorKind{DescriptorKindMethod},
+			expectedFullNames: []string{
+				"a.Foo",
+				"a.Foo.LabelsEntry",
+				"a.Foo.Bar",
+				"a.FooService",
+				"a.FooService.GetFoo",
+			},
+			expectedPaths: [][]int32{
+				{2},
+				{4, 0},
+				{4, 0, 3, 0},
+				{4, 0, 3, 1},
+				{6, 0},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		descriptorKinds := make(map[DescriptorKind]struct{})
+		for _, descriptorKind := range testCase.descriptorKinds {
+			descriptorKinds[descriptorKind] = struct{}{}
+		}
+		checkRequest := &checkv1beta1.CheckRequest{
+			Files: []*checkv1beta1.File{
+				{
+					FileDescriptorProto: testPruneFileDescriptorProto(),
+				},
+			},
+		}
+		prunedCheckRequest := pruneCheckRequest(checkRequest, descriptorKinds)
+		// The pruned CheckRequest must still result in a valid set of Files.
+		request, err := RequestForProtoRequest(prunedCheckRequest)
+		require.NoError(t, err, testCase.descriptorKinds)
+		require.Len(t, request.Files(), 1)
+		require.ElementsMatch(
+			t,
+			testCase.expectedFullNames,
+			testAllDescriptorFullNames(request.Files()[0].FileDescriptor()),
+			testCase.descriptorKinds,
+		)
+		var paths [][]int32
+		for _, location := range prunedCheckRequest.GetFiles()[0].GetFileDescriptorProto().GetSourceCodeInfo().GetLocation() {
+			paths = append(paths, location.GetPath())
+		}
+		require.Equal(t, testCase.expectedPaths, paths, testCase.descriptorKinds)
+		// The CheckRequest given to pruneCheckRequest is not modified.
+		require.True(
+			t,
+			proto.Equal(testPruneFileDescriptorProto(), checkRequest.GetFiles()[0].GetFileDescriptorProto()),
+			testCase.descriptorKinds,
+		)
+	}
+}
+
+func TestDescriptorKindsValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewLintRule(
+		"RULE1",
+		"Test rule1.",
+		nopRuleHandler,
+		RuleSpecWithDescriptorKinds(DescriptorKindMessage, DescriptorKind(100)),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown DescriptorKind: 100")
+
+	ruleSpec, err := NewLintRule(
+		"RULE1",
+		"Test rule1.",
+		nopRuleHandler,
+		RuleSpecWithDescriptorKinds(DescriptorKindMessage),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []DescriptorKind{DescriptorKindMessage}, ruleSpec.DescriptorKinds)
+	require.Equal(t, "message", DescriptorKindMessage.String())
+	descriptorKind, err := ParseDescriptorKind("enum_value")
+	require.NoError(t, err)
+	require.Equal(t, DescriptorKindEnumValue, descriptorKind)
+	_, err = ParseDescriptorKind("unknown")
+	require.Error(t, err)
+}
+
+type testDescriptorCounts struct {
+	Messages int
+	Fields   int
+	Enums    int
+	Services int
+}
+
+// testDescriptorKindsPruning runs one default Rule per element of ruleDescriptorKinds on
+// google/protobuf/struct.proto and a file with a service, and verifies the descriptors
+// that each Rule sees.
+func testDescriptorKindsPruning(
+	t *testing.T,
+	ruleDescriptorKinds [][]DescriptorKind,
+	expectedDescriptorCounts testDescriptorCounts,
+) {
+	ruleIDs := []string{"RULE1", "RULE2"}
+	ruleSpecs := make([]*RuleSpec, len(ruleDescriptorKinds))
+	ruleIDToDescriptorCounts := make(map[string]testDescriptorCounts, len(ruleDescriptorKinds))
+	for i, descriptorKinds := range ruleDescriptorKinds {
+		ruleID := ruleIDs[i]
+		ruleSpecs[i] = &RuleSpec{
+			ID:        ruleID,
+			IsDefault: true,
+			Purpose:   "Test rule.",
+			Type:      RuleTypeLint,
+			Handler: RuleHandlerFunc(
+				func(_ context.Context, _ ResponseWriter, request Request) error {
+					ruleIDToDescriptorCounts[ruleID] = testCountDescriptors(request.Files())
+					return nil
+				},
+			),
+			DescriptorKinds: descriptorKinds,
+		}
+	}
+	client, err := NewClientForSpec(
+		&Spec{Rules: ruleSpecs},
+		// RuleHandlers write to the map.
+		ClientWithSpecServerOptions(ServerWithParallelism(1)),
+	)
+	require.NoError(t, err)
+	files, err := FilesForProtoFiles(testDescriptorKindsProtoFiles())
+	require.NoError(t, err)
+	request, err := NewRequest(files)
+	require.NoError(t, err)
+	_, err = client.Check(context.Background(), request)
+	require.NoError(t, err)
+	require.Len(t, ruleIDToDescriptorCounts, len(ruleDescriptorKinds))
+	for _, descriptorCounts := range ruleIDToDescriptorCounts {
+		require.Equal(t, expectedDescriptorCounts, descriptorCounts)
+	}
+	// The Request given to the Client is not modified.
+	require.Equal(
+		t,
+		testDescriptorCounts{Messages: 4, Fields: 10, Enums: 1, Services: 1},
+		testCountDescriptors(request.Files()),
+	)
+}
+
+func testCountDescriptors(files []File) testDescriptorCounts {
+	var descriptorCounts testDescriptorCounts
+	var countMessages func([]*descriptorpb.DescriptorProto)
+	countMessages = func(descriptorProtos []*descriptorpb.DescriptorProto) {
+		for _, descriptorProto := range descriptorProtos {
+			descriptorCounts.Messages++
+			descriptorCounts.Fields += len(descriptorProto.GetField())
+			descriptorCounts.Enums += len(descriptorProto.GetEnumType())
+			countMessages(descriptorProto.GetNestedType())
+		}
+	}
+	for _, file := range files {
+		fileDescriptorProto := file.FileDescriptorProto()
+		countMessages(fileDescriptorProto.GetMessageType())
+		descriptorCounts.Enums += len(fileDescriptorProto.GetEnumType())
+		descriptorCounts.Services += len(fileDescriptorProto.GetService())
+	}
+	return descriptorCounts
+}
+
+func testDescriptorKindsProtoFiles() []*checkv1beta1.File {
+	return []*checkv1beta1.File{
+		{
+			FileDescriptorProto: protodesc.ToFileDescriptorProto(structpb.File_google_protobuf_struct_proto),
+			IsImport:            true,
+		},
+		{
+			FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+				Name:       proto.String("foo.proto"),
+				Syntax:     proto.String("proto3"),
+				Package:    proto.String("foo"),
+				Dependency: []string{"google/protobuf/struct.proto"},
+				Service: []*descriptorpb.ServiceDescriptorProto{
+					{
+						Name: proto.String("FooService"),
+						Method: []*descriptorpb.MethodDescriptorProto{
+							{
+								Name:       proto.String("Foo"),
+								InputType:  proto.String(".google.protobuf.Struct"),
+								OutputType: proto.String(".google.protobuf.Value"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// testPruneFileDescriptorProto returns the FileDescriptorProto for:
+//
+//	syntax = "proto2";
+//	package a;
+//	message Foo {
+//	  map<string, string> labels = 1;
+//	  oneof value {
+//	    string name = 2;
+//	  }
+//	  message Bar {
+//	    enum Kind {
+//	      KIND_UNSPECIFIED = 0;
+//	    }
+//	    optional Kind kind = 1;
+//	    extend Foo {
+//	      optional string nested_ext = 101;
+//	    }
+//	  }
+//	  extensions 100 to 200;
+//	}
+//	extend Foo {
+//	  optional string ext = 100;
+//	}
+//	service FooService {
+//	  rpc GetFoo(Foo) returns (Foo);
+//	}
+//
+// The SourceCodeInfo has one location per element, each on its own line.
+func testPruneFileDescriptorProto() *descriptorpb.FileDescriptorProto {
+	newField := func(
+		name string,
+		number int32,
+		label descriptorpb.FieldDescriptorProto_Label,
+		fieldType descriptorpb.FieldDescriptorProto_Type,
+	) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    label.Enum(),
+			Type:     fieldType.Enum(),
+			JsonName: proto.String(name),
+		}
+	}
+	labelsField := newField("labels", 1, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	labelsField.TypeName = proto.String(".a.Foo.LabelsEntry")
+	nameField := newField("name", 2, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	nameField.OneofIndex = proto.Int32(0)
+	kindField := newField("kind", 1, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_ENUM)
+	kindField.TypeName = proto.String(".a.Foo.Bar.Kind")
+	nestedExtField := newField("nested_ext", 101, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	nestedExtField.Extendee = proto.String(".a.Foo")
+	nestedExtField.JsonName = nil
+	extField := newField("ext", 100, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING)
+	extField.Extendee = proto.String(".a.Foo")
+	extField.JsonName = nil
+	var locations []*descriptorpb.SourceCodeInfo_Location
+	for i, path := range [][]int32{
+		{2},
+		{4, 0},
+		{4, 0, 2, 0},
+		{4, 0, 2, 1},
+		{4, 0, 8, 0},
+		{4, 0, 3, 0},
+		{4, 0, 3, 1},
+		{4, 0, 3, 1, 2, 0},
+		{4, 0, 3, 1, 4, 0},
+		{4, 0, 3, 1, 6},
+		{4, 0, 3, 1, 6, 0},
+		{7},
+		{7, 0},
+		{6, 0},
+	} {
+		locations = append(
+			locations,
+			&descriptorpb.SourceCodeInfo_Location{
+				Path: path,
+				Span: []int32{int32(i), 0, 1},
+			},
+		)
+	}
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("a.proto"),
+		Syntax:  proto.String("proto2"),
+		Package: proto.String("a"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:  proto.String("Foo"),
+				Field: []*descriptorpb.FieldDescriptorProto{labelsField, nameField},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("LabelsEntry"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							newField("key", 1, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+							newField("value", 2, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+						},
+						Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					},
+					{
+						Name:  proto.String("Bar"),
+						Field: []*descriptorpb.FieldDescriptorProto{kindField},
+						EnumType: []*descriptorpb.EnumDescriptorProto{
+							{
+								Name: proto.String("Kind"),
+								Value: []*descriptorpb.EnumValueDescriptorProto{
+									{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+								},
+							},
+						},
+						Extension: []*descriptorpb.FieldDescriptorProto{nestedExtField},
+					},
+				},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{
+					{Name: proto.String("value")},
+				},
+				ExtensionRange: []*descriptorpb.DescriptorProto_ExtensionRange{
+					{Start: proto.Int32(100), End: proto.Int32(201)},
+				},
+			},
+		},
+		Extension: []*descriptorpb.FieldDescriptorProto{extField},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("FooService"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("GetFoo"),
+						InputType:  proto.String(".a.Foo"),
+						OutputType: proto.String(".a.Foo"),
+					},
+				},
+			},
+		},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: locations,
+		},
+	}
+}
+
+// testAllDescriptorFullNames returns the full names of all descriptors within the
+// FileDescriptor, excluding the FileDescriptor itself.
+func testAllDescriptorFullNames(fileDescriptor protoreflect.FileDescriptor) []string {
+	var fullNames []string
+	addFields := func(fields protoreflect.ExtensionDescriptors) {
+		for i := range fields.Len() {
+			fullNames = append(fullNames, string(fields.Get(i).FullName()))
+		}
+	}
+	addEnums := func(enums protoreflect.EnumDescriptors) {
+		for i := range enums.Len() {
+			enum := enums.Get(i)
+			fullNames = append(fullNames, string(enum.FullName()))
+			for j := range enum.Values().Len() {
+				fullNames = append(fullNames, string(enum.Values().Get(j).FullName()))
+			}
+		}
+	}
+	var addMessages func(protoreflect.MessageDescriptors)
+	addMessages = func(messages protoreflect.MessageDescriptors) {
+		for i := range messages.Len() {
+			message := messages.Get(i)
+			fullNames = append(fullNames, string(message.FullName()))
+			for j := range message.Fields().Len() {
+				fullNames = append(fullNames, string(message.Fields().Get(j).FullName()))
+			}
+			for j := range message.Oneofs().Len() {
+				fullNames = append(fullNames, string(message.Oneofs().Get(j).FullName()))
+			}
+			addEnums(message.Enums())
+			addFields(message.Extensions())
+			addMessages(message.Messages())
+		}
+	}
+	addMessages(fileDescriptor.Messages())
+	addEnums(fileDescriptor.Enums())
+	addFields(fileDescriptor.Extensions())
+	for i := range fileDescriptor.Services().Len() {
+		service := fileDescriptor.Services().Get(i)
+		fullNames = append(fullNames, string(service.FullName()))
+		for j := range service.Methods().Len() {
+			fullNames = append(fullNames, string(service.Methods().Get(j).FullName()))
+		}
+	}
+	return fullNames
+}
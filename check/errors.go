@@ -15,9 +15,12 @@
 package check
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/bufbuild/pluginrpc-go"
 )
 
 type duplicateRuleIDError struct {
@@ -221,3 +224,11 @@ func (vr *validateSpecError) Unwrap() error {
 	}
 	return vr.delegate
 }
+
+// newContextDoneError returns a new pluginrpc.Error for the given error from context.Context.Err.
+func newContextDoneError(ctxErr error) *pluginrpc.Error {
+	if errors.Is(ctxErr, context.DeadlineExceeded) {
+		return pluginrpc.NewError(pluginrpc.CodeDeadlineExceeded, ctxErr)
+	}
+	return pluginrpc.NewError(pluginrpc.CodeCanceled, ctxErr)
+}
@@ -0,0 +1,248 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/bufbuild/pluginrpc-go"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ExceptionsOptionKey is the key of the standard Option that exempts Files and descriptors
+// from Rules.
+//
+// Plugins apply this Option automatically, so that each plugin does not need to implement
+// its own exemption configuration. The value is a []string, where each element has the
+// form "<rule ID selector>=<pattern>":
+//
+//	options:
+//	  bufplugin_exceptions:
+//	    - "FIELD_LOWER_SNAKE_CASE=vendor/**"
+//	    - "TIMESTAMP_SUFFIX=acme.legacy.v1.**"
+//	    - "category:STYLE=acme.v1.Legacy*"
+//
+// The rule ID selector is a Rule ID, or a selector as described in WithRuleIDs. The
+// pattern is matched against file paths if it contains a "/" or ends in ".proto", and
+// against the full names of descriptors otherwise. Within a pattern, "*" matches any
+// sequence of characters other than the separator, which is "/" for file paths and "." for
+// full names, "?" matches any single character other than the separator, and "**" matches
+// any sequence of characters including separators.
+//
+// Annotations of the selected Rules whose Location is within a matching File or descriptor
+// are suppressed, as with ResponseWriter.SuppressAnnotations. RuleHandlers are still run,
+// and the Option is still visible within the Options of the Request.
+const ExceptionsOptionKey = "bufplugin_exceptions"
+
+// *** PRIVATE ***
+
+// exception is a parsed element of the value of the ExceptionsOptionKey Option.
+type exception struct {
+	ruleIDs map[string]struct{}
+	// isFilePattern is true if the pattern is matched against file paths, and false if
+	// the pattern is matched against the full names of descriptors.
+	isFilePattern bool
+	regexp        *regexp.Regexp
+}
+
+// getExceptions parses the value of the ExceptionsOptionKey Option, resolving the rule ID
+// selectors against the Rules.
+//
+// Returns an empty slice if the Option is not set.
+func getExceptions(options Options, rules []Rule, ruleIDToRule map[string]Rule) ([]*exception, error) {
+	values, err := GetStringSliceValue(options, ExceptionsOptionKey)
+	if err != nil {
+		return nil, pluginrpc.NewError(pluginrpc.CodeInvalidArgument, err)
+	}
+	exceptions := make([]*exception, 0, len(values))
+	for _, value := range values {
+		exception, err := parseException(value, rules, ruleIDToRule)
+		if err != nil {
+			return nil, pluginrpc.NewErrorf(pluginrpc.CodeInvalidArgument, "invalid value %q for option %q: %v", value, ExceptionsOptionKey, err)
+		}
+		exceptions = append(exceptions, exception)
+	}
+	return exceptions, nil
+}
+
+func parseException(value string, rules []Rule, ruleIDToRule map[string]Rule) (*exception, error) {
+	ruleIDSelector, pattern, ok := strings.Cut(value, "=")
+	if !ok || ruleIDSelector == "" || pattern == "" {
+		return nil, errors.New(`must be of the form "<rule ID selector>=<pattern>"`)
+	}
+	ruleIDs, err := resolveRuleIDSelectors([]string{ruleIDSelector}, rules)
+	if err != nil {
+		return nil, err
+	}
+	for _, ruleID := range ruleIDs {
+		if _, ok := ruleIDToRule[ruleID]; !ok {
+			return nil, fmt.Errorf("unknown rule ID: %q", ruleID)
+		}
+	}
+	isFilePattern := strings.Contains(pattern, "/") || strings.HasSuffix(pattern, ".proto")
+	separator := byte('.')
+	if isFilePattern {
+		separator = '/'
+	}
+	patternRegexp, err := exceptionPatternToRegexp(pattern, separator)
+	if err != nil {
+		return nil, err
+	}
+	return &exception{
+		ruleIDs:       xslices.ToStructMap(ruleIDs),
+		isFilePattern: isFilePattern,
+		regexp:        patternRegexp,
+	}, nil
+}
+
+// exceptionPatternToRegexp converts the pattern to an anchored regular expression.
+//
+// See ExceptionsOptionKey for the pattern syntax. A "**" followed by the separator also
+// matches nothing, so that "**/a.proto" matches "a.proto".
+func exceptionPatternToRegexp(pattern string, separator byte) (*regexp.Regexp, error) {
+	quotedSeparator := regexp.QuoteMeta(string(separator))
+	var builder strings.Builder
+	_, _ = builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"+string(separator)):
+			_, _ = builder.WriteString("(?:.*" + quotedSeparator + ")?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			_, _ = builder.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			_, _ = builder.WriteString("[^" + quotedSeparator + "]*")
+		case pattern[i] == '?':
+			_, _ = builder.WriteString("[^" + quotedSeparator + "]")
+		default:
+			_, _ = builder.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	_, _ = builder.WriteString("$")
+	return regexp.Compile(builder.String())
+}
+
+// matches returns true if the Annotation is exempted by the exception.
+//
+// An Annotation is exempted if it is for one of the Rules, and its Location is within a
+// File whose path matches the pattern, or within a descriptor whose full name matches the
+// pattern.
+func (e *exception) matches(annotation Annotation) bool {
+	if _, ok := e.ruleIDs[annotation.RuleID()]; !ok {
+		return false
+	}
+	location := annotation.Location()
+	if location == nil {
+		return false
+	}
+	fileDescriptor := location.File().FileDescriptor()
+	if e.isFilePattern {
+		return e.regexp.MatchString(fileDescriptor.Path())
+	}
+	return slices.ContainsFunc(
+		descriptorsForSourcePath(fileDescriptor, location.UnclonedSourcePath()),
+		func(descriptor protoreflect.Descriptor) bool {
+			return e.regexp.MatchString(string(descriptor.FullName()))
+		},
+	)
+}
+
+// descriptorsForSourcePath returns the descriptors that the SourcePath is within, from the
+// outermost to the innermost, excluding the FileDescriptor itself.
+//
+// The SourcePath is followed for as long as it refers to descriptors, so that for example
+// a SourcePath to the name of a field results in the message and the field.
+func descriptorsForSourcePath(
+	fileDescriptor protoreflect.FileDescriptor,
+	sourcePath protoreflect.SourcePath,
+) []protoreflect.Descriptor {
+	var descriptors []protoreflect.Descriptor
+	var descriptor protoreflect.Descriptor = fileDescriptor
+	for i := 0; i+1 < len(sourcePath); i += 2 {
+		descriptor = childDescriptorForSourcePathElements(descriptor, sourcePath[i], int(sourcePath[i+1]))
+		if descriptor == nil {
+			break
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+	return descriptors
+}
+
+// childDescriptorForSourcePathElements returns the child descriptor of the descriptor with
+// the given field number and index within the corresponding descriptor proto, or nil if
+// the elements do not refer to a child descriptor.
+func childDescriptorForSourcePathElements(
+	descriptor protoreflect.Descriptor,
+	fieldNumber int32,
+	index int,
+) protoreflect.Descriptor {
+	switch descriptor := descriptor.(type) {
+	case protoreflect.FileDescriptor:
+		switch fieldNumber {
+		case fileDescriptorProtoMessageTypeFieldNumber:
+			return descriptorAtIndex[protoreflect.MessageDescriptor](descriptor.Messages(), index)
+		case fileDescriptorProtoEnumTypeFieldNumber:
+			return descriptorAtIndex[protoreflect.EnumDescriptor](descriptor.Enums(), index)
+		case fileDescriptorProtoServiceFieldNumber:
+			return descriptorAtIndex[protoreflect.ServiceDescriptor](descriptor.Services(), index)
+		case fileDescriptorProtoExtensionFieldNumber:
+			return descriptorAtIndex[protoreflect.ExtensionDescriptor](descriptor.Extensions(), index)
+		}
+	case protoreflect.MessageDescriptor:
+		switch fieldNumber {
+		case descriptorProtoFieldFieldNumber:
+			return descriptorAtIndex[protoreflect.FieldDescriptor](descriptor.Fields(), index)
+		case descriptorProtoNestedTypeFieldNumber:
+			return descriptorAtIndex[protoreflect.MessageDescriptor](descriptor.Messages(), index)
+		case descriptorProtoEnumTypeFieldNumber:
+			return descriptorAtIndex[protoreflect.EnumDescriptor](descriptor.Enums(), index)
+		case descriptorProtoExtensionFieldNumber:
+			return descriptorAtIndex[protoreflect.ExtensionDescriptor](descriptor.Extensions(), index)
+		case descriptorProtoOneofDeclFieldNumber:
+			return descriptorAtIndex[protoreflect.OneofDescriptor](descriptor.Oneofs(), index)
+		}
+	case protoreflect.EnumDescriptor:
+		if fieldNumber == enumDescriptorProtoValueFieldNumber {
+			return descriptorAtIndex[protoreflect.EnumValueDescriptor](descriptor.Values(), index)
+		}
+	case protoreflect.ServiceDescriptor:
+		if fieldNumber == serviceDescriptorProtoMethodFieldNumber {
+			return descriptorAtIndex[protoreflect.MethodDescriptor](descriptor.Methods(), index)
+		}
+	}
+	return nil
+}
+
+// descriptorList is implemented by the lists of descriptors within protoreflect, such as
+// protoreflect.FieldDescriptors.
+type descriptorList[T protoreflect.Descriptor] interface {
+	Len() int
+	Get(i int) T
+}
+
+// descriptorAtIndex returns the descriptor at the index within the list, or nil if the
+// index is out of range.
+func descriptorAtIndex[T protoreflect.Descriptor](list descriptorList[T], index int) protoreflect.Descriptor {
+	if index < 0 || index >= list.Len() {
+		return nil
+	}
+	return list.Get(index)
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExceptionPatternToRegexp(t *testing.T) {
+	t.Parallel()
+
+	testExceptionPatternMatches(t, "vendor/**", '/', "vendor/a.proto", true)
+	testExceptionPatternMatches(t, "vendor/**", '/', "vendor/foo/a.proto", true)
+	testExceptionPatternMatches(t, "vendor/*", '/', "vendor/foo/a.proto", false)
+	testExceptionPatternMatches(t, "**/a.proto", '/', "a.proto", true)
+	testExceptionPatternMatches(t, "**/a.proto", '/', "foo/bar/a.proto", true)
+	testExceptionPatternMatches(t, "**/a.proto", '/', "foo/ba.proto", false)
+	testExceptionPatternMatches(t, "a?.proto", '/', "ab.proto", true)
+	testExceptionPatternMatches(t, "a?.proto", '/', "a/.proto", false)
+	testExceptionPatternMatches(t, "acme.v1.Legacy*", '.', "acme.v1.LegacyFoo", true)
+	testExceptionPatternMatches(t, "acme.v1.Legacy*", '.', "acme.v1.LegacyFoo.name", false)
+	testExceptionPatternMatches(t, "acme.**", '.', "acme.v1.LegacyFoo.name", true)
+	testExceptionPatternMatches(t, "acme.*", '.', "acmeXv1", false)
+	testExceptionPatternMatches(t, "**.name", '.', "acme.v1.Foo.name", true)
+}
+
+func TestExceptionsOption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				testNewDescriptorAnnotatingRuleSpec("RULE1"),
+				testNewDescriptorAnnotatingRuleSpec("RULE2"),
+			},
+		},
+	)
+	require.NoError(t, err)
+	files, err := FilesForProtoFiles(
+		[]*checkv1beta1.File{
+			{
+				FileDescriptorProto: testNewSuppressionFileDescriptorProto("a.proto", "Foo", "Bar"),
+			},
+			{
+				FileDescriptorProto: testNewSuppressionFileDescriptorProto("b.proto", "Baz"),
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	testExceptionsOptionAnnotations(
+		t,
+		ctx,
+		client,
+		files,
+		[]string{
+			"RULE1=b.proto",
+			"RULE*=a.Foo",
+			"RULE2=a.*.value",
+		},
+		[]string{
+			"RULE1:a.Bar",
+			"RULE1:a.Bar.value",
+			"RULE2:a.Bar",
+			"RULE2:b.Baz",
+			"RULE2:b.Baz.value",
+		},
+	)
+
+	for _, invalidException := range []string{
+		"RULE1",
+		"=a.Foo",
+		"RULE9=a.Foo",
+		"OTHER*=a.Foo",
+	} {
+		request, err := NewRequest(files, WithOptions(testNewExceptionsOptions(t, invalidException)))
+		require.NoError(t, err)
+		_, err = client.Check(ctx, request)
+		require.ErrorContains(t, err, ExceptionsOptionKey, invalidException)
+	}
+}
+
+func testExceptionPatternMatches(t *testing.T, pattern string, separator byte, value string, expected bool) {
+	patternRegexp, err := exceptionPatternToRegexp(pattern, separator)
+	require.NoError(t, err)
+	require.Equal(t, expected, patternRegexp.MatchString(value), "%q %q", pattern, value)
+}
+
+func testExceptionsOptionAnnotations(
+	t *testing.T,
+	ctx context.Context,
+	client Client,
+	files []File,
+	exceptions []string,
+	expectedAnnotationStrings []string,
+) {
+	request, err := NewRequest(files, WithOptions(testNewExceptionsOptions(t, exceptions...)))
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		expectedAnnotationStrings,
+		xslices.Map(response.Annotations(), testRuleDependencyAnnotationString),
+	)
+}
+
+func testNewExceptionsOptions(t *testing.T, exceptions ...string) Options {
+	options, err := NewOptions(map[string]any{ExceptionsOptionKey: exceptions})
+	require.NoError(t, err)
+	return options
+}
+
+// testNewDescriptorAnnotatingRuleSpec returns a new RuleSpec that adds an Annotation for
+// every message and field of the Files, with the full name as the message.
+func testNewDescriptorAnnotatingRuleSpec(id string) *RuleSpec {
+	return &RuleSpec{
+		ID:        id,
+		IsDefault: true,
+		Purpose:   "Test rule.",
+		Type:      RuleTypeLint,
+		Handler: RuleHandlerFunc(
+			func(_ context.Context, responseWriter ResponseWriter, request Request) error {
+				for _, file := range request.Files() {
+					messages := file.FileDescriptor().Messages()
+					for i := range messages.Len() {
+						message := messages.Get(i)
+						responseWriter.AddAnnotation(WithDescriptor(message), WithMessage(string(message.FullName())))
+						fields := message.Fields()
+						for j := range fields.Len() {
+							field := fields.Get(j)
+							responseWriter.AddAnnotation(WithDescriptor(field), WithMessage(string(field.FullName())))
+						}
+					}
+				}
+				return nil
+			},
+		),
+	}
+}
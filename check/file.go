@@ -65,6 +65,10 @@ type File interface {
 	// This matches the shape of the PublicDependency and WeakDependency fields.
 	UnusedDependencyIndexes() []int32
 
+	// fileSource returns the source of the File, if given with WithSources or WithAgainstSources.
+	//
+	// Returns nil if the source is not available.
+	fileSource() *fileSource
 	toProto() *checkv1beta1.File
 
 	isFile()
@@ -139,6 +143,8 @@ type file struct {
 	isImport                bool
 	isSyntaxUnspecified     bool
 	unusedDependencyIndexes []int32
+	// source is nil if the source is not available.
+	source *fileSource
 }
 
 func newFile(
@@ -177,6 +183,10 @@ func (f *file) UnusedDependencyIndexes() []int32 {
 	return slices.Clone(f.unusedDependencyIndexes)
 }
 
+func (f *file) fileSource() *fileSource {
+	return f.source
+}
+
 func (f *file) toProto() *checkv1beta1.File {
 	return &checkv1beta1.File{
 		FileDescriptorProto: f.fileDescriptorProto,
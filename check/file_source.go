@@ -0,0 +1,120 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"unicode/utf8"
+)
+
+// *** PRIVATE ***
+
+// sourceTabWidth is the width of a tab stop when computing columns.
+//
+// This matches how protoc and protocompile compute the columns of SourceCodeInfo.
+const sourceTabWidth = 8
+
+// fileSource is the source of a File, given with WithSources or WithAgainstSources.
+type fileSource struct {
+	data []byte
+	// getLineOffsets returns the byte offset of the start of each line.
+	getLineOffsets func() []int
+}
+
+func newFileSource(data []byte) *fileSource {
+	return &fileSource{
+		data: data,
+		getLineOffsets: sync.OnceValue(
+			func() []int {
+				lineOffsets := []int{0}
+				for i, b := range data {
+					if b == '\n' {
+						lineOffsets = append(lineOffsets, i+1)
+					}
+				}
+				return lineOffsets
+			},
+		),
+	}
+}
+
+// offset returns the byte offset of the given zero-indexed line and column.
+//
+// Columns are computed as with SourceCodeInfo, counting each UTF-8 character as one column,
+// and advancing tabs to the next multiple of sourceTabWidth. Returns UnknownPosition if the
+// line and column are not within the source.
+func (s *fileSource) offset(line int, column int) int {
+	lineOffsets := s.getLineOffsets()
+	if line < 0 || line >= len(lineOffsets) || column < 0 {
+		return UnknownPosition
+	}
+	start := lineOffsets[line]
+	end := len(s.data)
+	if index := bytes.IndexByte(s.data[start:], '\n'); index >= 0 {
+		end = start + index
+	}
+	currentColumn := 0
+	for i := start; i < end; i++ {
+		switch {
+		case s.data[i] == '\t':
+			if currentColumn >= column {
+				return i
+			}
+			currentColumn += sourceTabWidth - (currentColumn % sourceTabWidth)
+		case utf8.RuneStart(s.data[i]):
+			if currentColumn >= column {
+				return i
+			}
+			currentColumn++
+		}
+	}
+	// The end column of a span is exclusive, so may be the end of the line.
+	if currentColumn >= column {
+		return end
+	}
+	return UnknownPosition
+}
+
+// filesWithSources returns copies of the Files with the given sources, by file name.
+//
+// Files without a source are not copied.
+func filesWithSources(files []File, fileNameToSource map[string][]byte) ([]File, error) {
+	if len(fileNameToSource) == 0 {
+		return files, nil
+	}
+	unusedFileNames := maps.Clone(fileNameToSource)
+	filesWithSources := make([]File, len(files))
+	for i, existingFile := range files {
+		fileName := existingFile.FileDescriptor().Path()
+		data, ok := fileNameToSource[fileName]
+		if !ok {
+			filesWithSources[i] = existingFile
+			continue
+		}
+		delete(unusedFileNames, fileName)
+		// All Files are created by newFile.
+		fileWithSource := *(existingFile.(*file))
+		fileWithSource.source = newFileSource(data)
+		filesWithSources[i] = &fileWithSource
+	}
+	if len(unusedFileNames) > 0 {
+		return nil, fmt.Errorf("source given for unknown file %q", slices.Sorted(maps.Keys(unusedFileNames))[0])
+	}
+	return filesWithSources, nil
+}
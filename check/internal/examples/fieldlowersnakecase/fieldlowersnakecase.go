@@ -22,10 +22,9 @@ package fieldlowersnakecase
 
 import (
 	"context"
-	"strings"
-	"unicode"
 
 	"github.com/bufbuild/bufplugin-go/check"
+	"github.com/bufbuild/bufplugin-go/check/checknaming"
 	"github.com/bufbuild/bufplugin-go/check/checkutil"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
@@ -58,7 +57,7 @@ func checkFieldLowerSnakeCase(
 	fieldDescriptor protoreflect.FieldDescriptor,
 ) error {
 	fieldName := string(fieldDescriptor.Name())
-	fieldNameToLowerSnakeCase := toLowerSnakeCase(fieldName)
+	fieldNameToLowerSnakeCase := checknaming.ToLowerSnakeCase(fieldName)
 	if fieldName != fieldNameToLowerSnakeCase {
 		responseWriter.AddAnnotation(
 			check.WithMessagef("Field name %q should be lower_snake_case, such as %q.", fieldName, fieldNameToLowerSnakeCase),
@@ -67,40 +66,3 @@ func checkFieldLowerSnakeCase(
 	}
 	return nil
 }
-
-func toLowerSnakeCase(s string) string {
-	return strings.ToLower(toSnakeCase(s))
-}
-
-func toSnakeCase(s string) string {
-	output := ""
-	s = strings.TrimFunc(s, isDelimiter)
-	for i, c := range s {
-		if isDelimiter(c) {
-			c = '_'
-		}
-		switch {
-		case i == 0:
-			output += string(c)
-		case isSnakeCaseNewWord(c, false) &&
-			output[len(output)-1] != '_' &&
-			((i < len(s)-1 && !isSnakeCaseNewWord(rune(s[i+1]), true) && !isDelimiter(rune(s[i+1]))) ||
-				(unicode.IsLower(rune(s[i-1])))):
-			output += "_" + string(c)
-		case !(isDelimiter(c) && output[len(output)-1] == '_'):
-			output += string(c)
-		}
-	}
-	return output
-}
-
-func isSnakeCaseNewWord(r rune, newWordOnDigits bool) bool {
-	if newWordOnDigits {
-		return unicode.IsUpper(r) || unicode.IsDigit(r)
-	}
-	return unicode.IsUpper(r)
-}
-
-func isDelimiter(r rune) bool {
-	return r == '.' || r == '-' || r == '_' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
-}
@@ -36,8 +36,6 @@ const (
 
 	// TimestampSuffixOptionKey is the option key to override the default timestamp suffix.
 	TimestampSuffixOptionKey = "timestamp_suffix"
-
-	defaultTimestampSuffix = "_time"
 )
 
 var (
@@ -55,6 +53,9 @@ var (
 		Rules: []*check.RuleSpec{
 			TimestampSuffixRuleSpec,
 		},
+		DefaultOptions: map[string]any{
+			TimestampSuffixOptionKey: "_time",
+		},
 	}
 )
 
@@ -64,14 +65,11 @@ func checkTimestampSuffix(
 	request check.Request,
 	fieldDescriptor protoreflect.FieldDescriptor,
 ) error {
-	timestampSuffix := defaultTimestampSuffix
-	timestampSuffixOptionValue, err := check.GetStringValue(request.Options(), TimestampSuffixOptionKey)
+	// The default is declared in Spec.DefaultOptions.
+	timestampSuffix, err := check.GetStringValue(request.Options(), TimestampSuffixOptionKey)
 	if err != nil {
 		return err
 	}
-	if timestampSuffixOptionValue != "" {
-		timestampSuffix = timestampSuffixOptionValue
-	}
 
 	fieldDescriptorType := fieldDescriptor.Message()
 	if fieldDescriptorType == nil {
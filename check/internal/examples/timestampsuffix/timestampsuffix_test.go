@@ -49,6 +49,42 @@ func TestSimple(t *testing.T) {
 	}.Run(t)
 }
 
+func TestSimpleBinary(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() {
+		t.Skip("skipping go build in short mode")
+	}
+	checktest.CheckTest{
+		Request: &checktest.RequestSpec{
+			Files: &checktest.ProtoFileSpec{
+				DirPaths:  []string{"testdata/simple"},
+				FilePaths: []string{"simple.proto"},
+			},
+		},
+		Client: checktest.NewGoBuildClient(t, "./cmd/buf-plugin-timestamp-suffix"),
+		ExpectedAnnotations: []checktest.ExpectedAnnotation{
+			{
+				RuleID: TimestampSuffixRuleID,
+				Location: &checktest.ExpectedLocation{
+					FileName:    "simple.proto",
+					StartLine:   8,
+					StartColumn: 2,
+					EndLine:     8,
+					EndColumn:   50,
+				},
+			},
+		},
+		ExpectedRules: []checktest.ExpectedRule{
+			{
+				ID:        TimestampSuffixRuleID,
+				IsDefault: true,
+			},
+		},
+		ExpectedCategoryIDs: []string{},
+	}.Run(t)
+}
+
 func TestOption(t *testing.T) {
 	t.Parallel()
 
@@ -21,9 +21,28 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
+// UnknownPosition is returned for the lines and columns of a Location that does not have
+// a position.
+//
+// See Location.HasPosition.
+const UnknownPosition = -1
+
 // Location is a reference to a File or to a location within a File.
 //
-// A Location always has a file name.
+// A Location always has a file name. A Location is either:
+//
+//   - A whole-file Location, which references the File as a whole, and has an empty
+//     SourcePath. IsWholeFile returns true and HasPosition returns false. On the wire, this
+//     is a Location with a file name and an empty source path. Hosts should render these
+//     without a position, for example "simple.proto: message".
+//   - A Location for an element within the File, which has a non-empty SourcePath.
+//     HasPosition returns true if the File has SourceCodeInfo for the SourcePath.
+//
+// If the File has no SourceCodeInfo, for example because the host stripped it, Locations
+// created with WithSourcePath keep their SourcePath, while Locations created with
+// WithDescriptor cannot determine a SourcePath and degrade to whole-file Locations. In
+// both cases, HasPosition returns false, the lines and columns are UnknownPosition, and
+// the comments are empty. The Annotation is still returned.
 type Location interface {
 	// File is the File associated with the Location.
 	//
@@ -31,15 +50,46 @@ type Location interface {
 	File() File
 	// SourcePath returns the path within the FileDescriptorProto of the Location.
 	SourcePath() protoreflect.SourcePath
+	// UnclonedSourcePath returns the same path as SourcePath, without copying it.
+	//
+	// This is for performance-sensitive callers. The returned SourcePath must not be modified.
+	UnclonedSourcePath() protoreflect.SourcePath
+	// IsWholeFile returns true if the Location references the File as a whole, that is
+	// if the SourcePath is empty.
+	IsWholeFile() bool
 
-	// StartLine returns the zero-indexed start line, if known.
+	// HasPosition returns true if the Location has a known position within the File.
+	//
+	// This is false if the Location is a whole-file Location, or if the File has no
+	// SourceCodeInfo for the SourcePath. Hosts should check HasPosition rather than
+	// rendering the lines and columns of the Location.
+	HasPosition() bool
+	// StartLine returns the zero-indexed start line, or UnknownPosition if HasPosition is false.
 	StartLine() int
-	// StartColumn returns the zero-indexed start column, if known.
+	// StartColumn returns the zero-indexed start column, or UnknownPosition if HasPosition is false.
 	StartColumn() int
-	// EndLine returns the zero-indexed end line, if known.
+	// EndLine returns the zero-indexed end line, or UnknownPosition if HasPosition is false.
 	EndLine() int
-	// EndColumn returns the zero-indexed end column, if known.
+	// EndColumn returns the zero-indexed end column, or UnknownPosition if HasPosition is false.
 	EndColumn() int
+	// StartOffset returns the zero-indexed byte offset of the start of the Location within the
+	// source of the File.
+	//
+	// Returns UnknownPosition if HasPosition is false, or if the source of the File is not
+	// available. Sources are given by hosts with WithSources and WithAgainstSources, and are not
+	// sent to plugins, so offsets are only available on Annotations returned from Clients.
+	StartOffset() int
+	// EndOffset returns the zero-indexed byte offset of the end of the Location within the
+	// source of the File, exclusive.
+	//
+	// Returns UnknownPosition in the same cases as StartOffset.
+	EndOffset() int
+	// SourceSnippet returns the raw text of the Location within the source of the File, that is
+	// the text between StartOffset and EndOffset.
+	//
+	// Returns an empty string in the same cases as StartOffset. This can be used to produce
+	// code-frame style output for Annotations.
+	SourceSnippet() string
 	// LeadingComments returns any leading comments, if known.
 	LeadingComments() string
 	// TrailingComments returns any trailing comments, if known.
@@ -47,7 +97,6 @@ type Location interface {
 	// LeadingDetachedComments returns any leading detached comments, if known.
 	LeadingDetachedComments() []string
 
-	unclonedSourcePath() protoreflect.SourcePath
 	unclonedLeadingDetachedComments() []string
 	toProto() *checkv1beta1.Location
 
@@ -57,16 +106,23 @@ type Location interface {
 // *** PRIVATE ***
 
 type location struct {
-	file           File
+	file File
+	// sourcePath is the SourcePath that the Location was created for.
+	//
+	// This is the same as sourceLocation.Path, unless the SourcePath was not found
+	// in the SourceCodeInfo of the File.
+	sourcePath     protoreflect.SourcePath
 	sourceLocation protoreflect.SourceLocation
 }
 
 func newLocation(
 	file File,
+	sourcePath protoreflect.SourcePath,
 	sourceLocation protoreflect.SourceLocation,
 ) *location {
 	return &location{
 		file:           file,
+		sourcePath:     sourcePath,
 		sourceLocation: sourceLocation,
 	}
 }
@@ -76,25 +132,75 @@ func (l *location) File() File {
 }
 
 func (l *location) SourcePath() protoreflect.SourcePath {
-	return slices.Clone(l.sourceLocation.Path)
+	return slices.Clone(l.sourcePath)
+}
+
+func (l *location) IsWholeFile() bool {
+	return len(l.sourcePath) == 0
+}
+
+func (l *location) HasPosition() bool {
+	// A protoreflect.SourceLocation that was not found in the SourceCodeInfo has no path.
+	return len(l.sourceLocation.Path) > 0
 }
 
 func (l *location) StartLine() int {
+	if !l.HasPosition() {
+		return UnknownPosition
+	}
 	return l.sourceLocation.StartLine
 }
 
 func (l *location) StartColumn() int {
+	if !l.HasPosition() {
+		return UnknownPosition
+	}
 	return l.sourceLocation.StartColumn
 }
 
 func (l *location) EndLine() int {
+	if !l.HasPosition() {
+		return UnknownPosition
+	}
 	return l.sourceLocation.EndLine
 }
 
 func (l *location) EndColumn() int {
+	if !l.HasPosition() {
+		return UnknownPosition
+	}
 	return l.sourceLocation.EndColumn
 }
 
+func (l *location) StartOffset() int {
+	fileSource := l.file.fileSource()
+	if fileSource == nil || !l.HasPosition() {
+		return UnknownPosition
+	}
+	return fileSource.offset(l.sourceLocation.StartLine, l.sourceLocation.StartColumn)
+}
+
+func (l *location) EndOffset() int {
+	fileSource := l.file.fileSource()
+	if fileSource == nil || !l.HasPosition() {
+		return UnknownPosition
+	}
+	return fileSource.offset(l.sourceLocation.EndLine, l.sourceLocation.EndColumn)
+}
+
+func (l *location) SourceSnippet() string {
+	fileSource := l.file.fileSource()
+	if fileSource == nil {
+		return ""
+	}
+	startOffset := l.StartOffset()
+	endOffset := l.EndOffset()
+	if startOffset == UnknownPosition || endOffset == UnknownPosition || startOffset > endOffset {
+		return ""
+	}
+	return string(fileSource.data[startOffset:endOffset])
+}
+
 func (l *location) LeadingComments() string {
 	return l.sourceLocation.LeadingComments
 }
@@ -107,8 +213,8 @@ func (l *location) LeadingDetachedComments() []string {
 	return slices.Clone(l.sourceLocation.LeadingDetachedComments)
 }
 
-func (l *location) unclonedSourcePath() protoreflect.SourcePath {
-	return l.sourceLocation.Path
+func (l *location) UnclonedSourcePath() protoreflect.SourcePath {
+	return l.sourcePath
 }
 
 func (l *location) unclonedLeadingDetachedComments() []string {
@@ -121,7 +227,7 @@ func (l *location) toProto() *checkv1beta1.Location {
 	}
 	return &checkv1beta1.Location{
 		FileName:   l.file.FileDescriptor().Path(),
-		SourcePath: l.sourceLocation.Path,
+		SourcePath: l.sourcePath,
 	}
 }
 
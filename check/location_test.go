@@ -0,0 +1,161 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestLocationHasPosition(t *testing.T) {
+	t.Parallel()
+
+	location := newLocation(nil, nil, protoreflect.SourceLocation{})
+	require.True(t, location.IsWholeFile())
+	require.False(t, location.HasPosition())
+	require.Equal(t, UnknownPosition, location.StartLine())
+	require.Equal(t, UnknownPosition, location.StartColumn())
+	require.Equal(t, UnknownPosition, location.EndLine())
+	require.Equal(t, UnknownPosition, location.EndColumn())
+
+	// A position at the start of the File is distinguishable from an unknown position.
+	location = newLocation(
+		nil,
+		protoreflect.SourcePath{12},
+		protoreflect.SourceLocation{
+			Path:      protoreflect.SourcePath{12},
+			EndColumn: 18,
+		},
+	)
+	require.False(t, location.IsWholeFile())
+	require.True(t, location.HasPosition())
+	require.Equal(t, 0, location.StartLine())
+	require.Equal(t, 0, location.StartColumn())
+	require.Equal(t, 0, location.EndLine())
+	require.Equal(t, 18, location.EndColumn())
+}
+
+func TestLocationWithoutSourceCodeInfo(t *testing.T) {
+	t.Parallel()
+
+	// The SourcePath was not found in the SourceCodeInfo, so the Location is not a whole-file
+	// Location, but does not have a position.
+	location := newLocation(nil, protoreflect.SourcePath{4, 0}, protoreflect.SourceLocation{})
+	require.False(t, location.IsWholeFile())
+	require.False(t, location.HasPosition())
+	require.Equal(t, protoreflect.SourcePath{4, 0}, location.SourcePath())
+	require.Equal(t, UnknownPosition, location.StartLine())
+}
+
+func TestLocationOffsets(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	// The tab and the multi-byte character make columns differ from byte offsets.
+	source := "syntax = \"proto3\";\n\npackage a;\n\nmessage Foo {\n\t/* \u00fc */ string value = 1;\n}\n"
+	files, err := FilesForProtoFiles(
+		[]*checkv1beta1.File{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:    proto.String("a.proto"),
+					Package: proto.String("a"),
+					Syntax:  proto.String("proto3"),
+					MessageType: []*descriptorpb.DescriptorProto{
+						{
+							Name: proto.String("Foo"),
+							Field: []*descriptorpb.FieldDescriptorProto{
+								{
+									Name:     proto.String("value"),
+									Number:   proto.Int32(1),
+									Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+									Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+									JsonName: proto.String("value"),
+								},
+							},
+						},
+					},
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+						Location: []*descriptorpb.SourceCodeInfo_Location{
+							{
+								Path: []int32{4, 0},
+								Span: []int32{4, 0, 6, 1},
+							},
+							{
+								Path: []int32{4, 0, 2, 0},
+								Span: []int32{5, 16, 33},
+							},
+						},
+					},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, responseWriter ResponseWriter, request Request) error {
+							message := request.Files()[0].FileDescriptor().Messages().Get(0)
+							responseWriter.AddAnnotation(WithDescriptor(message))
+							responseWriter.AddAnnotation(WithDescriptor(message.Fields().Get(0)))
+							return nil
+						},
+					),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	request, err := NewRequest(files, WithSources(map[string][]byte{"a.proto": []byte(source)}))
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	annotations := response.Annotations()
+	require.Len(t, annotations, 2)
+	messageLocation := annotations[0].Location()
+	require.Equal(t, strings.Index(source, "message"), messageLocation.StartOffset())
+	require.Equal(t, strings.Index(source, "}")+1, messageLocation.EndOffset())
+	fieldLocation := annotations[1].Location()
+	require.Equal(t, strings.Index(source, "string"), fieldLocation.StartOffset())
+	require.Equal(t, strings.Index(source, "= 1;")+4, fieldLocation.EndOffset())
+	require.Equal(t, "string value = 1;", fieldLocation.SourceSnippet())
+	require.Equal(t, "message Foo {\n\t/* \u00fc */ string value = 1;\n}", messageLocation.SourceSnippet())
+
+	// Without the source, offsets and snippets are unknown.
+	request, err = NewRequest(files)
+	require.NoError(t, err)
+	response, err = client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(t, UnknownPosition, response.Annotations()[1].Location().StartOffset())
+	require.Equal(t, UnknownPosition, response.Annotations()[1].Location().EndOffset())
+	require.Empty(t, response.Annotations()[1].Location().SourceSnippet())
+
+	_, err = NewRequest(files, WithSources(map[string][]byte{"b.proto": []byte(source)}))
+	require.EqualError(t, err, `source given for unknown file "b.proto"`)
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"strconv"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// LocationUnavailableReasonNone means that the Location of the Annotation is available,
+	// or is a whole-file Location as requested with WithDescriptor for a FileDescriptor or
+	// with WithFileName without WithSourcePath.
+	LocationUnavailableReasonNone LocationUnavailableReason = 0
+	// LocationUnavailableReasonNotSpecified means that the Annotation was added without
+	// WithDescriptor or WithFileName, so there is no Location.
+	LocationUnavailableReasonNotSpecified LocationUnavailableReason = 1
+	// LocationUnavailableReasonNoParentFile means that the Annotation was added with
+	// WithDescriptor for a descriptor that has no ParentFile, so there is no Location.
+	LocationUnavailableReasonNoParentFile LocationUnavailableReason = 2
+	// LocationUnavailableReasonUnknownFile means that the Annotation was added for a file that
+	// is not in the Request, so there is either no Location or a whole-file Location, per the
+	// UnknownFilePolicy.
+	LocationUnavailableReasonUnknownFile LocationUnavailableReason = 3
+	// LocationUnavailableReasonNoSourceCodeInfo means that the File has no SourceCodeInfo,
+	// for example because the host stripped it, so the Location has no position.
+	LocationUnavailableReasonNoSourceCodeInfo LocationUnavailableReason = 4
+	// LocationUnavailableReasonSourcePathNotFound means that the File has SourceCodeInfo, but
+	// not for the SourcePath, for example for the synthesized entry message of a map field, so
+	// the Location has no position.
+	LocationUnavailableReasonSourcePathNotFound LocationUnavailableReason = 5
+	// LocationUnavailableReasonUnknown means that the reason is not known, as the Annotation
+	// was returned from a Client and the plugin protocol does not carry the reason.
+	LocationUnavailableReasonUnknown LocationUnavailableReason = 6
+)
+
+var locationUnavailableReasonToString = map[LocationUnavailableReason]string{
+	LocationUnavailableReasonNone:               "none",
+	LocationUnavailableReasonNotSpecified:       "not_specified",
+	LocationUnavailableReasonNoParentFile:       "no_parent_file",
+	LocationUnavailableReasonUnknownFile:        "unknown_file",
+	LocationUnavailableReasonNoSourceCodeInfo:   "no_source_code_info",
+	LocationUnavailableReasonSourcePathNotFound: "source_path_not_found",
+	LocationUnavailableReasonUnknown:            "unknown",
+}
+
+// LocationUnavailableReason is the reason that an Annotation has no Location, or has a Location
+// without a position.
+//
+// This aids debugging of why hosts show Annotations without a file or without a position.
+// See Annotation.LocationUnavailableReason.
+type LocationUnavailableReason int
+
+// String implements fmt.Stringer.
+func (r LocationUnavailableReason) String() string {
+	if s, ok := locationUnavailableReasonToString[r]; ok {
+		return s
+	}
+	return strconv.Itoa(int(r))
+}
+
+// *** PRIVATE ***
+
+// getLocationUnavailableReason returns the LocationUnavailableReason for the Location that was
+// computed with getLocationForAddAnnotationOptions for the given options.
+func getLocationUnavailableReason(
+	fileNameToFile map[string]File,
+	location Location,
+	descriptor protoreflect.Descriptor,
+	fileName string,
+	sourcePath protoreflect.SourcePath,
+) LocationUnavailableReason {
+	if location != nil && location.HasPosition() {
+		return LocationUnavailableReasonNone
+	}
+	var isWholeFile bool
+	switch {
+	case descriptor != nil:
+		fileDescriptor := descriptor.ParentFile()
+		if fileDescriptor == nil {
+			return LocationUnavailableReasonNoParentFile
+		}
+		fileName = fileDescriptor.Path()
+		_, isWholeFile = descriptor.(protoreflect.FileDescriptor)
+	case fileName != "":
+		isWholeFile = len(sourcePath) == 0
+	default:
+		return LocationUnavailableReasonNotSpecified
+	}
+	file, ok := fileNameToFile[fileName]
+	if !ok {
+		return LocationUnavailableReasonUnknownFile
+	}
+	if isWholeFile {
+		return LocationUnavailableReasonNone
+	}
+	if len(file.FileDescriptorProto().GetSourceCodeInfo().GetLocation()) == 0 {
+		return LocationUnavailableReasonNoSourceCodeInfo
+	}
+	return LocationUnavailableReasonSourcePathNotFound
+}
+
+// getClientLocationUnavailableReason returns the LocationUnavailableReason for a Location
+// that a Client read from a CheckResponse.
+//
+// The reason is only known if the Location has a position. See
+// LocationUnavailableReasonUnknown.
+func getClientLocationUnavailableReason(location Location) LocationUnavailableReason {
+	if location != nil && location.HasPosition() {
+		return LocationUnavailableReasonNone
+	}
+	return LocationUnavailableReasonUnknown
+}
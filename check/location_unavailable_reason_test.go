@@ -0,0 +1,214 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestClientCheckLocationUnavailableReason(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		message                           string
+		getOptions                        func(request Request) []AddAnnotationOption
+		expectedLocationUnavailableReason LocationUnavailableReason
+		// expectedClientLocationUnavailableReason is the reason on the client-side, which is
+		// only known if the Location has a position.
+		expectedClientLocationUnavailableReason LocationUnavailableReason
+	}{
+		{
+			message: "message",
+			getOptions: func(request Request) []AddAnnotationOption {
+				return []AddAnnotationOption{
+					WithDescriptor(testFileDescriptorForPath(request, "a.proto").Messages().Get(0)),
+				}
+			},
+			expectedLocationUnavailableReason:       LocationUnavailableReasonNone,
+			expectedClientLocationUnavailableReason: LocationUnavailableReasonNone,
+		},
+		{
+			message: "file",
+			getOptions: func(request Request) []AddAnnotationOption {
+				return []AddAnnotationOption{
+					WithDescriptor(testFileDescriptorForPath(request, "a.proto")),
+				}
+			},
+			expectedLocationUnavailableReason:       LocationUnavailableReasonNone,
+			expectedClientLocationUnavailableReason: LocationUnavailableReasonUnknown,
+		},
+		{
+			message: "file name",
+			getOptions: func(Request) []AddAnnotationOption {
+				return []AddAnnotationOption{
+					WithFileName("a.proto"),
+				}
+			},
+			expectedLocationUnavailableReason:       LocationUnavailableReasonNone,
+			expectedClientLocationUnavailableReason: LocationUnavailableReasonUnknown,
+		},
+		{
+			message: "not specified",
+			getOptions: func(Request) []AddAnnotationOption {
+				return nil
+			},
+			expectedLocationUnavailableReason:       LocationUnavailableReasonNotSpecified,
+			expectedClientLocationUnavailableReason: LocationUnavailableReasonUnknown,
+		},
+		{
+			message: "no parent file",
+			getOptions: func(request Request) []AddAnnotationOption {
+				return []AddAnnotationOption{
+					WithDescriptor(
+						&testNoParentFileDescriptor{
+							Descriptor: testFileDescriptorForPath(request, "a.proto").Messages().Get(0),
+						},
+					),
+				}
+			},
+			expectedLocationUnavailableReason:       LocationUnavailableReasonNoParentFile,
+			expectedClientLocationUnavailableReason: LocationUnavailableReasonUnknown,
+		},
+		{
+			message: "unknown file",
+			getOptions: func(Request) []AddAnnotationOption {
+				return []AddAnnotationOption{
+					WithFileName("c.proto"),
+					WithSourcePath(protoreflect.SourcePath{4, 0}),
+				}
+			},
+			expectedLocationUnavailableReason:       LocationUnavailableReasonUnknownFile,
+			expectedClientLocationUnavailableReason: LocationUnavailableReasonUnknown,
+		},
+		{
+			message: "no source code info",
+			getOptions: func(request Request) []AddAnnotationOption {
+				return []AddAnnotationOption{
+					WithDescriptor(testFileDescriptorForPath(request, "b.proto").Messages().Get(0)),
+				}
+			},
+			expectedLocationUnavailableReason:       LocationUnavailableReasonNoSourceCodeInfo,
+			expectedClientLocationUnavailableReason: LocationUnavailableReasonUnknown,
+		},
+		{
+			message: "source path not found",
+			getOptions: func(Request) []AddAnnotationOption {
+				return []AddAnnotationOption{
+					WithFileName("a.proto"),
+					WithSourcePath(protoreflect.SourcePath{4, 0, 2, 5}),
+				}
+			},
+			expectedLocationUnavailableReason:       LocationUnavailableReasonSourcePathNotFound,
+			expectedClientLocationUnavailableReason: LocationUnavailableReasonUnknown,
+		},
+	}
+
+	messageToFinalizeLocationUnavailableReason := make(map[string]LocationUnavailableReason)
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, responseWriter ResponseWriter, request Request) error {
+							for _, testCase := range testCases {
+								responseWriter.AddAnnotation(
+									append(testCase.getOptions(request), WithMessage(testCase.message))...,
+								)
+							}
+							return nil
+						},
+					),
+				},
+			},
+			Finalize: func(_ context.Context, _ FinalizeResponseWriter, _ Request, annotations []Annotation) error {
+				for _, annotation := range annotations {
+					messageToFinalizeLocationUnavailableReason[annotation.Message()] = annotation.LocationUnavailableReason()
+				}
+				return nil
+			},
+			UnknownFilePolicy: UnknownFilePolicyDropLocation,
+		},
+	)
+	require.NoError(t, err)
+	withoutSourceCodeInfoFileDescriptorProto := testNewSuppressionFileDescriptorProto("b.proto", "Bar")
+	withoutSourceCodeInfoFileDescriptorProto.SourceCodeInfo = nil
+	files, err := FilesForProtoFiles(
+		[]*checkv1beta1.File{
+			{
+				FileDescriptorProto: testNewSuppressionFileDescriptorProto("a.proto", "Foo"),
+			},
+			{
+				FileDescriptorProto: withoutSourceCodeInfoFileDescriptorProto,
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(files)
+	require.NoError(t, err)
+	response, err := client.Check(context.Background(), request)
+	require.NoError(t, err)
+	annotations := response.Annotations()
+	require.Len(t, annotations, len(testCases))
+	messageToLocationUnavailableReason := make(map[string]LocationUnavailableReason)
+	for _, annotation := range annotations {
+		messageToLocationUnavailableReason[annotation.Message()] = annotation.LocationUnavailableReason()
+	}
+	for _, testCase := range testCases {
+		require.Equal(
+			t,
+			testCase.expectedLocationUnavailableReason,
+			messageToFinalizeLocationUnavailableReason[testCase.message],
+			testCase.message,
+		)
+		require.Equal(
+			t,
+			testCase.expectedClientLocationUnavailableReason,
+			messageToLocationUnavailableReason[testCase.message],
+			testCase.message,
+		)
+	}
+	require.Equal(t, "source_path_not_found", LocationUnavailableReasonSourcePathNotFound.String())
+	require.Equal(t, "unknown", LocationUnavailableReasonUnknown.String())
+	require.Equal(t, "100", LocationUnavailableReason(100).String())
+}
+
+type testNoParentFileDescriptor struct {
+	protoreflect.Descriptor
+}
+
+func (*testNoParentFileDescriptor) ParentFile() protoreflect.FileDescriptor {
+	return nil
+}
+
+// testFileDescriptorForPath returns the FileDescriptor of the File with the given path.
+//
+// The order of the Files of a Request is not deterministic.
+func testFileDescriptorForPath(request Request, path string) protoreflect.FileDescriptor {
+	for _, file := range request.Files() {
+		if fileDescriptor := file.FileDescriptor(); fileDescriptor.Path() == path {
+			return fileDescriptor
+		}
+	}
+	return nil
+}
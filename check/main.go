@@ -15,6 +15,12 @@
 package check
 
 import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/bufbuild/pluginrpc-go"
 )
 
@@ -37,19 +43,21 @@ import (
 //			},
 //		)
 //	}
+//
+// In addition to serving requests from a pluginrpc client, the plugin supports a one-shot mode
+// for scripting and language-agnostic testing. If invoked with --once, the plugin reads a
+// single serialized CheckRequest from stdin, writes the CheckResponse to stdout, and exits.
+// The format of the request and response is binary by default, or JSON with --format json:
+//
+//	my-plugin --once --format json < request.json > response.json
+//
+// The MainOptions apply in one-shot mode as they do when serving a pluginrpc client.
 func Main(spec *Spec, options ...MainOption) {
-	mainOptions := newMainOptions()
-	for _, option := range options {
-		option(mainOptions)
-	}
-	pluginrpc.Main(
-		func() (pluginrpc.Server, error) {
-			checkServiceHandler, err := newCheckServiceHandler(spec, mainOptions.parallelism)
-			if err != nil {
-				return nil, err
-			}
-			return newCheckServer(checkServiceHandler)
+	mainForSpecFunc(
+		func() (*Spec, error) {
+			return spec, nil
 		},
+		options...,
 	)
 }
 
@@ -72,12 +80,192 @@ func MainWithParallelism(parallelism int) MainOption {
 	}
 }
 
+// MainWithSandbox returns a new MainOption that restricts the plugin process before any
+// requests are handled.
+//
+// This is opt-in hardening that allows hosts to place more trust in third-party plugin
+// binaries. When set:
+//
+//   - All environment variables are removed.
+//   - The working directory is changed to a new empty directory, which is then removed where
+//     the platform allows, so that no files can be created relative to it.
+//   - On Linux amd64 and arm64, a seccomp filter is installed that causes the creation of IPv4
+//     and IPv6 sockets to fail with EACCES. Plugins communicate over stdin and stdout, so this
+//     does not affect Check or ListRules. The filter cannot be removed for the lifetime of
+//     the process.
+//
+// Filesystem access by absolute path is not restricted. If the restrictions cannot be
+// applied, the plugin exits with an error instead of running unsandboxed.
+//
+// RuleHandlers that read environment variables, relative paths, or the network will not
+// work with this option.
+func MainWithSandbox() MainOption {
+	return func(mainOptions *mainOptions) {
+		mainOptions.sandbox = true
+	}
+}
+
+// MainWithEnv returns a new MainOption that results in the plugin being served with the
+// given args and stdio streams instead of os.Args, os.Stdin, os.Stdout, and os.Stderr.
+//
+// This is useful for hosts that run plugins over a custom transport, for example within a
+// container or over SSH. To serve a Spec in a process that has its own main function, use
+// NewServer directly.
+func MainWithEnv(env pluginrpc.Env) MainOption {
+	return func(mainOptions *mainOptions) {
+		mainOptions.env = &env
+	}
+}
+
+// MainWithRequestSnapshots returns a new MainOption that, if a Check call fails or panics,
+// writes the binary-serialized CheckRequest to a new file within the given directory, and
+// references the file in the returned error.
+//
+// The file can be used to reproduce the failure locally with --once:
+//
+//	my-plugin --once < /tmp/bufplugin-check-request-1234.binpb
+//
+// If dirPath is empty, os.TempDir is used. See ServerWithRequestSnapshots for more details.
+func MainWithRequestSnapshots(dirPath string) MainOption {
+	return func(mainOptions *mainOptions) {
+		mainOptions.serverOptions = append(mainOptions.serverOptions, ServerWithRequestSnapshots(dirPath))
+	}
+}
+
+// MainWithRequestSnapshotRedactors returns a new MainOption that applies the given
+// RequestRedactors to each CheckRequest written by MainWithRequestSnapshots.
+//
+// This has no effect unless MainWithRequestSnapshots is also set.
+func MainWithRequestSnapshotRedactors(redactors ...RequestRedactor) MainOption {
+	return func(mainOptions *mainOptions) {
+		mainOptions.serverOptions = append(mainOptions.serverOptions, ServerWithRequestSnapshotRedactors(redactors...))
+	}
+}
+
+// MainWithLazyInit returns a new MainOption that defers the full validation of the Spec,
+// including the initialization of the protovalidate Validator, until the first Check call.
+//
+// This reduces the cold-start latency of ListRules and ListCategories, which are called in a
+// new plugin process per call. See ServerWithLazyInit for more details. This has no effect
+// in one-shot mode, which always runs a Check.
+func MainWithLazyInit() MainOption {
+	return func(mainOptions *mainOptions) {
+		mainOptions.serverOptions = append(mainOptions.serverOptions, ServerWithLazyInit())
+	}
+}
+
+// MainWithProfiling returns a new MainOption that allows CPU and heap profiles to be written
+// when the plugin is run with ProfileDirEnvKey set.
+//
+// This makes it practical to profile a plugin within a user's environment, without a
+// separate build. If ProfileDirEnvKey is not set, this has no effect. Otherwise, each plugin
+// invocation writes a CPU profile covering the whole invocation, and a heap profile taken
+// when it completes, to new files within the directory:
+//
+//	BUFPLUGIN_PROFILE_DIR=/tmp/profiles buf lint
+//	go tool pprof my-plugin /tmp/profiles/bufplugin-1234-5678.cpu.pprof
+//
+// The directory is created if it does not exist. ProfileDirEnvKey is read before
+// MainWithSandbox is applied, so the two options can be used together. Plugins are served
+// over stdin and stdout, so pprof is not served over HTTP.
+func MainWithProfiling() MainOption {
+	return func(mainOptions *mainOptions) {
+		mainOptions.profiling = true
+	}
+}
+
+// MainWithAnnotationSink returns a new MainOption that passes each Annotation added by
+// RuleHandlers and Finalize to the given AnnotationSink.
+//
+// This is useful for fleet-wide analytics on how often each Rule is hit, without changing the
+// behavior of clients. For example, to write each Annotation to a file as NDJSON:
+//
+//	check.Main(spec, check.MainWithAnnotationSink(check.NewNDJSONAnnotationSink(file)))
+//
+// This option can be given multiple times. See ServerWithAnnotationSink for more details.
+func MainWithAnnotationSink(annotationSink AnnotationSink) MainOption {
+	return func(mainOptions *mainOptions) {
+		mainOptions.serverOptions = append(mainOptions.serverOptions, ServerWithAnnotationSink(annotationSink))
+	}
+}
+
 // *** PRIVATE ***
 
 type mainOptions struct {
 	parallelism int
+	sandbox     bool
+	profiling   bool
+	// env is nil if pluginrpc.OSEnv should be used.
+	env *pluginrpc.Env
+	// serverOptions are additional ServerOptions passed to NewServer.
+	serverOptions []ServerOption
 }
 
 func newMainOptions() *mainOptions {
 	return &mainOptions{}
 }
+
+func mainForSpecFunc(getSpec func() (*Spec, error), options ...MainOption) {
+	mainOptions := newMainOptions()
+	for _, option := range options {
+		option(mainOptions)
+	}
+	env := pluginrpc.OSEnv
+	if mainOptions.env != nil {
+		env = *mainOptions.env
+	}
+	mainWithEnv(mainOptions, getSpec, env)
+}
+
+// mainWithEnv is pluginrpc.Main, but serving the given Env.
+func mainWithEnv(mainOptions *mainOptions, getSpec func() (*Spec, error), env pluginrpc.Env) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if err := serveMain(ctx, mainOptions, getSpec, env); err != nil {
+		stderr := env.Stderr
+		if stderr == nil {
+			stderr = os.Stderr
+		}
+		if errString := err.Error(); errString != "" {
+			_, _ = stderr.Write([]byte(errString + "\n"))
+		}
+		cancel()
+		os.Exit(pluginrpc.WrapExitError(err).ExitCode())
+	}
+}
+
+// serveMain starts profiling and applies the sandbox if requested, and then serves the Spec
+// over the given Env.
+func serveMain(ctx context.Context, mainOptions *mainOptions, getSpec func() (*Spec, error), env pluginrpc.Env) (retErr error) {
+	if mainOptions.profiling {
+		if dirPath := os.Getenv(ProfileDirEnvKey); dirPath != "" {
+			stopProfiling, err := startProfiling(dirPath)
+			if err != nil {
+				return err
+			}
+			// This must run before mainWithEnv calls os.Exit.
+			defer func() { retErr = errors.Join(retErr, stopProfiling()) }()
+		}
+	}
+	if mainOptions.sandbox {
+		if err := applySandbox(); err != nil {
+			return err
+		}
+	}
+	spec, err := getSpec()
+	if err != nil {
+		return err
+	}
+	serverOptions := append(
+		[]ServerOption{ServerWithParallelism(mainOptions.parallelism)},
+		mainOptions.serverOptions...,
+	)
+	if isOneShotArgs(env.Args) {
+		return serveOneShot(ctx, spec, serverOptions, env)
+	}
+	server, err := NewServer(spec, serverOptions...)
+	if err != nil {
+		return err
+	}
+	return server.Serve(ctx, env)
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/bufbuild/pluginrpc-go"
+)
+
+// MainMulti is the main entrypoint for a plugin binary that serves multiple named Specs.
+//
+// This allows a single binary to ship logically separate plugins, for example one for lint
+// Rules and one for breaking change Rules, while sharing code. The name of the Spec to serve
+// is selected by the first argument to the plugin, and the remaining arguments are handled
+// as they would be by Main:
+//
+//	func main() {
+//		check.MainMulti(
+//			map[string]*check.Spec{
+//				"lint":     lintSpec,
+//				"breaking": breakingSpec,
+//			},
+//		)
+//	}
+//
+// Callers select a Spec by passing its name as the first argument to the plugin, for example:
+//
+//	client := check.NewClientForRunner(
+//		pluginrpc.NewExecRunner("my-plugin", pluginrpc.ExecRunnerWithArgs("lint")),
+//	)
+//
+// Names must be non-empty and must not start with "-". If no name or an unknown name is
+// given, the plugin exits with an error that lists the available names.
+//
+// The MainOptions apply to whichever Spec is selected.
+func MainMulti(nameToSpec map[string]*Spec, options ...MainOption) {
+	mainOptions := newMainOptions()
+	for _, option := range options {
+		option(mainOptions)
+	}
+	env := pluginrpc.OSEnv
+	if mainOptions.env != nil {
+		env = *mainOptions.env
+	}
+	var name string
+	if len(env.Args) > 0 {
+		name = env.Args[0]
+		env.Args = env.Args[1:]
+	}
+	mainWithEnv(
+		mainOptions,
+		func() (*Spec, error) {
+			return specForName(nameToSpec, name)
+		},
+		env,
+	)
+}
+
+// *** PRIVATE ***
+
+// specForName returns the Spec for the name given as the first argument to MainMulti.
+func specForName(nameToSpec map[string]*Spec, name string) (*Spec, error) {
+	if len(nameToSpec) == 0 {
+		return nil, errors.New("no Specs given to MainMulti")
+	}
+	for specName := range nameToSpec {
+		if specName == "" || strings.HasPrefix(specName, "-") {
+			return nil, fmt.Errorf("invalid Spec name %q: must be non-empty and not start with %q", specName, "-")
+		}
+	}
+	names := strings.Join(xslices.MapKeysToSortedSlice(nameToSpec), ", ")
+	if name == "" || strings.HasPrefix(name, "-") {
+		return nil, fmt.Errorf("the name of the Spec to serve must be given as the first argument, one of: %s", names)
+	}
+	spec, ok := nameToSpec[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown Spec name %q, must be one of: %s", name, names)
+	}
+	return spec, nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecForName(t *testing.T) {
+	t.Parallel()
+
+	lintSpec := testVerifierSpec()
+	breakingSpec := testVerifierSpec()
+	nameToSpec := map[string]*Spec{
+		"lint":     lintSpec,
+		"breaking": breakingSpec,
+	}
+
+	spec, err := specForName(nameToSpec, "lint")
+	require.NoError(t, err)
+	require.Same(t, lintSpec, spec)
+	spec, err = specForName(nameToSpec, "breaking")
+	require.NoError(t, err)
+	require.Same(t, breakingSpec, spec)
+
+	_, err = specForName(nameToSpec, "")
+	require.ErrorContains(t, err, "one of: breaking, lint")
+	_, err = specForName(nameToSpec, "--protocol")
+	require.ErrorContains(t, err, "must be given as the first argument")
+	_, err = specForName(nameToSpec, "other")
+	require.ErrorContains(t, err, `unknown Spec name "other"`)
+	_, err = specForName(nil, "lint")
+	require.Error(t, err)
+	_, err = specForName(map[string]*Spec{"-lint": lintSpec}, "lint")
+	require.ErrorContains(t, err, `invalid Spec name "-lint"`)
+}
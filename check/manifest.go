@@ -0,0 +1,186 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// ManifestVersion is the version of the manifest format produced by ExportManifest.
+const ManifestVersion = "v1"
+
+// ExportManifest returns a manifest of the Rules and Categories of the plugin.
+//
+// The manifest is a versioned JSON document containing the result of ListRules and
+// ListCategories. It can be checked in alongside configuration, and read with
+// NewClientForManifest, so that tooling can validate Rule and Category references without
+// executing the plugin.
+//
+// The output is deterministic for a given set of Rules and Categories.
+func ExportManifest(ctx context.Context, client Client) ([]byte, error) {
+	rules, err := client.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := client.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	externalManifest := &externalManifest{
+		Version:         ManifestVersion,
+		ProtocolVersion: ProtocolVersion,
+	}
+	for _, rule := range rules {
+		data, err := protojson.Marshal(rule.toProto())
+		if err != nil {
+			return nil, err
+		}
+		externalManifest.Rules = append(externalManifest.Rules, data)
+	}
+	for _, category := range categories {
+		data, err := protojson.Marshal(category.toProto())
+		if err != nil {
+			return nil, err
+		}
+		externalManifest.Categories = append(externalManifest.Categories, data)
+	}
+	data, err := json.MarshalIndent(externalManifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// NewClientForManifest returns a new Client for a manifest produced by ExportManifest.
+//
+// ListRules and ListCategories return the Rules and Categories of the manifest without
+// executing the plugin. Check always returns an error.
+//
+// Returns error if the manifest is invalid, or is of a version other than ManifestVersion.
+func NewClientForManifest(data []byte) (Client, error) {
+	externalManifest := &externalManifest{}
+	if err := json.Unmarshal(data, externalManifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if externalManifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %q, expected %q", externalManifest.Version, ManifestVersion)
+	}
+	if externalManifest.ProtocolVersion != ProtocolVersion {
+		return nil, fmt.Errorf(
+			"unsupported manifest protocol version %q, expected %q",
+			externalManifest.ProtocolVersion,
+			ProtocolVersion,
+		)
+	}
+	categories, err := xslices.MapError(
+		externalManifest.Categories,
+		func(data json.RawMessage) (Category, error) {
+			protoCategory := &checkv1beta1.Category{}
+			if err := protojson.Unmarshal(data, protoCategory); err != nil {
+				return nil, fmt.Errorf("invalid manifest category: %w", err)
+			}
+			return categoryForProtoCategory(protoCategory)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNoDuplicateCategories(categories); err != nil {
+		return nil, err
+	}
+	categoryIDToCategory := make(map[string]Category, len(categories))
+	for _, category := range categories {
+		categoryIDToCategory[category.ID()] = category
+	}
+	rules, err := xslices.MapError(
+		externalManifest.Rules,
+		func(data json.RawMessage) (Rule, error) {
+			protoRule := &checkv1beta1.Rule{}
+			if err := protojson.Unmarshal(data, protoRule); err != nil {
+				return nil, fmt.Errorf("invalid manifest rule: %w", err)
+			}
+			return ruleForProtoRule(protoRule, categoryIDToCategory)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNoDuplicateRules(rules); err != nil {
+		return nil, err
+	}
+	if err := validateNoDuplicateRuleOrCategoryIDs(
+		append(xslices.Map(rules, Rule.ID), xslices.Map(categories, Category.ID)...),
+	); err != nil {
+		return nil, err
+	}
+	sortRules(rules)
+	sortCategories(categories)
+	return &manifestClient{
+		rules:      rules,
+		categories: categories,
+	}, nil
+}
+
+// *** PRIVATE ***
+
+type externalManifest struct {
+	Version         string            `json:"version"`
+	ProtocolVersion string            `json:"protocol_version"`
+	Rules           []json.RawMessage `json:"rules"`
+	Categories      []json.RawMessage `json:"categories"`
+}
+
+type manifestClient struct {
+	rules      []Rule
+	categories []Category
+}
+
+func (*manifestClient) Check(context.Context, Request, ...CheckCallOption) (Response, error) {
+	return nil, errors.New("Check cannot be called on a Client created from a manifest")
+}
+
+func (m *manifestClient) ListRules(_ context.Context, options ...ListRulesCallOption) ([]Rule, error) {
+	listRulesCallOptions := newListRulesCallOptions()
+	for _, option := range options {
+		option(listRulesCallOptions)
+	}
+	rules := slices.Clone(m.rules)
+	if listRulesCallOptions.categoryOrder {
+		sortRulesByCategory(rules)
+	}
+	return rules, nil
+}
+
+func (m *manifestClient) ListCategories(context.Context, ...ListCategoriesCallOption) ([]Category, error) {
+	return slices.Clone(m.categories), nil
+}
+
+func (m *manifestClient) ListAll(context.Context, ...ListAllCallOption) (PluginMetadata, error) {
+	// The protocol version of the manifest was validated to be ProtocolVersion.
+	return newPluginMetadata(m.rules, m.categories), nil
+}
+
+// ClearCache is a no-op, as the Rules and Categories of a manifest never change.
+func (*manifestClient) ClearCache() {}
+
+func (*manifestClient) isClient() {}
@@ -0,0 +1,92 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:             "RULE2",
+					CategoryIDs:    []string{"CATEGORY1"},
+					Purpose:        "Test rule2.",
+					Type:           RuleTypeBreaking,
+					Deprecated:     true,
+					ReplacementIDs: []string{"RULE1"},
+					Handler:        nopRuleHandler,
+				},
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule1.",
+					Type:      RuleTypeLint,
+					Handler:   nopRuleHandler,
+				},
+			},
+			Categories: []*CategorySpec{
+				{
+					ID:      "CATEGORY1",
+					Purpose: "Test category1.",
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	data, err := ExportManifest(ctx, client)
+	require.NoError(t, err)
+	// Deterministic.
+	data2, err := ExportManifest(ctx, client)
+	require.NoError(t, err)
+	require.Equal(t, string(data), string(data2))
+
+	manifestClient, err := NewClientForManifest(data)
+	require.NoError(t, err)
+	expectedRules, err := client.ListRules(ctx)
+	require.NoError(t, err)
+	actualRules, err := manifestClient.ListRules(ctx)
+	require.NoError(t, err)
+	require.Len(t, actualRules, len(expectedRules))
+	for i, expectedRule := range expectedRules {
+		require.Equal(t, 0, CompareRules(expectedRule, actualRules[i]))
+		require.Equal(t, expectedRule.ReplacementIDs(), actualRules[i].ReplacementIDs())
+		require.Equal(t, expectedRule.Deprecated(), actualRules[i].Deprecated())
+	}
+	categories, err := manifestClient.ListCategories(ctx)
+	require.NoError(t, err)
+	require.Len(t, categories, 1)
+	require.Equal(t, "CATEGORY1", categories[0].ID())
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	_, err = manifestClient.Check(ctx, request)
+	require.Error(t, err)
+
+	_, err = NewClientForManifest([]byte(strings.Replace(string(data), `"v1"`, `"v2"`, 1)))
+	require.ErrorContains(t, err, "unsupported manifest version")
+	_, err = NewClientForManifest([]byte(strings.Replace(string(data), `"CATEGORY1"`, `"CATEGORY2"`, 1)))
+	require.Error(t, err)
+	_, err = NewClientForManifest([]byte("{"))
+	require.Error(t, err)
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/bufbuild/pluginrpc-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// *** PRIVATE ***
+
+const (
+	oneShotFlagName         = "once"
+	oneShotFormatFlagName   = "format"
+	oneShotFormatBinaryName = "binary"
+	oneShotFormatJSONName   = "json"
+)
+
+// isOneShotArgs returns true if the args request one-shot mode.
+func isOneShotArgs(args []string) bool {
+	return slices.Contains(args, "--"+oneShotFlagName)
+}
+
+// serveOneShot reads a single CheckRequest from stdin, and writes the CheckResponse to stdout.
+//
+// The format of both the request and the response is given by --format, which is either
+// binary (the default) or json, matching the formats supported by pluginrpc. The Check is
+// handled with the same ServerOptions that NewServer would be given.
+func serveOneShot(ctx context.Context, spec *Spec, serverOptions []ServerOption, env pluginrpc.Env) error {
+	isJSON, err := parseOneShotArgs(env.Args)
+	if err != nil {
+		return err
+	}
+	if env.Stdin == nil {
+		return errors.New("--once requires a CheckRequest on stdin")
+	}
+	checkServiceHandler, err := newCheckServiceHandlerForServerOptions(spec, serverOptions...)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(env.Stdin)
+	if err != nil {
+		return err
+	}
+	checkRequest := &checkv1beta1.CheckRequest{}
+	if isJSON {
+		err = protojson.Unmarshal(data, checkRequest)
+	} else {
+		err = proto.Unmarshal(data, checkRequest)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid CheckRequest: %w", err)
+	}
+	checkResponse, err := checkServiceHandler.Check(ctx, checkRequest)
+	if err != nil {
+		return err
+	}
+	if isJSON {
+		data, err = protojson.Marshal(checkResponse)
+		data = append(data, '\n')
+	} else {
+		data, err = proto.Marshal(checkResponse)
+	}
+	if err != nil {
+		return err
+	}
+	if env.Stdout == nil {
+		return nil
+	}
+	_, err = env.Stdout.Write(data)
+	return err
+}
+
+// parseOneShotArgs parses the args for one-shot mode, returning true if the format is JSON.
+func parseOneShotArgs(args []string) (bool, error) {
+	flagSet := flag.NewFlagSet("", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	flagSet.Bool(oneShotFlagName, false, "")
+	format := flagSet.String(oneShotFormatFlagName, oneShotFormatBinaryName, "")
+	if err := flagSet.Parse(args); err != nil {
+		return false, err
+	}
+	if flagSet.NArg() > 0 {
+		return false, fmt.Errorf("unexpected arguments with --%s: %v", oneShotFlagName, flagSet.Args())
+	}
+	switch *format {
+	case oneShotFormatBinaryName:
+		return false, nil
+	case oneShotFormatJSONName:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid value for --%s: %q", oneShotFormatFlagName, *format)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/bufbuild/pluginrpc-go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestServeOneShot(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	spec := &Spec{
+		Rules: []*RuleSpec{
+			{
+				ID:        "RULE1",
+				IsDefault: true,
+				Purpose:   "Test rule1.",
+				Type:      RuleTypeLint,
+				Handler: RuleHandlerFunc(
+					func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+						responseWriter.AddAnnotation(WithMessage("hello"))
+						return nil
+					},
+				),
+			},
+		},
+	}
+
+	requestData, err := proto.Marshal(&checkv1beta1.CheckRequest{})
+	require.NoError(t, err)
+	stdout := bytes.NewBuffer(nil)
+	err = serveOneShot(
+		ctx,
+		spec,
+		nil,
+		pluginrpc.Env{
+			Args:   []string{"--once"},
+			Stdin:  bytes.NewReader(requestData),
+			Stdout: stdout,
+		},
+	)
+	require.NoError(t, err)
+	checkResponse := &checkv1beta1.CheckResponse{}
+	require.NoError(t, proto.Unmarshal(stdout.Bytes(), checkResponse))
+	require.Len(t, checkResponse.GetAnnotations(), 1)
+	require.Equal(t, "RULE1", checkResponse.GetAnnotations()[0].GetRuleId())
+	require.Equal(t, "hello", checkResponse.GetAnnotations()[0].GetMessage())
+
+	stdout.Reset()
+	err = serveOneShot(
+		ctx,
+		spec,
+		nil,
+		pluginrpc.Env{
+			Args:   []string{"--once", "--format", "json"},
+			Stdin:  strings.NewReader("{}"),
+			Stdout: stdout,
+		},
+	)
+	require.NoError(t, err)
+	checkResponse = &checkv1beta1.CheckResponse{}
+	require.NoError(t, protojson.Unmarshal(stdout.Bytes(), checkResponse))
+	require.Len(t, checkResponse.GetAnnotations(), 1)
+	require.Equal(t, "hello", checkResponse.GetAnnotations()[0].GetMessage())
+
+	err = serveOneShot(
+		ctx,
+		spec,
+		nil,
+		pluginrpc.Env{
+			Args:  []string{"--once", "--format=json"},
+			Stdin: strings.NewReader("not json"),
+		},
+	)
+	require.ErrorContains(t, err, "invalid CheckRequest")
+}
+
+func TestServeMainOneShotServerOptions(t *testing.T) {
+	t.Parallel()
+
+	var observedMessages []string
+	mainOptions := newMainOptions()
+	MainWithAnnotationSink(
+		AnnotationSinkFunc(
+			func(annotation Annotation) {
+				observedMessages = append(observedMessages, annotation.Message())
+			},
+		),
+	)(mainOptions)
+	requestData, err := proto.Marshal(&checkv1beta1.CheckRequest{})
+	require.NoError(t, err)
+	stdout := bytes.NewBuffer(nil)
+	err = serveMain(
+		context.Background(),
+		mainOptions,
+		func() (*Spec, error) {
+			return &Spec{
+				Rules: []*RuleSpec{
+					{
+						ID:        "RULE1",
+						IsDefault: true,
+						Purpose:   "Test rule1.",
+						Type:      RuleTypeLint,
+						Handler: RuleHandlerFunc(
+							func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+								responseWriter.AddAnnotation(WithMessage("hello"))
+								return nil
+							},
+						),
+					},
+				},
+			}, nil
+		},
+		pluginrpc.Env{
+			Args:   []string{"--once"},
+			Stdin:  bytes.NewReader(requestData),
+			Stdout: stdout,
+		},
+	)
+	require.NoError(t, err)
+	checkResponse := &checkv1beta1.CheckResponse{}
+	require.NoError(t, proto.Unmarshal(stdout.Bytes(), checkResponse))
+	require.Len(t, checkResponse.GetAnnotations(), 1)
+	// The AnnotationSink given to Main is called in one-shot mode.
+	require.Equal(t, []string{"hello"}, observedMessages)
+}
+
+func TestParseOneShotArgs(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, isOneShotArgs([]string{"--once"}))
+	require.False(t, isOneShotArgs([]string{"--protocol"}))
+
+	isJSON, err := parseOneShotArgs([]string{"--once"})
+	require.NoError(t, err)
+	require.False(t, isJSON)
+	isJSON, err = parseOneShotArgs([]string{"--format=binary", "--once"})
+	require.NoError(t, err)
+	require.False(t, isJSON)
+	isJSON, err = parseOneShotArgs([]string{"--once", "--format", "json"})
+	require.NoError(t, err)
+	require.True(t, isJSON)
+	_, err = parseOneShotArgs([]string{"--once", "--format", "yaml"})
+	require.ErrorContains(t, err, `invalid value for --format: "yaml"`)
+	_, err = parseOneShotArgs([]string{"--once", "check"})
+	require.ErrorContains(t, err, "unexpected arguments")
+	_, err = parseOneShotArgs([]string{"--once", "--spec"})
+	require.Error(t, err)
+}
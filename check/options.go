@@ -24,14 +24,38 @@
 package check
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
 	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
 )
 
-var emptyOptions = newOptionsNoValidate(nil)
+const (
+	minOptionKeyLength = 4
+	maxOptionKeyLength = 64
+
+	// maxOptionValuePreviewBytes is the maximum number of bytes of a string or bytes value
+	// to render in a preview.
+	maxOptionValuePreviewBytes = 64
+	// maxOptionValuePreviewElements is the maximum number of elements of a slice value to
+	// render in a preview.
+	maxOptionValuePreviewElements = 8
+)
+
+var (
+	emptyOptions = newOptionsNoValidate(nil)
+
+	optionKeyRegexp = regexp.MustCompile(`^[a-z][a-z_]*[a-z]$`)
+)
 
 // Options are key/values that can control the behavior of a RuleHandler,
 // and can control the value of the Purpose string of the Rule.
@@ -58,7 +82,7 @@ type Options interface {
 	//
 	// A caller should not modify a returned value.
 	//
-	// The key must have at least four characters.
+	// The key must have at least four characters, and at most 64 characters.
 	// The key must start and end with a lowercase letter from a-z, and only consist
 	// of lowercase letters from a-z and underscores.
 	Get(key string) (any, bool)
@@ -66,6 +90,18 @@ type Options interface {
 	//
 	// The range order is not deterministic.
 	Range(f func(key string, value any))
+	// DebugMap returns a map from each key to a human-readable preview of its value.
+	//
+	// Previews are size-limited: long strings and bytes are truncated, and long slices only
+	// show their first elements, with the full length noted. Strings and bytes that are not
+	// valid UTF-8 are rendered in hex. This is intended for debug logging and troubleshooting
+	// configuration mismatches, and the format of previews may change.
+	DebugMap() map[string]string
+	// String returns a human-readable representation of the Options, with keys in sorted order
+	// and values rendered as in DebugMap.
+	//
+	// For example: {max_length: 10, suffix: "_time"}.
+	String() string
 
 	toProto() ([]*checkv1beta1.Option, error)
 
@@ -73,6 +109,9 @@ type Options interface {
 }
 
 // NewOptions returns a new validated Options for the given key/value map.
+//
+// Keys and values are validated against the constraints of the protocol, so that invalid
+// Options result in an error when a Request is built, rather than within the plugin.
 func NewOptions(keyToValue map[string]any) (Options, error) {
 	if err := validateKeyToValue(keyToValue); err != nil {
 		return nil, err
@@ -239,6 +278,46 @@ func (o *options) Range(f func(key string, value any)) {
 	}
 }
 
+// mergeOptions returns a new Options with the key/values of defaults that are not set
+// within overrides, and all key/values of overrides.
+//
+// Returns overrides if defaults is empty.
+func mergeOptions(defaults map[string]any, overrides Options) Options {
+	if len(defaults) == 0 {
+		return overrides
+	}
+	keyToValue := maps.Clone(defaults)
+	overrides.Range(
+		func(key string, value any) {
+			keyToValue[key] = value
+		},
+	)
+	return newOptionsNoValidate(keyToValue)
+}
+
+func (o *options) DebugMap() map[string]string {
+	debugMap := make(map[string]string, len(o.keyToValue))
+	for key, value := range o.keyToValue {
+		debugMap[key] = previewOptionValue(value)
+	}
+	return debugMap
+}
+
+func (o *options) String() string {
+	var sb strings.Builder
+	_, _ = sb.WriteString("{")
+	for i, key := range slices.Sorted(maps.Keys(o.keyToValue)) {
+		if i > 0 {
+			_, _ = sb.WriteString(", ")
+		}
+		_, _ = sb.WriteString(key)
+		_, _ = sb.WriteString(": ")
+		_, _ = sb.WriteString(previewOptionValue(o.keyToValue[key]))
+	}
+	_, _ = sb.WriteString("}")
+	return sb.String()
+}
+
 func (o *options) toProto() ([]*checkv1beta1.Option, error) {
 	if o == nil {
 		return nil, nil
@@ -263,6 +342,59 @@ func (o *options) toProto() ([]*checkv1beta1.Option, error) {
 
 func (*options) isOption() {}
 
+// previewOptionValue returns a size-limited, human-readable preview of the value.
+//
+// You can assume that value is a valid value.
+func previewOptionValue(value any) string {
+	switch reflectValue := reflect.ValueOf(value); reflectValue.Kind() {
+	case reflect.String:
+		return previewOptionStringOrBytes(reflectValue.String(), "")
+	case reflect.Slice:
+		if t, ok := value.([]byte); ok {
+			return previewOptionStringOrBytes(string(t), "bytes")
+		}
+		length := reflectValue.Len()
+		elements := make([]string, 0, min(length, maxOptionValuePreviewElements)+1)
+		for i := range min(length, maxOptionValuePreviewElements) {
+			elements = append(elements, previewOptionValue(reflectValue.Index(i).Interface()))
+		}
+		if length > maxOptionValuePreviewElements {
+			elements = append(elements, fmt.Sprintf("... (%d elements)", length))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// previewOptionStringOrBytes returns a preview of a string or bytes value.
+//
+// Valid UTF-8 is quoted, and invalid UTF-8 is rendered in hex. If prefix is not empty, the
+// preview is wrapped as prefix(...), to distinguish bytes from strings.
+func previewOptionStringOrBytes(s string, prefix string) string {
+	isValidUTF8 := utf8.ValidString(s)
+	truncatedLength := min(len(s), maxOptionValuePreviewBytes)
+	if isValidUTF8 {
+		// Do not split a multi-byte character.
+		for truncatedLength < len(s) && truncatedLength > 0 && !utf8.RuneStart(s[truncatedLength]) {
+			truncatedLength--
+		}
+	}
+	var preview string
+	if isValidUTF8 {
+		preview = strconv.Quote(s[:truncatedLength])
+	} else {
+		preview = "0x" + hex.EncodeToString([]byte(s[:truncatedLength]))
+	}
+	if truncatedLength < len(s) {
+		preview = fmt.Sprintf("%s... (%d bytes)", preview, len(s))
+	}
+	if prefix != "" {
+		preview = prefix + "(" + preview + ")"
+	}
+	return preview
+}
+
 // You can assume that value is a valid value.
 func valueToProtoValue(value any) (*checkv1beta1.Value, error) {
 	switch reflectValue := reflect.ValueOf(value); reflectValue.Kind() {
@@ -272,12 +404,19 @@ func valueToProtoValue(value any) (*checkv1beta1.Value, error) {
 				BoolValue: reflectValue.Bool(),
 			},
 		}, nil
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return &checkv1beta1.Value{
 			Type: &checkv1beta1.Value_Int64Value{
 				Int64Value: reflectValue.Int(),
 			},
 		}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		// validateValue verifies that the value does not overflow int64.
+		return &checkv1beta1.Value{
+			Type: &checkv1beta1.Value_Int64Value{
+				Int64Value: int64(reflectValue.Uint()),
+			},
+		}, nil
 	case reflect.Float32, reflect.Float64:
 		return &checkv1beta1.Value{
 			Type: &checkv1beta1.Value_DoubleValue{
@@ -368,15 +507,34 @@ func protoValueToValue(protoValue *checkv1beta1.Value) (any, error) {
 }
 
 func validateKeyToValue(keyToValue map[string]any) error {
-	for key, value := range keyToValue {
-		// This should all be validated via protovalidate, and the below doesn't
-		// even encapsulate all the validation.
-		if len(key) == 0 {
-			return errors.New("invalid option key: key cannot be empty")
-		}
-		if err := validateValue(value); err != nil {
+	// Sort so that the error is deterministic if there are multiple invalid keys.
+	for _, key := range slices.Sorted(maps.Keys(keyToValue)) {
+		if err := validateKey(key); err != nil {
 			return err
 		}
+		if err := validateValue(keyToValue[key]); err != nil {
+			return fmt.Errorf("option %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// validateKey validates the key per the constraints of checkv1beta1.Option.
+func validateKey(key string) error {
+	if len(key) == 0 {
+		return errors.New("invalid option key: key cannot be empty")
+	}
+	if len(key) < minOptionKeyLength {
+		return fmt.Errorf("invalid option key %q: key must have at least %d characters", key, minOptionKeyLength)
+	}
+	if len(key) > maxOptionKeyLength {
+		return fmt.Errorf("invalid option key %q: key must have at most %d characters", key, maxOptionKeyLength)
+	}
+	if !optionKeyRegexp.MatchString(key) {
+		return fmt.Errorf(
+			"invalid option key %q: key must start and end with a lowercase letter from a-z, and only consist of lowercase letters from a-z and underscores",
+			key,
+		)
 	}
 	return nil
 }
@@ -392,12 +550,21 @@ func validateValue(value any) error {
 			return errors.New("invalid option value: bool must be true")
 		}
 		return nil
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		t := reflectValue.Int()
 		if t == 0 {
 			return errors.New("invalid option value: int must be non-zero")
 		}
 		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		t := reflectValue.Uint()
+		if t == 0 {
+			return errors.New("invalid option value: int must be non-zero")
+		}
+		if t > math.MaxInt64 {
+			return fmt.Errorf("invalid option value: int %d overflows int64", t)
+		}
+		return nil
 	case reflect.Float32, reflect.Float64:
 		t := reflectValue.Float()
 		if t == 0 {
@@ -411,19 +578,30 @@ func validateValue(value any) error {
 		}
 		return nil
 	case reflect.Slice:
+		// Bytes are sent as a single checkv1beta1.Value, not as a list of ints.
+		if t, ok := value.([]byte); ok {
+			if len(t) == 0 {
+				return errors.New("invalid option value: bytes must be non-empty")
+			}
+			return nil
+		}
 		vLen := reflectValue.Len()
 		if vLen == 0 {
 			return errors.New("invalid option value: slice must be non-empty")
 		}
 		firstValue := reflectValue.Index(0).Interface()
 		firstValueType := reflect.TypeOf(firstValue)
-		for i := 1; i < vLen; i++ {
+		for i := range vLen {
 			subValue := reflectValue.Index(i).Interface()
 			subValueType := reflect.TypeOf(subValue)
 			// reflect.Types are comparable with == per documentation.
 			if firstValueType != subValueType {
 				return fmt.Errorf("invalid option value: slice must have values of the same type but detected types %v and %v", firstValueType, subValueType)
 			}
+			// Each element is sent as its own checkv1beta1.Value, so the same constraints apply.
+			if err := validateValue(subValue); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
 		}
 		return nil
 	case reflect.Invalid, reflect.Uintptr, reflect.Complex64, reflect.Complex128, reflect.Array, reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer | reflect.Ptr, reflect.Struct, reflect.UnsafePointer:
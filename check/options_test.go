@@ -24,6 +24,8 @@
 package check
 
 import (
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -43,6 +45,7 @@ func TestOptionsRoundTrip(t *testing.T) {
 	testOptionsRoundTrip(t, []float64{1.0, 2.0})
 	testOptionsRoundTrip(t, []string{"foo", "bar"})
 	testOptionsRoundTrip(t, [][]string{{"foo", "bar"}, {"baz, bat"}})
+	testOptionsRoundTripDifferentInputOutput(t, uint32(1), int64(1))
 	testOptionsRoundTripDifferentInputOutput(
 		t,
 		[]any{"foo", "bar"},
@@ -55,6 +58,43 @@ func TestOptionsRoundTrip(t *testing.T) {
 	)
 }
 
+func TestOptionsDebug(t *testing.T) {
+	t.Parallel()
+
+	// Values are as returned by OptionsForProtoOptions.
+	options := newOptionsNoValidate(
+		map[string]any{
+			"suffix":     "_time",
+			"max_length": int64(10),
+			"enabled":    true,
+			"data":       []byte{0xff, 0x00},
+			"text":       []byte("abc"),
+			"long":       strings.Repeat("é", 40),
+			"names":      []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"},
+			"nested":     [][]int64{{1, 2}, {3}},
+		},
+	)
+	require.Equal(
+		t,
+		map[string]string{
+			"suffix":     `"_time"`,
+			"max_length": "10",
+			"enabled":    "true",
+			"data":       "bytes(0xff00)",
+			"text":       `bytes("abc")`,
+			// 64 bytes is 32 two-byte characters.
+			"long":   `"` + strings.Repeat("é", 32) + `"... (80 bytes)`,
+			"names":  `["a", "b", "c", "d", "e", "f", "g", "h", ... (10 elements)]`,
+			"nested": "[[1, 2], [3]]",
+		},
+		options.DebugMap(),
+	)
+	stringOptions, err := NewOptions(map[string]any{"suffix": "_time", "max_length": int64(10)})
+	require.NoError(t, err)
+	require.Equal(t, `{max_length: 10, suffix: "_time"}`, stringOptions.String())
+	require.Equal(t, "{}", emptyOptions.String())
+}
+
 func TestOptionsValidateValueError(t *testing.T) {
 	t.Parallel()
 
@@ -66,6 +106,47 @@ func TestOptionsValidateValueError(t *testing.T) {
 	assert.Error(t, err)
 	err = validateValue([]any{[]string{"foo"}, "foo"})
 	assert.Error(t, err)
+	err = validateValue([]string{"foo", ""})
+	assert.Error(t, err)
+	err = validateValue([][]int64{{1}, {}})
+	assert.Error(t, err)
+	err = validateValue([]byte{})
+	assert.Error(t, err)
+	err = validateValue(uint64(math.MaxUint64))
+	assert.Error(t, err)
+
+	assert.NoError(t, validateValue([]byte{0}))
+	assert.NoError(t, validateValue(uint32(1)))
+}
+
+func TestOptionsValidateKey(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{
+		"abcd",
+		"service_suffix",
+		"a__z",
+		strings.Repeat("a", 64),
+	} {
+		assert.NoError(t, validateKey(key), key)
+	}
+	for _, key := range []string{
+		"",
+		"abc",
+		strings.Repeat("a", 65),
+		"_abcd",
+		"abcd_",
+		"Abcd",
+		"ab1cd",
+		"ab-cd",
+		"ab cd",
+	} {
+		assert.Error(t, validateKey(key), key)
+	}
+	_, err := NewOptions(map[string]any{"abc": "foo", "ab": "foo"})
+	require.EqualError(t, err, `invalid option key "ab": key must have at least 4 characters`)
+	_, err = NewOptions(map[string]any{"service_suffix": []string{""}})
+	require.EqualError(t, err, `option "service_suffix": index 0: invalid option value: string must be non-empty`)
 }
 
 func testOptionsRoundTrip(t *testing.T, value any) {
@@ -0,0 +1,80 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"slices"
+)
+
+// PluginMetadata is the metadata of a plugin, as returned by Client.ListAll.
+//
+// This consolidates the Rules and Categories of a plugin, and information derived from them,
+// so that hosts do not need to coordinate multiple calls.
+type PluginMetadata interface {
+	// Rules returns the Rules of the plugin.
+	//
+	// The Rules are sorted by Rule ID.
+	Rules() []Rule
+	// Categories returns the Categories of the plugin.
+	//
+	// The Categories are sorted by Category ID.
+	Categories() []Category
+	// RuleTypes returns the capabilities of the plugin, that is the types of the Rules that
+	// the plugin provides.
+	//
+	// For example, a plugin that only provides lint Rules returns only RuleTypeLint, and
+	// does not need to be invoked for breaking change checks. The RuleTypes are sorted.
+	RuleTypes() []RuleType
+
+	isPluginMetadata()
+}
+
+// *** PRIVATE ***
+
+type pluginMetadata struct {
+	rules      []Rule
+	categories []Category
+	ruleTypes  []RuleType
+}
+
+// newPluginMetadata returns a new PluginMetadata for the sorted Rules and Categories.
+func newPluginMetadata(rules []Rule, categories []Category) *pluginMetadata {
+	var ruleTypes []RuleType
+	for _, rule := range rules {
+		if !slices.Contains(ruleTypes, rule.Type()) {
+			ruleTypes = append(ruleTypes, rule.Type())
+		}
+	}
+	slices.Sort(ruleTypes)
+	return &pluginMetadata{
+		rules:      rules,
+		categories: categories,
+		ruleTypes:  ruleTypes,
+	}
+}
+
+func (p *pluginMetadata) Rules() []Rule {
+	return slices.Clone(p.rules)
+}
+
+func (p *pluginMetadata) Categories() []Category {
+	return slices.Clone(p.categories)
+}
+
+func (p *pluginMetadata) RuleTypes() []RuleType {
+	return slices.Clone(p.ruleTypes)
+}
+
+func (*pluginMetadata) isPluginMetadata() {}
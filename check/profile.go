@@ -0,0 +1,84 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+)
+
+// ProfileDirEnvKey is the environment variable that, if set to a directory path and
+// MainWithProfiling is used, causes the plugin to write CPU and heap profiles to the
+// directory.
+//
+//	BUFPLUGIN_PROFILE_DIR=/tmp/profiles buf lint
+const ProfileDirEnvKey = "BUFPLUGIN_PROFILE_DIR"
+
+// *** PRIVATE ***
+
+const (
+	cpuProfileFileSuffix  = ".cpu.pprof"
+	heapProfileFileSuffix = ".heap.pprof"
+)
+
+// startProfiling starts writing a CPU profile to a new file within dirPath.
+//
+// The returned function stops the CPU profile and writes a heap profile next to it. Both
+// files share a unique prefix, so that the profiles of a single plugin invocation can be
+// correlated when many invocations write to the same directory.
+func startProfiling(dirPath string) (func() error, error) {
+	// The sandbox may change the working directory before the profiles are written.
+	dirPath, err := filepath.Abs(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("profile: %w", err)
+	}
+	if err := os.MkdirAll(dirPath, 0o755); err != nil {
+		return nil, fmt.Errorf("profile: %w", err)
+	}
+	cpuFile, err := os.CreateTemp(dirPath, fmt.Sprintf("bufplugin-%d-*%s", os.Getpid(), cpuProfileFileSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("profile: %w", err)
+	}
+	if err := pprof.StartCPUProfile(cpuFile); err != nil {
+		return nil, errors.Join(fmt.Errorf("profile: %w", err), cpuFile.Close())
+	}
+	heapFilePath := strings.TrimSuffix(cpuFile.Name(), cpuProfileFileSuffix) + heapProfileFileSuffix
+	return func() error {
+		pprof.StopCPUProfile()
+		if err := cpuFile.Close(); err != nil {
+			return fmt.Errorf("profile: %w", err)
+		}
+		if err := writeHeapProfile(heapFilePath); err != nil {
+			return fmt.Errorf("profile: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+func writeHeapProfile(filePath string) (retErr error) {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() { retErr = errors.Join(retErr, file.Close()) }()
+	// Get up-to-date statistics, as recommended by runtime/pprof.
+	runtime.GC()
+	return pprof.Lookup("heap").WriteTo(file, 0)
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartProfiling(t *testing.T) {
+	t.Parallel()
+
+	dirPath := filepath.Join(t.TempDir(), "profiles")
+	stopProfiling, err := startProfiling(dirPath)
+	require.NoError(t, err)
+	require.NoError(t, stopProfiling())
+
+	dirEntries, err := os.ReadDir(dirPath)
+	require.NoError(t, err)
+	require.Len(t, dirEntries, 2)
+	var cpuFileName string
+	var heapFileName string
+	for _, dirEntry := range dirEntries {
+		fileInfo, err := dirEntry.Info()
+		require.NoError(t, err)
+		require.NotZero(t, fileInfo.Size())
+		switch name := dirEntry.Name(); {
+		case strings.HasSuffix(name, cpuProfileFileSuffix):
+			cpuFileName = name
+		case strings.HasSuffix(name, heapProfileFileSuffix):
+			heapFileName = name
+		}
+	}
+	require.NotEmpty(t, cpuFileName)
+	require.Equal(
+		t,
+		strings.TrimSuffix(cpuFileName, cpuProfileFileSuffix),
+		strings.TrimSuffix(heapFileName, heapProfileFileSuffix),
+	)
+}
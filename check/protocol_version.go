@@ -0,0 +1,117 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	pluginrpcv1beta1 "buf.build/gen/go/bufbuild/pluginrpc/protocolbuffers/go/buf/pluginrpc/v1beta1"
+	"github.com/bufbuild/bufplugin-go/internal/gen/buf/plugin/check/v1beta1/v1beta1pluginrpc"
+	"github.com/bufbuild/pluginrpc-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtocolVersion is the version of the bufplugin check protocol implemented by this package.
+//
+// Plugins built with this package serve, and Clients created by this package call, the
+// buf.plugin.check.<ProtocolVersion>.CheckService.
+const ProtocolVersion = "v1beta1"
+
+// ClientWithRequiredProtocolVersion returns a new ClientOption that will result in the Client
+// verifying that the plugin speaks the given protocol version before it is first invoked.
+//
+// If the plugin does not serve the CheckService for the given version, or the Client does not
+// implement the given version, an error describing the mismatch is returned from every Client
+// call, instead of an error from calling a missing procedure or from unmarshaling a response.
+//
+// Verification reads the pluginrpc Spec of the plugin, which results in one additional call to
+// the plugin for the lifetime of the Client. The Spec can only be read by Clients created with
+// NewClientForRunner or NewClientForSpec. Other Clients return an error from every call.
+func ClientWithRequiredProtocolVersion(version string) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.requiredProtocolVersion = version
+	}
+}
+
+// *** PRIVATE ***
+
+// checkServiceProcedurePaths are the paths of the procedures of the CheckService for
+// ProtocolVersion.
+var checkServiceProcedurePaths = []string{
+	v1beta1pluginrpc.CheckServiceCheckPath,
+	v1beta1pluginrpc.CheckServiceListRulesPath,
+	v1beta1pluginrpc.CheckServiceListCategoriesPath,
+}
+
+// newProtocolVersionVerifier returns a new Verifier that verifies that the plugin run by the
+// pluginrpc.Runner serves the CheckService for requiredProtocolVersion.
+//
+// The runner is nil if the Client was not created with NewClientForRunner or NewClientForSpec,
+// in which case the pluginrpc Spec of the plugin cannot be read, and verification fails.
+func newProtocolVersionVerifier(runner pluginrpc.Runner, requiredProtocolVersion string) Verifier {
+	return VerifierFunc(
+		func(ctx context.Context) error {
+			if requiredProtocolVersion != ProtocolVersion {
+				return fmt.Errorf(
+					"bufplugin protocol version %s is required, but this client only supports version %s",
+					requiredProtocolVersion,
+					ProtocolVersion,
+				)
+			}
+			if runner == nil {
+				return errors.New("ClientWithRequiredProtocolVersion requires a Client created with NewClientForRunner")
+			}
+			spec, err := getPluginrpcSpec(ctx, runner)
+			if err != nil {
+				return err
+			}
+			for _, procedurePath := range checkServiceProcedurePaths {
+				if spec.ProcedureForPath(procedurePath) == nil {
+					return fmt.Errorf(
+						"plugin does not support bufplugin protocol version %s: plugin does not serve %s",
+						requiredProtocolVersion,
+						procedurePath,
+					)
+				}
+			}
+			return nil
+		},
+	)
+}
+
+// getPluginrpcSpec returns the pluginrpc Spec that the plugin run by the pluginrpc.Runner
+// serves.
+//
+// The pluginrpc.Client reads the Spec in the same way, but does not expose it.
+func getPluginrpcSpec(ctx context.Context, runner pluginrpc.Runner) (pluginrpc.Spec, error) {
+	stdout := bytes.NewBuffer(nil)
+	if err := runner.Run(
+		ctx,
+		pluginrpc.Env{
+			Args:   []string{"--spec", "--format", pluginrpc.FormatBinary.String()},
+			Stdout: stdout,
+		},
+	); err != nil {
+		return nil, err
+	}
+	protoSpec := &pluginrpcv1beta1.Spec{}
+	if err := proto.Unmarshal(stdout.Bytes(), protoSpec); err != nil {
+		return nil, fmt.Errorf("plugin did not return a valid pluginrpc spec: %w", err)
+	}
+	return pluginrpc.NewSpecForProto(protoSpec)
+}
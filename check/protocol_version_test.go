@@ -0,0 +1,67 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/pluginrpc-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientWithRequiredProtocolVersion(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := NewClientForSpec(testVerifierSpec(), ClientWithRequiredProtocolVersion(ProtocolVersion))
+	require.NoError(t, err)
+	_, err = client.ListRules(ctx)
+	require.NoError(t, err)
+
+	client, err = NewClientForSpec(testVerifierSpec(), ClientWithRequiredProtocolVersion("v2"))
+	require.NoError(t, err)
+	_, err = client.ListRules(ctx)
+	require.ErrorContains(t, err, "bufplugin protocol version v2 is required")
+
+	// A plugin that does not serve the CheckService at all.
+	procedure, err := pluginrpc.NewProcedure("/buf.plugin.check.v2.CheckService/Check")
+	require.NoError(t, err)
+	spec, err := pluginrpc.NewSpec([]pluginrpc.Procedure{procedure})
+	require.NoError(t, err)
+	serverRegistrar := pluginrpc.NewServerRegistrar()
+	serverRegistrar.Register(
+		procedure.Path(),
+		func(context.Context, pluginrpc.HandleEnv, ...pluginrpc.HandleOption) error {
+			return nil
+		},
+	)
+	server, err := pluginrpc.NewServer(spec, serverRegistrar)
+	require.NoError(t, err)
+	client = NewClientForRunner(
+		pluginrpc.NewServerRunner(server),
+		ClientWithRequiredProtocolVersion(ProtocolVersion),
+	)
+	_, err = client.ListRules(ctx)
+	require.ErrorContains(t, err, "plugin does not support bufplugin protocol version v1beta1")
+
+	// The pluginrpc Spec cannot be read without the pluginrpc.Runner.
+	client = NewClient(
+		pluginrpc.NewClient(pluginrpc.NewServerRunner(server)),
+		ClientWithRequiredProtocolVersion(ProtocolVersion),
+	)
+	_, err = client.ListRules(ctx)
+	require.ErrorContains(t, err, "requires a Client created with NewClientForRunner")
+}
@@ -0,0 +1,145 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Register registers the RuleSpecs with the global registry, for use with MainRegistered.
+//
+// This allows plugins composed of many packages to register their Rules within init
+// functions, instead of maintaining a central list of RuleSpecs:
+//
+//	func init() {
+//		check.Register(
+//			&check.RuleSpec{
+//				ID:        "TIMESTAMP_SUFFIX",
+//				IsDefault: true,
+//				Purpose:   "Checks that all google.protobuf.Timestamps end in _time.",
+//				Type:      check.RuleTypeLint,
+//				Handler:   check.RuleHandlerFunc(handleTimestampSuffix),
+//			},
+//		)
+//	}
+//
+// Registering a nil RuleSpec, or a RuleSpec with an ID that was already registered as a Rule or
+// Category, results in an error from RegisteredSpec and MainRegistered.
+func Register(ruleSpecs ...*RuleSpec) {
+	globalRegistry.registerRules(ruleSpecs...)
+}
+
+// RegisterCategory registers the CategorySpecs with the global registry, for use with
+// MainRegistered.
+//
+// Registering a nil CategorySpec, or a CategorySpec with an ID that was already registered as a
+// Rule or Category, results in an error from RegisteredSpec and MainRegistered.
+func RegisterCategory(categorySpecs ...*CategorySpec) {
+	globalRegistry.registerCategories(categorySpecs...)
+}
+
+// RegisteredSpec returns a new Spec containing all RuleSpecs and CategorySpecs registered with
+// Register and RegisterCategory, in the order they were registered.
+//
+// Returns error if a nil spec or duplicate ID was registered. As with any other Spec, the
+// returned Spec is further validated by Main.
+func RegisteredSpec() (*Spec, error) {
+	return globalRegistry.spec()
+}
+
+// MainRegistered is Main for the Spec returned by RegisteredSpec.
+//
+// If the registered RuleSpecs or CategorySpecs are invalid, the plugin exits with an error.
+func MainRegistered(options ...MainOption) {
+	mainForSpecFunc(RegisteredSpec, options...)
+}
+
+// *** PRIVATE ***
+
+var globalRegistry = newRegistry()
+
+type registry struct {
+	ruleSpecs     []*RuleSpec
+	categorySpecs []*CategorySpec
+	ids           map[string]struct{}
+	errs          []error
+	lock          sync.Mutex
+}
+
+func newRegistry() *registry {
+	return &registry{
+		ids: make(map[string]struct{}),
+	}
+}
+
+func (r *registry) registerRules(ruleSpecs ...*RuleSpec) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, ruleSpec := range ruleSpecs {
+		if ruleSpec == nil {
+			r.errs = append(r.errs, errors.New("cannot register nil RuleSpec"))
+			continue
+		}
+		if !r.addID(ruleSpec.ID) {
+			continue
+		}
+		r.ruleSpecs = append(r.ruleSpecs, ruleSpec)
+	}
+}
+
+func (r *registry) registerCategories(categorySpecs ...*CategorySpec) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, categorySpec := range categorySpecs {
+		if categorySpec == nil {
+			r.errs = append(r.errs, errors.New("cannot register nil CategorySpec"))
+			continue
+		}
+		if !r.addID(categorySpec.ID) {
+			continue
+		}
+		r.categorySpecs = append(r.categorySpecs, categorySpec)
+	}
+}
+
+// addID adds the ID, and returns false and records an error if the ID was already registered.
+//
+// Must be called with the lock held.
+func (r *registry) addID(id string) bool {
+	if _, ok := r.ids[id]; ok {
+		r.errs = append(r.errs, fmt.Errorf("ID %q registered more than once", id))
+		return false
+	}
+	r.ids[id] = struct{}{}
+	return true
+}
+
+func (r *registry) spec() (*Spec, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.errs) > 0 {
+		return nil, errors.Join(r.errs...)
+	}
+	return &Spec{
+		Rules:      slices.Clone(r.ruleSpecs),
+		Categories: slices.Clone(r.categorySpecs),
+	}, nil
+}
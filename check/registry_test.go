@@ -0,0 +1,70 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	registry := newRegistry()
+	registry.registerRules(
+		&RuleSpec{
+			ID:          "RULE2",
+			CategoryIDs: []string{"CATEGORY1"},
+			IsDefault:   true,
+			Purpose:     "Test rule2.",
+			Type:        RuleTypeLint,
+			Handler:     nopRuleHandler,
+		},
+	)
+	registry.registerCategories(
+		&CategorySpec{
+			ID:      "CATEGORY1",
+			Purpose: "Test category1.",
+		},
+	)
+	registry.registerRules(
+		&RuleSpec{
+			ID:        "RULE1",
+			IsDefault: true,
+			Purpose:   "Test rule1.",
+			Type:      RuleTypeLint,
+			Handler:   nopRuleHandler,
+		},
+	)
+	spec, err := registry.spec()
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE2", "RULE1"}, xslices.Map(spec.Rules, func(ruleSpec *RuleSpec) string { return ruleSpec.ID }))
+	client, err := NewClientForSpec(spec)
+	require.NoError(t, err)
+	rules, err := client.ListRules(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"RULE1", "RULE2"}, xslices.Map(rules, Rule.ID))
+
+	registry.registerRules(&RuleSpec{ID: "RULE1"})
+	registry.registerCategories(&CategorySpec{ID: "RULE2"}, nil)
+	_, err = registry.spec()
+	require.ErrorContains(t, err, `ID "RULE1" registered more than once`)
+	require.ErrorContains(t, err, `ID "RULE2" registered more than once`)
+	require.ErrorContains(t, err, "cannot register nil CategorySpec")
+}
@@ -15,6 +15,8 @@
 package check
 
 import (
+	"iter"
+	"maps"
 	"slices"
 	"sort"
 
@@ -30,11 +32,27 @@ type Request interface {
 	//
 	// Will never be nil or empty.
 	Files() []File
+	// UnclonedFiles returns the same Files as Files, without copying the slice.
+	//
+	// Files copies on every call so that callers cannot modify the Request. RuleHandlers that
+	// access the Files per descriptor should use UnclonedFiles to avoid an allocation per call.
+	// The returned slice must not be modified.
+	UnclonedFiles() []File
+	// AllFiles returns an iterator over the Files.
+	//
+	// This does not copy the Files, and allows handlers to range over the Files with early break.
+	AllFiles() iter.Seq[File]
 	// AgainstFiles contains the files to check against, in the case of breaking change plugins.
 	//
 	// May be empty, including in the case where we did actually specify against files.
 	// See the comment on the API for more details TODO this needs to be resolved.
 	AgainstFiles() []File
+	// UnclonedAgainstFiles returns the same Files as AgainstFiles, without copying the slice.
+	//
+	// The returned slice must not be modified.
+	UnclonedAgainstFiles() []File
+	// AllAgainstFiles returns an iterator over the AgainstFiles.
+	AllAgainstFiles() iter.Seq[File]
 	// Options contains any options passed to the plugin.
 	//
 	// Will never be nil, but may have no values.
@@ -51,6 +69,11 @@ type Request interface {
 	// been performed prior to the Request reaching the RuleHandler.
 	RuleIDs() []string
 
+	// original returns the Request that this Request was copied from for a RuleHandler or
+	// Finalize, or the Request itself if it is not a copy.
+	//
+	// See ServerWithRequestCopies.
+	original() Request
 	// toProtos converts the Request into one or more CheckRequests.
 	//
 	// If there are more than 250 Rule IDs, multiple CheckRequests will be produced by chunking up
@@ -92,12 +115,54 @@ func WithOptions(options Options) RequestOption {
 //
 // Multiple calls to WithRuleIDs will result in the new rule IDs being appended.
 // If duplicate rule IDs are specified, this will result in an error.
+//
+// In addition to Rule IDs, selectors can be given, which are resolved by Client.Check against
+// the result of ListRules before the plugin is invoked:
+//
+//   - A glob pattern, as with path.Match, such as "FIELD_*", selects all Rules with matching
+//     IDs.
+//   - RuleIDCategoryPrefix followed by a Category ID or glob pattern, such as "category:STYLE",
+//     selects all Rules in matching Categories, including Rules that are not default Rules.
+//
+// Check returns an error if a selector does not select any Rules. Selectors are a client-side
+// feature, and are not understood by plugins directly.
 func WithRuleIDs(ruleIDs ...string) RequestOption {
 	return func(requestOptions *requestOptions) {
 		requestOptions.ruleIDs = append(requestOptions.ruleIDs, ruleIDs...)
 	}
 }
 
+// WithSources adds the source of each of the given Files of the Request, by file name.
+//
+// Sources allow Locations within the Files to provide positions in terms of the source, such
+// as Location.StartOffset, so that hosts such as editor integrations do not need to read and
+// index the .proto files themselves. The file names must be the names of Files of the Request.
+// Multiple calls to WithSources are merged.
+//
+// Sources are not part of the plugin protocol, and are not sent to plugins. Locations on
+// Annotations returned from Client.Check are resolved against the Files of the Request, and
+// so have access to the sources.
+func WithSources(fileNameToSource map[string][]byte) RequestOption {
+	return func(requestOptions *requestOptions) {
+		if requestOptions.fileNameToSource == nil {
+			requestOptions.fileNameToSource = make(map[string][]byte)
+		}
+		maps.Copy(requestOptions.fileNameToSource, fileNameToSource)
+	}
+}
+
+// WithAgainstSources adds the source of each of the given AgainstFiles of the Request, by file name.
+//
+// See WithSources.
+func WithAgainstSources(againstFileNameToSource map[string][]byte) RequestOption {
+	return func(requestOptions *requestOptions) {
+		if requestOptions.againstFileNameToSource == nil {
+			requestOptions.againstFileNameToSource = make(map[string][]byte)
+		}
+		maps.Copy(requestOptions.againstFileNameToSource, againstFileNameToSource)
+	}
+}
+
 // RequestForProtoRequest returns a new Request for the given checkv1beta1.Request.
 func RequestForProtoRequest(protoRequest *checkv1beta1.CheckRequest) (Request, error) {
 	files, err := FilesForProtoFiles(protoRequest.GetFiles())
@@ -127,6 +192,8 @@ type request struct {
 	againstFiles []File
 	options      Options
 	ruleIDs      []string
+	// copiedFrom is the Request this Request was copied from, if any.
+	copiedFrom Request
 }
 
 func newRequest(
@@ -144,10 +211,18 @@ func newRequest(
 		return nil, err
 	}
 	sort.Strings(requestOptions.ruleIDs)
+	files, err := filesWithSources(files, requestOptions.fileNameToSource)
+	if err != nil {
+		return nil, err
+	}
+	againstFiles, err := filesWithSources(requestOptions.againstFiles, requestOptions.againstFileNameToSource)
+	if err != nil {
+		return nil, err
+	}
 	// TODO: need to validate Files and AgainstFiles per protovalidate specs
 	return &request{
 		files:        files,
-		againstFiles: requestOptions.againstFiles,
+		againstFiles: againstFiles,
 		options:      requestOptions.options,
 		ruleIDs:      requestOptions.ruleIDs,
 	}, nil
@@ -161,6 +236,22 @@ func (r *request) AgainstFiles() []File {
 	return slices.Clone(r.againstFiles)
 }
 
+func (r *request) UnclonedFiles() []File {
+	return r.files
+}
+
+func (r *request) UnclonedAgainstFiles() []File {
+	return r.againstFiles
+}
+
+func (r *request) AllFiles() iter.Seq[File] {
+	return slices.Values(r.files)
+}
+
+func (r *request) AllAgainstFiles() iter.Seq[File] {
+	return slices.Values(r.againstFiles)
+}
+
 func (r *request) Options() Options {
 	return r.options
 }
@@ -208,12 +299,21 @@ func (r *request) toProtos() ([]*checkv1beta1.CheckRequest, error) {
 	return checkRequests, nil
 }
 
+func (r *request) original() Request {
+	if r.copiedFrom != nil {
+		return r.copiedFrom
+	}
+	return r
+}
+
 func (*request) isRequest() {}
 
 type requestOptions struct {
-	againstFiles []File
-	options      Options
-	ruleIDs      []string
+	againstFiles            []File
+	options                 Options
+	ruleIDs                 []string
+	fileNameToSource        map[string][]byte
+	againstFileNameToSource map[string][]byte
 }
 
 func newRequestOptions() *requestOptions {
@@ -0,0 +1,91 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// *** PRIVATE ***
+
+// copyRequest returns a copy of the Request with deep copies of the FileDescriptorProtos of
+// the Files and AgainstFiles.
+//
+// The protoreflect FileDescriptors are immutable, and are shared with the original Request.
+// The copy shares the values of LoadOrComputeForRequest with the original Request.
+func copyRequest(request Request) (Request, error) {
+	copiedRequest, err := newRequest(
+		copyFiles(request.UnclonedFiles()),
+		WithAgainstFiles(copyFiles(request.UnclonedAgainstFiles())),
+		WithOptions(request.Options()),
+		WithRuleIDs(request.RuleIDs()...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	copiedRequest.copiedFrom = request.original()
+	return copiedRequest, nil
+}
+
+func copyFiles(files []File) []File {
+	if files == nil {
+		return nil
+	}
+	copiedFiles := make([]File, len(files))
+	for i, file := range files {
+		copiedFile := newFile(
+			file.FileDescriptor(),
+			proto.Clone(file.FileDescriptorProto()).(*descriptorpb.FileDescriptorProto),
+			file.IsImport(),
+			file.IsSyntaxUnspecified(),
+			file.UnusedDependencyIndexes(),
+		)
+		copiedFile.source = file.fileSource()
+		copiedFiles[i] = copiedFile
+	}
+	return copiedFiles
+}
+
+// validateRequestNotMutated returns an error if the FileDescriptorProtos of the copied
+// Request differ from those of the original Request.
+//
+// The name is the name of what was given the copied Request, such as "RuleHandler for
+// \"RULE_ID\"".
+func validateRequestNotMutated(name string, original Request, copied Request) error {
+	if err := validateFilesNotMutated(name, original.UnclonedFiles(), copied.UnclonedFiles()); err != nil {
+		return err
+	}
+	return validateFilesNotMutated(name, original.UnclonedAgainstFiles(), copied.UnclonedAgainstFiles())
+}
+
+func validateFilesNotMutated(name string, originalFiles []File, copiedFiles []File) error {
+	if len(originalFiles) != len(copiedFiles) {
+		// This should never happen, the slices are not exposed to the RuleHandler.
+		return fmt.Errorf("expected %d files, got %d", len(originalFiles), len(copiedFiles))
+	}
+	for i, originalFile := range originalFiles {
+		if !proto.Equal(originalFile.FileDescriptorProto(), copiedFiles[i].FileDescriptorProto()) {
+			return fmt.Errorf(
+				"%s modified the FileDescriptorProto of %q, Requests must not be modified",
+				name,
+				originalFile.FileDescriptorProto().GetName(),
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestServerWithRequestCopies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	request := testNewRequestIsolationRequest(t)
+
+	// Without copies, the modification by RULE1 is visible to RULE2, as Rules are run in
+	// order with a parallelism of 1.
+	client, err := NewClientForSpec(
+		testNewRequestIsolationSpec(),
+		ClientWithSpecServerOptions(ServerWithParallelism(1)),
+	)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, response.Annotations(), 1)
+	require.Equal(t, "modified", response.Annotations()[0].Message())
+
+	request = testNewRequestIsolationRequest(t)
+	client, err = NewClientForSpec(
+		testNewRequestIsolationSpec(),
+		ClientWithSpecServerOptions(ServerWithParallelism(1), ServerWithRequestCopies()),
+	)
+	require.NoError(t, err)
+	response, err = client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, response.Annotations(), 1)
+	require.Equal(t, "foo", response.Annotations()[0].Message())
+}
+
+func TestServerWithRequestMutationDetection(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := NewClientForSpec(
+		testNewRequestIsolationSpec(),
+		ClientWithSpecServerOptions(ServerWithRequestMutationDetection()),
+	)
+	require.NoError(t, err)
+	_, err = client.Check(ctx, testNewRequestIsolationRequest(t))
+	require.Error(t, err)
+	require.ErrorContains(t, err, `RuleHandler for "RULE1" modified the FileDescriptorProto of "a.proto"`)
+
+	// Rules that only read the Request do not result in an error.
+	request, err := NewRequest(testNewRequestIsolationRequest(t).Files(), WithRuleIDs("RULE2"))
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, response.Annotations(), 1)
+}
+
+func testNewRequestIsolationSpec() *Spec {
+	return &Spec{
+		Rules: []*RuleSpec{
+			{
+				ID:        "RULE1",
+				IsDefault: true,
+				Purpose:   "Test rule.",
+				Type:      RuleTypeLint,
+				Handler: RuleHandlerFunc(
+					func(_ context.Context, _ ResponseWriter, request Request) error {
+						request.Files()[0].FileDescriptorProto().Package = proto.String("modified")
+						return nil
+					},
+				),
+			},
+			{
+				ID:        "RULE2",
+				IsDefault: true,
+				Purpose:   "Test rule.",
+				Type:      RuleTypeLint,
+				Handler: RuleHandlerFunc(
+					func(_ context.Context, responseWriter ResponseWriter, request Request) error {
+						responseWriter.AddAnnotation(
+							WithMessage(request.Files()[0].FileDescriptorProto().GetPackage()),
+						)
+						return nil
+					},
+				),
+			},
+		},
+	}
+}
+
+func testNewRequestIsolationRequest(t *testing.T) Request {
+	files, err := FilesForProtoFiles(
+		[]*checkv1beta1.File{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:    proto.String("a.proto"),
+					Package: proto.String("foo"),
+					Syntax:  proto.String("proto3"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(files)
+	require.NoError(t, err)
+	return request
+}
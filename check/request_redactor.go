@@ -0,0 +1,155 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"strings"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// RequestRedactor modifies a CheckRequest in place to remove sensitive content.
+//
+// A RequestRedactor is only ever given a copy of a CheckRequest, so it can freely modify
+// the CheckRequest without affecting the caller.
+//
+// RequestRedactors are used with ClientWithRequestRedactors to remove content before it is
+// sent to a plugin, and with ServerWithRequestSnapshotRedactors to remove content before it
+// is written to disk.
+type RequestRedactor func(*checkv1beta1.CheckRequest)
+
+// RedactComments is a RequestRedactor that removes all leading, trailing, and detached
+// comments from the SourceCodeInfo of all Files.
+//
+// Spans are kept, so Annotations still have positions.
+func RedactComments(checkRequest *checkv1beta1.CheckRequest) {
+	rangeRequestFileDescriptorProtos(
+		checkRequest,
+		func(fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+			for _, location := range fileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+				location.LeadingComments = nil
+				location.TrailingComments = nil
+				location.LeadingDetachedComments = nil
+			}
+		},
+	)
+}
+
+// RedactSourceCodeInfo is a RequestRedactor that removes the SourceCodeInfo from all Files.
+//
+// Annotations produced for a redacted CheckRequest will not have positions.
+func RedactSourceCodeInfo(checkRequest *checkv1beta1.CheckRequest) {
+	rangeRequestFileDescriptorProtos(
+		checkRequest,
+		func(fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+			fileDescriptorProto.SourceCodeInfo = nil
+		},
+	)
+}
+
+// RedactCustomOptions is a RequestRedactor that removes all custom option values from all
+// Files.
+//
+// Custom options are extensions of the google.protobuf.*Options messages, and are removed
+// whether or not they are known to the host. Uninterpreted options are also removed. Standard
+// options such as deprecated, java_package, and features are kept.
+func RedactCustomOptions(checkRequest *checkv1beta1.CheckRequest) {
+	rangeRequestFileDescriptorProtos(
+		checkRequest,
+		func(fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+			rangeMessages(
+				fileDescriptorProto.ProtoReflect(),
+				func(message protoreflect.Message) {
+					if !isDescriptorOptionsMessage(message.Descriptor()) {
+						return
+					}
+					message.Range(
+						func(fieldDescriptor protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+							if fieldDescriptor.IsExtension() || fieldDescriptor.Name() == "uninterpreted_option" {
+								message.Clear(fieldDescriptor)
+							}
+							return true
+						},
+					)
+					message.SetUnknown(nil)
+				},
+			)
+		},
+	)
+}
+
+// RedactDefaultValues is a RequestRedactor that removes the default values of all proto2
+// fields from all Files.
+func RedactDefaultValues(checkRequest *checkv1beta1.CheckRequest) {
+	rangeRequestFileDescriptorProtos(
+		checkRequest,
+		func(fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+			rangeMessages(
+				fileDescriptorProto.ProtoReflect(),
+				func(message protoreflect.Message) {
+					if fieldDescriptorProto, ok := message.Interface().(*descriptorpb.FieldDescriptorProto); ok {
+						fieldDescriptorProto.DefaultValue = nil
+					}
+				},
+			)
+		},
+	)
+}
+
+// *** PRIVATE ***
+
+func rangeRequestFileDescriptorProtos(
+	checkRequest *checkv1beta1.CheckRequest,
+	f func(*descriptorpb.FileDescriptorProto),
+) {
+	for _, file := range checkRequest.GetFiles() {
+		if fileDescriptorProto := file.GetFileDescriptorProto(); fileDescriptorProto != nil {
+			f(fileDescriptorProto)
+		}
+	}
+	for _, file := range checkRequest.GetAgainstFiles() {
+		if fileDescriptorProto := file.GetFileDescriptorProto(); fileDescriptorProto != nil {
+			f(fileDescriptorProto)
+		}
+	}
+}
+
+// rangeMessages calls f for the message and all messages set within it, depth-first.
+func rangeMessages(message protoreflect.Message, f func(protoreflect.Message)) {
+	f(message)
+	message.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			if fieldDescriptor.Message() == nil || fieldDescriptor.IsMap() {
+				return true
+			}
+			if fieldDescriptor.IsList() {
+				list := value.List()
+				for i := range list.Len() {
+					rangeMessages(list.Get(i).Message(), f)
+				}
+				return true
+			}
+			rangeMessages(value.Message(), f)
+			return true
+		},
+	)
+}
+
+func isDescriptorOptionsMessage(messageDescriptor protoreflect.MessageDescriptor) bool {
+	return messageDescriptor.ParentFile().Path() == descriptorpb.File_google_protobuf_descriptor_proto.Path() &&
+		strings.HasSuffix(string(messageDescriptor.Name()), "Options")
+}
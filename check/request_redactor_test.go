@@ -0,0 +1,164 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestRedactComments(t *testing.T) {
+	t.Parallel()
+
+	checkRequest := testRedactorCheckRequest()
+	RedactComments(checkRequest)
+	location := checkRequest.GetFiles()[0].GetFileDescriptorProto().GetSourceCodeInfo().GetLocation()[0]
+	require.Empty(t, location.GetLeadingComments())
+	require.Empty(t, location.GetTrailingComments())
+	require.Empty(t, location.GetLeadingDetachedComments())
+	require.Equal(t, []int32{1, 2, 1, 3}, location.GetSpan())
+}
+
+func TestRedactSourceCodeInfo(t *testing.T) {
+	t.Parallel()
+
+	checkRequest := testRedactorCheckRequest()
+	RedactSourceCodeInfo(checkRequest)
+	require.Nil(t, checkRequest.GetFiles()[0].GetFileDescriptorProto().GetSourceCodeInfo())
+}
+
+func TestRedactCustomOptions(t *testing.T) {
+	t.Parallel()
+
+	checkRequest := testRedactorCheckRequest()
+	RedactCustomOptions(checkRequest)
+	messageOptions := checkRequest.GetFiles()[0].GetFileDescriptorProto().GetMessageType()[0].GetOptions()
+	require.Empty(t, messageOptions.ProtoReflect().GetUnknown())
+	require.Empty(t, messageOptions.GetUninterpretedOption())
+	// Standard options are kept.
+	require.True(t, messageOptions.GetDeprecated())
+}
+
+func TestRedactDefaultValues(t *testing.T) {
+	t.Parallel()
+
+	checkRequest := testRedactorCheckRequest()
+	RedactDefaultValues(checkRequest)
+	fieldDescriptorProto := checkRequest.GetFiles()[0].GetFileDescriptorProto().GetMessageType()[0].GetField()[0]
+	require.Nil(t, fieldDescriptorProto.DefaultValue)
+	require.Equal(t, "name", fieldDescriptorProto.GetName())
+}
+
+func TestClientWithRequestRedactors(t *testing.T) {
+	t.Parallel()
+
+	var leadingComments []string
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule1.",
+					Type:      RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, _ ResponseWriter, request Request) error {
+							for _, file := range request.Files() {
+								for _, location := range file.FileDescriptorProto().GetSourceCodeInfo().GetLocation() {
+									leadingComments = append(leadingComments, location.GetLeadingComments())
+								}
+							}
+							return nil
+						},
+					),
+				},
+			},
+		},
+		ClientWithRequestRedactors(RedactComments),
+	)
+	require.NoError(t, err)
+	files, err := FilesForProtoFiles(testRedactorCheckRequest().GetFiles())
+	require.NoError(t, err)
+	request, err := NewRequest(files)
+	require.NoError(t, err)
+	_, err = client.Check(context.Background(), request)
+	require.NoError(t, err)
+	require.Equal(t, []string{""}, leadingComments)
+	// The Request given to the Client is not modified.
+	require.Equal(
+		t,
+		" Secret comment.\n",
+		request.Files()[0].FileDescriptorProto().GetSourceCodeInfo().GetLocation()[0].GetLeadingComments(),
+	)
+}
+
+func testRedactorCheckRequest() *checkv1beta1.CheckRequest {
+	messageOptions := &descriptorpb.MessageOptions{
+		Deprecated: proto.Bool(true),
+		UninterpretedOption: []*descriptorpb.UninterpretedOption{
+			{
+				IdentifierValue: proto.String("secret"),
+			},
+		},
+	}
+	// A custom option with field number 50000 that is not known to the host.
+	messageOptions.ProtoReflect().SetUnknown(
+		protowire.AppendString(protowire.AppendTag(nil, 50000, protowire.BytesType), "secret"),
+	)
+	return &checkv1beta1.CheckRequest{
+		Files: []*checkv1beta1.File{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name:    proto.String("foo.proto"),
+					Syntax:  proto.String("proto2"),
+					Package: proto.String("foo"),
+					MessageType: []*descriptorpb.DescriptorProto{
+						{
+							Name: proto.String("Foo"),
+							Field: []*descriptorpb.FieldDescriptorProto{
+								{
+									Name:         proto.String("name"),
+									Number:       proto.Int32(1),
+									Label:        descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+									Type:         descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+									JsonName:     proto.String("name"),
+									DefaultValue: proto.String("secret"),
+								},
+							},
+							Options: messageOptions,
+						},
+					},
+					SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+						Location: []*descriptorpb.SourceCodeInfo_Location{
+							{
+								Path:                    []int32{4, 0},
+								Span:                    []int32{1, 2, 1, 3},
+								LeadingComments:         proto.String(" Secret comment.\n"),
+								TrailingComments:        proto.String(" Secret trailing comment.\n"),
+								LeadingDetachedComments: []string{" Secret detached comment.\n"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
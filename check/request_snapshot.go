@@ -0,0 +1,103 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/bufbuild/pluginrpc-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// *** PRIVATE ***
+
+const requestSnapshotFilePattern = "bufplugin-check-request-*.binpb"
+
+// requestSnapshotter writes snapshots of CheckRequests that resulted in an error.
+type requestSnapshotter struct {
+	dirPath   string
+	redactors []RequestRedactor
+}
+
+func newRequestSnapshotter(dirPath string, redactors []RequestRedactor) *requestSnapshotter {
+	if dirPath == "" {
+		dirPath = os.TempDir()
+	}
+	return &requestSnapshotter{
+		dirPath:   dirPath,
+		redactors: redactors,
+	}
+}
+
+// wrapError writes a snapshot of the CheckRequest, and returns err with a reference to
+// the snapshot, preserving the pluginrpc.Code of err.
+//
+// Errors due to the context being done are returned as-is, as they are not caused by the
+// CheckRequest. If the snapshot cannot be written, the returned error includes the reason.
+func (r *requestSnapshotter) wrapError(err error, checkRequest *checkv1beta1.CheckRequest) error {
+	code := pluginrpc.WrapError(err).Code()
+	switch code {
+	case pluginrpc.CodeCanceled, pluginrpc.CodeDeadlineExceeded:
+		return err
+	}
+	filePath, snapshotErr := r.writeSnapshot(checkRequest)
+	if snapshotErr != nil {
+		return pluginrpc.NewErrorf(code, "%v (failed to write request snapshot: %v)", err, snapshotErr)
+	}
+	return pluginrpc.NewErrorf(code, "%v (request snapshot written to %s, reproduce with --once)", err, filePath)
+}
+
+func (r *requestSnapshotter) writeSnapshot(checkRequest *checkv1beta1.CheckRequest) (string, error) {
+	if len(r.redactors) > 0 {
+		checkRequest, _ = proto.Clone(checkRequest).(*checkv1beta1.CheckRequest)
+		for _, redactor := range r.redactors {
+			redactor(checkRequest)
+		}
+	}
+	data, err := proto.Marshal(checkRequest)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp(r.dirPath, requestSnapshotFilePattern)
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// callRecover calls f, converting any panic into an error if shouldRecover is true.
+//
+// Panics must be recovered on the goroutine they occur on, so callRecover is used
+// within each job given to thread.Parallelize.
+func callRecover(shouldRecover bool, description string, f func() error) (retErr error) {
+	if shouldRecover {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				retErr = fmt.Errorf("%s panicked: %v\n%s", description, recovered, debug.Stack())
+			}
+		}()
+	}
+	return f()
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+var testRequestSnapshotFilePathRegexp = regexp.MustCompile(`request snapshot written to (\S+),`)
+
+func TestRequestSnapshotOnError(t *testing.T) {
+	t.Parallel()
+
+	checkRequest := testRequestSnapshot(
+		t,
+		RuleHandlerFunc(
+			func(context.Context, ResponseWriter, Request) error {
+				return errors.New("failure")
+			},
+		),
+		"failure",
+	)
+	// Without a redactor, the CheckRequest is written as-is.
+	require.Len(t, checkRequest.GetOptions(), 1)
+}
+
+func TestRequestSnapshotOnPanic(t *testing.T) {
+	t.Parallel()
+
+	checkRequest := testRequestSnapshot(
+		t,
+		RuleHandlerFunc(
+			func(context.Context, ResponseWriter, Request) error {
+				var m map[string]string
+				m["key"] = "value"
+				return nil
+			},
+		),
+		`RuleHandler for "RULE1" panicked`,
+		ServerWithRequestSnapshotRedactors(
+			func(checkRequest *checkv1beta1.CheckRequest) {
+				checkRequest.Options = nil
+			},
+		),
+	)
+	require.Empty(t, checkRequest.GetOptions())
+}
+
+func TestRequestSnapshotNoError(t *testing.T) {
+	t.Parallel()
+
+	dirPath := t.TempDir()
+	client, err := NewClientForSpec(
+		testRequestSnapshotSpec(nopRuleHandler),
+		ClientWithSpecServerOptions(ServerWithRequestSnapshots(dirPath)),
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	_, err = client.Check(context.Background(), request)
+	require.NoError(t, err)
+	entries, err := os.ReadDir(dirPath)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+// testRequestSnapshot runs a Check with the RuleHandler and a single option, verifies that the
+// error contains expectedErrorSubstring and references a snapshot, and returns the snapshot.
+func testRequestSnapshot(
+	t *testing.T,
+	ruleHandler RuleHandler,
+	expectedErrorSubstring string,
+	serverOptions ...ServerOption,
+) *checkv1beta1.CheckRequest {
+	dirPath := t.TempDir()
+	client, err := NewClientForSpec(
+		testRequestSnapshotSpec(ruleHandler),
+		ClientWithSpecServerOptions(
+			append([]ServerOption{ServerWithRequestSnapshots(dirPath)}, serverOptions...)...,
+		),
+	)
+	require.NoError(t, err)
+	options, err := NewOptions(map[string]any{"secret": "value"})
+	require.NoError(t, err)
+	request, err := NewRequest(nil, WithOptions(options))
+	require.NoError(t, err)
+	_, err = client.Check(context.Background(), request)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), expectedErrorSubstring)
+	matches := testRequestSnapshotFilePathRegexp.FindStringSubmatch(err.Error())
+	require.Len(t, matches, 2)
+	require.Equal(t, dirPath, filepath.Dir(matches[1]))
+	data, err := os.ReadFile(matches[1])
+	require.NoError(t, err)
+	checkRequest := &checkv1beta1.CheckRequest{}
+	require.NoError(t, proto.Unmarshal(data, checkRequest))
+	return checkRequest
+}
+
+func testRequestSnapshotSpec(ruleHandler RuleHandler) *Spec {
+	return &Spec{
+		Rules: []*RuleSpec{
+			{
+				ID:        "RULE1",
+				IsDefault: true,
+				Purpose:   "Test rule1.",
+				Type:      RuleTypeLint,
+				Handler:   ruleHandler,
+			},
+		},
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// RequestStore is a concurrency-safe key/value store that is scoped to a single Check call.
+//
+// A new RequestStore is created for every Check call before Spec.Before is invoked, and is
+// discarded once the Check call completes. It is available to Before and to all RuleHandlers
+// via RequestStoreFromContext. This allows RuleHandlers, which may run in parallel, to share
+// expensive computed values such as a symbol table within a single Check call.
+//
+// As with context.WithValue, keys should be of an unexported type to avoid collisions
+// between packages.
+type RequestStore interface {
+	// Load returns the value stored for the key.
+	//
+	// If the value is being computed by LoadOrCompute, this blocks until the computation
+	// completes. Returns false if there is no value, or if the computation failed.
+	Load(key any) (any, bool)
+	// Store sets the value for the key.
+	Store(key any, value any)
+	// LoadOrStore returns the existing value for the key if present. Otherwise, it stores
+	// and returns the given value. The loaded result is true if the value was loaded.
+	LoadOrStore(key any, value any) (any, bool)
+
+	isRequestStore()
+}
+
+// RequestStoreFromContext returns the RequestStore for the current Check call.
+//
+// Returns false if the context is not within a Check call.
+func RequestStoreFromContext(ctx context.Context) (RequestStore, bool) {
+	requestStore, ok := ctx.Value(requestStoreContextKey{}).(RequestStore)
+	return requestStore, ok
+}
+
+// LoadOrCompute returns the value for the key within the RequestStore of the context,
+// calling f to compute and store the value if it is not present.
+//
+// f is called at most once per key per Check call, even if LoadOrCompute is called
+// concurrently by multiple RuleHandlers. Concurrent callers block until f returns. If f
+// returns an error, the error is stored and returned to all callers for the key.
+//
+// If the context is not within a Check call, f is called and its result is not stored.
+//
+// Returns error if the stored value for the key is not of type T.
+func LoadOrCompute[T any](ctx context.Context, key any, f func() (T, error)) (T, error) {
+	store, ok := ctx.Value(requestStoreContextKey{}).(*requestStore)
+	if !ok {
+		return f()
+	}
+	value, err := store.loadOrCompute(
+		key,
+		func() (any, error) {
+			return f()
+		},
+	)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	typedValue, ok := value.(T)
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("RequestStore value for key %v is of type %T, expected %T", key, value, zero)
+	}
+	return typedValue, nil
+}
+
+// LoadOrComputeForRequest is LoadOrCompute for a value that is derived from the Request,
+// such as an index of its Files.
+//
+// The value is stored for the combination of the Request and the key. Spec.Before may replace
+// the Request, so values derived from the Request before and after Before are stored separately.
+// The copies of the Request that RuleHandlers and Finalize are given if ServerWithRequestCopies
+// is used share their values with the Request they were copied from, so the value is still
+// computed at most once per Check call.
+func LoadOrComputeForRequest[T any](ctx context.Context, request Request, key any, f func() (T, error)) (T, error) {
+	return LoadOrCompute(
+		ctx,
+		requestScopedKey{
+			request: request.original(),
+			key:     key,
+		},
+		f,
+	)
+}
+
+// *** PRIVATE ***
+
+type requestStoreContextKey struct{}
+
+// requestScopedKey is the key for values stored with LoadOrComputeForRequest.
+type requestScopedKey struct {
+	request Request
+	key     any
+}
+
+type requestStore struct {
+	// entries is a map from key to *requestStoreEntry.
+	entries sync.Map
+}
+
+func newRequestStore() *requestStore {
+	return &requestStore{}
+}
+
+func (r *requestStore) Load(key any) (any, bool) {
+	entry, ok := r.entries.Load(key)
+	if !ok {
+		return nil, false
+	}
+	value, err := entry.(*requestStoreEntry).wait()
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+func (r *requestStore) Store(key any, value any) {
+	r.entries.Store(key, newStoredRequestStoreEntry(value))
+}
+
+func (r *requestStore) LoadOrStore(key any, value any) (any, bool) {
+	for {
+		entry, loaded := r.entries.LoadOrStore(key, newStoredRequestStoreEntry(value))
+		if !loaded {
+			return value, false
+		}
+		existingValue, err := entry.(*requestStoreEntry).wait()
+		if err == nil {
+			return existingValue, true
+		}
+		// The existing entry failed to compute, replace it.
+		if r.entries.CompareAndSwap(key, entry, newStoredRequestStoreEntry(value)) {
+			return value, false
+		}
+	}
+}
+
+func (r *requestStore) loadOrCompute(key any, f func() (any, error)) (any, error) {
+	newEntry := newRequestStoreEntry()
+	entry, loaded := r.entries.LoadOrStore(key, newEntry)
+	if !loaded {
+		newEntry.compute(f)
+	}
+	return entry.(*requestStoreEntry).wait()
+}
+
+func (*requestStore) isRequestStore() {}
+
+type requestStoreEntry struct {
+	// done is closed once value and err are set.
+	done  chan struct{}
+	value any
+	err   error
+}
+
+func newRequestStoreEntry() *requestStoreEntry {
+	return &requestStoreEntry{
+		done: make(chan struct{}),
+	}
+}
+
+func newStoredRequestStoreEntry(value any) *requestStoreEntry {
+	entry := newRequestStoreEntry()
+	entry.value = value
+	close(entry.done)
+	return entry
+}
+
+func (e *requestStoreEntry) compute(f func() (any, error)) {
+	defer close(e.done)
+	// If f panics, waiters will receive this error instead of blocking forever.
+	e.err = errors.New("RequestStore value computation panicked")
+	e.value, e.err = f()
+}
+
+func (e *requestStoreEntry) wait() (any, error) {
+	<-e.done
+	return e.value, e.err
+}
+
+func withRequestStore(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestStoreContextKey{}, newRequestStore())
+}
@@ -0,0 +1,217 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRequestStoreKey struct{}
+
+func TestRequestStoreSharedAcrossRules(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var computeCount atomic.Int64
+	ruleHandler := RuleHandlerFunc(
+		func(ctx context.Context, responseWriter ResponseWriter, _ Request) error {
+			value, err := LoadOrCompute(
+				ctx,
+				testRequestStoreKey{},
+				func() (int64, error) {
+					return computeCount.Add(1), nil
+				},
+			)
+			if err != nil {
+				return err
+			}
+			requestStore, ok := RequestStoreFromContext(ctx)
+			if !ok {
+				return errors.New("no RequestStore")
+			}
+			fromBefore, ok := requestStore.Load("before")
+			if !ok {
+				return errors.New("no value from Before")
+			}
+			responseWriter.AddAnnotation(WithMessagef("%d %v", value, fromBefore))
+			return nil
+		},
+	)
+	ruleSpecs := make([]*RuleSpec, 10)
+	for i := range ruleSpecs {
+		ruleSpecs[i] = &RuleSpec{
+			ID:        fmt.Sprintf("RULE%d", i),
+			IsDefault: true,
+			Purpose:   "Test rule.",
+			Type:      RuleTypeLint,
+			Handler:   ruleHandler,
+		}
+	}
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: ruleSpecs,
+			Before: func(ctx context.Context, request Request) (context.Context, Request, error) {
+				requestStore, ok := RequestStoreFromContext(ctx)
+				if !ok {
+					return nil, nil, errors.New("no RequestStore")
+				}
+				// Each Check call has its own RequestStore.
+				if _, loaded := requestStore.LoadOrStore("before", "value"); loaded {
+					return nil, nil, errors.New("RequestStore shared across Check calls")
+				}
+				return ctx, request, nil
+			},
+		},
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	for i := range 2 {
+		response, err := client.Check(ctx, request)
+		require.NoError(t, err)
+		annotations := response.Annotations()
+		require.Len(t, annotations, len(ruleSpecs))
+		for _, annotation := range annotations {
+			require.Equal(t, fmt.Sprintf("%d value", i+1), annotation.Message())
+		}
+	}
+	require.Equal(t, int64(2), computeCount.Load())
+}
+
+func TestLoadOrComputeForRequestWithRequestCopies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var computeCount atomic.Int64
+	loadOrCompute := func(ctx context.Context, request Request) (int64, error) {
+		return LoadOrComputeForRequest(
+			ctx,
+			request,
+			testRequestStoreKey{},
+			func() (int64, error) {
+				return computeCount.Add(1), nil
+			},
+		)
+	}
+	ruleHandler := RuleHandlerFunc(
+		func(ctx context.Context, responseWriter ResponseWriter, request Request) error {
+			value, err := loadOrCompute(ctx, request)
+			if err != nil {
+				return err
+			}
+			responseWriter.AddAnnotation(WithMessagef("%d", value))
+			return nil
+		},
+	)
+	ruleSpecs := make([]*RuleSpec, 10)
+	for i := range ruleSpecs {
+		ruleSpecs[i] = &RuleSpec{
+			ID:        fmt.Sprintf("RULE%d", i),
+			IsDefault: true,
+			Purpose:   "Test rule.",
+			Type:      RuleTypeLint,
+			Handler:   ruleHandler,
+		}
+	}
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: ruleSpecs,
+			Before: func(ctx context.Context, request Request) (context.Context, Request, error) {
+				if _, err := loadOrCompute(ctx, request); err != nil {
+					return nil, nil, err
+				}
+				return ctx, request, nil
+			},
+			Finalize: func(ctx context.Context, _ FinalizeResponseWriter, request Request, _ []Annotation) error {
+				value, err := loadOrCompute(ctx, request)
+				if err != nil {
+					return err
+				}
+				if value != 1 {
+					return fmt.Errorf("expected 1 in Finalize, got %d", value)
+				}
+				return nil
+			},
+		},
+		// Each RuleHandler and Finalize is given its own copy of the Request.
+		ClientWithSpecServerOptions(ServerWithRequestCopies()),
+	)
+	require.NoError(t, err)
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	annotations := response.Annotations()
+	require.Len(t, annotations, len(ruleSpecs))
+	for _, annotation := range annotations {
+		require.Equal(t, "1", annotation.Message())
+	}
+	require.Equal(t, int64(1), computeCount.Load())
+}
+
+func TestLoadOrCompute(t *testing.T) {
+	t.Parallel()
+
+	ctx := withRequestStore(context.Background())
+	computeErr := errors.New("compute error")
+	var computeCount int
+	for range 2 {
+		_, err := LoadOrCompute(
+			ctx,
+			"error",
+			func() (string, error) {
+				computeCount++
+				return "", computeErr
+			},
+		)
+		require.ErrorIs(t, err, computeErr)
+	}
+	// Errors are stored.
+	require.Equal(t, 1, computeCount)
+	requestStore, ok := RequestStoreFromContext(ctx)
+	require.True(t, ok)
+	_, ok = requestStore.Load("error")
+	require.False(t, ok)
+	value, loaded := requestStore.LoadOrStore("error", "replaced")
+	require.False(t, loaded)
+	require.Equal(t, "replaced", value)
+
+	requestStore.Store("int", 1)
+	_, err := LoadOrCompute(ctx, "int", func() (string, error) { return "", nil })
+	require.Error(t, err)
+
+	// Outside of a Check call, the value is computed every time.
+	computeCount = 0
+	for range 2 {
+		_, err := LoadOrCompute(
+			context.Background(),
+			"key",
+			func() (string, error) {
+				computeCount++
+				return "", nil
+			},
+		)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, computeCount)
+	_, ok = RequestStoreFromContext(context.Background())
+	require.False(t, ok)
+}
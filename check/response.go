@@ -16,6 +16,7 @@ package check
 
 import (
 	"slices"
+	"sync"
 
 	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
 	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
@@ -27,16 +28,31 @@ type Response interface {
 	//
 	// The returned annotations will be sorted.
 	Annotations() []Annotation
+	// Stats returns summary statistics for the Annotations.
+	//
+	// The Stats are computed on the first call and then reused. They are not carried
+	// by the wire protocol, and are computed from the Annotations on the client-side.
+	Stats() Stats
 
 	toProto() *checkv1beta1.CheckResponse
 
 	isResponse()
 }
 
+// NewResponse returns a new Response for the given Annotations.
+//
+// This allows hosts that wrap a Client to return Responses with a subset of the Annotations
+// of the underlying Client. The Annotations are sorted, and the given slice is not modified.
+func NewResponse(annotations []Annotation) (Response, error) {
+	return newResponse(slices.Clone(annotations))
+}
+
 // *** PRIVATE ***
 
 type response struct {
 	annotations []Annotation
+
+	getStats func() *stats
 }
 
 func newResponse(annotations []Annotation) (*response, error) {
@@ -44,6 +60,11 @@ func newResponse(annotations []Annotation) (*response, error) {
 	// TODO: validation? Leaving error for now
 	return &response{
 		annotations: annotations,
+		getStats: sync.OnceValue(
+			func() *stats {
+				return newStats(annotations)
+			},
+		),
 	}, nil
 }
 
@@ -51,6 +72,10 @@ func (r *response) Annotations() []Annotation {
 	return slices.Clone(r.annotations)
 }
 
+func (r *response) Stats() Stats {
+	return r.getStats()
+}
+
 func (r *response) toProto() *checkv1beta1.CheckResponse {
 	return &checkv1beta1.CheckResponse{
 		Annotations: xslices.Map(r.annotations, Annotation.toProto),
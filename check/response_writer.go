@@ -17,8 +17,10 @@ package check
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
@@ -50,10 +52,42 @@ type ResponseWriter interface {
 	//
 	// Most users will use WithDescriptor/WithAgainstDescriptor as opposed to their lower-level variants.
 	AddAnnotation(options ...AddAnnotationOption)
+	// SuppressAnnotations suppresses the Annotations of the Rule with the given ID for the
+	// descriptor and any descriptors within it.
+	//
+	// This allows a Rule to exempt descriptors from other Rules, for example based on an
+	// allowlist read from the Options of the Request. Suppressions are resolved once all
+	// RuleHandlers have completed, before Spec.Finalize is called and before the Response is
+	// built, so the order in which Rules are run does not matter. Annotations added by
+	// Spec.Finalize are also suppressed.
+	//
+	// The descriptor must be from the Files of the Request. If the descriptor is a
+	// FileDescriptor, all Annotations of the Rule for the File are suppressed. If the File has
+	// no SourceCodeInfo, the descriptor cannot be resolved to a location within the File, and
+	// only Annotations of the Rule with a whole-file Location for the File are suppressed,
+	// matching how WithDescriptor degrades.
+	//
+	// The rule ID must be the ID of a Rule within the Spec. Errors are verified when building
+	// a Response, in the same way as with AddAnnotation.
+	SuppressAnnotations(ruleID string, descriptor protoreflect.Descriptor)
 
 	isResponseWriter()
 }
 
+// FinalizeResponseWriter is used by Spec.Finalize to add Annotations after all RuleHandlers
+// have completed.
+//
+// Unlike a ResponseWriter, a FinalizeResponseWriter is not tied to a specific rule.
+type FinalizeResponseWriter interface {
+	// AddAnnotation adds an Annotation for the given rule ID.
+	//
+	// The rule ID must be the ID of a Rule that was run as part of the Request.
+	// AddAnnotationOptions are handled the same as with ResponseWriter.AddAnnotation.
+	AddAnnotation(ruleID string, options ...AddAnnotationOption)
+
+	isFinalizeResponseWriter()
+}
+
 // AddAnnotationOption is an option with adding an Annotation to a ResponseWriter.
 type AddAnnotationOption func(*addAnnotationOptions)
 
@@ -79,6 +113,8 @@ func WithMessagef(format string, args ...any) AddAnnotationOption {
 // information from the descriptor itself.
 //
 // It is not valid to use WithDescriptor if also using either WithFileName or WithSourcePath.
+// The descriptor must be from the Files of the Request. Descriptors from the AgainstFiles
+// result in a MixedLocationError.
 func WithDescriptor(descriptor protoreflect.Descriptor) AddAnnotationOption {
 	return func(addAnnotationOptions *addAnnotationOptions) {
 		addAnnotationOptions.descriptor = descriptor
@@ -89,7 +125,8 @@ func WithDescriptor(descriptor protoreflect.Descriptor) AddAnnotationOption {
 // source path information from the descriptor itself.
 //
 // It is not valid to use WithAgainstDescriptor if also using either WithAgainstFileName or
-// WithAgainstSourcePath.
+// WithAgainstSourcePath. The descriptor must be from the AgainstFiles of the Request.
+// Descriptors from the Files result in a MixedLocationError.
 func WithAgainstDescriptor(againstDescriptor protoreflect.Descriptor) AddAnnotationOption {
 	return func(addAnnotationOptions *addAnnotationOptions) {
 		addAnnotationOptions.againstDescriptor = againstDescriptor
@@ -142,6 +179,23 @@ func WithAgainstSourcePath(againstSourcePath protoreflect.SourcePath) AddAnnotat
 	}
 }
 
+// WithDescriptorLocationFallback will make WithDescriptor and WithAgainstDescriptor fall back
+// to the Location of the closest parent declaration if the descriptor itself has no span in the
+// SourceCodeInfo of its File.
+//
+// Some descriptors have no span, for example the synthesized entry messages of map fields. By
+// default, the Location for such a descriptor is a whole-file Location. With this option, the
+// Location is instead that of the closest parent that has a span, such as the message that
+// contains the map field, so that the Annotation has the most specific available position.
+//
+// If no parent has a span, for example because the File has no SourceCodeInfo, the Location is
+// still a whole-file Location.
+func WithDescriptorLocationFallback() AddAnnotationOption {
+	return func(addAnnotationOptions *addAnnotationOptions) {
+		addAnnotationOptions.descriptorLocationFallback = true
+	}
+}
+
 // *** PRIVATE ***
 
 // multiResponseWriter is a ResponseWriter that can be used for multiple IDs. It differs
@@ -152,25 +206,50 @@ func WithAgainstSourcePath(againstSourcePath protoreflect.SourcePath) AddAnnotat
 type multiResponseWriter struct {
 	fileNameToFile        map[string]File
 	againstFileNameToFile map[string]File
+	// ruleIDToRule is used to populate the RuleCategories of Annotations.
+	//
+	// May be nil.
+	ruleIDToRule      map[string]Rule
+	unknownFilePolicy UnknownFilePolicy
+	// annotationSinks observe each Annotation as it is added.
+	//
+	// May be empty.
+	annotationSinks []AnnotationSink
+	// isClient is set if the Annotations are read from CheckResponses by a Client.
+	isClient bool
 
 	annotations []Annotation
-	written     bool
-	errs        []error
-	lock        sync.RWMutex
+	// suppressedAnnotations are the Annotations suppressed with RuleDependencies.Suppress.
+	suppressedAnnotations map[Annotation]struct{}
+	// annotationSuppressions are added with ResponseWriter.SuppressAnnotations.
+	annotationSuppressions []*annotationSuppression
+	// exceptions are parsed from the ExceptionsOptionKey Option.
+	//
+	// May be empty.
+	exceptions          []*exception
+	written             bool
+	addAnnotationErrors []*AddAnnotationError
+	lock                sync.RWMutex
 }
 
-func newMultiResponseWriter(request Request) (*multiResponseWriter, error) {
-	fileNameToFile, err := fileNameToFileForFiles(request.Files())
+func newMultiResponseWriter(
+	request Request,
+	ruleIDToRule map[string]Rule,
+	unknownFilePolicy UnknownFilePolicy,
+) (*multiResponseWriter, error) {
+	fileNameToFile, err := fileNameToFileForFiles(request.UnclonedFiles())
 	if err != nil {
 		return nil, err
 	}
-	againstFileNameToFile, err := fileNameToFileForFiles(request.AgainstFiles())
+	againstFileNameToFile, err := fileNameToFileForFiles(request.UnclonedAgainstFiles())
 	if err != nil {
 		return nil, err
 	}
 	return &multiResponseWriter{
 		fileNameToFile:        fileNameToFile,
 		againstFileNameToFile: againstFileNameToFile,
+		ruleIDToRule:          ruleIDToRule,
+		unknownFilePolicy:     unknownFilePolicy,
 	}, nil
 }
 
@@ -178,10 +257,155 @@ func (m *multiResponseWriter) newResponseWriter(id string) *responseWriter {
 	return newResponseWriter(m, id)
 }
 
+func (m *multiResponseWriter) newFinalizeResponseWriter(ruleIDs []string) *finalizeResponseWriter {
+	return newFinalizeResponseWriter(m, ruleIDs)
+}
+
+// sortedAnnotations returns a sorted copy of the Annotations added so far, excluding
+// suppressed Annotations.
+func (m *multiResponseWriter) sortedAnnotations() []Annotation {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	annotations := m.unsuppressedAnnotations()
+	sortAnnotations(annotations)
+	return annotations
+}
+
+// sortedAnnotationsForRuleIDs returns a sorted copy of the Annotations added so far for the
+// given rule IDs, including suppressed Annotations.
+func (m *multiResponseWriter) sortedAnnotationsForRuleIDs(ruleIDs []string) []Annotation {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	ruleIDMap := xslices.ToStructMap(ruleIDs)
+	annotations := xslices.Filter(
+		m.annotations,
+		func(annotation Annotation) bool {
+			_, ok := ruleIDMap[annotation.RuleID()]
+			return ok
+		},
+	)
+	sortAnnotations(annotations)
+	return annotations
+}
+
+func (m *multiResponseWriter) suppress(annotation Annotation) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.suppressedAnnotations == nil {
+		m.suppressedAnnotations = make(map[Annotation]struct{})
+	}
+	m.suppressedAnnotations[annotation] = struct{}{}
+}
+
+// suppressAnnotations adds an annotationSuppression for the Rule and descriptor.
+//
+// Returns error if the suppression is invalid. The caller records the error with addError.
+func (m *multiResponseWriter) suppressAnnotations(ruleID string, descriptor protoreflect.Descriptor) error {
+	if descriptor == nil {
+		return errors.New("cannot suppress Annotations for a nil descriptor")
+	}
+	if _, ok := m.ruleIDToRule[ruleID]; !ok {
+		return fmt.Errorf("cannot suppress Annotations for unknown rule ID %q", ruleID)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.written {
+		return errCannotReuseResponseWriter
+	}
+	location, err := getLocationForAddAnnotationOptions(
+		m.fileNameToFile,
+		m.againstFileNameToFile,
+		false,
+		descriptor,
+		"",
+		nil,
+		false,
+		m.unknownFilePolicy,
+	)
+	if err != nil {
+		return err
+	}
+	// The descriptor has no File, or the File is unknown and dropped by the
+	// UnknownFilePolicy, so there are no Annotations to suppress.
+	if location == nil {
+		return nil
+	}
+	m.annotationSuppressions = append(m.annotationSuppressions, newAnnotationSuppression(ruleID, descriptor, location))
+	return nil
+}
+
+// unsuppressedAnnotations returns a copy of the Annotations added so far, excluding
+// suppressed Annotations.
+//
+// Must be called with the lock held.
+func (m *multiResponseWriter) unsuppressedAnnotations() []Annotation {
+	return xslices.Filter(
+		m.annotations,
+		func(annotation Annotation) bool {
+			if _, ok := m.suppressedAnnotations[annotation]; ok {
+				return false
+			}
+			if slices.ContainsFunc(
+				m.exceptions,
+				func(exception *exception) bool {
+					return exception.matches(annotation)
+				},
+			) {
+				return false
+			}
+			return !slices.ContainsFunc(
+				m.annotationSuppressions,
+				func(annotationSuppression *annotationSuppression) bool {
+					return annotationSuppression.matches(annotation)
+				},
+			)
+		},
+	)
+}
+
+// addAnnotation adds an Annotation for the Rule.
+//
+// Returns error if the Annotation is invalid. The caller records the error with addError, so
+// that the call site is only determined if there is an error.
 func (m *multiResponseWriter) addAnnotation(
 	ruleID string,
 	options ...AddAnnotationOption,
-) {
+) error {
+	annotation, err := m.addAnnotationOrError(ruleID, options...)
+	if err != nil {
+		return err
+	}
+	// The AnnotationSinks are called without holding the lock, so that they do not serialize
+	// the Rules.
+	for _, annotationSink := range m.annotationSinks {
+		annotationSink.ObserveAnnotation(annotation)
+	}
+	return nil
+}
+
+func (m *multiResponseWriter) addError(ruleID string, callSite string, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.addAnnotationErrors = append(
+		m.addAnnotationErrors,
+		&AddAnnotationError{
+			RuleID:   ruleID,
+			CallSite: callSite,
+			Err:      err,
+		},
+	)
+}
+
+func (m *multiResponseWriter) addAnnotationOrError(
+	ruleID string,
+	options ...AddAnnotationOption,
+) (Annotation, error) {
 	addAnnotationOptions := newAddAnnotationOptions()
 	for _, option := range options {
 		option(addAnnotationOptions)
@@ -191,62 +415,85 @@ func (m *multiResponseWriter) addAnnotation(
 	defer m.lock.Unlock()
 
 	if err := validateAddAnnotationOptions(addAnnotationOptions); err != nil {
-		m.errs = append(m.errs, err)
-		return
+		return nil, err
 	}
 
 	if m.written {
-		m.errs = append(m.errs, errCannotReuseResponseWriter)
-		return
+		return nil, errCannotReuseResponseWriter
 	}
 
 	location, err := getLocationForAddAnnotationOptions(
 		m.fileNameToFile,
+		m.againstFileNameToFile,
+		false,
 		addAnnotationOptions.descriptor,
 		addAnnotationOptions.fileName,
 		addAnnotationOptions.sourcePath,
+		addAnnotationOptions.descriptorLocationFallback,
+		m.unknownFilePolicy,
 	)
 	if err != nil {
-		m.errs = append(m.errs, err)
-		return
+		return nil, err
+	}
+	var locationUnavailableReason LocationUnavailableReason
+	if m.isClient {
+		locationUnavailableReason = getClientLocationUnavailableReason(location)
+	} else {
+		locationUnavailableReason = getLocationUnavailableReason(
+			m.fileNameToFile,
+			location,
+			addAnnotationOptions.descriptor,
+			addAnnotationOptions.fileName,
+			addAnnotationOptions.sourcePath,
+		)
 	}
 	againstLocation, err := getLocationForAddAnnotationOptions(
 		m.againstFileNameToFile,
+		m.fileNameToFile,
+		true,
 		addAnnotationOptions.againstDescriptor,
 		addAnnotationOptions.againstFileName,
 		addAnnotationOptions.againstSourcePath,
+		addAnnotationOptions.descriptorLocationFallback,
+		m.unknownFilePolicy,
 	)
 	if err != nil {
-		m.errs = append(m.errs, err)
-		return
+		return nil, err
+	}
+	var ruleCategories []Category
+	if rule, ok := m.ruleIDToRule[ruleID]; ok {
+		ruleCategories = rule.UnclonedCategories()
 	}
 	annotation, err := newAnnotation(
 		ruleID,
+		ruleCategories,
 		addAnnotationOptions.message,
 		location,
 		againstLocation,
+		locationUnavailableReason,
 	)
 	if err != nil {
-		m.errs = append(m.errs, err)
-		return
+		return nil, err
 	}
 
 	m.annotations = append(m.annotations, annotation)
+	return annotation, nil
 }
 
+// toResponse returns the Response for the added Annotations.
 func (m *multiResponseWriter) toResponse() (Response, error) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	if len(m.errs) > 0 {
-		return nil, errors.Join(m.errs...)
+	if aggregateError := newAggregateError(m.addAnnotationErrors); aggregateError != nil {
+		return nil, aggregateError
 	}
 	if m.written {
 		return nil, errCannotReuseResponseWriter
 	}
 	m.written = true
 
-	return newResponse(m.annotations)
+	return newResponse(m.unsuppressedAnnotations())
 }
 
 type responseWriter struct {
@@ -267,11 +514,52 @@ func newResponseWriter(
 func (r *responseWriter) AddAnnotation(
 	options ...AddAnnotationOption,
 ) {
-	r.multiResponseWriter.addAnnotation(r.id, options...)
+	if err := r.multiResponseWriter.addAnnotation(r.id, options...); err != nil {
+		r.multiResponseWriter.addError(r.id, getCallSite(), err)
+	}
+}
+
+func (r *responseWriter) SuppressAnnotations(
+	ruleID string,
+	descriptor protoreflect.Descriptor,
+) {
+	if err := r.multiResponseWriter.suppressAnnotations(ruleID, descriptor); err != nil {
+		r.multiResponseWriter.addError(r.id, getCallSite(), err)
+	}
 }
 
 func (*responseWriter) isResponseWriter() {}
 
+type finalizeResponseWriter struct {
+	multiResponseWriter *multiResponseWriter
+	ruleIDs             map[string]struct{}
+}
+
+func newFinalizeResponseWriter(
+	multiResponseWriter *multiResponseWriter,
+	ruleIDs []string,
+) *finalizeResponseWriter {
+	return &finalizeResponseWriter{
+		multiResponseWriter: multiResponseWriter,
+		ruleIDs:             xslices.ToStructMap(ruleIDs),
+	}
+}
+
+func (f *finalizeResponseWriter) AddAnnotation(
+	ruleID string,
+	options ...AddAnnotationOption,
+) {
+	if _, ok := f.ruleIDs[ruleID]; !ok {
+		f.multiResponseWriter.addError(ruleID, getCallSite(), fmt.Errorf("cannot add Annotation in Finalize for rule ID %q that was not run", ruleID))
+		return
+	}
+	if err := f.multiResponseWriter.addAnnotation(ruleID, options...); err != nil {
+		f.multiResponseWriter.addError(ruleID, getCallSite(), err)
+	}
+}
+
+func (*finalizeResponseWriter) isFinalizeResponseWriter() {}
+
 type addAnnotationOptions struct {
 	message           string
 	descriptor        protoreflect.Descriptor
@@ -280,6 +568,8 @@ type addAnnotationOptions struct {
 	sourcePath        protoreflect.SourcePath
 	againstFileName   string
 	againstSourcePath protoreflect.SourcePath
+	// descriptorLocationFallback is set by WithDescriptorLocationFallback.
+	descriptorLocationFallback bool
 }
 
 func newAddAnnotationOptions() *addAnnotationOptions {
@@ -306,20 +596,36 @@ func validateAddAnnotationOptions(addAnnotationOptions *addAnnotationOptions) er
 
 func getLocationForAddAnnotationOptions(
 	fileNameToFile map[string]File,
+	otherFileNameToFile map[string]File,
+	isAgainst bool,
 	descriptor protoreflect.Descriptor,
 	fileName string,
 	path protoreflect.SourcePath,
+	descriptorLocationFallback bool,
+	unknownFilePolicy UnknownFilePolicy,
 ) (Location, error) {
 	if descriptor != nil {
 		// Technically, ParentFile() can be nil.
 		if fileDescriptor := descriptor.ParentFile(); fileDescriptor != nil {
 			file, ok := fileNameToFile[fileDescriptor.Path()]
+			// The Files and AgainstFiles commonly contain files with the same name, so we
+			// compare the FileDescriptors to detect descriptors of the other Files.
+			if otherFile, otherOK := otherFileNameToFile[fileDescriptor.Path()]; otherOK &&
+				otherFile.FileDescriptor() == fileDescriptor &&
+				(!ok || file.FileDescriptor() != fileDescriptor) {
+				return nil, newMixedLocationError(fileDescriptor.Path(), isAgainst, true)
+			}
 			if !ok {
-				return nil, fmt.Errorf("cannot add annotation for unknown file: %q", fileDescriptor.Path())
+				return getLocationForUnknownFile(unknownFilePolicy, fileDescriptor.Path(), fileDescriptor, isAgainst)
+			}
+			sourceLocation := fileDescriptor.SourceLocations().ByDescriptor(descriptor)
+			if descriptorLocationFallback {
+				sourceLocation = getParentSourceLocationIfNotFound(fileDescriptor, descriptor, sourceLocation)
 			}
 			return newLocation(
 				file,
-				fileDescriptor.SourceLocations().ByDescriptor(descriptor),
+				sourceLocation.Path,
+				sourceLocation,
 			), nil
 		}
 		return nil, nil
@@ -328,12 +634,41 @@ func getLocationForAddAnnotationOptions(
 		var sourceLocation protoreflect.SourceLocation
 		file, ok := fileNameToFile[fileName]
 		if !ok {
-			return nil, fmt.Errorf("cannot add annotation for unknown file: %q", fileName)
+			if _, otherOK := otherFileNameToFile[fileName]; otherOK {
+				return nil, newMixedLocationError(fileName, isAgainst, false)
+			}
+			return getLocationForUnknownFile(unknownFilePolicy, fileName, nil, isAgainst)
 		}
 		if len(path) > 0 {
 			sourceLocation = file.FileDescriptor().SourceLocations().ByPath(path)
 		}
-		return newLocation(file, sourceLocation), nil
+		return newLocation(file, slices.Clone(path), sourceLocation), nil
 	}
 	return nil, nil
 }
+
+// getParentSourceLocationIfNotFound returns the protoreflect.SourceLocation of the closest parent
+// of the descriptor that has a span, if sourceLocation was not found.
+//
+// If sourceLocation was found, or no parent has a span, sourceLocation is returned.
+func getParentSourceLocationIfNotFound(
+	fileDescriptor protoreflect.FileDescriptor,
+	descriptor protoreflect.Descriptor,
+	sourceLocation protoreflect.SourceLocation,
+) protoreflect.SourceLocation {
+	// A protoreflect.SourceLocation that was not found in the SourceCodeInfo has no path.
+	if len(sourceLocation.Path) > 0 {
+		return sourceLocation
+	}
+	for parent := descriptor.Parent(); parent != nil; parent = parent.Parent() {
+		// The span of the FileDescriptor is the whole file, which is not more specific than a
+		// whole-file Location.
+		if _, ok := parent.(protoreflect.FileDescriptor); ok {
+			break
+		}
+		if parentSourceLocation := fileDescriptor.SourceLocations().ByDescriptor(parent); len(parentSourceLocation.Path) > 0 {
+			return parentSourceLocation
+		}
+	}
+	return sourceLocation
+}
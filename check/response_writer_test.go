@@ -0,0 +1,297 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFinalize(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ruleHandler := RuleHandlerFunc(
+		func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+			responseWriter.AddAnnotation(WithMessage("first pass"))
+			return nil
+		},
+	)
+	spec := &Spec{
+		Rules: []*RuleSpec{
+			{
+				ID:        "RULE1",
+				IsDefault: true,
+				Purpose:   "Test rule.",
+				Type:      RuleTypeLint,
+				Handler:   ruleHandler,
+			},
+			{
+				ID:        "RULE2",
+				IsDefault: true,
+				Purpose:   "Test rule.",
+				Type:      RuleTypeLint,
+				Handler:   ruleHandler,
+			},
+			{
+				ID:      "SUMMARY",
+				Purpose: "Test rule.",
+				Type:    RuleTypeLint,
+				Handler: nopRuleHandler,
+			},
+		},
+		Finalize: func(
+			_ context.Context,
+			responseWriter FinalizeResponseWriter,
+			_ Request,
+			annotations []Annotation,
+		) error {
+			require.Equal(t, []string{"RULE1", "RULE2"}, xslices.Map(annotations, Annotation.RuleID))
+			responseWriter.AddAnnotation("SUMMARY", WithMessagef("%d annotations", len(annotations)))
+			return nil
+		},
+	}
+
+	client, err := NewClientForSpec(spec)
+	require.NoError(t, err)
+	request, err := NewRequest(nil, WithRuleIDs("RULE1", "RULE2", "SUMMARY"))
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	annotations := response.Annotations()
+	require.Equal(t, []string{"RULE1", "RULE2", "SUMMARY"}, xslices.Map(annotations, Annotation.RuleID))
+	require.Equal(t, "2 annotations", annotations[2].Message())
+
+	// SUMMARY is not a default Rule, so it is not run, and Annotations cannot be added for it.
+	request, err = NewRequest(nil)
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.Error(t, err)
+}
+
+func TestAggregateError(t *testing.T) {
+	t.Parallel()
+
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	multiResponseWriter, err := newMultiResponseWriter(request, nil, UnknownFilePolicyError)
+	require.NoError(t, err)
+	multiResponseWriter.newResponseWriter("RULE2").AddAnnotation(WithSourcePath(protoreflect.SourcePath{4, 0}))
+	multiResponseWriter.newResponseWriter("RULE1").AddAnnotation(WithFileName("foo.proto"))
+	multiResponseWriter.newResponseWriter("RULE1").AddAnnotation(WithMessage("valid"))
+	multiResponseWriter.newFinalizeResponseWriter([]string{"RULE1"}).AddAnnotation("RULE1", WithFileName("bar.proto"))
+	multiResponseWriter.newResponseWriter("RULE3").AddAnnotation(WithAgainstFileName("baz.proto"))
+	_, err = multiResponseWriter.toResponse()
+	require.Error(t, err)
+
+	var aggregateError *AggregateError
+	require.ErrorAs(t, err, &aggregateError)
+	require.Equal(
+		t,
+		[]string{"RULE1", "RULE1", "RULE2", "RULE3"},
+		xslices.Map(aggregateError.Errors, func(addAnnotationError *AddAnnotationError) string { return addAnnotationError.RuleID }),
+	)
+	require.Equal(t, []string{"RULE1", "RULE2", "RULE3"}, aggregateError.RuleIDs())
+	require.True(t, strings.HasPrefix(err.Error(), `4 invalid Annotations for rules "RULE1", "RULE2", "RULE3":`+"\n"), err.Error())
+	var unknownFileError *UnknownFileError
+	require.ErrorAs(t, aggregateError.Errors[0], &unknownFileError)
+	require.Equal(t, &UnknownFileError{FileName: "foo.proto"}, unknownFileError)
+	require.ErrorAs(t, aggregateError.Errors[3], &unknownFileError)
+	require.Equal(t, &UnknownFileError{FileName: "baz.proto", IsAgainstFile: true}, unknownFileError)
+	require.EqualError(t, unknownFileError, `cannot add annotation for unknown against file: "baz.proto"`)
+	for _, addAnnotationError := range aggregateError.Errors {
+		require.Contains(t, addAnnotationError.CallSite, "response_writer_test.go:")
+		require.Error(t, addAnnotationError.Err)
+		require.Contains(t, err.Error(), addAnnotationError.Error())
+	}
+	require.Contains(t, aggregateError.Errors[0].Err.Error(), "foo.proto")
+	require.Contains(t, aggregateError.Errors[1].Err.Error(), "bar.proto")
+}
+
+func TestUnknownFilePolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	newSpec := func(unknownFilePolicy UnknownFilePolicy) *Spec {
+		return &Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+							// The file of the descriptor is not in the Request.
+							responseWriter.AddAnnotation(
+								WithMessage("descriptor"),
+								WithDescriptor((&descriptorpb.FileDescriptorProto{}).ProtoReflect().Descriptor()),
+							)
+							responseWriter.AddAnnotation(
+								WithMessage("file name"),
+								WithFileName("unknown.proto"),
+								WithSourcePath(protoreflect.SourcePath{4, 0}),
+							)
+							return nil
+						},
+					),
+				},
+			},
+			UnknownFilePolicy: unknownFilePolicy,
+		}
+	}
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+
+	client, err := NewClientForSpec(newSpec(UnknownFilePolicyError))
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.ErrorContains(t, err, `cannot add annotation for unknown file: "google/protobuf/descriptor.proto"`)
+	require.ErrorContains(t, err, `cannot add annotation for unknown file: "unknown.proto"`)
+
+	client, err = NewClientForSpec(newSpec(UnknownFilePolicyDropLocation))
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	annotations := response.Annotations()
+	require.Len(t, annotations, 2)
+	for _, annotation := range annotations {
+		require.Nil(t, annotation.Location())
+	}
+
+	client, err = NewClientForSpec(newSpec(UnknownFilePolicyFileNameOnly))
+	require.NoError(t, err)
+	response, err = client.Check(ctx, request)
+	require.NoError(t, err)
+	annotations = response.Annotations()
+	require.Len(t, annotations, 2)
+	require.Equal(
+		t,
+		[]string{"google/protobuf/descriptor.proto", "unknown.proto"},
+		xslices.Map(annotations, func(annotation Annotation) string { return annotation.Location().File().FileDescriptor().Path() }),
+	)
+	for _, annotation := range annotations {
+		require.True(t, annotation.Location().IsWholeFile())
+		require.False(t, annotation.Location().HasPosition())
+		require.True(t, annotation.Location().File().IsImport())
+	}
+
+	// The Client does not accept the Locations of the plugin unless it uses the same policy.
+	client, err = NewClientForSpec(newSpec(UnknownFilePolicyFileNameOnly), ClientWithUnknownFilePolicy(UnknownFilePolicyError))
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.Error(t, err)
+
+	_, err = NewClientForSpec(newSpec(UnknownFilePolicy(3)))
+	require.Error(t, err)
+}
+
+func TestMixedLocationError(t *testing.T) {
+	t.Parallel()
+
+	newFiles := func() []File {
+		files, err := FilesForProtoFiles(
+			[]*checkv1beta1.File{
+				{
+					FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+						Name:        proto.String("a.proto"),
+						Syntax:      proto.String("proto3"),
+						MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Foo")}},
+					},
+				},
+			},
+		)
+		require.NoError(t, err)
+		return files
+	}
+	files := newFiles()
+	againstFiles := newFiles()
+	otherFiles, err := FilesForProtoFiles(
+		[]*checkv1beta1.File{
+			{
+				FileDescriptorProto: &descriptorpb.FileDescriptorProto{
+					Name: proto.String("b.proto"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	message := files[0].FileDescriptor().Messages().Get(0)
+	againstMessage := againstFiles[0].FileDescriptor().Messages().Get(0)
+	request, err := NewRequest(append(files, otherFiles...), WithAgainstFiles(againstFiles))
+	require.NoError(t, err)
+
+	testAddAnnotation := func(options ...AddAnnotationOption) error {
+		multiResponseWriter, err := newMultiResponseWriter(request, nil, UnknownFilePolicyError)
+		require.NoError(t, err)
+		multiResponseWriter.newResponseWriter("RULE1").AddAnnotation(options...)
+		_, err = multiResponseWriter.toResponse()
+		return err
+	}
+	require.NoError(t, testAddAnnotation(WithDescriptor(message), WithAgainstDescriptor(againstMessage)))
+	for _, testCase := range []struct {
+		options  []AddAnnotationOption
+		expected *MixedLocationError
+	}{
+		{
+			options: []AddAnnotationOption{WithDescriptor(againstMessage)},
+			expected: &MixedLocationError{
+				FileName:        "a.proto",
+				IsAgainstFile:   true,
+				Option:          "WithDescriptor",
+				SuggestedOption: "WithAgainstDescriptor",
+			},
+		},
+		{
+			options: []AddAnnotationOption{WithAgainstDescriptor(message)},
+			expected: &MixedLocationError{
+				FileName:        "a.proto",
+				Option:          "WithAgainstDescriptor",
+				SuggestedOption: "WithDescriptor",
+			},
+		},
+		{
+			options: []AddAnnotationOption{WithAgainstFileName("b.proto")},
+			expected: &MixedLocationError{
+				FileName:        "b.proto",
+				Option:          "WithAgainstFileName",
+				SuggestedOption: "WithFileName",
+			},
+		},
+	} {
+		err := testAddAnnotation(testCase.options...)
+		var mixedLocationError *MixedLocationError
+		require.ErrorAs(t, err, &mixedLocationError)
+		require.Equal(t, testCase.expected, mixedLocationError)
+	}
+	require.Equal(
+		t,
+		`WithDescriptor was given against file "a.proto", use WithAgainstDescriptor instead`,
+		(&MixedLocationError{
+			FileName:        "a.proto",
+			IsAgainstFile:   true,
+			Option:          "WithDescriptor",
+			SuggestedOption: "WithAgainstDescriptor",
+		}).Error(),
+	)
+}
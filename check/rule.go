@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"slices"
 	"sort"
+	"strings"
 
 	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
 	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
@@ -42,6 +43,10 @@ type Rule interface {
 	//
 	// Optional.
 	Categories() []Category
+	// UnclonedCategories returns the same Categories as Categories, without copying the slice.
+	//
+	// This is for performance-sensitive callers. The returned slice must not be modified.
+	UnclonedCategories() []Category
 	// Whether or not the Rule is a default Rule.
 	//
 	// If a Rule is a default Rule, it will be called if a Request specifies no specific Rule IDs.
@@ -70,6 +75,12 @@ type Rule interface {
 	//
 	// It is not valid for a deprecated Rule to specfiy another deprecated Rule as a replacement.
 	ReplacementIDs() []string
+	// Provenance returns the origin of the Rule, such as its source repository, author,
+	// and license.
+	//
+	// The returned RuleProvenance is zero if no provenance was set. Provenance is not part of
+	// the plugin protocol, and is only available for Clients created with NewClientForSpec.
+	Provenance() RuleProvenance
 
 	toProto() *checkv1beta1.Rule
 
@@ -86,6 +97,12 @@ type rule struct {
 	ruleType       RuleType
 	deprecated     bool
 	replacementIDs []string
+	metadata       ruleMetadata
+}
+
+// ruleMetadata is the metadata of a Rule that is not part of the plugin protocol.
+type ruleMetadata struct {
+	provenance RuleProvenance
 }
 
 func newRule(
@@ -96,6 +113,7 @@ func newRule(
 	ruleType RuleType,
 	deprecated bool,
 	replacementIDs []string,
+	metadata ruleMetadata,
 ) *rule {
 	return &rule{
 		id:             id,
@@ -105,6 +123,7 @@ func newRule(
 		ruleType:       ruleType,
 		deprecated:     deprecated,
 		replacementIDs: replacementIDs,
+		metadata:       metadata,
 	}
 }
 
@@ -116,6 +135,10 @@ func (r *rule) Categories() []Category {
 	return slices.Clone(r.categories)
 }
 
+func (r *rule) UnclonedCategories() []Category {
+	return r.categories
+}
+
 func (r *rule) IsDefault() bool {
 	return r.isDefault
 }
@@ -136,6 +159,10 @@ func (r *rule) ReplacementIDs() []string {
 	return slices.Clone(r.replacementIDs)
 }
 
+func (r *rule) Provenance() RuleProvenance {
+	return r.metadata.provenance
+}
+
 func (r *rule) toProto() *checkv1beta1.Rule {
 	if r == nil {
 		return nil
@@ -178,13 +205,58 @@ func ruleForProtoRule(protoRule *checkv1beta1.Rule, idToCategory map[string]Cate
 		ruleType,
 		protoRule.GetDeprecated(),
 		protoRule.GetReplacementIds(),
+		// Metadata is not part of the plugin protocol.
+		ruleMetadata{},
 	), nil
 }
 
+// ruleWithMetadata returns a copy of the Rule with the given metadata.
+func ruleWithMetadata(r Rule, metadata ruleMetadata) Rule {
+	return newRule(
+		r.ID(),
+		r.UnclonedCategories(),
+		r.IsDefault(),
+		r.Purpose(),
+		r.Type(),
+		r.Deprecated(),
+		r.ReplacementIDs(),
+		metadata,
+	)
+}
+
 func sortRules(rules []Rule) {
 	sort.Slice(rules, func(i int, j int) bool { return CompareRules(rules[i], rules[j]) < 0 })
 }
 
+// sortRulesByCategory sorts the Rules by the lowest ID of their Categories, and then by
+// Rule ID. Rules without Categories are sorted last.
+func sortRulesByCategory(rules []Rule) {
+	lowestCategoryID := func(rule Rule) (string, bool) {
+		categories := rule.UnclonedCategories()
+		if len(categories) == 0 {
+			return "", false
+		}
+		return slices.Min(xslices.Map(categories, Category.ID)), true
+	}
+	slices.SortStableFunc(
+		rules,
+		func(one Rule, two Rule) int {
+			oneCategoryID, oneOK := lowestCategoryID(one)
+			twoCategoryID, twoOK := lowestCategoryID(two)
+			switch {
+			case oneOK && !twoOK:
+				return -1
+			case !oneOK && twoOK:
+				return 1
+			}
+			if c := strings.Compare(oneCategoryID, twoCategoryID); c != 0 {
+				return c
+			}
+			return CompareRules(one, two)
+		},
+	)
+}
+
 func validateNoDuplicateRules(rules []Rule) error {
 	return validateNoDuplicateRuleIDs(xslices.Map(rules, Rule.ID))
 }
@@ -0,0 +1,163 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// RuleDependencies gives a RuleHandler access to the Annotations of the Rules it depends on.
+//
+// See RuleSpec.DependencyIDs for more details.
+type RuleDependencies interface {
+	// Annotations returns the sorted Annotations added by the dependencies of the Rule.
+	//
+	// Only dependencies that were run as part of the Check call have Annotations. Annotations
+	// that were suppressed by other Rules are still returned, so that the result does not
+	// depend on the order in which Rules that share a dependency are run.
+	Annotations() []Annotation
+	// Suppress removes the Annotation from the Response.
+	//
+	// The Annotation must have been returned by Annotations. To refine an Annotation, suppress
+	// it and add a new Annotation with the ResponseWriter of the Rule. Suppressing an
+	// Annotation more than once has no effect.
+	Suppress(annotation Annotation) error
+
+	isRuleDependencies()
+}
+
+// RuleDependenciesFromContext returns the RuleDependencies for the RuleHandler being run.
+//
+// Returns false if the context is not within a RuleHandler for a Rule with DependencyIDs.
+func RuleDependenciesFromContext(ctx context.Context) (RuleDependencies, bool) {
+	ruleDependencies, ok := ctx.Value(ruleDependenciesContextKey{}).(RuleDependencies)
+	return ruleDependencies, ok
+}
+
+// *** PRIVATE ***
+
+type ruleDependenciesContextKey struct{}
+
+type ruleDependencies struct {
+	multiResponseWriter *multiResponseWriter
+	annotations         []Annotation
+}
+
+// withRuleDependencies returns a context with the RuleDependencies for the given dependency IDs.
+//
+// Must only be called once all of the dependencies have completed.
+func withRuleDependencies(
+	ctx context.Context,
+	multiResponseWriter *multiResponseWriter,
+	dependencyIDs []string,
+) context.Context {
+	return context.WithValue(
+		ctx,
+		ruleDependenciesContextKey{},
+		&ruleDependencies{
+			multiResponseWriter: multiResponseWriter,
+			annotations:         multiResponseWriter.sortedAnnotationsForRuleIDs(dependencyIDs),
+		},
+	)
+}
+
+func (r *ruleDependencies) Annotations() []Annotation {
+	return slices.Clone(r.annotations)
+}
+
+func (r *ruleDependencies) Suppress(annotation Annotation) error {
+	if !slices.Contains(r.annotations, annotation) {
+		return errors.New("cannot suppress an Annotation that was not added by a dependency")
+	}
+	r.multiResponseWriter.suppress(annotation)
+	return nil
+}
+
+func (*ruleDependencies) isRuleDependencies() {}
+
+// sortRulesByDependencies sorts the Rules such that each Rule comes after all of its
+// dependencies within the Rules, and otherwise retains the order of the Rules.
+//
+// This allows the Rules to be started in order with a limited parallelism without a
+// Rule waiting on a dependency that cannot start. Assumes there are no cycles.
+func sortRulesByDependencies(rules []Rule, ruleIDToRuleSpec map[string]*RuleSpec) []Rule {
+	ruleIDToRule := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		ruleIDToRule[rule.ID()] = rule
+	}
+	sortedRules := make([]Rule, 0, len(rules))
+	visited := make(map[string]struct{}, len(rules))
+	var visit func(rule Rule)
+	visit = func(rule Rule) {
+		if _, ok := visited[rule.ID()]; ok {
+			return
+		}
+		visited[rule.ID()] = struct{}{}
+		if ruleSpec, ok := ruleIDToRuleSpec[rule.ID()]; ok {
+			for _, dependencyID := range ruleSpec.DependencyIDs {
+				if dependencyRule, ok := ruleIDToRule[dependencyID]; ok {
+					visit(dependencyRule)
+				}
+			}
+		}
+		sortedRules = append(sortedRules, rule)
+	}
+	for _, rule := range rules {
+		visit(rule)
+	}
+	return sortedRules
+}
+
+// validateNoRuleDependencyCycles validates that the DependencyIDs of the RuleSpecs do not
+// form a cycle.
+//
+// Assumes that all DependencyIDs are in ruleIDToRuleSpec.
+func validateNoRuleDependencyCycles(ruleSpecs []*RuleSpec, ruleIDToRuleSpec map[string]*RuleSpec) error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	ruleIDToState := make(map[string]int, len(ruleSpecs))
+	var path []string
+	var visit func(ruleSpec *RuleSpec) error
+	visit = func(ruleSpec *RuleSpec) error {
+		switch ruleIDToState[ruleSpec.ID] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := slices.Concat(path[slices.Index(path, ruleSpec.ID):], []string{ruleSpec.ID})
+			return newValidateRuleSpecErrorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+		ruleIDToState[ruleSpec.ID] = visiting
+		path = append(path, ruleSpec.ID)
+		for _, dependencyID := range ruleSpec.DependencyIDs {
+			if err := visit(ruleIDToRuleSpec[dependencyID]); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		ruleIDToState[ruleSpec.ID] = visited
+		return nil
+	}
+	for _, ruleSpec := range ruleSpecs {
+		if err := visit(ruleSpec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
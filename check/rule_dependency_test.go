@@ -0,0 +1,182 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleDependencies(t *testing.T) {
+	t.Parallel()
+
+	testRuleDependencies(t, 0)
+	// With a parallelism of 1, a Rule that waits for a dependency must not prevent the
+	// dependency from running.
+	testRuleDependencies(t, 1)
+}
+
+func TestRuleDependenciesValidation(t *testing.T) {
+	t.Parallel()
+
+	validator, err := getValidator()
+	require.NoError(t, err)
+	validateRuleSpecError := &validateRuleSpecError{}
+
+	spec := &Spec{
+		Rules: []*RuleSpec{
+			testNewSimpleLintRuleSpec("RULE1", nil, true, false, nil),
+			testNewDependentLintRuleSpec("RULE2", "RULE1"),
+			testNewDependentLintRuleSpec("RULE3", "RULE1", "RULE2"),
+		},
+	}
+	require.NoError(t, validateSpec(validator, spec))
+
+	spec = &Spec{
+		Rules: []*RuleSpec{
+			testNewDependentLintRuleSpec("RULE1", "RULE2"),
+		},
+	}
+	err = validateSpec(validator, spec)
+	require.ErrorAs(t, err, &validateRuleSpecError)
+	require.ErrorContains(t, err, "not found")
+
+	spec = &Spec{
+		Rules: []*RuleSpec{
+			testNewDependentLintRuleSpec("RULE1", "RULE1"),
+		},
+	}
+	err = validateSpec(validator, spec)
+	require.ErrorAs(t, err, &validateRuleSpecError)
+	require.ErrorContains(t, err, "itself")
+
+	spec = &Spec{
+		Rules: []*RuleSpec{
+			testNewSimpleLintRuleSpec("RULE1", nil, true, false, nil),
+			testNewDependentLintRuleSpec("RULE2", "RULE1", "RULE4"),
+			testNewDependentLintRuleSpec("RULE3", "RULE2"),
+			testNewDependentLintRuleSpec("RULE4", "RULE3"),
+		},
+	}
+	err = validateSpec(validator, spec)
+	require.ErrorAs(t, err, &validateRuleSpecError)
+	require.ErrorContains(t, err, "dependency cycle: RULE2 -> RULE4 -> RULE3 -> RULE2")
+
+	_, err = NewLintRule(
+		"RULE1",
+		"Test rule.",
+		nopRuleHandler,
+		RuleSpecWithDependencyIDs("invalid"),
+	)
+	require.Error(t, err)
+}
+
+func testRuleDependencies(t *testing.T, parallelism int) {
+	ctx := context.Background()
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:        "RULE3",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+							// Give dependent Rules a chance to run early if they are not ordered.
+							time.Sleep(10 * time.Millisecond)
+							responseWriter.AddAnnotation(WithMessage("one"))
+							responseWriter.AddAnnotation(WithMessage("two"))
+							return nil
+						},
+					),
+				},
+				{
+					ID:            "RULE2",
+					IsDefault:     true,
+					Purpose:       "Test rule.",
+					Type:          RuleTypeLint,
+					DependencyIDs: []string{"RULE3"},
+					Handler: RuleHandlerFunc(
+						func(ctx context.Context, responseWriter ResponseWriter, _ Request) error {
+							ruleDependencies, ok := RuleDependenciesFromContext(ctx)
+							if !ok {
+								return errors.New("no RuleDependencies")
+							}
+							for _, annotation := range ruleDependencies.Annotations() {
+								if annotation.Message() != "two" {
+									continue
+								}
+								if err := ruleDependencies.Suppress(annotation); err != nil {
+									return err
+								}
+								responseWriter.AddAnnotation(WithMessage("two refined"))
+							}
+							return nil
+						},
+					),
+				},
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(ctx context.Context, _ ResponseWriter, _ Request) error {
+							if _, ok := RuleDependenciesFromContext(ctx); ok {
+								return errors.New("unexpected RuleDependencies")
+							}
+							return nil
+						},
+					),
+				},
+			},
+		},
+		ClientWithSpecServerOptions(ServerWithParallelism(parallelism)),
+	)
+	require.NoError(t, err)
+
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	response, err := client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{"RULE2:two refined", "RULE3:one"},
+		xslices.Map(response.Annotations(), testRuleDependencyAnnotationString),
+	)
+
+	// Dependencies that are not run are not run implicitly.
+	request, err = NewRequest(nil, WithRuleIDs("RULE2"))
+	require.NoError(t, err)
+	response, err = client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Empty(t, response.Annotations())
+}
+
+func testNewDependentLintRuleSpec(id string, dependencyIDs ...string) *RuleSpec {
+	ruleSpec := testNewSimpleLintRuleSpec(id, nil, true, false, nil)
+	ruleSpec.DependencyIDs = dependencyIDs
+	return ruleSpec
+}
+
+func testRuleDependencyAnnotationString(annotation Annotation) string {
+	return annotation.RuleID() + ":" + annotation.Message()
+}
@@ -16,6 +16,7 @@ package check
 
 import (
 	"context"
+	"sync"
 )
 
 var nopRuleHandler = RuleHandlerFunc(func(context.Context, ResponseWriter, Request) error { return nil })
@@ -23,6 +24,11 @@ var nopRuleHandler = RuleHandlerFunc(func(context.Context, ResponseWriter, Reque
 // RuleHandler implements the check logic for a single Rule.
 //
 // A RuleHandler takes in a Request, and writes Annotations to the ResponseWriter.
+//
+// If the host cancels a Check call, no further RuleHandlers are started, and the Check call
+// returns an error with pluginrpc.CodeCanceled (or pluginrpc.CodeDeadlineExceeded) once the
+// running RuleHandlers return. Long-running RuleHandlers should check ctx.Err() periodically
+// and return early if the context is done. The RuleHandlers in checkutil do this between Files.
 type RuleHandler interface {
 	Handle(ctx context.Context, responseWriter ResponseWriter, request Request) error
 }
@@ -34,3 +40,30 @@ type RuleHandlerFunc func(context.Context, ResponseWriter, Request) error
 func (r RuleHandlerFunc) Handle(ctx context.Context, responseWriter ResponseWriter, request Request) error {
 	return r(ctx, responseWriter, request)
 }
+
+// NewLazyRuleHandler returns a new RuleHandler that constructs the underlying RuleHandler with
+// the given function on the first call to Handle.
+//
+// This defers expensive setup, such as compiling regular expressions, until the Rule is first
+// run, so that plugin invocations that do not run the Rule, such as ListRules, do not pay for
+// it. The function is called at most once. If it returns an error, every call to Handle
+// returns the error.
+func NewLazyRuleHandler(newRuleHandler func() (RuleHandler, error)) RuleHandler {
+	return &lazyRuleHandler{
+		getRuleHandler: sync.OnceValues(newRuleHandler),
+	}
+}
+
+// *** PRIVATE ***
+
+type lazyRuleHandler struct {
+	getRuleHandler func() (RuleHandler, error)
+}
+
+func (l *lazyRuleHandler) Handle(ctx context.Context, responseWriter ResponseWriter, request Request) error {
+	ruleHandler, err := l.getRuleHandler()
+	if err != nil {
+		return err
+	}
+	return ruleHandler.Handle(ctx, responseWriter, request)
+}
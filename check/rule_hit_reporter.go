@@ -0,0 +1,146 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"sync"
+
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+)
+
+// RuleHitReporter receives counts of the Rules run by Check calls, and of the Annotations
+// returned for each Rule.
+//
+// This is an opt-in hook for hosts, to aggregate how often each Rule is run and hit across
+// Check calls, for example to allow organizations to find and retire Rules that never produce
+// Annotations. Pass a RuleHitReporter to ClientWithRuleHitReporter. The default is to not
+// report anything.
+//
+// Reports are anonymous: they only contain Rule IDs and counts. They never contain file names,
+// file contents, descriptor names, Annotation messages, Locations, options, or any identifier
+// of the user, machine, or Check call. Rule IDs are defined by plugins, so Rule IDs of private
+// plugins may themselves reveal information. Hosts that send reports off of the machine should
+// disclose this to users, and allow users to opt out.
+//
+// ReportRuleHits is called once per successful Check call, after the Response is computed, and
+// may be called concurrently if Check is called concurrently. Check calls that fail are not
+// reported.
+type RuleHitReporter interface {
+	// ReportRuleHits reports the RuleHits of a single Check call, sorted by Rule ID.
+	ReportRuleHits(ruleHits []RuleHits)
+}
+
+// RuleHitReporterFunc is a function that implements RuleHitReporter.
+type RuleHitReporterFunc func([]RuleHits)
+
+// ReportRuleHits implements RuleHitReporter.
+func (r RuleHitReporterFunc) ReportRuleHits(ruleHits []RuleHits) {
+	r(ruleHits)
+}
+
+// RuleHits are the counts for a single Rule.
+type RuleHits struct {
+	// RuleID is the ID of the Rule.
+	RuleID string
+	// Runs is the number of Check calls that ran the Rule.
+	//
+	// This is always 1 within a single Check call.
+	Runs int
+	// AnnotationCount is the number of Annotations returned for the Rule.
+	//
+	// A Rule that was run but returned no Annotations has an AnnotationCount of 0.
+	AnnotationCount int
+}
+
+// RuleHitCounter is a RuleHitReporter that accumulates the RuleHits of all Check calls.
+//
+// A RuleHitCounter is safe to use concurrently.
+type RuleHitCounter interface {
+	RuleHitReporter
+
+	// RuleHits returns a snapshot of the RuleHits accumulated so far, sorted by Rule ID.
+	RuleHits() []RuleHits
+
+	isRuleHitCounter()
+}
+
+// NewRuleHitCounter returns a new RuleHitCounter.
+func NewRuleHitCounter() RuleHitCounter {
+	return newRuleHitCounter()
+}
+
+// *** PRIVATE ***
+
+type ruleHitCounter struct {
+	ruleIDToRuleHits map[string]RuleHits
+	lock             sync.Mutex
+}
+
+func newRuleHitCounter() *ruleHitCounter {
+	return &ruleHitCounter{
+		ruleIDToRuleHits: make(map[string]RuleHits),
+	}
+}
+
+func (r *ruleHitCounter) ReportRuleHits(ruleHits []RuleHits) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, ruleHit := range ruleHits {
+		existingRuleHits := r.ruleIDToRuleHits[ruleHit.RuleID]
+		existingRuleHits.RuleID = ruleHit.RuleID
+		existingRuleHits.Runs += ruleHit.Runs
+		existingRuleHits.AnnotationCount += ruleHit.AnnotationCount
+		r.ruleIDToRuleHits[ruleHit.RuleID] = existingRuleHits
+	}
+}
+
+func (r *ruleHitCounter) RuleHits() []RuleHits {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ruleHits := make([]RuleHits, 0, len(r.ruleIDToRuleHits))
+	for _, ruleID := range xslices.MapKeysToSortedSlice(r.ruleIDToRuleHits) {
+		ruleHits = append(ruleHits, r.ruleIDToRuleHits[ruleID])
+	}
+	return ruleHits
+}
+
+func (*ruleHitCounter) isRuleHitCounter() {}
+
+// getRuleHits returns the RuleHits for a single Check call that ran the given Rules.
+//
+// A Rule with Annotations is considered run even if it is not in ruleIDs.
+func getRuleHits(ruleIDs []string, annotations []Annotation) []RuleHits {
+	ruleIDToRuleHits := make(map[string]RuleHits, len(ruleIDs))
+	for _, ruleID := range ruleIDs {
+		ruleIDToRuleHits[ruleID] = RuleHits{
+			RuleID: ruleID,
+			Runs:   1,
+		}
+	}
+	for _, annotation := range annotations {
+		ruleHits := ruleIDToRuleHits[annotation.RuleID()]
+		ruleHits.RuleID = annotation.RuleID()
+		ruleHits.Runs = 1
+		ruleHits.AnnotationCount++
+		ruleIDToRuleHits[annotation.RuleID()] = ruleHits
+	}
+	ruleHits := make([]RuleHits, 0, len(ruleIDToRuleHits))
+	for _, ruleID := range xslices.MapKeysToSortedSlice(ruleIDToRuleHits) {
+		ruleHits = append(ruleHits, ruleIDToRuleHits[ruleID])
+	}
+	return ruleHits
+}
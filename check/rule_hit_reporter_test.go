@@ -0,0 +1,108 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleHitReporter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ruleHitCounter := NewRuleHitCounter()
+	var lastRuleHits []RuleHits
+	newClient := func(ruleHitReporter RuleHitReporter) Client {
+		client, err := NewClientForSpec(
+			&Spec{
+				Rules: []*RuleSpec{
+					{
+						ID:        "RULE1",
+						IsDefault: true,
+						Purpose:   "Test rule1.",
+						Type:      RuleTypeLint,
+						Handler: RuleHandlerFunc(
+							func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+								responseWriter.AddAnnotation(WithMessage("first"))
+								responseWriter.AddAnnotation(WithMessage("second"))
+								return nil
+							},
+						),
+					},
+					{
+						ID:        "RULE2",
+						IsDefault: true,
+						Purpose:   "Test rule2.",
+						Type:      RuleTypeLint,
+						Handler:   nopRuleHandler,
+					},
+					{
+						ID:        "RULE3",
+						IsDefault: false,
+						Purpose:   "Test rule3.",
+						Type:      RuleTypeLint,
+						Handler:   nopRuleHandler,
+					},
+				},
+			},
+			ClientWithRuleHitReporter(ruleHitReporter),
+		)
+		require.NoError(t, err)
+		return client
+	}
+	client := newClient(ruleHitCounter)
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	for range 2 {
+		_, err = client.Check(ctx, request)
+		require.NoError(t, err)
+	}
+	request, err = NewRequest(nil, WithRuleIDs("RULE3"))
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]RuleHits{
+			{RuleID: "RULE1", Runs: 2, AnnotationCount: 4},
+			{RuleID: "RULE2", Runs: 2, AnnotationCount: 0},
+			{RuleID: "RULE3", Runs: 1, AnnotationCount: 0},
+		},
+		ruleHitCounter.RuleHits(),
+	)
+
+	request, err = NewRequest(nil, WithRuleIDs("RULE*"))
+	require.NoError(t, err)
+	_, err = newClient(
+		RuleHitReporterFunc(
+			func(ruleHits []RuleHits) {
+				lastRuleHits = ruleHits
+			},
+		),
+	).Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]RuleHits{
+			{RuleID: "RULE1", Runs: 1, AnnotationCount: 2},
+			{RuleID: "RULE2", Runs: 1, AnnotationCount: 0},
+			{RuleID: "RULE3", Runs: 1, AnnotationCount: 0},
+		},
+		lastRuleHits,
+	)
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// RuleIDCategoryPrefix is the prefix of a Rule ID selector that selects all Rules within
+// a Category.
+//
+// See WithRuleIDs for more details.
+const RuleIDCategoryPrefix = "category:"
+
+// ResolveRuleIDs resolves the rule ID selectors within the rule IDs against the Rules,
+// returning the sorted, deduplicated IDs of all selected Rules.
+//
+// Rule IDs that are not selectors are returned as-is. Client.Check resolves selectors in the
+// same way, this allows hosts that wrap a Client to determine the Rules that a Request
+// selects. See WithRuleIDs for the selector syntax.
+//
+// Returns error if a selector is invalid or does not select any Rules.
+func ResolveRuleIDs(ruleIDs []string, rules []Rule) ([]string, error) {
+	return resolveRuleIDSelectors(ruleIDs, rules)
+}
+
+// *** PRIVATE ***
+
+// hasRuleIDSelectors returns true if any of the rule IDs are selectors that need to be
+// resolved against the Rules of the plugin.
+func hasRuleIDSelectors(ruleIDs []string) bool {
+	return slices.ContainsFunc(ruleIDs, isRuleIDSelector)
+}
+
+func isRuleIDSelector(ruleID string) bool {
+	return strings.HasPrefix(ruleID, RuleIDCategoryPrefix) || strings.ContainsAny(ruleID, "*?[")
+}
+
+// resolveRuleIDSelectors resolves the rule IDs against the Rules, returning the sorted,
+// deduplicated IDs of all selected Rules.
+//
+// Rule IDs that are not selectors are returned as-is, and are validated by the plugin.
+// Returns error if a selector is invalid or does not select any Rules.
+func resolveRuleIDSelectors(ruleIDs []string, rules []Rule) ([]string, error) {
+	resolvedRuleIDMap := make(map[string]struct{}, len(ruleIDs))
+	for _, ruleID := range ruleIDs {
+		if !isRuleIDSelector(ruleID) {
+			resolvedRuleIDMap[ruleID] = struct{}{}
+			continue
+		}
+		var isCategory bool
+		pattern := ruleID
+		if categoryPattern, ok := strings.CutPrefix(ruleID, RuleIDCategoryPrefix); ok {
+			isCategory = true
+			pattern = categoryPattern
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid rule ID selector %q: %w", ruleID, err)
+		}
+		var matched bool
+		for _, rule := range rules {
+			if isCategory {
+				if !slices.ContainsFunc(
+					rule.UnclonedCategories(),
+					func(category Category) bool {
+						// Error is checked above, and path.Match only errors on malformed patterns.
+						match, _ := path.Match(pattern, category.ID())
+						return match
+					},
+				) {
+					continue
+				}
+			} else if match, _ := path.Match(pattern, rule.ID()); !match {
+				continue
+			}
+			resolvedRuleIDMap[rule.ID()] = struct{}{}
+			matched = true
+		}
+		if !matched {
+			return nil, fmt.Errorf("rule ID selector %q did not match any rules", ruleID)
+		}
+	}
+	resolvedRuleIDs := make([]string, 0, len(resolvedRuleIDMap))
+	for ruleID := range resolvedRuleIDMap {
+		resolvedRuleIDs = append(resolvedRuleIDs, ruleID)
+	}
+	slices.Sort(resolvedRuleIDs)
+	return resolvedRuleIDs, nil
+}
+
+// resolveRequestRuleIDSelectors returns a new Request with the rule ID selectors of the
+// Request resolved against the Rules.
+func resolveRequestRuleIDSelectors(request Request, rules []Rule) (Request, error) {
+	ruleIDs, err := resolveRuleIDSelectors(request.RuleIDs(), rules)
+	if err != nil {
+		return nil, err
+	}
+	return newRequest(
+		request.UnclonedFiles(),
+		WithAgainstFiles(request.UnclonedAgainstFiles()),
+		WithOptions(request.Options()),
+		WithRuleIDs(ruleIDs...),
+	)
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleIDSelectors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	handler := RuleHandlerFunc(
+		func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+			responseWriter.AddAnnotation()
+			return nil
+		},
+	)
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:          "FIELD_A",
+					CategoryIDs: []string{"STYLE"},
+					IsDefault:   true,
+					Purpose:     "Test field a.",
+					Type:        RuleTypeLint,
+					Handler:     handler,
+				},
+				{
+					ID:          "FIELD_B",
+					CategoryIDs: []string{"STYLE"},
+					Purpose:     "Test field b.",
+					Type:        RuleTypeLint,
+					Handler:     handler,
+				},
+				{
+					ID:        "MESSAGE_A",
+					IsDefault: true,
+					Purpose:   "Test message a.",
+					Type:      RuleTypeLint,
+					Handler:   handler,
+				},
+			},
+			Categories: []*CategorySpec{
+				{
+					ID:      "STYLE",
+					Purpose: "Test style.",
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	testRuleIDs := func(ruleIDs ...string) ([]string, error) {
+		request, err := NewRequest(nil, WithRuleIDs(ruleIDs...))
+		require.NoError(t, err)
+		response, err := client.Check(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return xslices.Map(response.Annotations(), Annotation.RuleID), nil
+	}
+
+	ruleIDs, err := testRuleIDs("FIELD_*")
+	require.NoError(t, err)
+	require.Equal(t, []string{"FIELD_A", "FIELD_B"}, ruleIDs)
+	ruleIDs, err = testRuleIDs("category:STYLE")
+	require.NoError(t, err)
+	require.Equal(t, []string{"FIELD_A", "FIELD_B"}, ruleIDs)
+	ruleIDs, err = testRuleIDs("category:ST*", "MESSAGE_A", "FIELD_?")
+	require.NoError(t, err)
+	require.Equal(t, []string{"FIELD_A", "FIELD_B", "MESSAGE_A"}, ruleIDs)
+	ruleIDs, err = testRuleIDs("*_A")
+	require.NoError(t, err)
+	require.Equal(t, []string{"FIELD_A", "MESSAGE_A"}, ruleIDs)
+
+	_, err = testRuleIDs("ENUM_*")
+	require.ErrorContains(t, err, `rule ID selector "ENUM_*" did not match any rules`)
+	_, err = testRuleIDs("category:OTHER")
+	require.ErrorContains(t, err, `rule ID selector "category:OTHER" did not match any rules`)
+	_, err = testRuleIDs("FIELD_[")
+	require.ErrorContains(t, err, `invalid rule ID selector "FIELD_["`)
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RuleProvenance is the origin of a Rule.
+//
+// Plugins that aggregate Rules from multiple vendors, for example by registering the RuleSpecs of
+// multiple packages with Register, can set a RuleProvenance on each RuleSpec so that reports can
+// attribute each Rule to its vendor.
+//
+// All fields are optional.
+type RuleProvenance struct {
+	// SourceRepository is the repository that the Rule is maintained in, such as
+	// "https://github.com/acme/protolint".
+	SourceRepository string
+	// Author is the author or vendor of the Rule.
+	Author string
+	// License is the SPDX license expression that the Rule is distributed under, such as
+	// "Apache-2.0" or "MIT OR Apache-2.0".
+	License string
+}
+
+// IsZero returns true if no fields of the RuleProvenance are set.
+func (p RuleProvenance) IsZero() bool {
+	return p == RuleProvenance{}
+}
+
+// String returns a human-readable representation of the RuleProvenance, containing only
+// the fields that are set.
+//
+// Returns the empty string if no fields are set.
+func (p RuleProvenance) String() string {
+	var parts []string
+	if p.Author != "" {
+		parts = append(parts, "author: "+p.Author)
+	}
+	if p.License != "" {
+		parts = append(parts, "license: "+p.License)
+	}
+	if p.SourceRepository != "" {
+		parts = append(parts, "source: "+p.SourceRepository)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// *** PRIVATE ***
+
+// licenseRegexp matches SPDX license identifiers joined by the AND, OR, and WITH operators.
+//
+// Parentheses are allowed around identifiers for compound expressions.
+var licenseRegexp = regexp.MustCompile(`^[(]*[A-Za-z0-9.+-]+[)]*( (AND|OR|WITH) [(]*[A-Za-z0-9.+-]+[)]*)*$`)
+
+func validateRuleProvenance(provenance RuleProvenance) error {
+	if err := validateRuleProvenanceLine("SourceRepository", provenance.SourceRepository); err != nil {
+		return err
+	}
+	if err := validateRuleProvenanceLine("Author", provenance.Author); err != nil {
+		return err
+	}
+	if provenance.License != "" && !licenseRegexp.MatchString(provenance.License) {
+		return fmt.Errorf("provenance License %q must be an SPDX license expression such as \"Apache-2.0\"", provenance.License)
+	}
+	return nil
+}
+
+func validateRuleProvenanceLine(name string, value string) error {
+	if value != strings.TrimSpace(value) || strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("provenance %s %q must be a single line without leading or trailing whitespace", name, value)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleProvenance(t *testing.T) {
+	t.Parallel()
+
+	provenance := RuleProvenance{
+		SourceRepository: "https://github.com/acme/protolint",
+		Author:           "Acme",
+		License:          "Apache-2.0",
+	}
+	ruleSpec, err := NewLintRule("RULE1", "Test rule1.", nopRuleHandler, RuleSpecWithProvenance(provenance))
+	require.NoError(t, err)
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				ruleSpec,
+				{
+					ID:      "RULE2",
+					Purpose: "Test rule2.",
+					Type:    RuleTypeLint,
+					Handler: nopRuleHandler,
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	rules, err := client.ListRules(context.Background())
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Equal(t, provenance, rules[0].Provenance())
+	require.True(t, rules[1].Provenance().IsZero())
+
+	require.Equal(t, "author: Acme, license: Apache-2.0, source: https://github.com/acme/protolint", provenance.String())
+	require.Equal(t, "license: MIT", RuleProvenance{License: "MIT"}.String())
+	require.Empty(t, RuleProvenance{}.String())
+}
+
+func TestRuleProvenanceValidation(t *testing.T) {
+	t.Parallel()
+
+	for _, license := range []string{
+		"Apache-2.0",
+		"MIT OR Apache-2.0",
+		"GPL-2.0-or-later WITH Classpath-exception-2.0",
+		"(MIT AND BSD-3-Clause) OR Apache-2.0",
+		"LicenseRef-Acme",
+	} {
+		require.NoError(t, validateRuleProvenance(RuleProvenance{License: license}), license)
+	}
+	for _, provenance := range []RuleProvenance{
+		{License: "Apache 2.0"},
+		{License: "MIT or Apache-2.0"},
+		{Author: " Acme"},
+		{SourceRepository: "https://github.com/acme/protolint\n"},
+	} {
+		require.Error(t, validateRuleProvenance(provenance), provenance)
+		_, err := NewLintRule("RULE1", "Test rule1.", nopRuleHandler, RuleSpecWithProvenance(provenance))
+		require.Error(t, err)
+	}
+}
@@ -16,6 +16,8 @@ package check
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
 	"sort"
 
 	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
@@ -42,10 +44,225 @@ type RuleSpec struct {
 	ReplacementIDs []string
 	// Required.
 	Handler RuleHandler
+	// DescriptorKinds are the kinds of descriptors that the Handler inspects.
+	//
+	// If all Rules run by a Check call declare their DescriptorKinds, descriptors of other
+	// kinds are pruned from the Files of the Request before the Files are built, which reduces
+	// the time spent building descriptors for large schemas. Pruning is best-effort, so the
+	// Handler may still see descriptors of other kinds, and must not rely on their absence.
+	//
+	// DescriptorKinds are not part of the plugin protocol, so the full request is still sent to
+	// the plugin. If empty, the Handler may inspect any descriptors, and nothing is pruned.
+	DescriptorKinds []DescriptorKind
+	// Provenance is the origin of the Rule, such as its source repository, author, and license.
+	//
+	// This allows plugins that aggregate Rules from multiple vendors to attribute each Rule
+	// in reports. See RuleProvenance for more details. Optional.
+	Provenance RuleProvenance
+	// DependencyIDs are the IDs of the Rules that must complete before the Handler is run.
+	//
+	// This allows composite Rules that refine or suppress the Annotations of other Rules. The
+	// Handler can read and suppress the Annotations of its dependencies with
+	// RuleDependenciesFromContext.
+	//
+	// Dependencies only affect the order in which Rules are run. A dependency that is not run
+	// as part of a Check call, for example because it is not a default Rule, is not run
+	// implicitly, and has no Annotations. Dependencies must not form a cycle.
+	DependencyIDs []string
+}
+
+// NewLintRule returns a new RuleSpec for a lint Rule.
+//
+// The returned RuleSpec is a default Rule unless RuleSpecWithIsDefault(false) or
+// RuleSpecWithDeprecated is used.
+//
+// The ID, Purpose, and any Category and replacement IDs are validated against the constraints
+// of the protocol when the RuleSpec is constructed, so that mistakes are caught at plugin
+// initialization rather than on the first request. References to other Rules and Categories
+// are validated when the Spec is validated.
+//
+//	timestampSuffixRuleSpec, err := check.NewLintRule(
+//		"TIMESTAMP_SUFFIX",
+//		"Checks that all google.protobuf.Timestamps end in _time.",
+//		check.RuleHandlerFunc(handleTimestampSuffix),
+//	)
+func NewLintRule(id string, purpose string, handler RuleHandler, options ...RuleSpecOption) (*RuleSpec, error) {
+	return newRuleSpec(RuleTypeLint, id, purpose, handler, options...)
+}
+
+// NewBreakingRule returns a new RuleSpec for a breaking change Rule.
+//
+// See NewLintRule for the defaults and validation that are applied.
+func NewBreakingRule(id string, purpose string, handler RuleHandler, options ...RuleSpecOption) (*RuleSpec, error) {
+	return newRuleSpec(RuleTypeBreaking, id, purpose, handler, options...)
+}
+
+// RuleSpecOption is an option for NewLintRule and NewBreakingRule.
+type RuleSpecOption func(*ruleSpecOptions)
+
+// RuleSpecWithCategoryIDs returns a new RuleSpecOption that adds the given Category IDs.
+func RuleSpecWithCategoryIDs(categoryIDs ...string) RuleSpecOption {
+	return func(ruleSpecOptions *ruleSpecOptions) {
+		ruleSpecOptions.categoryIDs = append(ruleSpecOptions.categoryIDs, categoryIDs...)
+	}
+}
+
+// RuleSpecWithIsDefault returns a new RuleSpecOption that sets whether or not the Rule is a
+// default Rule.
+//
+// The default is true.
+func RuleSpecWithIsDefault(isDefault bool) RuleSpecOption {
+	return func(ruleSpecOptions *ruleSpecOptions) {
+		ruleSpecOptions.isDefault = isDefault
+	}
+}
+
+// RuleSpecWithDeprecated returns a new RuleSpecOption that marks the Rule as deprecated, with
+// the given replacement IDs, if any.
+//
+// Deprecated Rules cannot be default Rules, so this also results in the Rule not being a
+// default Rule, regardless of RuleSpecWithIsDefault.
+func RuleSpecWithDeprecated(replacementIDs ...string) RuleSpecOption {
+	return func(ruleSpecOptions *ruleSpecOptions) {
+		ruleSpecOptions.deprecated = true
+		ruleSpecOptions.replacementIDs = append(ruleSpecOptions.replacementIDs, replacementIDs...)
+	}
+}
+
+// RuleSpecWithDescriptorKinds returns a new RuleSpecOption that declares the kinds of
+// descriptors that the RuleHandler inspects.
+//
+// See RuleSpec.DescriptorKinds for more details.
+func RuleSpecWithDescriptorKinds(descriptorKinds ...DescriptorKind) RuleSpecOption {
+	return func(ruleSpecOptions *ruleSpecOptions) {
+		ruleSpecOptions.descriptorKinds = append(ruleSpecOptions.descriptorKinds, descriptorKinds...)
+	}
+}
+
+// RuleSpecWithProvenance returns a new RuleSpecOption that sets the origin of the Rule.
+//
+// See RuleSpec.Provenance for more details.
+func RuleSpecWithProvenance(provenance RuleProvenance) RuleSpecOption {
+	return func(ruleSpecOptions *ruleSpecOptions) {
+		ruleSpecOptions.provenance = provenance
+	}
+}
+
+// RuleSpecWithDependencyIDs returns a new RuleSpecOption that adds the given dependency IDs.
+//
+// See RuleSpec.DependencyIDs for more details.
+func RuleSpecWithDependencyIDs(dependencyIDs ...string) RuleSpecOption {
+	return func(ruleSpecOptions *ruleSpecOptions) {
+		ruleSpecOptions.dependencyIDs = append(ruleSpecOptions.dependencyIDs, dependencyIDs...)
+	}
 }
 
 // *** PRIVATE ***
 
+const (
+	minRuleOrCategoryIDLength = 4
+	maxRuleOrCategoryIDLength = 64
+	minPurposeLength          = 2
+	maxPurposeLength          = 256
+)
+
+var (
+	ruleOrCategoryIDRegexp = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_]*[A-Z0-9]$`)
+	purposeRegexp          = regexp.MustCompile(`^[A-Z].*[.]$`)
+)
+
+func newRuleSpec(
+	ruleType RuleType,
+	id string,
+	purpose string,
+	handler RuleHandler,
+	options ...RuleSpecOption,
+) (*RuleSpec, error) {
+	ruleSpecOptions := newRuleSpecOptions()
+	for _, option := range options {
+		option(ruleSpecOptions)
+	}
+	if err := validateRuleOrCategoryIDFormat(id); err != nil {
+		return nil, newValidateRuleSpecError(err.Error())
+	}
+	if err := validatePurposeFormat(purpose); err != nil {
+		return nil, newValidateRuleSpecErrorf("ID %q: %v", id, err)
+	}
+	if handler == nil {
+		return nil, newValidateRuleSpecErrorf("Handler is not set for ID %q", id)
+	}
+	otherIDs := slices.Concat(ruleSpecOptions.categoryIDs, ruleSpecOptions.replacementIDs, ruleSpecOptions.dependencyIDs)
+	for _, otherID := range otherIDs {
+		if err := validateRuleOrCategoryIDFormat(otherID); err != nil {
+			return nil, newValidateRuleSpecErrorf("ID %q: %v", id, err)
+		}
+	}
+	if err := validateDescriptorKinds(ruleSpecOptions.descriptorKinds); err != nil {
+		return nil, newValidateRuleSpecErrorf("ID %q: %v", id, err)
+	}
+	if err := validateRuleProvenance(ruleSpecOptions.provenance); err != nil {
+		return nil, newValidateRuleSpecErrorf("ID %q: %v", id, err)
+	}
+	return &RuleSpec{
+		ID:              id,
+		CategoryIDs:     ruleSpecOptions.categoryIDs,
+		IsDefault:       ruleSpecOptions.isDefault && !ruleSpecOptions.deprecated,
+		Purpose:         purpose,
+		Type:            ruleType,
+		Deprecated:      ruleSpecOptions.deprecated,
+		ReplacementIDs:  ruleSpecOptions.replacementIDs,
+		Handler:         handler,
+		DescriptorKinds: ruleSpecOptions.descriptorKinds,
+		Provenance:      ruleSpecOptions.provenance,
+		DependencyIDs:   ruleSpecOptions.dependencyIDs,
+	}, nil
+}
+
+type ruleSpecOptions struct {
+	categoryIDs     []string
+	isDefault       bool
+	deprecated      bool
+	replacementIDs  []string
+	descriptorKinds []DescriptorKind
+	provenance      RuleProvenance
+	dependencyIDs   []string
+}
+
+func newRuleSpecOptions() *ruleSpecOptions {
+	return &ruleSpecOptions{
+		isDefault: true,
+	}
+}
+
+func validateRuleOrCategoryIDFormat(id string) error {
+	if len(id) < minRuleOrCategoryIDLength || len(id) > maxRuleOrCategoryIDLength {
+		return fmt.Errorf("rule or category ID %q must have between %d and %d characters", id, minRuleOrCategoryIDLength, maxRuleOrCategoryIDLength)
+	}
+	if !ruleOrCategoryIDRegexp.MatchString(id) {
+		return fmt.Errorf("rule or category ID %q must only consist of A-Z, 0-9, and underscores, and must not start or end with an underscore", id)
+	}
+	return nil
+}
+
+func validateDescriptorKinds(descriptorKinds []DescriptorKind) error {
+	for _, descriptorKind := range descriptorKinds {
+		if _, ok := descriptorKindToString[descriptorKind]; !ok {
+			return fmt.Errorf("unknown DescriptorKind: %v", descriptorKind)
+		}
+	}
+	return nil
+}
+
+func validatePurposeFormat(purpose string) error {
+	if len(purpose) < minPurposeLength || len(purpose) > maxPurposeLength {
+		return fmt.Errorf("purpose must have between %d and %d characters", minPurposeLength, maxPurposeLength)
+	}
+	if !purposeRegexp.MatchString(purpose) {
+		return fmt.Errorf("purpose %q must start with a capital letter from A-Z and end with a period", purpose)
+	}
+	return nil
+}
+
 // Assumes that the RuleSpec is validated.
 func ruleSpecToRule(ruleSpec *RuleSpec, idToCategory map[string]Category) (Rule, error) {
 	categories, err := xslices.MapError(
@@ -69,6 +286,7 @@ func ruleSpecToRule(ruleSpec *RuleSpec, idToCategory map[string]Category) (Rule,
 		ruleSpec.Type,
 		ruleSpec.Deprecated,
 		ruleSpec.ReplacementIDs,
+		ruleSpecToRuleMetadata(ruleSpec),
 	), nil
 }
 
@@ -93,7 +311,7 @@ func validateRuleSpecs(
 			return err
 		}
 	}
-	return nil
+	return validateNoRuleDependencyCycles(ruleSpecs, ruleIDToRuleSpec)
 }
 
 func validateRuleSpec(
@@ -119,6 +337,12 @@ func validateRuleSpec(
 	if ruleSpec.Handler == nil {
 		return newValidateRuleSpecErrorf("Handler is not set for ID %q", ruleSpec.ID)
 	}
+	if err := validateDescriptorKinds(ruleSpec.DescriptorKinds); err != nil {
+		return newValidateRuleSpecErrorf("ID %q: %v", ruleSpec.ID, err)
+	}
+	if err := validateRuleProvenance(ruleSpec.Provenance); err != nil {
+		return newValidateRuleSpecErrorf("ID %q: %v", ruleSpec.ID, err)
+	}
 	if ruleSpec.IsDefault && ruleSpec.Deprecated {
 		return newValidateRuleSpecErrorf("ID %q was a default Rule but Deprecated was false", ruleSpec.ID)
 	}
@@ -134,12 +358,26 @@ func validateRuleSpec(
 			return newValidateRuleSpecErrorf("Deprecated ID %q specified replacement ID %q which also deprecated", ruleSpec.ID, replacementID)
 		}
 	}
+	for _, dependencyID := range ruleSpec.DependencyIDs {
+		if dependencyID == ruleSpec.ID {
+			return newValidateRuleSpecErrorf("ID %q specified itself as a dependency", ruleSpec.ID)
+		}
+		if _, ok := ruleIDToRuleSpec[dependencyID]; !ok {
+			return newValidateRuleSpecErrorf("ID %q specified dependency ID %q which was not found", ruleSpec.ID, dependencyID)
+		}
+	}
 	// We do this on the server-side only, this shouldn't be used client-side.
 	// TODO: This isn't working
 	return nil
 	// return validator.Validate(ruleSpecToRule(ruleSpec, emptyOptions).toProto())
 }
 
+func ruleSpecToRuleMetadata(ruleSpec *RuleSpec) ruleMetadata {
+	return ruleMetadata{
+		provenance: ruleSpec.Provenance,
+	}
+}
+
 func sortRuleSpecs(ruleSpecs []*RuleSpec) {
 	sort.Slice(ruleSpecs, func(i int, j int) bool { return compareRuleSpecs(ruleSpecs[i], ruleSpecs[j]) < 0 })
 }
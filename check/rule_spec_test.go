@@ -0,0 +1,63 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLintRule(t *testing.T) {
+	t.Parallel()
+
+	ruleSpec, err := NewLintRule("RULE1", "Checks things.", nopRuleHandler)
+	require.NoError(t, err)
+	require.Equal(t, "RULE1", ruleSpec.ID)
+	require.Equal(t, RuleTypeLint, ruleSpec.Type)
+	require.True(t, ruleSpec.IsDefault)
+	require.False(t, ruleSpec.Deprecated)
+
+	ruleSpec, err = NewBreakingRule(
+		"RULE2",
+		"Checks things.",
+		nopRuleHandler,
+		RuleSpecWithCategoryIDs("CATEGORY1"),
+		RuleSpecWithIsDefault(true),
+		RuleSpecWithDeprecated("RULE1"),
+	)
+	require.NoError(t, err)
+	require.Equal(t, RuleTypeBreaking, ruleSpec.Type)
+	require.Equal(t, []string{"CATEGORY1"}, ruleSpec.CategoryIDs)
+	require.Equal(t, []string{"RULE1"}, ruleSpec.ReplacementIDs)
+	require.True(t, ruleSpec.Deprecated)
+	// Deprecated Rules cannot be default Rules.
+	require.False(t, ruleSpec.IsDefault)
+
+	for _, id := range []string{"", "RUL", "rule1", "_RULE", "RULE_", "RU-LE"} {
+		_, err := NewLintRule(id, "Checks things.", nopRuleHandler)
+		require.Error(t, err, id)
+	}
+	for _, purpose := range []string{"", "checks things.", "Checks things"} {
+		_, err := NewLintRule("RULE1", purpose, nopRuleHandler)
+		require.Error(t, err, purpose)
+	}
+	_, err = NewLintRule("RULE1", "Checks things.", nil)
+	require.Error(t, err)
+	_, err = NewLintRule("RULE1", "Checks things.", nopRuleHandler, RuleSpecWithCategoryIDs("category"))
+	require.Error(t, err)
+	_, err = NewLintRule("RULE1", "Checks things.", nopRuleHandler, RuleSpecWithDeprecated("rule"))
+	require.Error(t, err)
+}
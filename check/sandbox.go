@@ -0,0 +1,43 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"os"
+)
+
+// *** PRIVATE ***
+
+// applySandbox applies the restrictions documented on MainWithSandbox to the current process.
+//
+// This is irreversible, and must only be called from Main.
+func applySandbox() error {
+	os.Clearenv()
+	dirPath, err := os.MkdirTemp("", "bufplugin-sandbox")
+	if err != nil {
+		return fmt.Errorf("sandbox: %w", err)
+	}
+	if err := os.Chdir(dirPath); err != nil {
+		return fmt.Errorf("sandbox: %w", err)
+	}
+	// Removing the working directory while we are in it means no files can be created relative
+	// to it. This may fail on some platforms, in which case we are left with an empty directory.
+	_ = os.Remove(dirPath)
+	if err := applyPlatformSandbox(); err != nil {
+		return fmt.Errorf("sandbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux && (amd64 || arm64)
+
+package check
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// *** PRIVATE ***
+
+// seccompDataArchOffset, seccompDataNrOffset, and seccompDataArg0Offset are the offsets
+// within struct seccomp_data. Both supported architectures are little-endian, so the low
+// 32 bits of the first argument are at seccompDataArg0Offset.
+const (
+	seccompDataNrOffset   = 0
+	seccompDataArchOffset = 4
+	seccompDataArg0Offset = 16
+	// x32SyscallBit is set on syscall numbers of the x32 ABI on amd64.
+	x32SyscallBit = 0x40000000
+)
+
+// applyPlatformSandbox installs a seccomp filter on all threads of the process that
+// denies the creation of IPv4 and IPv6 sockets with EACCES.
+func applyPlatformSandbox() error {
+	filter := []unix.SockFilter{
+		bpfStatement(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArchOffset),
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, sandboxAuditArch, 0, 7),
+		bpfStatement(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataNrOffset),
+		bpfJump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, x32SyscallBit, 5, 0),
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.SYS_SOCKET, 0, 3),
+		bpfStatement(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArg0Offset),
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.AF_INET, 2, 0),
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.AF_INET6, 1, 0),
+		bpfStatement(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ALLOW),
+		bpfStatement(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ERRNO|(uint32(unix.EACCES)&unix.SECCOMP_RET_DATA)),
+	}
+	// Required to install a seccomp filter without CAP_SYS_ADMIN.
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return err
+	}
+	program := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+	// The Go runtime has already started multiple threads, so the filter must be
+	// synchronized across all of them.
+	if _, _, errno := unix.Syscall(
+		unix.SYS_SECCOMP,
+		unix.SECCOMP_SET_MODE_FILTER,
+		unix.SECCOMP_FILTER_FLAG_TSYNC,
+		uintptr(unsafe.Pointer(&program)),
+	); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func bpfStatement(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func bpfJump(code uint16, k uint32, jt uint8, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
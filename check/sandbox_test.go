@@ -0,0 +1,59 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSandboxEnvKey = "BUFPLUGIN_TEST_SANDBOX_CHILD"
+
+func TestApplySandbox(t *testing.T) {
+	t.Parallel()
+
+	if os.Getenv(testSandboxEnvKey) != "" {
+		testApplySandboxChild(t)
+		return
+	}
+	// The sandbox is irreversible, so it is applied within a child test process.
+	cmd := exec.Command(os.Args[0], "-test.run=^TestApplySandbox$", "-test.v")
+	cmd.Env = append(os.Environ(), testSandboxEnvKey+"=1")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func testApplySandboxChild(t *testing.T) {
+	previousWorkingDirPath, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, applySandbox())
+	require.Empty(t, os.Environ())
+	workingDirPath, err := os.Getwd()
+	if err == nil {
+		require.NotEqual(t, previousWorkingDirPath, workingDirPath)
+	}
+	if runtime.GOOS == "linux" && (runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64") {
+		_, err := net.Listen("tcp", "127.0.0.1:0")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, syscall.EACCES), err.Error())
+	}
+}
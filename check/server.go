@@ -0,0 +1,238 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"github.com/bufbuild/pluginrpc-go"
+)
+
+// NewServer returns a new pluginrpc.Server that serves the given Spec.
+//
+// Main uses NewServer to serve a Spec over os.Args and stdio. Use NewServer directly to serve
+// a Spec over a custom transport, by calling Serve with a custom pluginrpc.Env.
+//
+// The Spec is validated.
+func NewServer(spec *Spec, options ...ServerOption) (pluginrpc.Server, error) {
+	checkServiceHandler, err := newCheckServiceHandlerForServerOptions(spec, options...)
+	if err != nil {
+		return nil, err
+	}
+	return newCheckServer(checkServiceHandler)
+}
+
+// ServerOption is an option for NewServer.
+type ServerOption func(*serverOptions)
+
+// ServerWithParallelism returns a new ServerOption that sets the parallelism by which Rules
+// will be run.
+//
+// If this is set to a value >= 1, this many concurrent Rules can be run at the same time.
+// A value of 0 indicates the default behavior, which is to use runtime.GOMAXPROCS(0).
+//
+// A value if < 0 has no effect.
+func ServerWithParallelism(parallelism int) ServerOption {
+	return func(serverOptions *serverOptions) {
+		if parallelism < 0 {
+			parallelism = 0
+		}
+		serverOptions.parallelism = parallelism
+	}
+}
+
+// ServerWithCoverageRecorder returns a new ServerOption that records the Files and
+// descriptors visited by each Rule to the given CoverageRecorder.
+//
+// See CoverageRecorder for more details. The default is to not record coverage.
+func ServerWithCoverageRecorder(recorder CoverageRecorder) ServerOption {
+	return func(serverOptions *serverOptions) {
+		serverOptions.coverageRecorder, _ = recorder.(*coverageRecorder)
+	}
+}
+
+// ServerWithMaxPageSize returns a new ServerOption that limits the number of Rules and
+// Categories returned per page by ListRules and ListCategories, regardless of the page size
+// requested by the client.
+//
+// This is primarily useful for testing the pagination behavior of clients. A value of 0
+// indicates the default behavior, which is to return the page size requested by the client.
+//
+// A value of < 0 has no effect.
+func ServerWithMaxPageSize(maxPageSize int) ServerOption {
+	return func(serverOptions *serverOptions) {
+		if maxPageSize < 0 {
+			maxPageSize = 0
+		}
+		serverOptions.maxPageSize = maxPageSize
+	}
+}
+
+// ServerWithRequestSnapshots returns a new ServerOption that, if a Check call fails, writes
+// the binary-serialized CheckRequest to a new file within the given directory, and references
+// the file in the returned error.
+//
+// This allows plugin failures on user schemas to be reproduced locally, by passing the file
+// on stdin to a plugin run with --once (see Main).
+//
+// If dirPath is empty, os.TempDir is used. When set, panics within RuleHandlers, Before, and
+// Finalize are recovered and returned as errors, so that the CheckRequest can be written
+// before the plugin exits. Errors due to the context being done do not result in a snapshot.
+func ServerWithRequestSnapshots(dirPath string) ServerOption {
+	return func(serverOptions *serverOptions) {
+		serverOptions.requestSnapshots = true
+		serverOptions.requestSnapshotDirPath = dirPath
+	}
+}
+
+// ServerWithRequestSnapshotRedactors returns a new ServerOption that applies the given
+// RequestRedactors to each CheckRequest written by ServerWithRequestSnapshots.
+//
+// This has no effect unless ServerWithRequestSnapshots is also set.
+func ServerWithRequestSnapshotRedactors(redactors ...RequestRedactor) ServerOption {
+	return func(serverOptions *serverOptions) {
+		serverOptions.requestSnapshotRedactors = append(serverOptions.requestSnapshotRedactors, redactors...)
+	}
+}
+
+// ServerWithLazyInit returns a new ServerOption that defers the full validation of the Spec,
+// including the initialization of the protovalidate Validator, until the first Check call.
+//
+// This reduces the latency of plugin invocations that do not run Rules, such as ListRules
+// and ListCategories, which matters when a plugin process is executed per call. The Spec
+// is still checked for duplicate Rule and Category IDs on construction, so that ListRules
+// and ListCategories never serve ambiguous IDs. Any other validation error is returned by
+// every Check call instead of by NewServer.
+//
+// Use NewLazyRuleHandler to also defer the setup of individual RuleHandlers.
+func ServerWithLazyInit() ServerOption {
+	return func(serverOptions *serverOptions) {
+		serverOptions.lazyInit = true
+	}
+}
+
+// ServerWithRequestCopies returns a new ServerOption that gives each RuleHandler, and
+// Finalize, its own deep copy of the FileDescriptorProtos of the Request.
+//
+// File.FileDescriptorProto is not a copy, and RuleHandlers must not modify it. However, a
+// RuleHandler that does so anyway affects every other Rule in the same Check call. This
+// protects the other Rules from such RuleHandlers, at the cost of copying the
+// FileDescriptorProtos once per Rule. The protoreflect FileDescriptors are immutable, and
+// are not copied.
+//
+// Values stored with LoadOrComputeForRequest, such as checkutil.SymbolIndexForRequest, are
+// shared between the copies. Values in the RequestStore that are keyed by the Request itself
+// are not, as each RuleHandler is given a different Request.
+//
+// The default is to share the Request between all RuleHandlers.
+func ServerWithRequestCopies() ServerOption {
+	return func(serverOptions *serverOptions) {
+		serverOptions.requestCopies = true
+	}
+}
+
+// ServerWithRequestMutationDetection returns a new ServerOption that results in an error
+// from Check if a RuleHandler or Finalize modifies the FileDescriptorProtos of the Request.
+//
+// This implies ServerWithRequestCopies, and additionally compares each copy against the
+// original Request after the RuleHandler returns. This is intended for testing, see
+// checktest.CheckTest.DetectRequestMutation.
+func ServerWithRequestMutationDetection() ServerOption {
+	return func(serverOptions *serverOptions) {
+		serverOptions.requestMutationDetection = true
+	}
+}
+
+// ServerWithShuffle returns a new ServerOption that shuffles the order in which Rules are
+// started, and the order of the Files and AgainstFiles of the Request given to Before,
+// RuleHandlers, and Finalize, using the given seed.
+//
+// Plugins should not depend on the order in which Rules are run or Files are iterated, but
+// may do so accidentally, for example by keeping the first of several duplicate declarations.
+// Shuffling flushes out such dependencies. Each Check call with the same seed shuffles in the
+// same way, so that a failure can be reproduced with the seed that caused it. Dependencies
+// given with RuleSpec.DependencyIDs are still run before the Rules that depend on them. This
+// is intended for testing, see checktest.CheckTest.DetectOrderDependence.
+func ServerWithShuffle(seed int64) ServerOption {
+	return func(serverOptions *serverOptions) {
+		serverOptions.shuffle = true
+		serverOptions.shuffleSeed = seed
+	}
+}
+
+// ServerWithAnnotationSink returns a new ServerOption that passes each Annotation added by
+// RuleHandlers and Finalize to the given AnnotationSink.
+//
+// This option can be given multiple times, in which case each AnnotationSink observes each
+// Annotation, in the order the options were given. See AnnotationSink for more details.
+func ServerWithAnnotationSink(annotationSink AnnotationSink) ServerOption {
+	return func(serverOptions *serverOptions) {
+		if annotationSink != nil {
+			serverOptions.annotationSinks = append(serverOptions.annotationSinks, annotationSink)
+		}
+	}
+}
+
+// *** PRIVATE ***
+
+// newCheckServiceHandlerForServerOptions returns a new checkServiceHandler for the Spec,
+// configured by the ServerOptions.
+//
+// This is used by NewServer, and by Main for one-shot mode, so that both serve with the
+// same configuration.
+func newCheckServiceHandlerForServerOptions(spec *Spec, options ...ServerOption) (*checkServiceHandler, error) {
+	serverOptions := newServerOptions()
+	for _, option := range options {
+		option(serverOptions)
+	}
+	checkServiceHandler, err := newCheckServiceHandler(spec, serverOptions.parallelism, serverOptions.lazyInit)
+	if err != nil {
+		return nil, err
+	}
+	checkServiceHandler.coverageRecorder = serverOptions.coverageRecorder
+	checkServiceHandler.annotationSinks = serverOptions.annotationSinks
+	checkServiceHandler.maxPageSize = serverOptions.maxPageSize
+	checkServiceHandler.requestCopies = serverOptions.requestCopies || serverOptions.requestMutationDetection
+	checkServiceHandler.requestMutationDetection = serverOptions.requestMutationDetection
+	if serverOptions.shuffle {
+		shuffleSeed := serverOptions.shuffleSeed
+		checkServiceHandler.shuffleSeed = &shuffleSeed
+	}
+	if serverOptions.requestSnapshots {
+		checkServiceHandler.requestSnapshotter = newRequestSnapshotter(
+			serverOptions.requestSnapshotDirPath,
+			serverOptions.requestSnapshotRedactors,
+		)
+	}
+	return checkServiceHandler, nil
+}
+
+type serverOptions struct {
+	parallelism int
+	maxPageSize int
+	// coverageRecorder is nil if coverage is not recorded.
+	coverageRecorder         *coverageRecorder
+	requestSnapshots         bool
+	requestSnapshotDirPath   string
+	requestSnapshotRedactors []RequestRedactor
+	lazyInit                 bool
+	requestCopies            bool
+	requestMutationDetection bool
+	shuffle                  bool
+	shuffleSeed              int64
+	annotationSinks          []AnnotationSink
+}
+
+func newServerOptions() *serverOptions {
+	return &serverOptions{}
+}
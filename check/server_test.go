@@ -0,0 +1,126 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bufbuild/pluginrpc-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	_, err := NewServer(&Spec{})
+	require.Error(t, err)
+	server, err := NewServer(testVerifierSpec(), ServerWithParallelism(1))
+	require.NoError(t, err)
+	client := NewClient(pluginrpc.NewClient(pluginrpc.NewServerRunner(server)))
+	rules, err := client.ListRules(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Equal(t, "RULE1", rules[0].ID())
+}
+
+func TestServerWithLazyInit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	// The Purpose is validated by the full validation of the Spec only.
+	spec := &Spec{
+		Rules: []*RuleSpec{
+			{
+				ID:        "RULE1",
+				IsDefault: true,
+				Type:      RuleTypeLint,
+				Handler:   nopRuleHandler,
+			},
+		},
+	}
+	_, err := NewServer(spec)
+	require.Error(t, err)
+	server, err := NewServer(spec, ServerWithLazyInit())
+	require.NoError(t, err)
+	client := NewClient(pluginrpc.NewClient(pluginrpc.NewServerRunner(server)))
+	rules, err := client.ListRules(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.ErrorContains(t, err, `Purpose is not set for ID "RULE1"`)
+	_, err = client.Check(ctx, request)
+	require.ErrorContains(t, err, `Purpose is not set for ID "RULE1"`)
+
+	// Duplicate IDs are always validated on construction.
+	spec.Rules = append(spec.Rules, spec.Rules[0])
+	_, err = NewServer(spec, ServerWithLazyInit())
+	require.Error(t, err)
+}
+
+func TestNewLazyRuleHandler(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var calls int
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule.",
+					Type:      RuleTypeLint,
+					Handler: NewLazyRuleHandler(
+						func() (RuleHandler, error) {
+							calls++
+							return RuleHandlerFunc(
+								func(_ context.Context, responseWriter ResponseWriter, _ Request) error {
+									responseWriter.AddAnnotation(WithMessage("lazy"))
+									return nil
+								},
+							), nil
+						},
+					),
+				},
+			},
+		},
+		ClientWithSpecServerOptions(ServerWithLazyInit()),
+	)
+	require.NoError(t, err)
+	_, err = client.ListRules(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, calls)
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	for range 2 {
+		response, err := client.Check(ctx, request)
+		require.NoError(t, err)
+		require.Len(t, response.Annotations(), 1)
+	}
+	require.Equal(t, 1, calls)
+
+	lazyRuleHandler := NewLazyRuleHandler(
+		func() (RuleHandler, error) {
+			return nil, errors.New("invalid pattern")
+		},
+	)
+	require.EqualError(t, lazyRuleHandler.Handle(ctx, nil, request), "invalid pattern")
+	require.EqualError(t, lazyRuleHandler.Handle(ctx, nil, request), "invalid pattern")
+}
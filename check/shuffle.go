@@ -0,0 +1,71 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// *** PRIVATE ***
+
+// newShuffleRand returns a new *rand.Rand for ServerWithShuffle with the given seed.
+//
+// A new *rand.Rand is used for each Check call, so that each Check call with the same
+// seed shuffles in the same way.
+func newShuffleRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(seed), 0))
+}
+
+// shuffleRequest returns a copy of the Request with the Files and AgainstFiles shuffled.
+func shuffleRequest(shuffleRand *rand.Rand, request Request) (Request, error) {
+	return newRequest(
+		shuffleFiles(shuffleRand, request.UnclonedFiles()),
+		WithAgainstFiles(shuffleFiles(shuffleRand, request.UnclonedAgainstFiles())),
+		WithOptions(request.Options()),
+		WithRuleIDs(request.RuleIDs()...),
+	)
+}
+
+// shuffleFiles returns a shuffled copy of the Files.
+//
+// The order of Files is not otherwise guaranteed, as FilesForProtoFiles returns Files in
+// the order of a protoregistry.Files, so the Files are sorted by path before being shuffled
+// so that the same seed results in the same order.
+func shuffleFiles(shuffleRand *rand.Rand, files []File) []File {
+	files = slices.Clone(files)
+	slices.SortFunc(
+		files,
+		func(one File, two File) int {
+			return strings.Compare(one.FileDescriptor().Path(), two.FileDescriptor().Path())
+		},
+	)
+	return shuffleSlice(shuffleRand, files)
+}
+
+// shuffleSlice returns a shuffled copy of the slice.
+//
+// Returns nil if the slice is nil.
+func shuffleSlice[T any](shuffleRand *rand.Rand, s []T) []T {
+	s = slices.Clone(s)
+	shuffleRand.Shuffle(
+		len(s),
+		func(i int, j int) {
+			s[i], s[j] = s[j], s[i]
+		},
+	)
+	return s
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerWithShuffle(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var protoFiles []*checkv1beta1.File
+	for _, fileName := range []string{"a.proto", "b.proto", "c.proto", "d.proto", "e.proto"} {
+		protoFiles = append(
+			protoFiles,
+			&checkv1beta1.File{
+				FileDescriptorProto: testNewSuppressionFileDescriptorProto(fileName, "Foo"),
+			},
+		)
+	}
+	files, err := FilesForProtoFiles(protoFiles)
+	require.NoError(t, err)
+	request, err := NewRequest(files)
+	require.NoError(t, err)
+
+	// getOrders returns the order in which the Rules were run, and the order of the Files
+	// that the first Rule saw.
+	getOrders := func(serverOptions ...ServerOption) ([]string, []string) {
+		var lock sync.Mutex
+		var ruleIDs []string
+		var fileNames []string
+		var ruleSpecs []*RuleSpec
+		for _, ruleID := range []string{"RULE1", "RULE2", "RULE3", "RULE4", "RULE5"} {
+			ruleSpec := testNewSimpleLintRuleSpec(ruleID, nil, true, false, nil)
+			ruleSpec.Handler = RuleHandlerFunc(
+				func(_ context.Context, _ ResponseWriter, request Request) error {
+					lock.Lock()
+					defer lock.Unlock()
+					if len(ruleIDs) == 0 {
+						for _, file := range request.Files() {
+							fileNames = append(fileNames, file.FileDescriptor().Path())
+						}
+					}
+					ruleIDs = append(ruleIDs, ruleID)
+					return nil
+				},
+			)
+			ruleSpecs = append(ruleSpecs, ruleSpec)
+		}
+		// RULE1 must always run after RULE2.
+		ruleSpecs[0].DependencyIDs = []string{"RULE2"}
+		client, err := NewClientForSpec(
+			&Spec{Rules: ruleSpecs},
+			ClientWithSpecServerOptions(
+				slices.Concat([]ServerOption{ServerWithParallelism(1)}, serverOptions)...,
+			),
+		)
+		require.NoError(t, err)
+		_, err = client.Check(ctx, request)
+		require.NoError(t, err)
+		return ruleIDs, fileNames
+	}
+
+	unshuffledRuleIDs, unshuffledFileNames := getOrders()
+	require.Equal(t, []string{"RULE2", "RULE1", "RULE3", "RULE4", "RULE5"}, unshuffledRuleIDs)
+	// The order of Files is not guaranteed without shuffling.
+	require.ElementsMatch(t, []string{"a.proto", "b.proto", "c.proto", "d.proto", "e.proto"}, unshuffledFileNames)
+	var ruleIDsShuffled bool
+	var fileNamesShuffled bool
+	for seed := range int64(10) {
+		ruleIDs, fileNames := getOrders(ServerWithShuffle(seed))
+		require.ElementsMatch(t, unshuffledRuleIDs, ruleIDs)
+		require.Less(t, slices.Index(ruleIDs, "RULE2"), slices.Index(ruleIDs, "RULE1"))
+		require.ElementsMatch(t, unshuffledFileNames, fileNames)
+		ruleIDsShuffled = ruleIDsShuffled || !slices.Equal(unshuffledRuleIDs, ruleIDs)
+		fileNamesShuffled = fileNamesShuffled || !slices.IsSorted(fileNames)
+		// The same seed shuffles in the same way.
+		sameSeedRuleIDs, sameSeedFileNames := getOrders(ServerWithShuffle(seed))
+		require.Equal(t, ruleIDs, sameSeedRuleIDs)
+		require.Equal(t, fileNames, sameSeedFileNames)
+	}
+	require.True(t, ruleIDsShuffled)
+	require.True(t, fileNamesShuffled)
+}
@@ -16,6 +16,7 @@ package check
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/bufbuild/bufplugin-go/internal/pkg/xslices"
 	"github.com/bufbuild/protovalidate-go"
@@ -41,32 +42,87 @@ type Spec struct {
 	//
 	// No IDs can overlap with Rule IDs in Rules.
 	Categories []*CategorySpec
+	// DefaultOptions are the default option values for the plugin.
+	//
+	// DefaultOptions are merged under the Options of each Request before Before and any
+	// RuleHandlers are invoked, with the Options of the Request taking precedence. This allows
+	// the defaults of a plugin to be declared in one place, and read by RuleHandlers through
+	// Request.Options as if they had been set by the caller.
+	//
+	// Keys and values must satisfy the same constraints as NewOptions.
+	//
+	// Optional.
+	DefaultOptions map[string]any
+	// UnknownFilePolicy is the policy for Annotations that RuleHandlers and Finalize add for
+	// files that are not in the Request, for example for descriptors resolved through imports.
+	//
+	// If UnknownFilePolicyFileNameOnly is used, Clients must use ClientWithUnknownFilePolicy
+	// with the same policy to accept the returned Annotations. NewClientForSpec does this
+	// automatically.
+	//
+	// The default is UnknownFilePolicyError.
+	UnknownFilePolicy UnknownFilePolicy
 
 	// Before is a function that will be executed before any RuleHandlers are
 	// invoked that returns a new Context and Request. This new Context and
 	// Request will be passed to the RuleHandlers. This allows for any
 	// pre-processing that needs to occur.
+	//
+	// The Context passed to Before contains the RequestStore for the Check call, see
+	// RequestStoreFromContext.
 	Before func(ctx context.Context, request Request) (context.Context, Request, error)
+
+	// Finalize is a function that will be executed after all RuleHandlers have completed, but
+	// before the Response is built. This allows for a second pass over the results, for example
+	// to add summary Annotations after all Rules have run.
+	//
+	// Finalize is given the Annotations added by the RuleHandlers, sorted, and a
+	// FinalizeResponseWriter that can add further Annotations for any Rule that was run as part
+	// of the Request. Once Finalize returns, the Response is sealed.
+	Finalize func(
+		ctx context.Context,
+		responseWriter FinalizeResponseWriter,
+		request Request,
+		annotations []Annotation,
+	) error
 }
 
 // *** PRIVATE ***
 
 func validateSpec(validator *protovalidate.Validator, spec *Spec) error {
+	if err := validateSpecIDs(spec); err != nil {
+		return err
+	}
+	categoryIDs := xslices.Map(spec.Categories, func(categorySpec *CategorySpec) string { return categorySpec.ID })
+	if err := validateKeyToValue(spec.DefaultOptions); err != nil {
+		return wrapValidateSpecError(fmt.Errorf("DefaultOptions: %w", err))
+	}
+	if err := validateUnknownFilePolicy(spec.UnknownFilePolicy); err != nil {
+		return wrapValidateSpecError(err)
+	}
+	categoryIDMap := xslices.ToStructMap(categoryIDs)
+	if err := validateRuleSpecs(validator, spec.Rules, categoryIDMap); err != nil {
+		return err
+	}
+	return validateCategorySpecs(validator, spec.Categories, spec.Rules)
+}
+
+// validateSpecIDs validates that the Spec has Rules, and that the Rule and Category IDs are
+// unique.
+//
+// This is the validation required to serve ListRules and ListCategories, and is a subset of
+// validateSpec.
+func validateSpecIDs(spec *Spec) error {
 	if len(spec.Rules) == 0 {
 		return newValidateSpecError("Rules is empty")
 	}
-	categoryIDs := xslices.Map(spec.Categories, func(categorySpec *CategorySpec) string { return categorySpec.ID })
 	if err := validateNoDuplicateRuleOrCategoryIDs(
 		append(
 			xslices.Map(spec.Rules, func(ruleSpec *RuleSpec) string { return ruleSpec.ID }),
-			categoryIDs...,
+			xslices.Map(spec.Categories, func(categorySpec *CategorySpec) string { return categorySpec.ID })...,
 		),
 	); err != nil {
 		return wrapValidateSpecError(err)
 	}
-	categoryIDMap := xslices.ToStructMap(categoryIDs)
-	if err := validateRuleSpecs(validator, spec.Rules, categoryIDMap); err != nil {
-		return err
-	}
-	return validateCategorySpecs(validator, spec.Categories, spec.Rules)
+	return nil
 }
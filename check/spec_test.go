@@ -15,16 +15,27 @@
 package check
 
 import (
+	"context"
 	"testing"
 
-	"github.com/bufbuild/protovalidate-go"
 	"github.com/stretchr/testify/require"
 )
 
+func TestGetValidator(t *testing.T) {
+	t.Parallel()
+
+	validator, err := getValidator()
+	require.NoError(t, err)
+	otherValidator, err := getValidator()
+	require.NoError(t, err)
+	// The Validator is shared within the process.
+	require.Same(t, validator, otherValidator)
+}
+
 func TestValidateSpec(t *testing.T) {
 	t.Parallel()
 
-	validator, err := protovalidate.New()
+	validator, err := getValidator()
 	require.NoError(t, err)
 
 	validateRuleSpecError := &validateRuleSpecError{}
@@ -139,6 +150,66 @@ func TestValidateSpec(t *testing.T) {
 		},
 	}
 	require.ErrorAs(t, validateSpec(validator, spec), &validateCategorySpecError)
+
+	// Invalid DefaultOptions key.
+	spec = &Spec{
+		Rules: []*RuleSpec{
+			testNewSimpleLintRuleSpec("rule1", nil, true, false, nil),
+		},
+		DefaultOptions: map[string]any{
+			"Invalid": "value",
+		},
+	}
+	require.ErrorAs(t, validateSpec(validator, spec), &validateSpecError)
+}
+
+func TestSpecDefaultOptions(t *testing.T) {
+	t.Parallel()
+
+	var keyToValue map[string]any
+	client, err := NewClientForSpec(
+		&Spec{
+			Rules: []*RuleSpec{
+				{
+					ID:        "RULE1",
+					IsDefault: true,
+					Purpose:   "Test rule1.",
+					Type:      RuleTypeLint,
+					Handler: RuleHandlerFunc(
+						func(_ context.Context, _ ResponseWriter, request Request) error {
+							keyToValue = make(map[string]any)
+							request.Options().Range(
+								func(key string, value any) {
+									keyToValue[key] = value
+								},
+							)
+							return nil
+						},
+					),
+				},
+			},
+			DefaultOptions: map[string]any{
+				"suffix":     "_time",
+				"max_length": int64(10),
+			},
+		},
+	)
+	require.NoError(t, err)
+	ctx := context.Background()
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"suffix": "_time", "max_length": int64(10)}, keyToValue)
+
+	// Options of the Request take precedence.
+	options, err := NewOptions(map[string]any{"suffix": "_at", "other": true})
+	require.NoError(t, err)
+	request, err = NewRequest(nil, WithOptions(options))
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"suffix": "_at", "max_length": int64(10), "other": true}, keyToValue)
 }
 
 func testNewSimpleLintRuleSpec(
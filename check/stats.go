@@ -0,0 +1,92 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"maps"
+)
+
+// Stats are summary statistics for the Annotations of a Response.
+//
+// Stats are computed by the client from the Annotations of the Response, at most once per
+// Response. They are not carried by the wire protocol.
+//
+// There is no breakdown by severity, as Annotations do not have a severity. Hosts that
+// assign severities, for example per Rule ID, can derive them from RuleIDToCount.
+type Stats interface {
+	// TotalCount returns the total number of Annotations.
+	TotalCount() int
+	// RuleIDToCount returns the number of Annotations per Rule ID.
+	RuleIDToCount() map[string]int
+	// FileNameToCount returns the number of Annotations per file name of the Location.
+	//
+	// Annotations without a Location are counted under the empty file name.
+	FileNameToCount() map[string]int
+	// CategoryIDToCount returns the number of Annotations per Category ID.
+	//
+	// An Annotation is counted once for each of its RuleCategories. This will be empty
+	// if RuleCategories were not populated, see CheckCallWithRuleCategories.
+	CategoryIDToCount() map[string]int
+
+	isStats()
+}
+
+// *** PRIVATE ***
+
+type stats struct {
+	totalCount        int
+	ruleIDToCount     map[string]int
+	fileNameToCount   map[string]int
+	categoryIDToCount map[string]int
+}
+
+func newStats(annotations []Annotation) *stats {
+	stats := &stats{
+		totalCount:        len(annotations),
+		ruleIDToCount:     make(map[string]int),
+		fileNameToCount:   make(map[string]int),
+		categoryIDToCount: make(map[string]int),
+	}
+	for _, annotation := range annotations {
+		stats.ruleIDToCount[annotation.RuleID()]++
+		var fileName string
+		if location := annotation.Location(); location != nil {
+			fileName = location.File().FileDescriptor().Path()
+		}
+		stats.fileNameToCount[fileName]++
+		for _, category := range annotation.RuleCategories() {
+			stats.categoryIDToCount[category.ID()]++
+		}
+	}
+	return stats
+}
+
+func (s *stats) TotalCount() int {
+	return s.totalCount
+}
+
+func (s *stats) RuleIDToCount() map[string]int {
+	return maps.Clone(s.ruleIDToCount)
+}
+
+func (s *stats) FileNameToCount() map[string]int {
+	return maps.Clone(s.fileNameToCount)
+}
+
+func (s *stats) CategoryIDToCount() map[string]int {
+	return maps.Clone(s.categoryIDToCount)
+}
+
+func (*stats) isStats() {}
@@ -0,0 +1,121 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// UnknownFilePolicyError results in an error for the Check call if an Annotation is added
+	// for a file that is not in the Request.
+	//
+	// This is the default.
+	UnknownFilePolicyError UnknownFilePolicy = 0
+	// UnknownFilePolicyDropLocation results in Annotations for files that are not in the
+	// Request being added without the Location.
+	UnknownFilePolicyDropLocation UnknownFilePolicy = 1
+	// UnknownFilePolicyFileNameOnly results in Annotations for files that are not in the
+	// Request being added with a whole-file Location that only has the file name.
+	//
+	// The File of the Location is built from the descriptor if the Annotation was added with
+	// WithDescriptor, and is an empty placeholder File otherwise. The File is an import, and
+	// the Location never has a SourcePath or position.
+	UnknownFilePolicyFileNameOnly UnknownFilePolicy = 2
+)
+
+var unknownFilePolicyToString = map[UnknownFilePolicy]string{
+	UnknownFilePolicyError:        "error",
+	UnknownFilePolicyDropLocation: "drop_location",
+	UnknownFilePolicyFileNameOnly: "file_name_only",
+}
+
+// UnknownFilePolicy is the policy for Annotations that are added for files that are not in
+// the Request.
+//
+// RuleHandlers may annotate descriptors that were resolved through imports, such as the
+// message type of a field, whose files are not part of the Request. By default, this results
+// in an error for the whole Check call. The policy is set with Spec.UnknownFilePolicy within
+// plugins, and with ClientWithUnknownFilePolicy for Annotations returned by plugins.
+type UnknownFilePolicy int
+
+// String implements fmt.Stringer.
+func (p UnknownFilePolicy) String() string {
+	if s, ok := unknownFilePolicyToString[p]; ok {
+		return s
+	}
+	return strconv.Itoa(int(p))
+}
+
+// *** PRIVATE ***
+
+func validateUnknownFilePolicy(unknownFilePolicy UnknownFilePolicy) error {
+	if _, ok := unknownFilePolicyToString[unknownFilePolicy]; !ok {
+		return fmt.Errorf("unknown UnknownFilePolicy: %v", unknownFilePolicy)
+	}
+	return nil
+}
+
+// getLocationForUnknownFile returns the Location for an Annotation for a file that is not in
+// the Request per the UnknownFilePolicy.
+//
+// The fileDescriptor may be nil if the Annotation was added by file name. isAgainst is true
+// if the file was given for the against Location.
+func getLocationForUnknownFile(
+	unknownFilePolicy UnknownFilePolicy,
+	fileName string,
+	fileDescriptor protoreflect.FileDescriptor,
+	isAgainst bool,
+) (Location, error) {
+	switch unknownFilePolicy {
+	case UnknownFilePolicyDropLocation:
+		return nil, nil
+	case UnknownFilePolicyFileNameOnly:
+		file, err := newUnknownFile(fileName, fileDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		return newLocation(file, nil, protoreflect.SourceLocation{}), nil
+	default:
+		return nil, &UnknownFileError{
+			FileName:      fileName,
+			IsAgainstFile: isAgainst,
+		}
+	}
+}
+
+// newUnknownFile returns a new File for a file that is not in the Request.
+//
+// The fileDescriptor may be nil, in which case an empty File with the file name is returned.
+func newUnknownFile(fileName string, fileDescriptor protoreflect.FileDescriptor) (File, error) {
+	if fileDescriptor == nil {
+		fileDescriptorProto := &descriptorpb.FileDescriptorProto{
+			Name: proto.String(fileName),
+		}
+		var err error
+		fileDescriptor, err = protodesc.NewFile(fileDescriptorProto, nil)
+		if err != nil {
+			return nil, err
+		}
+		return newFile(fileDescriptor, fileDescriptorProto, true, false, nil), nil
+	}
+	return newFile(fileDescriptor, protodesc.ToFileDescriptorProto(fileDescriptor), true, false, nil), nil
+}
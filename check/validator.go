@@ -0,0 +1,41 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"sync"
+
+	checkv1beta1 "buf.build/gen/go/bufbuild/bufplugin/protocolbuffers/go/buf/plugin/check/v1beta1"
+	"github.com/bufbuild/protovalidate-go"
+)
+
+// *** PRIVATE ***
+
+// getValidator returns the protovalidate.Validator for the process.
+//
+// Constructing a Validator builds a CEL environment, which is expensive relative to the
+// lifetime of a plugin invoked once per Check call. A single Validator is therefore shared
+// by all Specs, servers, and Clients within the process. The Validator is warmed with the
+// messages that Specs are validated against, and is safe for concurrent use.
+var getValidator = sync.OnceValues(
+	func() (*protovalidate.Validator, error) {
+		return protovalidate.New(
+			protovalidate.WithMessages(
+				&checkv1beta1.Rule{},
+				&checkv1beta1.Category{},
+			),
+		)
+	},
+)
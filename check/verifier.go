@@ -0,0 +1,276 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/pluginrpc-go"
+)
+
+// Verifier verifies a plugin before it is invoked by a Client.
+//
+// Verifiers are used by supply-chain-conscious hosts to ensure that a plugin matches a known
+// signature before it is executed, for example with sigstore, by implementing a Verifier with
+// the appropriate library. To verify the digest of a plugin binary, use NewSHA256Runner or
+// NewSHA256ManifestRunner instead, which verify the exact file that is executed.
+type Verifier interface {
+	// Verify returns error if the plugin should not be invoked.
+	Verify(ctx context.Context) error
+}
+
+// VerifierFunc is a function that implements Verifier.
+type VerifierFunc func(ctx context.Context) error
+
+// Verify implements Verifier.
+func (v VerifierFunc) Verify(ctx context.Context) error {
+	return v(ctx)
+}
+
+// ClientWithVerifier returns a new ClientOption that will result in the Verifier being called
+// before the plugin is first invoked.
+//
+// If the Verifier returns an error, the plugin is not invoked, and the error is returned from the
+// Client call. Verification is retried on the next call. Once the Verifier succeeds, it is not
+// called again for the lifetime of the Client.
+//
+// The Verifier does not execute the plugin itself, and the Client does not re-verify before
+// each invocation. A binary that is replaced on disk after verification will be executed
+// unverified. To verify the digest of the binary that is executed, use NewSHA256Runner or
+// NewSHA256ManifestRunner.
+func ClientWithVerifier(verifier Verifier) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.verifier = verifier
+	}
+}
+
+// NewSHA256Runner returns a new pluginrpc.Runner that runs the binary at binaryPath only if it
+// has the given hex-encoded SHA-256 digest.
+//
+// On every run, the binary is copied to a new temporary directory that only the current user
+// can write to, and the copy is hashed as it is written. The copy is then executed if its digest
+// matches, and removed afterwards. The file that is executed is therefore the file that was
+// verified, even if the file at binaryPath is replaced concurrently.
+//
+// The ExecRunnerOptions are passed to the pluginrpc.NewExecRunner that runs the copy.
+//
+// Returns error if the digest is invalid.
+func NewSHA256Runner(
+	binaryPath string,
+	hexDigest string,
+	options ...pluginrpc.ExecRunnerOption,
+) (pluginrpc.Runner, error) {
+	return newSHA256Runner(binaryPath, hexDigest, options...)
+}
+
+// NewSHA256ManifestRunner returns a new pluginrpc.Runner that runs the binary at binaryPath
+// only if it has the SHA-256 digest listed for it in the given manifest, as with
+// NewSHA256Runner.
+//
+// The manifest is in the format produced by sha256sum, that is one "<hex digest>  <file path>"
+// entry per line. The file paths of the entries are relative to manifestDirPath, which is
+// typically the directory that sha256sum was run in. The entry is looked up by the path of
+// binaryPath relative to manifestDirPath, so that binaries with the same name in different
+// directories do not collide.
+//
+// Returns error if the manifest is invalid or has no entry for binaryPath.
+func NewSHA256ManifestRunner(
+	binaryPath string,
+	manifestDirPath string,
+	manifestData []byte,
+	options ...pluginrpc.ExecRunnerOption,
+) (pluginrpc.Runner, error) {
+	filePathToHexDigest, err := parseSHA256Manifest(manifestData)
+	if err != nil {
+		return nil, err
+	}
+	relFilePath, err := filepath.Rel(manifestDirPath, binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	relFilePath = filepath.ToSlash(relFilePath)
+	if relFilePath == ".." || strings.HasPrefix(relFilePath, "../") {
+		return nil, fmt.Errorf("%q is not within manifest directory %q", binaryPath, manifestDirPath)
+	}
+	hexDigest, ok := filePathToHexDigest[relFilePath]
+	if !ok {
+		return nil, fmt.Errorf("no entry for %q in manifest", relFilePath)
+	}
+	return newSHA256Runner(binaryPath, hexDigest, options...)
+}
+
+// *** PRIVATE ***
+
+// onceVerifier calls a set of Verifiers, in order, until they all succeed.
+//
+// After that, the plugin is executed without verification on every call. This is a
+// time-of-check to time-of-use gap that is documented on ClientWithVerifier.
+type onceVerifier struct {
+	verifiers []Verifier
+	verified  bool
+	lock      sync.Mutex
+}
+
+func newOnceVerifier(verifiers ...Verifier) *onceVerifier {
+	return &onceVerifier{
+		verifiers: verifiers,
+	}
+}
+
+func (o *onceVerifier) Verify(ctx context.Context) error {
+	if o == nil {
+		return nil
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.verified {
+		return nil
+	}
+	for _, verifier := range o.verifiers {
+		if err := verifier.Verify(ctx); err != nil {
+			return err
+		}
+	}
+	o.verified = true
+	return nil
+}
+
+func newPluginVerifier(verifier Verifier) Verifier {
+	return VerifierFunc(
+		func(ctx context.Context) error {
+			if err := verifier.Verify(ctx); err != nil {
+				return fmt.Errorf("plugin verification failed: %w", err)
+			}
+			return nil
+		},
+	)
+}
+
+type sha256Runner struct {
+	binaryPath        string
+	digest            []byte
+	execRunnerOptions []pluginrpc.ExecRunnerOption
+}
+
+func newSHA256Runner(
+	binaryPath string,
+	hexDigest string,
+	execRunnerOptions ...pluginrpc.ExecRunnerOption,
+) (*sha256Runner, error) {
+	digest, err := hex.DecodeString(hexDigest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHA-256 digest %q: %w", hexDigest, err)
+	}
+	if len(digest) != sha256.Size {
+		return nil, fmt.Errorf("invalid SHA-256 digest %q: expected %d bytes", hexDigest, sha256.Size)
+	}
+	return &sha256Runner{
+		binaryPath:        binaryPath,
+		digest:            digest,
+		execRunnerOptions: execRunnerOptions,
+	}, nil
+}
+
+func (s *sha256Runner) Run(ctx context.Context, env pluginrpc.Env) (retErr error) {
+	// os.MkdirTemp creates the directory with permissions 0700.
+	dirPath, err := os.MkdirTemp("", "bufplugin-verified-")
+	if err != nil {
+		return err
+	}
+	defer func() { retErr = errors.Join(retErr, os.RemoveAll(dirPath)) }()
+	// The base name is kept, as some platforms determine how to execute a file by its extension.
+	copyFilePath := filepath.Join(dirPath, filepath.Base(s.binaryPath))
+	if err := copyAndVerifySHA256(s.binaryPath, copyFilePath, s.digest); err != nil {
+		return fmt.Errorf("plugin verification failed: %w", err)
+	}
+	return pluginrpc.NewExecRunner(copyFilePath, s.execRunnerOptions...).Run(ctx, env)
+}
+
+// copyAndVerifySHA256 copies the file at filePath to the new file at copyFilePath, and verifies
+// that the copy has the expected digest.
+//
+// The digest is computed from the data written to the copy, not from the file at filePath.
+func copyAndVerifySHA256(filePath string, copyFilePath string, expectedDigest []byte) (retErr error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() { retErr = errors.Join(retErr, file.Close()) }()
+	copyFile, err := os.OpenFile(copyFilePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o700)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(copyFile, hash), file)
+	if err = errors.Join(err, copyFile.Close()); err != nil {
+		return err
+	}
+	if actualDigest := hash.Sum(nil); !bytes.Equal(actualDigest, expectedDigest) {
+		return fmt.Errorf(
+			"SHA-256 digest of %q is %s, expected %s",
+			filePath,
+			hex.EncodeToString(actualDigest),
+			hex.EncodeToString(expectedDigest),
+		)
+	}
+	return nil
+}
+
+func parseSHA256Manifest(data []byte) (map[string]string, error) {
+	filePathToHexDigest := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		hexDigest, filePath, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid manifest line %d: %q", lineNumber, line)
+		}
+		// sha256sum prefixes the file path with "*" in binary mode, and " " in text mode.
+		filePath = strings.TrimPrefix(strings.TrimPrefix(filePath, " "), "*")
+		if filePath == "" {
+			return nil, fmt.Errorf("invalid manifest line %d: %q", lineNumber, line)
+		}
+		// For example, "./bin/plugin" and "bin/plugin" are the same entry.
+		filePath = path.Clean(filePath)
+		if _, ok := filePathToHexDigest[filePath]; ok {
+			return nil, fmt.Errorf("duplicate manifest entry for %q", filePath)
+		}
+		filePathToHexDigest[filePath] = hexDigest
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(filePathToHexDigest) == 0 {
+		return nil, errors.New("manifest is empty")
+	}
+	return filePathToHexDigest, nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/pluginrpc-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientWithVerifier(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	verifyErr := errors.New("verify error")
+	var verifyCount int
+	client, err := NewClientForSpec(
+		testVerifierSpec(),
+		ClientWithVerifier(
+			VerifierFunc(
+				func(context.Context) error {
+					verifyCount++
+					if verifyCount == 1 {
+						return verifyErr
+					}
+					return nil
+				},
+			),
+		),
+	)
+	require.NoError(t, err)
+	_, err = client.ListRules(ctx)
+	require.ErrorIs(t, err, verifyErr)
+	// Verification is retried until it succeeds, and then is not called again.
+	_, err = client.ListRules(ctx)
+	require.NoError(t, err)
+	request, err := NewRequest(nil)
+	require.NoError(t, err)
+	_, err = client.Check(ctx, request)
+	require.NoError(t, err)
+	require.Equal(t, 2, verifyCount)
+}
+
+func TestSHA256Runners(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("test plugins are shell scripts")
+	}
+	ctx := context.Background()
+	dirPath := t.TempDir()
+	// The plugins print the path they were executed from and their name.
+	binaryPath := testWriteSHA256RunnerPlugin(t, filepath.Join(dirPath, "a", "buf-plugin-test"), "a")
+	otherBinaryPath := testWriteSHA256RunnerPlugin(t, filepath.Join(dirPath, "b", "buf-plugin-test"), "b")
+	hexDigest := testSHA256HexDigestForFile(t, binaryPath)
+	otherHexDigest := testSHA256HexDigestForFile(t, otherBinaryPath)
+
+	runner, err := NewSHA256Runner(binaryPath, hexDigest)
+	require.NoError(t, err)
+	executedPath, name, err := testRunSHA256RunnerPlugin(ctx, runner)
+	require.NoError(t, err)
+	require.Equal(t, "a", name)
+	// The verified copy is executed, not the file at binaryPath.
+	require.NotEqual(t, binaryPath, executedPath)
+	require.Equal(t, "buf-plugin-test", filepath.Base(executedPath))
+	_, err = os.Stat(executedPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	// The binary is verified on every run.
+	require.NoError(t, os.Rename(otherBinaryPath, binaryPath))
+	_, _, err = testRunSHA256RunnerPlugin(ctx, runner)
+	require.ErrorContains(t, err, "plugin verification failed")
+	require.ErrorContains(t, err, "expected "+hexDigest)
+	_, err = NewSHA256Runner(binaryPath, "invalid")
+	require.Error(t, err)
+	runner, err = NewSHA256Runner(binaryPath+"-missing", hexDigest)
+	require.NoError(t, err)
+	_, _, err = testRunSHA256RunnerPlugin(ctx, runner)
+	require.Error(t, err)
+
+	// Both plugins have the same file name, so entries must be matched by their relative path.
+	binaryPath = testWriteSHA256RunnerPlugin(t, filepath.Join(dirPath, "a", "buf-plugin-test"), "a")
+	otherBinaryPath = testWriteSHA256RunnerPlugin(t, filepath.Join(dirPath, "b", "buf-plugin-test"), "b")
+	manifestData := []byte(fmt.Sprintf("%s  a/buf-plugin-test\n%s *./b/buf-plugin-test\n", hexDigest, otherHexDigest))
+	runner, err = NewSHA256ManifestRunner(otherBinaryPath, dirPath, manifestData)
+	require.NoError(t, err)
+	_, name, err = testRunSHA256RunnerPlugin(ctx, runner)
+	require.NoError(t, err)
+	require.Equal(t, "b", name)
+	runner, err = NewSHA256ManifestRunner(binaryPath, dirPath, manifestData)
+	require.NoError(t, err)
+	_, name, err = testRunSHA256RunnerPlugin(ctx, runner)
+	require.NoError(t, err)
+	require.Equal(t, "a", name)
+	runner, err = NewSHA256ManifestRunner(
+		binaryPath,
+		dirPath,
+		[]byte(fmt.Sprintf("%s  a/buf-plugin-test\n", otherHexDigest)),
+	)
+	require.NoError(t, err)
+	_, _, err = testRunSHA256RunnerPlugin(ctx, runner)
+	require.ErrorContains(t, err, "plugin verification failed")
+	_, err = NewSHA256ManifestRunner(binaryPath, dirPath, []byte(fmt.Sprintf("%s  buf-plugin-test\n", hexDigest)))
+	require.ErrorContains(t, err, `no entry for "a/buf-plugin-test"`)
+	_, err = NewSHA256ManifestRunner(binaryPath, filepath.Join(dirPath, "b"), manifestData)
+	require.ErrorContains(t, err, "is not within manifest directory")
+	_, err = NewSHA256ManifestRunner(binaryPath, dirPath, nil)
+	require.Error(t, err)
+	_, err = NewSHA256ManifestRunner(binaryPath, dirPath, []byte(hexDigest))
+	require.Error(t, err)
+	_, err = NewSHA256ManifestRunner(
+		binaryPath,
+		dirPath,
+		[]byte(fmt.Sprintf("%s  a/buf-plugin-test\n%s  ./a/buf-plugin-test\n", hexDigest, hexDigest)),
+	)
+	require.ErrorContains(t, err, "duplicate manifest entry")
+}
+
+func testVerifierSpec() *Spec {
+	return &Spec{
+		Rules: []*RuleSpec{
+			{
+				ID:        "RULE1",
+				IsDefault: true,
+				Purpose:   "Test rule.",
+				Type:      RuleTypeLint,
+				Handler:   nopRuleHandler,
+			},
+		},
+	}
+}
+
+func testWriteSHA256RunnerPlugin(t *testing.T, binaryPath string, name string) string {
+	require.NoError(t, os.MkdirAll(filepath.Dir(binaryPath), 0o755))
+	require.NoError(t, os.WriteFile(binaryPath, []byte("#!/bin/sh\necho \"$0\" "+name+"\n"), 0o700))
+	return binaryPath
+}
+
+func testSHA256HexDigestForFile(t *testing.T, filePath string) string {
+	data, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
+
+// testRunSHA256RunnerPlugin runs a plugin written by testWriteSHA256RunnerPlugin, and returns
+// the path it was executed from and its name.
+func testRunSHA256RunnerPlugin(ctx context.Context, runner pluginrpc.Runner) (string, string, error) {
+	stdout := bytes.NewBuffer(nil)
+	if err := runner.Run(ctx, pluginrpc.Env{Stdout: stdout}); err != nil {
+		return "", "", err
+	}
+	executedPath, name, _ := strings.Cut(strings.TrimSpace(stdout.String()), " ")
+	return executedPath, name, nil
+}
@@ -0,0 +1,32 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements bufplugin-gen-rule-ids, a generator of Go constants for the Rule IDs
+// of a plugin.
+//
+// bufplugin-gen-rule-ids invokes a plugin binary, lists its Rules, and writes a Go file with a
+// typed constant for each Rule ID:
+//
+//	bufplugin-gen-rule-ids --plugin ./buf-plugin-timestamp-suffix --package timestampsuffix --output rule_ids.gen.go
+//
+// See checkgen.Main for all flags.
+package main
+
+import (
+	"github.com/bufbuild/bufplugin-go/check/checkgen"
+)
+
+func main() {
+	checkgen.Main()
+}
@@ -0,0 +1,31 @@
+// Copyright 2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements bufplugin-run, a local runner for plugins.
+//
+// bufplugin-run compiles .proto files, invokes a plugin binary against them, and prints the
+// resulting Annotations:
+//
+//	bufplugin-run --plugin ./buf-plugin-timestamp-suffix -I proto foo/v1/foo.proto
+//
+// See checkrun.Main for all flags.
+package main
+
+import (
+	"github.com/bufbuild/bufplugin-go/check/checkrun"
+)
+
+func main() {
+	checkrun.Main()
+}
@@ -25,6 +25,9 @@ var defaultParallelism = runtime.GOMAXPROCS(0)
 
 // Parallelize runs the jobs in parallel.
 //
+// No new jobs are started once the context is done, in which case the context's error is
+// included in the returned error. Jobs that have already started are waited for.
+//
 // Returns the combined error from the jobs.
 func Parallelize(ctx context.Context, jobs []func(context.Context) error, options ...ParallelizeOption) error {
 	parallelizeOptions := newParallelizeOptions()
@@ -35,6 +38,9 @@ func Parallelize(ctx context.Context, jobs []func(context.Context) error, option
 	case 0:
 		return nil
 	case 1:
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		return jobs[0](ctx)
 	}
 	parallelism := parallelizeOptions.parallelism
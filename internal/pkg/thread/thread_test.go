@@ -65,3 +65,19 @@ func TestParallelizeImmediateCancellation(t *testing.T) {
 	assert.Error(t, Parallelize(ctx, jobs))
 	assert.Equal(t, int64(0), executed.Load())
 }
+
+func TestParallelizeSingleJobImmediateCancellation(t *testing.T) {
+	t.Parallel()
+
+	var executed atomic.Int64
+	jobs := []func(context.Context) error{
+		func(context.Context) error {
+			executed.Add(1)
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, Parallelize(ctx, jobs), context.Canceled)
+	assert.Equal(t, int64(0), executed.Load())
+}